@@ -0,0 +1,509 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/omnitrix-sh/cli/internal/config"
+	"github.com/omnitrix-sh/cli/internal/llm/tools"
+	"github.com/omnitrix-sh/cli/internal/lsp"
+	"github.com/spf13/cobra"
+)
+
+// lspStartTimeout bounds the initialization of a server started from the
+// command line
+const lspStartTimeout = 30 * time.Second
+
+var lspCmd = &cobra.Command{
+	Use:   "lsp",
+	Short: "Manage language servers",
+	Long: `Inspect and configure the language servers used for code intelligence.
+
+The subcommands start the servers configured for the project, run a query
+against them and stop them again, which helps debugging the lsp configuration
+without the TUI. Use --server to limit them to some servers and --trace to
+print the JSON-RPC traffic to stderr.`,
+}
+
+var lspStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show the state and capabilities of the language servers",
+	Example: `
+  # Show the state and capabilities of all configured servers
+  omnitrix lsp status
+
+  # Print the full capabilities announced by gopls
+  omnitrix lsp status --server gopls --json`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		asJSON, _ := cmd.Flags().GetBool("json")
+		return withLSPClients(cmd, func(ctx context.Context, clients *lsp.Clients) error {
+			cfg := config.Get()
+			for _, name := range selectedServers(cmd) {
+				lspCfg := cfg.LSP[name]
+				command := strings.Join(append([]string{lspCfg.Command}, lspCfg.Args...), " ")
+				client, ok := clients.Get(name)
+				switch {
+				case lspCfg.Disabled:
+					fmt.Printf("%s: disabled (%s)\n", name, command)
+					continue
+				case lspCfg.Command == "":
+					fmt.Printf("%s: formatter only\n", name)
+					continue
+				case !ok:
+					fmt.Printf("%s: failed to start (%s)\n", name, command)
+					continue
+				}
+
+				fmt.Printf("%s: %s (%s)\n", name, client.GetServerState(), command)
+				if asJSON {
+					data, err := json.MarshalIndent(client.ServerCapabilities(), "", "  ")
+					if err != nil {
+						return fmt.Errorf("failed to marshal capabilities: %w", err)
+					}
+					fmt.Println(string(data))
+				} else {
+					fmt.Printf("  capabilities: %s\n", strings.Join(capabilityNames(client), ", "))
+				}
+			}
+			return nil
+		})
+	},
+}
+
+var lspDiagnosticsCmd = &cobra.Command{
+	Use:   "diagnostics [file]",
+	Short: "Show diagnostics for a file or the whole workspace",
+	Example: `
+  # Diagnostics for a file
+  omnitrix lsp diagnostics internal/app/app.go
+
+  # Diagnostics published for the workspace within 10 seconds
+  omnitrix lsp diagnostics --wait 10s`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		wait, _ := cmd.Flags().GetDuration("wait")
+		return withLSPClients(cmd, func(ctx context.Context, clients *lsp.Clients) error {
+			params := tools.DiagnosticsParams{}
+			if len(args) > 0 {
+				path, err := filepath.Abs(args[0])
+				if err != nil {
+					return err
+				}
+				params.FilePath = path
+			} else {
+				// Servers publish workspace diagnostics once they have loaded
+				// the project
+				select {
+				case <-ctx.Done():
+					return ctx.Err()
+				case <-time.After(wait):
+				}
+			}
+			return runLSPTool(ctx, tools.NewDiagnosticsTool(clients), params)
+		})
+	},
+}
+
+var lspDefinitionCmd = &cobra.Command{
+	Use:   "definition file[:line[:column]]",
+	Short: "Find the definition of a symbol",
+	Example: `
+  # Definition of the symbol at line 42, column 10
+  omnitrix lsp definition internal/app/app.go:42:10
+
+  # Definition of the first occurrence of New in the file
+  omnitrix lsp definition internal/app/app.go --symbol New`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		params, err := symbolPositionParams(cmd, args[0])
+		if err != nil {
+			return err
+		}
+		return withLSPClients(cmd, func(ctx context.Context, clients *lsp.Clients) error {
+			return runLSPTool(ctx, tools.NewDefinitionTool(clients), params)
+		})
+	},
+}
+
+var lspReferencesCmd = &cobra.Command{
+	Use:   "references file[:line[:column]]",
+	Short: "Find the references to a symbol",
+	Example: `
+  # References to the symbol at line 42, column 10
+  omnitrix lsp references internal/app/app.go:42:10
+
+  # References to New on line 42
+  omnitrix lsp references internal/app/app.go:42 --symbol New`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		params, err := symbolPositionParams(cmd, args[0])
+		if err != nil {
+			return err
+		}
+		return withLSPClients(cmd, func(ctx context.Context, clients *lsp.Clients) error {
+			return runLSPTool(ctx, tools.NewReferencesTool(clients), params)
+		})
+	},
+}
+
+var lspSymbolsCmd = &cobra.Command{
+	Use:   "symbols [file]",
+	Short: "List the symbols of a file or search the workspace",
+	Example: `
+  # Outline of a file
+  omnitrix lsp symbols internal/app/app.go
+
+  # Functions in the workspace matching a query
+  omnitrix lsp symbols --query NewClient --kind function`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		params := tools.SymbolsParams{}
+		params.Query, _ = cmd.Flags().GetString("query")
+		params.Kind, _ = cmd.Flags().GetString("kind")
+		if len(args) > 0 {
+			path, err := filepath.Abs(args[0])
+			if err != nil {
+				return err
+			}
+			params.FilePath = path
+		} else if params.Query == "" {
+			return errors.New("either a file or --query is required")
+		}
+		return withLSPClients(cmd, func(ctx context.Context, clients *lsp.Clients) error {
+			return runLSPTool(ctx, tools.NewSymbolsTool(clients), params)
+		})
+	},
+}
+
+var lspTraceCmd = &cobra.Command{
+	Use:   "trace [file...]",
+	Short: "Print the JSON-RPC traffic of the language servers",
+	Long: `Start the language servers, open the given files in them and print the
+JSON-RPC messages exchanged with them until interrupted.`,
+	Example: `
+  # Follow gopls while it loads the workspace and checks a file
+  omnitrix lsp trace --server gopls main.go`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return withLSPClientsTraced(cmd, os.Stdout, func(ctx context.Context, clients *lsp.Clients) error {
+			if clients.Len() == 0 {
+				return errors.New("no language server could be started")
+			}
+			for _, arg := range args {
+				path, err := filepath.Abs(arg)
+				if err != nil {
+					return err
+				}
+				for name, client := range clients.Snapshot() {
+					if !client.HandlesFile(path) {
+						continue
+					}
+					if err := client.OpenFile(ctx, path); err != nil {
+						fmt.Fprintf(os.Stderr, "%s: failed to open %s: %s\n", name, arg, err)
+					}
+				}
+			}
+			<-ctx.Done()
+			return nil
+		})
+	},
+}
+
+var lspDetectCmd = &cobra.Command{
+	Use:   "detect",
+	Short: "Detect the project's languages and enable their language servers",
+	Long: `Detect the languages used in the project from files like go.mod, package.json,
+Cargo.toml and pyproject.toml, and look up their language servers on PATH.
+
+The servers found are added to the lsp section of the project's .omnitrix.json
+after confirmation. Install hints are shown for servers that are missing.`,
+	Example: `
+  # Detect servers and confirm before enabling them
+  omnitrix lsp detect
+
+  # Enable all detected servers without asking
+  omnitrix lsp detect --yes`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := loadCommandConfig(cmd); err != nil {
+			return err
+		}
+		yes, _ := cmd.Flags().GetBool("yes")
+
+		detected := lsp.DetectServers(config.WorkingDirectory())
+		if len(detected) == 0 {
+			fmt.Println("No languages with a known language server were detected")
+			return nil
+		}
+
+		servers := make(map[string]config.LSPConfig)
+		var names []string
+		for _, d := range detected {
+			switch {
+			case d.Configured:
+				fmt.Printf("%s (%s): already configured\n", d.Language, d.Marker)
+			case d.Command == "":
+				fmt.Printf("%s (%s): no language server found, install one with: %s\n", d.Language, d.Marker, d.Install)
+			default:
+				fmt.Printf("%s (%s): %s\n", d.Language, d.Marker, strings.Join(append([]string{d.Command}, d.Args...), " "))
+				servers[d.Name] = d.Config()
+				names = append(names, d.Name)
+			}
+		}
+		if len(servers) == 0 {
+			return nil
+		}
+
+		if !yes && !confirm(fmt.Sprintf("Enable %s in .omnitrix.json? [Y/n] ", strings.Join(names, ", "))) {
+			return nil
+		}
+		if err := config.AddLSPServers(servers); err != nil {
+			return fmt.Errorf("failed to enable language servers: %w", err)
+		}
+		fmt.Printf("Enabled %s\n", strings.Join(names, ", "))
+		return nil
+	},
+}
+
+// withLSPClients starts the selected language servers, runs fn with them and
+// stops them again. Interrupting the command cancels the context passed to fn.
+func withLSPClients(cmd *cobra.Command, fn func(ctx context.Context, clients *lsp.Clients) error) error {
+	var trace io.Writer
+	if enabled, _ := cmd.Flags().GetBool("trace"); enabled {
+		trace = os.Stderr
+	}
+	return withLSPClientsTraced(cmd, trace, fn)
+}
+
+// withLSPClientsTraced is withLSPClients with the JSON-RPC traffic written to
+// trace, if not nil
+func withLSPClientsTraced(cmd *cobra.Command, trace io.Writer, fn func(ctx context.Context, clients *lsp.Clients) error) error {
+	if err := loadCommandConfig(cmd); err != nil {
+		return err
+	}
+	names := selectedServers(cmd)
+	if len(names) == 0 {
+		return errors.New("no language servers configured, run 'omnitrix lsp detect' to set them up")
+	}
+
+	ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	clients := lsp.NewClients()
+	defer func() {
+		for _, client := range clients.Snapshot() {
+			client.Close()
+		}
+	}()
+
+	cfg := config.Get()
+	for _, name := range names {
+		lspCfg := cfg.LSP[name]
+		if lspCfg.Disabled || lspCfg.Command == "" {
+			continue
+		}
+		client, err := startLSPClient(ctx, name, lspCfg, trace)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", name, err)
+			continue
+		}
+		clients.Set(name, client)
+	}
+	return fn(ctx, clients)
+}
+
+// startLSPClient starts and initializes a language server
+func startLSPClient(ctx context.Context, name string, lspCfg config.LSPConfig, trace io.Writer) (*lsp.Client, error) {
+	client, err := lsp.NewClient(ctx, lspCfg.Command, lspCfg.Args...)
+	if err != nil {
+		return nil, err
+	}
+	client.SetName(name)
+	if trace != nil {
+		client.SetTraceWriter(trace)
+	}
+
+	initCtx, cancel := context.WithTimeout(ctx, lspStartTimeout)
+	defer cancel()
+
+	if _, err := client.InitializeLSPClient(initCtx, config.WorkingDirectory()); err != nil {
+		client.Close()
+		return nil, err
+	}
+	if err := client.WaitForServerReady(initCtx); err != nil {
+		fmt.Fprintf(os.Stderr, "%s: server not ready: %s\n", name, err)
+		client.SetServerState(lsp.StateError)
+	} else {
+		client.SetServerState(lsp.StateReady)
+	}
+	return client, nil
+}
+
+// selectedServers returns the names of the servers given with --server, or
+// of all configured servers, sorted
+func selectedServers(cmd *cobra.Command) []string {
+	cfg := config.Get()
+	selected, _ := cmd.Flags().GetStringSlice("server")
+
+	var names []string
+	for name := range cfg.LSP {
+		if len(selected) == 0 || slices.Contains(selected, name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// runLSPTool runs one of the agent's LSP tools and prints its output
+func runLSPTool(ctx context.Context, tool tools.BaseTool, params any) error {
+	input, err := json.Marshal(params)
+	if err != nil {
+		return fmt.Errorf("failed to marshal parameters: %w", err)
+	}
+	response, err := tool.Run(ctx, tools.ToolCall{Input: string(input)})
+	if err != nil {
+		return err
+	}
+	if response.IsError {
+		return errors.New(response.Content)
+	}
+	if strings.TrimSpace(response.Content) == "" {
+		fmt.Println("No results")
+		return nil
+	}
+	fmt.Println(response.Content)
+	return nil
+}
+
+// symbolPositionParams parses a file[:line[:column]] location and the
+// --symbol flag
+func symbolPositionParams(cmd *cobra.Command, location string) (tools.SymbolPositionParams, error) {
+	params := tools.SymbolPositionParams{}
+	params.Symbol, _ = cmd.Flags().GetString("symbol")
+
+	path := location
+	var numbers []int
+	for range 2 {
+		i := strings.LastIndex(path, ":")
+		if i < 0 {
+			break
+		}
+		n, err := strconv.Atoi(path[i+1:])
+		if err != nil {
+			break
+		}
+		numbers = append([]int{n}, numbers...)
+		path = path[:i]
+	}
+	if len(numbers) > 0 {
+		params.Line = numbers[0]
+	}
+	if len(numbers) > 1 {
+		params.Column = numbers[1]
+	}
+	if params.Line <= 0 && params.Symbol == "" {
+		return params, errors.New("a line (file:line[:column]) or --symbol is required")
+	}
+
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return params, err
+	}
+	params.FilePath = abs
+	return params, nil
+}
+
+// capabilityNames lists the providers announced by a server
+func capabilityNames(client *lsp.Client) []string {
+	data, err := json.Marshal(client.ServerCapabilities())
+	if err != nil {
+		return nil
+	}
+	var caps map[string]json.RawMessage
+	if err := json.Unmarshal(data, &caps); err != nil {
+		return nil
+	}
+
+	var names []string
+	for key, value := range caps {
+		if !strings.HasSuffix(key, "Provider") || string(value) == "false" || string(value) == "null" {
+			continue
+		}
+		names = append(names, strings.TrimSuffix(key, "Provider"))
+	}
+	sort.Strings(names)
+	return names
+}
+
+// loadCommandConfig loads the configuration for subcommands that run
+// without the TUI
+func loadCommandConfig(cmd *cobra.Command) error {
+	cwd, _ := cmd.Flags().GetString("cwd")
+	debug, _ := cmd.Flags().GetBool("debug")
+
+	if cwd == "" {
+		var err error
+		cwd, err = os.Getwd()
+		if err != nil {
+			return fmt.Errorf("failed to get current working directory: %v", err)
+		}
+	}
+
+	if _, err := config.Load(cwd, debug); err != nil {
+		return fmt.Errorf("failed to load config: %v", err)
+	}
+	return nil
+}
+
+// confirm asks a yes/no question on the terminal, defaulting to yes
+func confirm(question string) bool {
+	fmt.Print(question)
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && answer == "" {
+		return false
+	}
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "", "y", "yes":
+		return true
+	}
+	return false
+}
+
+func init() {
+	lspDetectCmd.Flags().BoolP("yes", "y", false, "Enable the detected servers without asking")
+
+	lspCmd.PersistentFlags().StringSliceP("server", "s", nil, "Only use the named servers from the lsp configuration")
+	lspCmd.PersistentFlags().Bool("trace", false, "Print the JSON-RPC traffic to stderr")
+
+	lspStatusCmd.Flags().Bool("json", false, "Print the full capabilities as JSON")
+	lspDiagnosticsCmd.Flags().Duration("wait", 3*time.Second, "How long to wait for workspace diagnostics")
+	lspDefinitionCmd.Flags().String("symbol", "", "Name of the symbol, on the given line or its first occurrence")
+	lspReferencesCmd.Flags().String("symbol", "", "Name of the symbol, on the given line or its first occurrence")
+	lspSymbolsCmd.Flags().String("query", "", "Search the workspace for symbols matching the query")
+	lspSymbolsCmd.Flags().String("kind", "", "Only list symbols of this kind, like function or struct")
+
+	lspCmd.AddCommand(
+		lspDetectCmd,
+		lspStatusCmd,
+		lspDiagnosticsCmd,
+		lspDefinitionCmd,
+		lspReferencesCmd,
+		lspSymbolsCmd,
+		lspTraceCmd,
+	)
+	rootCmd.AddCommand(lspCmd)
+}
@@ -14,7 +14,11 @@ import (
 	"github.com/omnitrix-sh/cli/internal/db"
 	"github.com/omnitrix-sh/cli/internal/format"
 	"github.com/omnitrix-sh/cli/internal/llm/agent"
+	"github.com/omnitrix-sh/cli/internal/llm/models"
+	"github.com/omnitrix-sh/cli/internal/llm/provider"
+	"github.com/omnitrix-sh/cli/internal/llm/tools"
 	"github.com/omnitrix-sh/cli/internal/logging"
+	"github.com/omnitrix-sh/cli/internal/lsp"
 	"github.com/omnitrix-sh/cli/internal/pubsub"
 	"github.com/omnitrix-sh/cli/internal/tui"
 	"github.com/omnitrix-sh/cli/internal/version"
@@ -254,7 +258,12 @@ func setupSubscriptions(app *app.App, parentCtx context.Context) (chan tea.Msg,
 	setupSubscriber(ctx, &wg, "sessions", app.Sessions.Subscribe, ch)
 	setupSubscriber(ctx, &wg, "messages", app.Messages.Subscribe, ch)
 	setupSubscriber(ctx, &wg, "permissions", app.Permissions.Subscribe, ch)
+	setupSubscriber(ctx, &wg, "budgets", app.Budgets.Subscribe, ch)
 	setupSubscriber(ctx, &wg, "coderAgent", app.CoderAgent.Subscribe, ch)
+	setupSubscriber(ctx, &wg, "fileDrift", tools.SubscribeFileDrift, ch)
+	setupSubscriber(ctx, &wg, "lspStatus", lsp.SubscribeStatus, ch)
+	setupSubscriber(ctx, &wg, "retryStatus", provider.SubscribeRetryStatus, ch)
+	setupSubscriber(ctx, &wg, "ollamaPulls", models.SubscribeOllamaPulls, ch)
 
 	cleanupFunc := func() {
 		logging.Info("Cancelling all subscriptions")
@@ -289,8 +298,9 @@ func Execute() {
 func init() {
 	rootCmd.Flags().BoolP("help", "h", false, "Help")
 	rootCmd.Flags().BoolP("version", "v", false, "Version")
-	rootCmd.Flags().BoolP("debug", "d", false, "Debug")
-	rootCmd.Flags().StringP("cwd", "c", "", "Current working directory")
+	// Subcommands load the configuration of the working directory as well
+	rootCmd.PersistentFlags().BoolP("debug", "d", false, "Debug")
+	rootCmd.PersistentFlags().StringP("cwd", "c", "", "Current working directory")
 	rootCmd.Flags().StringP("prompt", "p", "", "Prompt to run in non-interactive mode")
 
 	// Add format flag with validation logic
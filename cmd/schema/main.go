@@ -114,6 +114,34 @@ func generateSchema() map[string]any {
 		},
 	}
 
+	budgetLimit := func(description string) map[string]any {
+		return map[string]any{
+			"type":        "object",
+			"description": description,
+			"properties": map[string]any{
+				"cost": map[string]any{
+					"type":        "number",
+					"description": "Maximum spend in dollars",
+					"minimum":     0,
+				},
+				"tokens": map[string]any{
+					"type":        "integer",
+					"description": "Maximum number of input, output and cached tokens",
+					"minimum":     0,
+				},
+			},
+		}
+	}
+	schema["properties"].(map[string]any)["budget"] = map[string]any{
+		"type":        "object",
+		"description": "Spend and token caps, the agent asks before going over them",
+		"properties": map[string]any{
+			"session": budgetLimit("Caps for a single session"),
+			"daily":   budgetLimit("Caps for the sessions of the project active today"),
+			"project": budgetLimit("Caps for all the sessions of the project"),
+		},
+	}
+
 	// Add MCP servers
 	schema["properties"].(map[string]any)["mcpServers"] = map[string]any{
 		"type":        "object",
@@ -179,6 +207,22 @@ func generateSchema() map[string]any {
 					"description": "Whether the provider is disabled",
 					"default":     false,
 				},
+				"maxRetries": map[string]any{
+					"type":        "integer",
+					"description": "How many times a request failing with a rate limit or server error is retried",
+					"default":     8,
+					"minimum":     0,
+				},
+				"requestsPerMinute": map[string]any{
+					"type":        "integer",
+					"description": "Maximum number of requests sent to the provider per minute, unlimited if not set",
+					"minimum":     1,
+				},
+				"responsesAPI": map[string]any{
+					"type":        "boolean",
+					"description": "Use the Responses API for OpenAI models, which shows the reasoning of reasoning models",
+					"default":     false,
+				},
 			},
 		},
 	}
@@ -193,6 +237,7 @@ func generateSchema() map[string]any {
 		string(models.ProviderBedrock),
 		string(models.ProviderAzure),
 		string(models.ProviderVertexAI),
+		string(models.ProviderOllama),
 	}
 
 	providerSchema["additionalProperties"].(map[string]any)["properties"].(map[string]any)["provider"] = map[string]any{
@@ -213,7 +258,7 @@ func generateSchema() map[string]any {
 			"properties": map[string]any{
 				"model": map[string]any{
 					"type":        "string",
-					"description": "Model ID for the agent",
+					"description": "Model ID for the agent, a built-in model or one declared in models",
 				},
 				"maxTokens": map[string]any{
 					"type":        "integer",
@@ -225,6 +270,13 @@ func generateSchema() map[string]any {
 					"description": "Reasoning effort for models that support it (OpenAI, Anthropic)",
 					"enum":        []string{"low", "medium", "high"},
 				},
+				"fallbacks": map[string]any{
+					"type":        "array",
+					"description": "Model IDs tried in order when the model fails",
+					"items": map[string]any{
+						"type": "string",
+					},
+				},
 			},
 			"required": []string{"model"},
 		},
@@ -235,7 +287,11 @@ func generateSchema() map[string]any {
 	for modelID := range models.SupportedModels {
 		modelEnum = append(modelEnum, string(modelID))
 	}
-	agentSchema["additionalProperties"].(map[string]any)["properties"].(map[string]any)["model"].(map[string]any)["enum"] = modelEnum
+	// Models declared in the configuration are allowed as well
+	agentSchema["additionalProperties"].(map[string]any)["properties"].(map[string]any)["model"].(map[string]any)["anyOf"] = []map[string]any{
+		{"enum": modelEnum},
+		{"type": "string"},
+	}
 
 	// Add specific agent properties
 	agentProperties := map[string]any{}
@@ -264,6 +320,99 @@ func generateSchema() map[string]any {
 		"agent": agentSchema["additionalProperties"],
 	}
 
+	// Add custom providers
+	schema["properties"].(map[string]any)["customProviders"] = map[string]any{
+		"type":        "object",
+		"description": "OpenAI or Anthropic compatible endpoints that models can be declared for",
+		"additionalProperties": map[string]any{
+			"type":        "object",
+			"description": "Custom provider configuration",
+			"properties": map[string]any{
+				"type": map[string]any{
+					"type":        "string",
+					"description": "API the endpoint is compatible with",
+					"enum":        []string{string(config.CustomProviderOpenAI), string(config.CustomProviderAnthropic)},
+					"default":     string(config.CustomProviderOpenAI),
+				},
+				"baseURL": map[string]any{
+					"type":        "string",
+					"description": "Base URL of the API",
+				},
+				"apiKeyEnv": map[string]any{
+					"type":        "string",
+					"description": "Environment variable holding the API key",
+				},
+				"headers": map[string]any{
+					"type":        "object",
+					"description": "HTTP headers sent with every request",
+					"additionalProperties": map[string]any{
+						"type": "string",
+					},
+				},
+			},
+			"required": []string{"baseURL"},
+		},
+	}
+
+	// Add model declarations
+	schema["properties"].(map[string]any)["models"] = map[string]any{
+		"type":        "object",
+		"description": "Models in addition to the built-in ones, keyed by model ID",
+		"additionalProperties": map[string]any{
+			"type":        "object",
+			"description": "Model configuration",
+			"properties": map[string]any{
+				"provider": map[string]any{
+					"type":        "string",
+					"description": "Built-in or custom provider serving the model",
+				},
+				"name": map[string]any{
+					"type":        "string",
+					"description": "Display name of the model",
+				},
+				"apiModel": map[string]any{
+					"type":        "string",
+					"description": "Model name sent to the provider, the model ID by default",
+				},
+				"contextWindow": map[string]any{
+					"type":        "integer",
+					"description": "Context window in tokens",
+				},
+				"defaultMaxTokens": map[string]any{
+					"type":        "integer",
+					"description": "Default maximum output tokens",
+				},
+				"costPer1MIn": map[string]any{
+					"type":        "number",
+					"description": "Cost per million input tokens",
+				},
+				"costPer1MOut": map[string]any{
+					"type":        "number",
+					"description": "Cost per million output tokens",
+				},
+				"costPer1MInCached": map[string]any{
+					"type":        "number",
+					"description": "Cost per million input tokens written to the cache",
+				},
+				"costPer1MOutCached": map[string]any{
+					"type":        "number",
+					"description": "Cost per million input tokens read from the cache",
+				},
+				"canReason": map[string]any{
+					"type":        "boolean",
+					"description": "Whether the model supports reasoning",
+					"default":     false,
+				},
+				"supportsAttachments": map[string]any{
+					"type":        "boolean",
+					"description": "Whether the model accepts image attachments",
+					"default":     false,
+				},
+			},
+			"required": []string{"provider"},
+		},
+	}
+
 	// Add LSP configuration
 	schema["properties"].(map[string]any)["lsp"] = map[string]any{
 		"type":        "object",
@@ -292,6 +441,32 @@ func generateSchema() map[string]any {
 					"type":        "object",
 					"description": "Additional options for the LSP server",
 				},
+				"filetypes": map[string]any{
+					"type":        "array",
+					"description": "Language IDs or file extensions the LSP server handles",
+					"items": map[string]any{
+						"type": "string",
+					},
+				},
+				"rootMarkers": map[string]any{
+					"type":        "array",
+					"description": "Files of which one must exist in a directory above a file for it to be sent to the LSP server",
+					"items": map[string]any{
+						"type": "string",
+					},
+				},
+				"formatOnWrite": map[string]any{
+					"type":        "boolean",
+					"description": "Format files of this language after the agent changes them",
+					"default":     false,
+				},
+				"formatter": map[string]any{
+					"type":        "array",
+					"description": "Formatter command used instead of the LSP server, reading the content on stdin. {file} in its arguments is replaced with the file path",
+					"items": map[string]any{
+						"type": "string",
+					},
+				},
 			},
 			"required": []string{"command"},
 		},
@@ -0,0 +1,156 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/omnitrix-sh/cli/internal/db"
+	"github.com/omnitrix-sh/cli/internal/usage"
+	"github.com/spf13/cobra"
+)
+
+var usageCmd = &cobra.Command{
+	Use:   "usage",
+	Short: "Report the tokens and the spend of the sessions",
+	Long: `Report the tokens and the spend of the sessions stored for the project.
+
+The report counts the input, output and cached tokens and the cost of the
+sessions created in the given dates, including the sessions of task agents,
+and lists the most expensive sessions. It also compares the models that
+answered in the given dates, with their average latency and time to first
+token, and lists the most expensive turns. Sessions can be filtered by the
+model that answered in them, which limits the totals to the responses of that
+model, and by the project they were created in, which matters when several
+projects share a data directory.`,
+	Example: `
+  # Usage of all sessions
+  omnitrix usage
+
+  # Usage of September as CSV, one row per session
+  omnitrix usage --month 2026-09 --format csv --top 0 > september.csv
+
+  # Cost and latency of each model as CSV, one row per model
+  omnitrix usage --format csv --by model
+
+  # Usage of a model in the last days as JSON
+  omnitrix usage --since 2026-10-01 --model claude-3.7-sonnet --format json`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		filter, err := usageFilter(cmd)
+		if err != nil {
+			return err
+		}
+		outputFormat, _ := cmd.Flags().GetString("format")
+		top, _ := cmd.Flags().GetInt("top")
+		if top < 0 {
+			return fmt.Errorf("invalid top: %d", top)
+		}
+		by, _ := cmd.Flags().GetString("by")
+		rows := usage.CSVRows(by)
+		switch rows {
+		case usage.SessionRows, usage.ModelRows, usage.TurnRows:
+		default:
+			return fmt.Errorf("invalid rows: %s, use session, model or turn", by)
+		}
+
+		if err := loadCommandConfig(cmd); err != nil {
+			return err
+		}
+		conn, err := db.Connect()
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+
+		report, err := usage.Generate(cmd.Context(), db.New(conn), filter, top)
+		if err != nil {
+			return err
+		}
+		switch outputFormat {
+		case "json":
+			return report.WriteJSON(os.Stdout)
+		case "csv":
+			return report.WriteCSV(os.Stdout, rows)
+		default:
+			return report.WriteText(os.Stdout)
+		}
+	},
+}
+
+// usageFilter builds the filter of the report from the flags. Dates are
+// local days and the until day is included.
+func usageFilter(cmd *cobra.Command) (usage.Filter, error) {
+	var filter usage.Filter
+	since, _ := cmd.Flags().GetString("since")
+	until, _ := cmd.Flags().GetString("until")
+	month, _ := cmd.Flags().GetString("month")
+	filter.Model, _ = cmd.Flags().GetString("model")
+	project, _ := cmd.Flags().GetString("project")
+	outputFormat, _ := cmd.Flags().GetString("format")
+
+	switch outputFormat {
+	case "text", "json", "csv":
+	default:
+		return filter, fmt.Errorf("invalid format: %s, use text, json or csv", outputFormat)
+	}
+
+	if month != "" {
+		if since != "" || until != "" {
+			return filter, fmt.Errorf("--month cannot be used with --since or --until")
+		}
+		start, err := time.ParseInLocation("2006-01", month, time.Local)
+		if err != nil {
+			return filter, fmt.Errorf("invalid month %q, use YYYY-MM", month)
+		}
+		filter.Since = start
+		filter.Until = start.AddDate(0, 1, 0)
+	}
+	if since != "" {
+		day, err := time.ParseInLocation("2006-01-02", since, time.Local)
+		if err != nil {
+			return filter, fmt.Errorf("invalid since date %q, use YYYY-MM-DD", since)
+		}
+		filter.Since = day
+	}
+	if until != "" {
+		day, err := time.ParseInLocation("2006-01-02", until, time.Local)
+		if err != nil {
+			return filter, fmt.Errorf("invalid until date %q, use YYYY-MM-DD", until)
+		}
+		filter.Until = day.AddDate(0, 0, 1)
+	}
+	if !filter.Since.IsZero() && !filter.Until.IsZero() && !filter.Until.After(filter.Since) {
+		return filter, fmt.Errorf("the until date is before the since date")
+	}
+
+	if project != "" {
+		path, err := filepath.Abs(project)
+		if err != nil {
+			return filter, fmt.Errorf("invalid project path: %w", err)
+		}
+		filter.Project = path
+	}
+	return filter, nil
+}
+
+func init() {
+	usageCmd.Flags().String("since", "", "Only count sessions created on or after this day (YYYY-MM-DD)")
+	usageCmd.Flags().String("until", "", "Only count sessions created on or before this day (YYYY-MM-DD)")
+	usageCmd.Flags().String("month", "", "Only count sessions created in this month (YYYY-MM)")
+	usageCmd.Flags().String("model", "", "Only count sessions in which this model answered, and its responses in the totals")
+	usageCmd.Flags().String("project", "", "Only count sessions created in this project directory")
+	usageCmd.Flags().StringP("format", "f", "text", "Output format (text, json, csv)")
+	usageCmd.Flags().Int("top", 10, "Number of most expensive sessions and turns to list, 0 for all")
+	usageCmd.Flags().String("by", "session", "Rows of the CSV output (session, model, turn)")
+
+	usageCmd.RegisterFlagCompletionFunc("format", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return []string{"text", "json", "csv"}, cobra.ShellCompDirectiveNoFileComp
+	})
+	usageCmd.RegisterFlagCompletionFunc("by", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return []string{"session", "model", "turn"}, cobra.ShellCompDirectiveNoFileComp
+	})
+
+	rootCmd.AddCommand(usageCmd)
+}
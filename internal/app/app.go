@@ -5,17 +5,19 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
-	"maps"
 	"sync"
 	"time"
 
+	"github.com/omnitrix-sh/cli/internal/budget"
 	"github.com/omnitrix-sh/cli/internal/config"
 	"github.com/omnitrix-sh/cli/internal/db"
 	"github.com/omnitrix-sh/cli/internal/format"
 	"github.com/omnitrix-sh/cli/internal/history"
 	"github.com/omnitrix-sh/cli/internal/llm/agent"
+	"github.com/omnitrix-sh/cli/internal/llm/tools"
 	"github.com/omnitrix-sh/cli/internal/logging"
 	"github.com/omnitrix-sh/cli/internal/lsp"
+	"github.com/omnitrix-sh/cli/internal/lsp/watcher"
 	"github.com/omnitrix-sh/cli/internal/message"
 	"github.com/omnitrix-sh/cli/internal/permission"
 	"github.com/omnitrix-sh/cli/internal/session"
@@ -27,12 +29,15 @@ type App struct {
 	Messages    message.Service
 	History     history.Service
 	Permissions permission.Service
+	Budgets     budget.Service
 
 	CoderAgent agent.Service
 
-	LSPClients map[string]*lsp.Client
+	LSPClients *lsp.Clients
 
-	clientsMutex sync.RWMutex
+	// workspace is the file watcher shared by the change watcher and the
+	// workspace watchers of the language servers
+	workspace *watcher.Workspace
 
 	watcherCancelFuncs []context.CancelFunc
 	cancelFuncsMutex   sync.Mutex
@@ -50,7 +55,9 @@ func New(ctx context.Context, conn *sql.DB) (*App, error) {
 		Messages:    messages,
 		History:     files,
 		Permissions: permission.NewPermissionService(),
-		LSPClients:  make(map[string]*lsp.Client),
+		Budgets:     budget.NewService(sessions),
+		LSPClients:  lsp.NewClients(),
+		workspace:   watcher.NewWorkspace(config.WorkingDirectory()),
 	}
 
 	// Initialize theme based on configuration
@@ -59,18 +66,17 @@ func New(ctx context.Context, conn *sql.DB) (*App, error) {
 	// Initialize LSP clients in the background
 	go app.initLSPClients(ctx)
 
+	// Watch the workspace for the language servers and for changes made
+	// outside of the session
+	app.startWorkspaceWatcher(ctx)
+
 	var err error
 	app.CoderAgent, err = agent.NewAgent(
 		config.AgentCoder,
 		app.Sessions,
 		app.Messages,
-		agent.CoderAgentTools(
-			app.Permissions,
-			app.Sessions,
-			app.Messages,
-			app.History,
-			app.LSPClients,
-		),
+		app.Budgets,
+		app.coderAgentTools(),
 	)
 	if err != nil {
 		logging.Error("Failed to create coder agent", err)
@@ -80,6 +86,38 @@ func New(ctx context.Context, conn *sql.DB) (*App, error) {
 	return app, nil
 }
 
+func (app *App) coderAgentTools() []tools.BaseTool {
+	return agent.CoderAgentTools(
+		app.Permissions,
+		app.Sessions,
+		app.Messages,
+		app.Budgets,
+		app.History,
+		app.LSPClients,
+	)
+}
+
+// startWorkspaceWatcher watches the workspace for the workspace watchers of
+// the language servers, and reports files changed outside of the session to
+// the tools, which refuse to edit them until they are read again
+func (app *App) startWorkspaceWatcher(ctx context.Context) {
+	watchCtx, cancelFunc := context.WithCancel(ctx)
+
+	app.cancelFuncsMutex.Lock()
+	app.watcherCancelFuncs = append(app.watcherCancelFuncs, cancelFunc)
+	app.cancelFuncsMutex.Unlock()
+
+	app.watcherWG.Add(2)
+	go func() {
+		defer app.watcherWG.Done()
+		app.workspace.Watch(watchCtx)
+	}()
+	go func() {
+		defer app.watcherWG.Done()
+		watcher.NewChangeWatcher(tools.HandleExternalChange).Watch(watchCtx, app.workspace)
+	}()
+}
+
 // initTheme sets the application theme based on the configuration
 func (app *App) initTheme() {
 	cfg := config.Get()
@@ -127,6 +165,8 @@ func (a *App) RunNonInteractive(ctx context.Context, prompt string, outputFormat
 
 	// Automatically approve all permission requests for this non-interactive session
 	a.Permissions.AutoApproveSession(sess.ID)
+	// Nobody can confirm going over a budget cap, so the run stops instead
+	a.Budgets.RefuseSession(sess.ID)
 
 	done, err := a.CoderAgent.Run(ctx, sess.ID, prompt)
 	if err != nil {
@@ -171,12 +211,7 @@ func (app *App) Shutdown() {
 	app.watcherWG.Wait()
 
 	// Perform additional cleanup for LSP clients
-	app.clientsMutex.RLock()
-	clients := make(map[string]*lsp.Client, len(app.LSPClients))
-	maps.Copy(clients, app.LSPClients)
-	app.clientsMutex.RUnlock()
-
-	for name, client := range clients {
+	for name, client := range app.LSPClients.Snapshot() {
 		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 		if err := client.Shutdown(shutdownCtx); err != nil {
 			logging.Error("Failed to shutdown LSP client", "name", name, "error", err)
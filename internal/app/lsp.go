@@ -2,6 +2,8 @@ package app
 
 import (
 	"context"
+	"fmt"
+	"os"
 	"time"
 
 	"github.com/omnitrix-sh/cli/internal/config"
@@ -10,40 +12,155 @@ import (
 	"github.com/omnitrix-sh/cli/internal/lsp/watcher"
 )
 
+const (
+	// lspRestartBackoff is the delay before the first restart of a failed
+	// server, doubled for every further failure up to lspMaxRestartBackoff
+	lspRestartBackoff    = time.Second
+	lspMaxRestartBackoff = 30 * time.Second
+	// lspMaxRestarts is how many times in a row a server may fail before it
+	// is given up on
+	lspMaxRestarts = 5
+	// lspStableUptime is how long a server has to run for its failures to be
+	// forgotten
+	lspStableUptime = time.Minute
+)
+
 func (app *App) initLSPClients(ctx context.Context) {
 	cfg := config.Get()
 
 	// Initialize LSP clients
 	for name, clientConfig := range cfg.LSP {
-		// Start each client initialization in its own goroutine
-		go app.createAndStartLSPClient(ctx, name, clientConfig.Command, clientConfig.Args...)
+		app.startLSPClient(ctx, name, clientConfig)
 	}
 	logging.Info("LSP clients initialization started in background")
 }
 
-// createAndStartLSPClient creates a new LSP client, initializes it, and starts its workspace watcher
-func (app *App) createAndStartLSPClient(ctx context.Context, name string, command string, args ...string) {
-	// Create a specific context for initialization with a timeout
-	logging.Info("Creating LSP client", "name", name, "command", command, "args", args)
-	
+// StartLSPClients starts the servers added to the configuration after the
+// app was created, such as those enabled from the init dialog, and gives the
+// agent the LSP tools it only has when a server is configured. It fails when
+// the agent is busy, the tools are then added on the next start.
+func (app *App) StartLSPClients(ctx context.Context, names []string) error {
+	cfg := config.Get()
+	for _, name := range names {
+		if clientConfig, ok := cfg.LSP[name]; ok {
+			app.startLSPClient(ctx, name, clientConfig)
+		}
+	}
+	return app.CoderAgent.UpdateTools(app.coderAgentTools())
+}
+
+// startLSPClient starts the supervisor of a server in its own goroutine,
+// stopped on shutdown
+func (app *App) startLSPClient(ctx context.Context, name string, clientConfig config.LSPConfig) {
+	// Entries that only configure a formatter have no server to start
+	if clientConfig.Command == "" {
+		return
+	}
+
+	superviseCtx, cancelFunc := context.WithCancel(ctx)
+	app.cancelFuncsMutex.Lock()
+	app.watcherCancelFuncs = append(app.watcherCancelFuncs, cancelFunc)
+	app.cancelFuncsMutex.Unlock()
+
+	app.watcherWG.Add(1)
+	go app.superviseLSPClient(ctx, superviseCtx, name, clientConfig)
+}
+
+// superviseLSPClient keeps the server configured as name running. When its
+// process exits or the connection to it breaks, the server is restarted with
+// exponential backoff and the files open in the old server are opened in the
+// new one. Supervision ends when superviseCtx is canceled.
+func (app *App) superviseLSPClient(ctx, superviseCtx context.Context, name string, clientConfig config.LSPConfig) {
+	defer app.watcherWG.Done()
+
+	var backoff restartBackoff
+	var openFiles []string
+	for {
+		lsp.PublishServerStatus(name, lsp.StateStarting, nil)
+		var uptime time.Duration
+		lspClient, stopWatcher, err := app.createAndStartLSPClient(ctx, superviseCtx, name, clientConfig, openFiles)
+		if err != nil {
+			logging.Error("Failed to start LSP client", "name", name, "error", err)
+		} else {
+			started := time.Now()
+			select {
+			case <-superviseCtx.Done():
+				stopWatcher()
+				return
+			case <-lspClient.Done():
+			}
+			stopWatcher()
+			if superviseCtx.Err() != nil {
+				return
+			}
+
+			uptime = time.Since(started)
+			err = lspClient.Err()
+			openFiles = lspClient.OpenFilePaths()
+			app.removeLSPClient(name, lspClient)
+			logging.Warn("LSP server stopped", "name", name, "error", err)
+		}
+
+		delay, ok := backoff.next(uptime)
+		if !ok {
+			lsp.PublishServerStatus(name, lsp.StateError, err)
+			logging.ErrorPersist(fmt.Sprintf("LSP server %s failed %d times, giving up: %s", name, lspMaxRestarts, err))
+			return
+		}
+
+		lsp.PublishServerStatus(name, lsp.StateRestarting, err)
+		logging.Info("Restarting LSP server", "name", name, "attempt", backoff.failures, "backoff", delay)
+		select {
+		case <-superviseCtx.Done():
+			return
+		case <-time.After(delay):
+		}
+	}
+}
+
+// restartBackoff schedules the restarts of a failing server
+type restartBackoff struct {
+	// failures is the number of failures in a row
+	failures int
+}
+
+// next records a failure of a server that ran for uptime, zero when it did
+// not start, and returns how long to wait before restarting it. It returns
+// false when the server failed too often in a row and should be given up on.
+func (b *restartBackoff) next(uptime time.Duration) (time.Duration, bool) {
+	if uptime > lspStableUptime {
+		b.failures = 0
+	}
+	b.failures++
+	if b.failures > lspMaxRestarts {
+		return 0, false
+	}
+	return min(lspRestartBackoff<<(b.failures-1), lspMaxRestartBackoff), true
+}
+
+// createAndStartLSPClient creates a new LSP client, initializes it, opens
+// the given files and starts its workspace watcher. The returned function
+// stops the watcher.
+func (app *App) createAndStartLSPClient(ctx, superviseCtx context.Context, name string, clientConfig config.LSPConfig, openFiles []string) (*lsp.Client, context.CancelFunc, error) {
+	logging.Info("Creating LSP client", "name", name, "command", clientConfig.Command, "args", clientConfig.Args)
+
 	// Create the LSP client
-	lspClient, err := lsp.NewClient(ctx, command, args...)
+	lspClient, err := lsp.NewClient(ctx, clientConfig.Command, clientConfig.Args...)
 	if err != nil {
-		logging.Error("Failed to create LSP client for", name, err)
-		return
+		return nil, nil, err
 	}
+	lspClient.SetName(name)
 
 	// Create a longer timeout for initialization (some servers take time to start)
-	initCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	initCtx, cancel := context.WithTimeout(superviseCtx, 30*time.Second)
 	defer cancel()
-	
+
 	// Initialize with the initialization context
 	_, err = lspClient.InitializeLSPClient(initCtx, config.WorkingDirectory())
 	if err != nil {
-		logging.Error("Initialize failed", "name", name, "error", err)
 		// Clean up the client to prevent resource leaks
 		lspClient.Close()
-		return
+		return nil, nil, fmt.Errorf("initialize failed: %w", err)
 	}
 
 	// Wait for the server to be ready
@@ -57,70 +174,56 @@ func (app *App) createAndStartLSPClient(ctx context.Context, name string, comman
 	}
 
 	logging.Info("LSP client initialized", "name", name)
-	
-	// Create a child context that can be canceled when the app is shutting down
-	watchCtx, cancelFunc := context.WithCancel(ctx)
-	
+
+	// Reopen the files the previous server had open
+	for _, path := range openFiles {
+		if _, err := os.Stat(path); err != nil || !lspClient.HandlesFile(path) {
+			continue
+		}
+		if err := lspClient.OpenFile(superviseCtx, path); err != nil {
+			logging.Debug("Failed to reopen file after restart", "name", name, "file", path, "error", err)
+		}
+	}
+
+	// Create a child context that is canceled when the client stops
+	watchCtx, cancelFunc := context.WithCancel(superviseCtx)
+
 	// Create a context with the server name for better identification
 	watchCtx = context.WithValue(watchCtx, "serverName", name)
-	
+
 	// Create the workspace watcher
 	workspaceWatcher := watcher.NewWorkspaceWatcher(lspClient)
 
-	// Store the cancel function to be called during cleanup
-	app.cancelFuncsMutex.Lock()
-	app.watcherCancelFuncs = append(app.watcherCancelFuncs, cancelFunc)
-	app.cancelFuncsMutex.Unlock()
-
 	// Add the watcher to a WaitGroup to track active goroutines
 	app.watcherWG.Add(1)
 
-	// Add to map with mutex protection before starting goroutine
-	app.clientsMutex.Lock()
-	app.LSPClients[name] = lspClient
-	app.clientsMutex.Unlock()
+	// Register the client before starting goroutine
+	app.LSPClients.Set(name, lspClient)
 
-	go app.runWorkspaceWatcher(watchCtx, name, workspaceWatcher)
+	go app.runWorkspaceWatcher(watchCtx, name, lspClient, workspaceWatcher)
+	return lspClient, cancelFunc, nil
 }
 
 // runWorkspaceWatcher executes the workspace watcher for an LSP client
-func (app *App) runWorkspaceWatcher(ctx context.Context, name string, workspaceWatcher *watcher.WorkspaceWatcher) {
+func (app *App) runWorkspaceWatcher(ctx context.Context, name string, lspClient *lsp.Client, workspaceWatcher *watcher.WorkspaceWatcher) {
 	defer app.watcherWG.Done()
 	defer logging.RecoverPanic("LSP-"+name, func() {
-		// Try to restart the client
-		app.restartLSPClient(ctx, name)
+		// Stop the client, its supervisor starts a new one
+		lspClient.Close()
 	})
 
-	workspaceWatcher.WatchWorkspace(ctx, config.WorkingDirectory())
+	workspaceWatcher.WatchWorkspace(ctx, app.workspace)
 	logging.Info("Workspace watcher stopped", "client", name)
 }
 
-// restartLSPClient attempts to restart a crashed or failed LSP client
-func (app *App) restartLSPClient(ctx context.Context, name string) {
-	// Get the original configuration
-	cfg := config.Get()
-	clientConfig, exists := cfg.LSP[name]
-	if !exists {
-		logging.Error("Cannot restart client, configuration not found", "client", name)
-		return
-	}
-
-	// Clean up the old client if it exists
-	app.clientsMutex.Lock()
-	oldClient, exists := app.LSPClients[name]
-	if exists {
-		delete(app.LSPClients, name) // Remove from map before potentially slow shutdown
-	}
-	app.clientsMutex.Unlock()
-
-	if exists && oldClient != nil {
-		// Try to shut it down gracefully, but don't block on errors
-		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-		_ = oldClient.Shutdown(shutdownCtx)
-		cancel()
-	}
+// removeLSPClient removes a stopped client and cleans up what is left of its
+// process
+func (app *App) removeLSPClient(name string, lspClient *lsp.Client) {
+	app.LSPClients.Remove(name, lspClient)
 
-	// Create a new client using the shared function
-	app.createAndStartLSPClient(ctx, name, clientConfig.Command, clientConfig.Args...)
-	logging.Info("Successfully restarted LSP client", "client", name)
+	go func() {
+		if err := lspClient.Close(); err != nil {
+			logging.Debug("Error closing stopped LSP client", "name", name, "error", err)
+		}
+	}()
 }
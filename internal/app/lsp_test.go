@@ -0,0 +1,61 @@
+package app
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRestartBackoff(t *testing.T) {
+	type restart struct {
+		uptime time.Duration
+		delay  time.Duration
+		ok     bool
+	}
+	tests := []struct {
+		name     string
+		restarts []restart
+	}{
+		{
+			name: "doubles until the server is given up on",
+			restarts: []restart{
+				{0, time.Second, true},
+				{0, 2 * time.Second, true},
+				{0, 4 * time.Second, true},
+				{0, 8 * time.Second, true},
+				{0, 16 * time.Second, true},
+				{0, 0, false},
+			},
+		},
+		{
+			name: "short runs count as failures in a row",
+			restarts: []restart{
+				{0, time.Second, true},
+				{10 * time.Second, 2 * time.Second, true},
+				{lspStableUptime, 4 * time.Second, true},
+			},
+		},
+		{
+			name: "a stable run resets the schedule",
+			restarts: []restart{
+				{0, time.Second, true},
+				{0, 2 * time.Second, true},
+				{0, 4 * time.Second, true},
+				{2 * lspStableUptime, time.Second, true},
+				{0, 2 * time.Second, true},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var backoff restartBackoff
+			for i, r := range tt.restarts {
+				delay, ok := backoff.next(r.uptime)
+				assert.Equal(t, r.ok, ok, "restart %d", i+1)
+				assert.Equal(t, r.delay, delay, "restart %d", i+1)
+				assert.LessOrEqual(t, delay, lspMaxRestartBackoff)
+			}
+		})
+	}
+}
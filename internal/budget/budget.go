@@ -0,0 +1,239 @@
+package budget
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/omnitrix-sh/cli/internal/config"
+	"github.com/omnitrix-sh/cli/internal/pubsub"
+	"github.com/omnitrix-sh/cli/internal/session"
+)
+
+var ErrBudgetExceeded = errors.New("budget exceeded")
+
+// Scope is what a cap applies to
+type Scope string
+
+const (
+	ScopeSession Scope = "session"
+	ScopeDaily   Scope = "daily"
+	ScopeProject Scope = "project"
+)
+
+// Request asks whether the agent may go on with a request that would exceed
+// a cap
+type Request struct {
+	ID string `json:"id"`
+	// SessionID is the top level session, the one of the user for requests
+	// made by a task agent
+	SessionID string             `json:"session_id"`
+	Scope     Scope              `json:"scope"`
+	Limit     config.BudgetLimit `json:"limit"`
+	// Used is the usage counted against the cap so far and Estimate the
+	// usage expected from the request
+	Used     session.Usage `json:"used"`
+	Estimate session.Usage `json:"estimate"`
+}
+
+// Description explains which cap the request would exceed
+func (r Request) Description() string {
+	var of string
+	switch r.Scope {
+	case ScopeSession:
+		of = "this session"
+	case ScopeDaily:
+		of = "today"
+	default:
+		of = "this project"
+	}
+	total := r.Used.Add(r.Estimate)
+	if r.Limit.Cost > 0 && total.Cost > r.Limit.Cost {
+		return fmt.Sprintf("The next request would bring the spend of %s to $%.2f, over the cap of $%.2f", of, total.Cost, r.Limit.Cost)
+	}
+	return fmt.Sprintf("The next request would bring the tokens of %s to %d, over the cap of %d", of, total.Tokens, r.Limit.Tokens)
+}
+
+type Service interface {
+	pubsub.Suscriber[Request]
+	// Check returns an error wrapping ErrBudgetExceeded when a request of a
+	// session estimated at the given usage would exceed a cap and it is not
+	// approved. It blocks until the request is approved or denied.
+	Check(ctx context.Context, sessionID string, estimate session.Usage) error
+	// Approve lets the session exceed the cap of the request
+	Approve(request Request)
+	Deny(request Request)
+	// RefuseSession denies the requests of a session without asking, for
+	// sessions nobody can answer for
+	RefuseSession(sessionID string)
+}
+
+type budgetService struct {
+	*pubsub.Broker[Request]
+
+	sessions session.Service
+	now      func() time.Time
+
+	mu sync.Mutex
+	// approvals holds the caps each session was let go over, see approval
+	approvals       map[string][]string
+	refusedSessions []string
+	pendingRequests sync.Map
+}
+
+func (s *budgetService) Check(ctx context.Context, sessionID string, estimate session.Usage) error {
+	cfg := config.Get()
+	if cfg == nil || cfg.Budget == (config.BudgetConfig{}) {
+		return nil
+	}
+
+	sess, err := s.sessions.Get(ctx, sessionID)
+	if err != nil {
+		return fmt.Errorf("failed to get session: %w", err)
+	}
+	root := sess
+	// The usage of a task session is only added to its parent once the task
+	// is done
+	var pending session.Usage
+	if sess.ParentSessionID != "" {
+		root, err = s.sessions.Get(ctx, sess.ParentSessionID)
+		if err != nil {
+			return fmt.Errorf("failed to get parent session: %w", err)
+		}
+		pending = sess.Usage()
+	}
+
+	for _, scope := range []Scope{ScopeSession, ScopeDaily, ScopeProject} {
+		limit := limitOf(cfg.Budget, scope)
+		if limit == (config.BudgetLimit{}) {
+			continue
+		}
+		used, err := s.used(ctx, scope, root)
+		if err != nil {
+			return fmt.Errorf("failed to get %s usage: %w", scope, err)
+		}
+		used = used.Add(pending)
+		if !exceeds(limit, used, estimate) {
+			continue
+		}
+		err = s.confirm(ctx, Request{
+			ID:        uuid.New().String(),
+			SessionID: root.ID,
+			Scope:     scope,
+			Limit:     limit,
+			Used:      used,
+			Estimate:  estimate,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// used returns the usage counted against the caps of a scope, computed from
+// the stored sessions
+func (s *budgetService) used(ctx context.Context, scope Scope, root session.Session) (session.Usage, error) {
+	switch scope {
+	case ScopeSession:
+		return root.Usage(), nil
+	case ScopeDaily:
+		year, month, day := s.now().Date()
+		return s.sessions.Totals(ctx, time.Date(year, month, day, 0, 0, 0, 0, time.Local))
+	default:
+		return s.sessions.Totals(ctx, time.Unix(0, 0))
+	}
+}
+
+func (s *budgetService) confirm(ctx context.Context, request Request) error {
+	s.mu.Lock()
+	approved := slices.Contains(s.approvals[request.SessionID], s.approval(request.Scope))
+	refused := slices.Contains(s.refusedSessions, request.SessionID)
+	s.mu.Unlock()
+	if approved {
+		return nil
+	}
+	if refused {
+		return fmt.Errorf("%w: %s", ErrBudgetExceeded, request.Description())
+	}
+
+	respCh := make(chan bool, 1)
+	s.pendingRequests.Store(request.ID, respCh)
+	defer s.pendingRequests.Delete(request.ID)
+
+	s.Publish(pubsub.CreatedEvent, request)
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case resp := <-respCh:
+		if !resp {
+			return fmt.Errorf("%w: %s", ErrBudgetExceeded, request.Description())
+		}
+		return nil
+	}
+}
+
+func (s *budgetService) Approve(request Request) {
+	s.mu.Lock()
+	s.approvals[request.SessionID] = append(s.approvals[request.SessionID], s.approval(request.Scope))
+	s.mu.Unlock()
+	respCh, ok := s.pendingRequests.Load(request.ID)
+	if ok {
+		respCh.(chan bool) <- true
+	}
+}
+
+func (s *budgetService) Deny(request Request) {
+	respCh, ok := s.pendingRequests.Load(request.ID)
+	if ok {
+		respCh.(chan bool) <- false
+	}
+}
+
+func (s *budgetService) RefuseSession(sessionID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.refusedSessions = append(s.refusedSessions, sessionID)
+}
+
+// approval identifies the cap of a scope a session is let go over. Going over
+// the daily cap is only approved for the day.
+func (s *budgetService) approval(scope Scope) string {
+	if scope == ScopeDaily {
+		return string(scope) + ":" + s.now().Format("2006-01-02")
+	}
+	return string(scope)
+}
+
+func limitOf(budget config.BudgetConfig, scope Scope) config.BudgetLimit {
+	switch scope {
+	case ScopeSession:
+		return budget.Session
+	case ScopeDaily:
+		return budget.Daily
+	default:
+		return budget.Project
+	}
+}
+
+// exceeds reports whether the usage expected from a request would go over a
+// cap
+func exceeds(limit config.BudgetLimit, used, estimate session.Usage) bool {
+	total := used.Add(estimate)
+	return (limit.Cost > 0 && total.Cost > limit.Cost) ||
+		(limit.Tokens > 0 && total.Tokens > limit.Tokens)
+}
+
+func NewService(sessions session.Service) Service {
+	return &budgetService{
+		Broker:    pubsub.NewBroker[Request](),
+		sessions:  sessions,
+		now:       time.Now,
+		approvals: make(map[string][]string),
+	}
+}
@@ -0,0 +1,109 @@
+package budget
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/omnitrix-sh/cli/internal/config"
+	"github.com/omnitrix-sh/cli/internal/session"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeSessions struct {
+	session.Service
+	sessions map[string]session.Session
+	totals   session.Usage
+}
+
+func (f *fakeSessions) Get(ctx context.Context, id string) (session.Session, error) {
+	return f.sessions[id], nil
+}
+
+func (f *fakeSessions) Totals(ctx context.Context, since time.Time) (session.Usage, error) {
+	return f.totals, nil
+}
+
+func TestCheck(t *testing.T) {
+	cfg, err := config.Load(t.TempDir(), false)
+	require.NoError(t, err)
+
+	sessions := &fakeSessions{
+		sessions: map[string]session.Session{
+			"parent": {ID: "parent", Cost: 0.8, InputTokens: 1000},
+			"task":   {ID: "task", ParentSessionID: "parent", Cost: 0.15},
+		},
+		totals: session.Usage{Cost: 4, Tokens: 50000},
+	}
+	estimate := session.Usage{Cost: 0.1, Tokens: 500}
+
+	t.Run("allows requests under the caps", func(t *testing.T) {
+		cfg.Budget = config.BudgetConfig{
+			Session: config.BudgetLimit{Cost: 1},
+			Daily:   config.BudgetLimit{Tokens: 100000},
+		}
+		s := NewService(sessions)
+		s.RefuseSession("parent")
+		assert.NoError(t, s.Check(context.Background(), "parent", estimate))
+	})
+
+	t.Run("counts the usage of a running task", func(t *testing.T) {
+		cfg.Budget = config.BudgetConfig{Session: config.BudgetLimit{Cost: 1}}
+		s := NewService(sessions)
+		s.RefuseSession("parent")
+		err := s.Check(context.Background(), "task", estimate)
+		assert.ErrorIs(t, err, ErrBudgetExceeded)
+		assert.ErrorContains(t, err, "$1.05")
+	})
+
+	t.Run("asks before going over a cap", func(t *testing.T) {
+		cfg.Budget = config.BudgetConfig{Daily: config.BudgetLimit{Cost: 4}}
+		s := NewService(sessions)
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		requests := s.Subscribe(ctx)
+
+		done := make(chan error)
+		go func() { done <- s.Check(context.Background(), "parent", estimate) }()
+		event := <-requests
+		assert.Equal(t, ScopeDaily, event.Payload.Scope)
+		assert.Equal(t, "parent", event.Payload.SessionID)
+		s.Approve(event.Payload)
+		require.NoError(t, <-done)
+
+		// The session was let go over the cap, it is not asked again
+		assert.NoError(t, s.Check(context.Background(), "parent", estimate))
+	})
+
+	t.Run("asks again for the daily cap the next day", func(t *testing.T) {
+		cfg.Budget = config.BudgetConfig{Daily: config.BudgetLimit{Cost: 4}}
+		s := NewService(sessions)
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		requests := s.Subscribe(ctx)
+
+		done := make(chan error)
+		go func() { done <- s.Check(context.Background(), "parent", estimate) }()
+		s.Approve((<-requests).Payload)
+		require.NoError(t, <-done)
+
+		s.(*budgetService).now = func() time.Time { return time.Now().AddDate(0, 0, 1) }
+		s.RefuseSession("parent")
+		assert.ErrorIs(t, s.Check(context.Background(), "parent", estimate), ErrBudgetExceeded)
+	})
+
+	t.Run("stops when denied", func(t *testing.T) {
+		cfg.Budget = config.BudgetConfig{Project: config.BudgetLimit{Tokens: 50000}}
+		s := NewService(sessions)
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		requests := s.Subscribe(ctx)
+
+		done := make(chan error)
+		go func() { done <- s.Check(context.Background(), "parent", estimate) }()
+		event := <-requests
+		s.Deny(event.Payload)
+		assert.ErrorIs(t, <-done, ErrBudgetExceeded)
+	})
+}
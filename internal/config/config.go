@@ -5,9 +5,11 @@ import (
 	"encoding/json"
 	"fmt"
 	"log/slog"
+	"maps"
 	"os"
 	"path/filepath"
 	"runtime"
+	"slices"
 	"strings"
 
 	"github.com/omnitrix-sh/cli/internal/llm/models"
@@ -48,12 +50,62 @@ type Agent struct {
 	Model           models.ModelID `json:"model"`
 	MaxTokens       int64          `json:"maxTokens"`
 	ReasoningEffort string         `json:"reasoningEffort"` // For openai models low,medium,heigh
+	// Fallbacks are the models tried in order when the model fails
+	Fallbacks []models.ModelID `json:"fallbacks,omitempty"`
 }
 
 // Provider defines configuration for an LLM provider.
 type Provider struct {
 	APIKey   string `json:"apiKey"`
 	Disabled bool   `json:"disabled"`
+	// MaxRetries is how many times a request failing with a transient error
+	// is retried, 0 for the default
+	MaxRetries int `json:"maxRetries,omitempty"`
+	// RequestsPerMinute limits the requests sent to the provider, 0 for no
+	// limit
+	RequestsPerMinute int `json:"requestsPerMinute,omitempty"`
+	// ResponsesAPI makes OpenAI models use the Responses API, which streams
+	// summaries of the models' reasoning, instead of Chat Completions
+	ResponsesAPI bool `json:"responsesAPI,omitempty"`
+}
+
+// CustomProviderType is the API a custom provider is compatible with.
+type CustomProviderType string
+
+// Supported custom provider types
+const (
+	CustomProviderOpenAI    CustomProviderType = "openai"
+	CustomProviderAnthropic CustomProviderType = "anthropic"
+)
+
+// CustomProvider defines an OpenAI or Anthropic compatible endpoint, such as
+// a gateway or a self-hosted server, that models can be declared for.
+type CustomProvider struct {
+	// Type is the API the endpoint is compatible with, openai by default.
+	Type    CustomProviderType `json:"type,omitempty"`
+	BaseURL string             `json:"baseURL"`
+	// APIKeyEnv is the environment variable holding the API key. Endpoints
+	// without one need no key.
+	APIKeyEnv string            `json:"apiKeyEnv,omitempty"`
+	Headers   map[string]string `json:"headers,omitempty"`
+}
+
+// ModelConfig declares a model for a built-in or custom provider, or
+// overrides the details of a built-in model.
+type ModelConfig struct {
+	Provider models.ModelProvider `json:"provider"`
+	Name     string               `json:"name,omitempty"`
+	// APIModel is the model name sent to the provider, the model ID by
+	// default.
+	APIModel            string  `json:"apiModel,omitempty"`
+	ContextWindow       int64   `json:"contextWindow,omitempty"`
+	DefaultMaxTokens    int64   `json:"defaultMaxTokens,omitempty"`
+	CostPer1MIn         float64 `json:"costPer1MIn,omitempty"`
+	CostPer1MOut        float64 `json:"costPer1MOut,omitempty"`
+	CostPer1MInCached   float64 `json:"costPer1MInCached,omitempty"`
+	CostPer1MOutCached  float64 `json:"costPer1MOutCached,omitempty"`
+	CanReason           bool    `json:"canReason,omitempty"`
+	SupportsAttachments bool    `json:"supportsAttachments,omitempty"`
 }
 
 // Data defines storage configuration.
@@ -67,6 +119,18 @@ type LSPConfig struct {
 	Command  string   `json:"command"`
 	Args     []string `json:"args"`
 	Options  any      `json:"options"`
+	// Filetypes are the language IDs or file extensions the server handles.
+	Filetypes []string `json:"filetypes,omitempty"`
+	// RootMarkers are files such as go.mod, one of which must exist in a
+	// directory above a file for it to be sent to the server.
+	RootMarkers []string `json:"rootMarkers,omitempty"`
+	// FormatOnWrite formats files of this language after the agent changes
+	// them, before the change is shown to the user and written.
+	FormatOnWrite bool `json:"formatOnWrite,omitempty"`
+	// Formatter is a command used for formatting instead of the language
+	// server. It reads the content on stdin and writes the formatted content
+	// to stdout, "{file}" in its arguments is replaced with the file path.
+	Formatter []string `json:"formatter,omitempty"`
 }
 
 // TUIConfig defines the configuration for the Terminal User Interface.
@@ -80,6 +144,21 @@ type ShellConfig struct {
 	Args []string `json:"args,omitempty"`
 }
 
+// BudgetLimit caps the spend in dollars and the tokens of the agents. A zero
+// value sets no cap.
+type BudgetLimit struct {
+	Cost   float64 `json:"cost,omitempty"`
+	Tokens int64   `json:"tokens,omitempty"`
+}
+
+// BudgetConfig defines the caps on the usage of a session, of a day and of
+// the project.
+type BudgetConfig struct {
+	Session BudgetLimit `json:"session,omitempty"`
+	Daily   BudgetLimit `json:"daily,omitempty"`
+	Project BudgetLimit `json:"project,omitempty"`
+}
+
 // Config is the main configuration structure for the application.
 type Config struct {
 	Data         Data                              `json:"data"`
@@ -95,6 +174,14 @@ type Config struct {
 	Shell        ShellConfig                       `json:"shell,omitempty"`
 	AutoCompact  bool                              `json:"autoCompact,omitempty"`
 	AutoMode     bool                              `json:"autoMode,omitempty"`
+	Budget       BudgetConfig                      `json:"budget,omitempty"`
+
+	// Models declares models in addition to the built-in ones, keyed by
+	// model ID.
+	Models map[models.ModelID]ModelConfig `json:"models,omitempty"`
+	// CustomProviders declares OpenAI or Anthropic compatible endpoints,
+	// keyed by provider name.
+	CustomProviders map[models.ModelProvider]CustomProvider `json:"customProviders,omitempty"`
 }
 
 // Application constants
@@ -149,6 +236,11 @@ func Load(workingDir string, debug bool) (*Config, error) {
 	// Load and merge local config
 	mergeLocalConfig(workingDir)
 
+	// Models discovered in Ollama come before the provider defaults, which
+	// take precedence over them
+	if os.Getenv("OLLAMA_HOST") != "" || viper.IsSet("providers.ollama") {
+		models.LoadOllamaModels()
+	}
 	setProviderDefaults()
 
 	// Apply configuration to the struct
@@ -157,6 +249,10 @@ func Load(workingDir string, debug bool) (*Config, error) {
 	}
 
 	applyDefaultValues()
+	if err := loadCustomModels(); err != nil {
+		return cfg, err
+	}
+	registerCustomModels()
 	defaultLevel := slog.LevelInfo
 	if cfg.Debug {
 		defaultLevel = slog.LevelDebug
@@ -212,6 +308,7 @@ func Load(workingDir string, debug bool) (*Config, error) {
 	cfg.Agents[AgentTitle] = Agent{
 		Model:     cfg.Agents[AgentTitle].Model,
 		MaxTokens: 80,
+		Fallbacks: cfg.Agents[AgentTitle].Fallbacks,
 	}
 	return cfg, nil
 }
@@ -473,6 +570,138 @@ func applyDefaultValues() {
 	}
 }
 
+// loadCustomModels reads the models and customProviders sections. Viper
+// splits keys at dots, so IDs like "gateway.gpt-4.1" end up nested and the
+// entries are reassembled from the settings tree.
+func loadCustomModels() error {
+	modelEntries := make(map[string]any)
+	collectEntries(viper.GetStringMap("models"), "provider", "", modelEntries)
+	providerEntries := make(map[string]any)
+	collectEntries(viper.GetStringMap("customProviders"), "baseurl", "", providerEntries)
+
+	cfg.Models = make(map[models.ModelID]ModelConfig, len(modelEntries))
+	for id, entry := range modelEntries {
+		var modelCfg ModelConfig
+		if err := decodeEntry(entry, &modelCfg); err != nil {
+			return fmt.Errorf("invalid model %s: %w", id, err)
+		}
+		cfg.Models[models.ModelID(id)] = modelCfg
+	}
+
+	cfg.CustomProviders = make(map[models.ModelProvider]CustomProvider, len(providerEntries))
+	for name, entry := range providerEntries {
+		var custom CustomProvider
+		if err := decodeEntry(entry, &custom); err != nil {
+			return fmt.Errorf("invalid custom provider %s: %w", name, err)
+		}
+		cfg.CustomProviders[models.ModelProvider(name)] = custom
+	}
+	return nil
+}
+
+// collectEntries finds the entries in tree, the maps containing leafKey, and
+// adds them to entries under their dot separated path
+func collectEntries(tree map[string]any, leafKey, prefix string, entries map[string]any) {
+	for key, value := range tree {
+		sub, ok := value.(map[string]any)
+		if !ok {
+			continue
+		}
+		if _, ok := sub[leafKey]; ok {
+			entries[prefix+key] = sub
+			continue
+		}
+		collectEntries(sub, leafKey, prefix+key+".", entries)
+	}
+}
+
+// decodeEntry decodes a settings entry, whose keys viper lowercased, into v
+func decodeEntry(entry any, v any) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	// Field names are matched case-insensitively
+	return json.Unmarshal(data, v)
+}
+
+// registerCustomModels adds the custom providers to the providers and the
+// declared models to the supported models, so agents can use them like the
+// built-in ones.
+func registerCustomModels() {
+	// Providers with built-in models, before custom ones are added
+	builtinProviders := make(map[models.ModelProvider]bool)
+	for _, model := range models.SupportedModels {
+		builtinProviders[model.Provider] = true
+	}
+
+	for name, custom := range cfg.CustomProviders {
+		if builtinProviders[name] {
+			logging.Warn("custom provider has the name of a built-in provider, ignoring", "provider", name)
+			delete(cfg.CustomProviders, name)
+			continue
+		}
+		if custom.BaseURL == "" {
+			logging.Warn("custom provider has no base URL, ignoring", "provider", name)
+			delete(cfg.CustomProviders, name)
+			continue
+		}
+		switch custom.Type {
+		case "":
+			custom.Type = CustomProviderOpenAI
+			cfg.CustomProviders[name] = custom
+		case CustomProviderOpenAI, CustomProviderAnthropic:
+		default:
+			logging.Warn("custom provider has an unsupported type, ignoring", "provider", name, "type", custom.Type)
+			delete(cfg.CustomProviders, name)
+			continue
+		}
+
+		if _, ok := cfg.Providers[name]; ok {
+			continue
+		}
+		// Endpoints without an API key still need one to be enabled
+		apiKey := "dummy"
+		if custom.APIKeyEnv != "" {
+			apiKey = os.Getenv(custom.APIKeyEnv)
+		}
+		cfg.Providers[name] = Provider{APIKey: apiKey}
+	}
+
+	for id, modelCfg := range cfg.Models {
+		if _, ok := cfg.CustomProviders[modelCfg.Provider]; !ok && !builtinProviders[modelCfg.Provider] {
+			logging.Warn("model has an unknown provider, ignoring", "model", id, "provider", modelCfg.Provider)
+			continue
+		}
+		models.SupportedModels[id] = modelCfg.model(id)
+	}
+}
+
+// model converts the declaration of a model to the model with ID id
+func (m ModelConfig) model(id models.ModelID) models.Model {
+	model := models.Model{
+		ID:                  id,
+		Name:                m.Name,
+		Provider:            m.Provider,
+		APIModel:            m.APIModel,
+		CostPer1MIn:         m.CostPer1MIn,
+		CostPer1MOut:        m.CostPer1MOut,
+		CostPer1MInCached:   m.CostPer1MInCached,
+		CostPer1MOutCached:  m.CostPer1MOutCached,
+		ContextWindow:       m.ContextWindow,
+		DefaultMaxTokens:    m.DefaultMaxTokens,
+		CanReason:           m.CanReason,
+		SupportsAttachments: m.SupportsAttachments,
+	}
+	if model.Name == "" {
+		model.Name = string(id)
+	}
+	if model.APIModel == "" {
+		model.APIModel = string(id)
+	}
+	return model
+}
+
 // It validates model IDs and providers, ensuring they are supported.
 func validateAgent(cfg *Config, name AgentName, agent Agent) error {
 	// Check if model exists
@@ -565,7 +794,8 @@ func validateAgent(cfg *Config, name AgentName, agent Agent) error {
 	}
 
 	// Validate reasoning effort for models that support reasoning
-	if model.CanReason && provider == models.ProviderOpenAI || provider == models.ProviderLocal {
+	customOpenAI := cfg.CustomProviders[provider].Type == CustomProviderOpenAI
+	if model.CanReason && (provider == models.ProviderOpenAI || customOpenAI) || provider == models.ProviderLocal {
 		if agent.ReasoningEffort == "" {
 			// Set default reasoning effort for models that support it
 			logging.Info("setting default reasoning effort for model that supports reasoning",
@@ -607,6 +837,31 @@ func validateAgent(cfg *Config, name AgentName, agent Agent) error {
 	return nil
 }
 
+// validateFallbacks drops the fallback models of an agent that are unknown or
+// the same as its model.
+func validateFallbacks(cfg *Config, name AgentName) {
+	agent := cfg.Agents[name]
+	if len(agent.Fallbacks) == 0 {
+		return
+	}
+
+	fallbacks := make([]models.ModelID, 0, len(agent.Fallbacks))
+	for _, modelID := range agent.Fallbacks {
+		if _, ok := models.SupportedModels[modelID]; !ok {
+			logging.Warn("unsupported fallback model configured, ignoring",
+				"agent", name,
+				"fallback_model", modelID)
+			continue
+		}
+		if modelID == agent.Model || slices.Contains(fallbacks, modelID) {
+			continue
+		}
+		fallbacks = append(fallbacks, modelID)
+	}
+	agent.Fallbacks = fallbacks
+	cfg.Agents[name] = agent
+}
+
 // Validate checks if the configuration is valid and applies defaults where needed.
 func Validate() error {
 	if cfg == nil {
@@ -618,6 +873,7 @@ func Validate() error {
 		if err := validateAgent(cfg, name, agent); err != nil {
 			return err
 		}
+		validateFallbacks(cfg, name)
 	}
 
 	// Validate providers
@@ -633,7 +889,10 @@ func Validate() error {
 	// Validate LSP configurations
 	for language, lspConfig := range cfg.LSP {
 		if lspConfig.Command == "" && !lspConfig.Disabled {
-			logging.Warn("LSP configuration has no command, marking as disabled", "language", language)
+			// Entries that only configure a formatter need no server
+			if len(lspConfig.Formatter) == 0 {
+				logging.Warn("LSP configuration has no command, marking as disabled", "language", language)
+			}
 			lspConfig.Disabled = true
 			cfg.LSP[language] = lspConfig
 		}
@@ -870,6 +1129,13 @@ func Get() *Config {
 	return cfg
 }
 
+// Reset discards the loaded configuration, so the next Load reads it again.
+// Tests use it to load the configuration for their own working directory.
+func Reset() {
+	cfg = nil
+	viper.Reset()
+}
+
 // WorkingDirectory returns the current working directory from the configuration.
 func WorkingDirectory() string {
 	if cfg == nil {
@@ -899,6 +1165,7 @@ func UpdateAgentModel(agentName AgentName, modelID models.ModelID) error {
 		Model:           modelID,
 		MaxTokens:       maxTokens,
 		ReasoningEffort: existingAgentCfg.ReasoningEffort,
+		Fallbacks:       existingAgentCfg.Fallbacks,
 	}
 	cfg.Agents[agentName] = newAgentCfg
 
@@ -931,6 +1198,53 @@ func UpdateTheme(themeName string) error {
 	})
 }
 
+// AddLSPServers adds the commands of language servers to the configuration
+// and to the project's local config file, which is created if needed. Other
+// settings in the file are left as they are.
+func AddLSPServers(servers map[string]LSPConfig) error {
+	if cfg == nil {
+		return fmt.Errorf("config not loaded")
+	}
+
+	configFile := filepath.Join(cfg.WorkingDir, fmt.Sprintf(".%s.json", appName))
+	localCfg := map[string]any{}
+	data, err := os.ReadFile(configFile)
+	if err == nil {
+		if err := json.Unmarshal(data, &localCfg); err != nil {
+			return fmt.Errorf("failed to parse %s: %w", configFile, err)
+		}
+	} else if !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read %s: %w", configFile, err)
+	}
+
+	lspCfg, _ := localCfg["lsp"].(map[string]any)
+	if lspCfg == nil {
+		lspCfg = map[string]any{}
+	}
+	for name, server := range servers {
+		entry := map[string]any{"command": server.Command}
+		if len(server.Args) > 0 {
+			entry["args"] = server.Args
+		}
+		lspCfg[name] = entry
+	}
+	localCfg["lsp"] = lspCfg
+
+	updatedData, err := json.MarshalIndent(localCfg, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal config: %w", err)
+	}
+	if err := os.WriteFile(configFile, updatedData, 0o644); err != nil {
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+
+	if cfg.LSP == nil {
+		cfg.LSP = make(map[string]LSPConfig)
+	}
+	maps.Copy(cfg.LSP, servers)
+	return nil
+}
+
 // AutoModeEnabled returns whether automode is currently enabled.
 func AutoModeEnabled() bool {
 	if cfg == nil {
@@ -0,0 +1,29 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCollectEntries(t *testing.T) {
+	// Viper nests keys containing dots
+	tree := map[string]any{
+		"gateway": map[string]any{
+			"gpt-4": map[string]any{
+				"1": map[string]any{"provider": "gateway"},
+			},
+			"small": map[string]any{"provider": "gateway"},
+		},
+		"plain": map[string]any{"provider": "openai"},
+		"empty": map[string]any{},
+	}
+
+	entries := make(map[string]any)
+	collectEntries(tree, "provider", "", entries)
+
+	assert.Len(t, entries, 3)
+	assert.Contains(t, entries, "gateway.gpt-4.1")
+	assert.Contains(t, entries, "gateway.small")
+	assert.Contains(t, entries, "plain")
+}
@@ -60,6 +60,12 @@ func Prepare(ctx context.Context, db DBTX) (*Queries, error) {
 	if q.getSessionByIDStmt, err = db.PrepareContext(ctx, getSessionByID); err != nil {
 		return nil, fmt.Errorf("error preparing query GetSessionByID: %w", err)
 	}
+	if q.getUsageTotalsStmt, err = db.PrepareContext(ctx, getUsageTotals); err != nil {
+		return nil, fmt.Errorf("error preparing query GetUsageTotals: %w", err)
+	}
+	if q.listExpensiveTurnsStmt, err = db.PrepareContext(ctx, listExpensiveTurns); err != nil {
+		return nil, fmt.Errorf("error preparing query ListExpensiveTurns: %w", err)
+	}
 	if q.listFilesByPathStmt, err = db.PrepareContext(ctx, listFilesByPath); err != nil {
 		return nil, fmt.Errorf("error preparing query ListFilesByPath: %w", err)
 	}
@@ -75,9 +81,21 @@ func Prepare(ctx context.Context, db DBTX) (*Queries, error) {
 	if q.listNewFilesStmt, err = db.PrepareContext(ctx, listNewFiles); err != nil {
 		return nil, fmt.Errorf("error preparing query ListNewFiles: %w", err)
 	}
+	if q.listModelUsageStmt, err = db.PrepareContext(ctx, listModelUsage); err != nil {
+		return nil, fmt.Errorf("error preparing query ListModelUsage: %w", err)
+	}
+	if q.listSessionModelsStmt, err = db.PrepareContext(ctx, listSessionModels); err != nil {
+		return nil, fmt.Errorf("error preparing query ListSessionModels: %w", err)
+	}
 	if q.listSessionsStmt, err = db.PrepareContext(ctx, listSessions); err != nil {
 		return nil, fmt.Errorf("error preparing query ListSessions: %w", err)
 	}
+	if q.listUsageSessionsStmt, err = db.PrepareContext(ctx, listUsageSessions); err != nil {
+		return nil, fmt.Errorf("error preparing query ListUsageSessions: %w", err)
+	}
+	if q.renameSessionFileStmt, err = db.PrepareContext(ctx, renameSessionFile); err != nil {
+		return nil, fmt.Errorf("error preparing query RenameSessionFile: %w", err)
+	}
 	if q.updateFileStmt, err = db.PrepareContext(ctx, updateFile); err != nil {
 		return nil, fmt.Errorf("error preparing query UpdateFile: %w", err)
 	}
@@ -152,6 +170,16 @@ func (q *Queries) Close() error {
 			err = fmt.Errorf("error closing getSessionByIDStmt: %w", cerr)
 		}
 	}
+	if q.getUsageTotalsStmt != nil {
+		if cerr := q.getUsageTotalsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getUsageTotalsStmt: %w", cerr)
+		}
+	}
+	if q.listExpensiveTurnsStmt != nil {
+		if cerr := q.listExpensiveTurnsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listExpensiveTurnsStmt: %w", cerr)
+		}
+	}
 	if q.listFilesByPathStmt != nil {
 		if cerr := q.listFilesByPathStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing listFilesByPathStmt: %w", cerr)
@@ -177,11 +205,31 @@ func (q *Queries) Close() error {
 			err = fmt.Errorf("error closing listNewFilesStmt: %w", cerr)
 		}
 	}
+	if q.listModelUsageStmt != nil {
+		if cerr := q.listModelUsageStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listModelUsageStmt: %w", cerr)
+		}
+	}
+	if q.listSessionModelsStmt != nil {
+		if cerr := q.listSessionModelsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listSessionModelsStmt: %w", cerr)
+		}
+	}
 	if q.listSessionsStmt != nil {
 		if cerr := q.listSessionsStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing listSessionsStmt: %w", cerr)
 		}
 	}
+	if q.listUsageSessionsStmt != nil {
+		if cerr := q.listUsageSessionsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listUsageSessionsStmt: %w", cerr)
+		}
+	}
+	if q.renameSessionFileStmt != nil {
+		if cerr := q.renameSessionFileStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing renameSessionFileStmt: %w", cerr)
+		}
+	}
 	if q.updateFileStmt != nil {
 		if cerr := q.updateFileStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing updateFileStmt: %w", cerr)
@@ -248,12 +296,18 @@ type Queries struct {
 	getFileByPathAndSessionStmt *sql.Stmt
 	getMessageStmt              *sql.Stmt
 	getSessionByIDStmt          *sql.Stmt
+	getUsageTotalsStmt          *sql.Stmt
+	listExpensiveTurnsStmt      *sql.Stmt
 	listFilesByPathStmt         *sql.Stmt
 	listFilesBySessionStmt      *sql.Stmt
 	listLatestSessionFilesStmt  *sql.Stmt
 	listMessagesBySessionStmt   *sql.Stmt
 	listNewFilesStmt            *sql.Stmt
+	listModelUsageStmt          *sql.Stmt
+	listSessionModelsStmt       *sql.Stmt
 	listSessionsStmt            *sql.Stmt
+	listUsageSessionsStmt       *sql.Stmt
+	renameSessionFileStmt       *sql.Stmt
 	updateFileStmt              *sql.Stmt
 	updateMessageStmt           *sql.Stmt
 	updateSessionStmt           *sql.Stmt
@@ -275,12 +329,18 @@ func (q *Queries) WithTx(tx *sql.Tx) *Queries {
 		getFileByPathAndSessionStmt: q.getFileByPathAndSessionStmt,
 		getMessageStmt:              q.getMessageStmt,
 		getSessionByIDStmt:          q.getSessionByIDStmt,
+		getUsageTotalsStmt:          q.getUsageTotalsStmt,
+		listExpensiveTurnsStmt:      q.listExpensiveTurnsStmt,
 		listFilesByPathStmt:         q.listFilesByPathStmt,
 		listFilesBySessionStmt:      q.listFilesBySessionStmt,
 		listLatestSessionFilesStmt:  q.listLatestSessionFilesStmt,
 		listMessagesBySessionStmt:   q.listMessagesBySessionStmt,
 		listNewFilesStmt:            q.listNewFilesStmt,
+		listModelUsageStmt:          q.listModelUsageStmt,
+		listSessionModelsStmt:       q.listSessionModelsStmt,
 		listSessionsStmt:            q.listSessionsStmt,
+		listUsageSessionsStmt:       q.listUsageSessionsStmt,
+		renameSessionFileStmt:       q.renameSessionFileStmt,
 		updateFileStmt:              q.updateFileStmt,
 		updateMessageStmt:           q.updateMessageStmt,
 		updateSessionStmt:           q.updateSessionStmt,
@@ -279,6 +279,25 @@ func (q *Queries) ListNewFiles(ctx context.Context) ([]File, error) {
 	return items, nil
 }
 
+const renameSessionFile = `-- name: RenameSessionFile :exec
+UPDATE files
+SET
+    path = ?,
+    updated_at = strftime('%s', 'now')
+WHERE session_id = ? AND path = ?
+`
+
+type RenameSessionFileParams struct {
+	NewPath   string `json:"new_path"`
+	SessionID string `json:"session_id"`
+	OldPath   string `json:"old_path"`
+}
+
+func (q *Queries) RenameSessionFile(ctx context.Context, arg RenameSessionFileParams) error {
+	_, err := q.exec(ctx, q.renameSessionFileStmt, renameSessionFile, arg.NewPath, arg.SessionID, arg.OldPath)
+	return err
+}
+
 const updateFile = `-- name: UpdateFile :one
 UPDATE files
 SET
@@ -22,7 +22,7 @@ INSERT INTO messages (
 ) VALUES (
     ?, ?, ?, ?, ?, strftime('%s', 'now'), strftime('%s', 'now')
 )
-RETURNING id, session_id, role, parts, model, created_at, updated_at, finished_at
+RETURNING id, session_id, role, parts, model, created_at, updated_at, finished_at, input_tokens, output_tokens, cache_read_tokens, cache_creation_tokens, cost, latency_ms, first_token_ms
 `
 
 type CreateMessageParams struct {
@@ -51,6 +51,13 @@ func (q *Queries) CreateMessage(ctx context.Context, arg CreateMessageParams) (M
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.FinishedAt,
+		&i.InputTokens,
+		&i.OutputTokens,
+		&i.CacheReadTokens,
+		&i.CacheCreationTokens,
+		&i.Cost,
+		&i.LatencyMs,
+		&i.FirstTokenMs,
 	)
 	return i, err
 }
@@ -76,7 +83,7 @@ func (q *Queries) DeleteSessionMessages(ctx context.Context, sessionID string) e
 }
 
 const getMessage = `-- name: GetMessage :one
-SELECT id, session_id, role, parts, model, created_at, updated_at, finished_at
+SELECT id, session_id, role, parts, model, created_at, updated_at, finished_at, input_tokens, output_tokens, cache_read_tokens, cache_creation_tokens, cost, latency_ms, first_token_ms
 FROM messages
 WHERE id = ? LIMIT 1
 `
@@ -93,12 +100,19 @@ func (q *Queries) GetMessage(ctx context.Context, id string) (Message, error) {
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.FinishedAt,
+		&i.InputTokens,
+		&i.OutputTokens,
+		&i.CacheReadTokens,
+		&i.CacheCreationTokens,
+		&i.Cost,
+		&i.LatencyMs,
+		&i.FirstTokenMs,
 	)
 	return i, err
 }
 
 const listMessagesBySession = `-- name: ListMessagesBySession :many
-SELECT id, session_id, role, parts, model, created_at, updated_at, finished_at
+SELECT id, session_id, role, parts, model, created_at, updated_at, finished_at, input_tokens, output_tokens, cache_read_tokens, cache_creation_tokens, cost, latency_ms, first_token_ms
 FROM messages
 WHERE session_id = ?
 ORDER BY created_at ASC
@@ -122,6 +136,13 @@ func (q *Queries) ListMessagesBySession(ctx context.Context, sessionID string) (
 			&i.CreatedAt,
 			&i.UpdatedAt,
 			&i.FinishedAt,
+			&i.InputTokens,
+			&i.OutputTokens,
+			&i.CacheReadTokens,
+			&i.CacheCreationTokens,
+			&i.Cost,
+			&i.LatencyMs,
+			&i.FirstTokenMs,
 		); err != nil {
 			return nil, err
 		}
@@ -140,18 +161,46 @@ const updateMessage = `-- name: UpdateMessage :exec
 UPDATE messages
 SET
     parts = ?,
+    model = ?,
     finished_at = ?,
+    input_tokens = ?,
+    output_tokens = ?,
+    cache_read_tokens = ?,
+    cache_creation_tokens = ?,
+    cost = ?,
+    latency_ms = ?,
+    first_token_ms = ?,
     updated_at = strftime('%s', 'now')
 WHERE id = ?
 `
 
 type UpdateMessageParams struct {
-	Parts      string        `json:"parts"`
-	FinishedAt sql.NullInt64 `json:"finished_at"`
-	ID         string        `json:"id"`
+	Parts               string         `json:"parts"`
+	Model               sql.NullString `json:"model"`
+	FinishedAt          sql.NullInt64  `json:"finished_at"`
+	InputTokens         int64          `json:"input_tokens"`
+	OutputTokens        int64          `json:"output_tokens"`
+	CacheReadTokens     int64          `json:"cache_read_tokens"`
+	CacheCreationTokens int64          `json:"cache_creation_tokens"`
+	Cost                float64        `json:"cost"`
+	LatencyMs           int64          `json:"latency_ms"`
+	FirstTokenMs        int64          `json:"first_token_ms"`
+	ID                  string         `json:"id"`
 }
 
 func (q *Queries) UpdateMessage(ctx context.Context, arg UpdateMessageParams) error {
-	_, err := q.exec(ctx, q.updateMessageStmt, updateMessage, arg.Parts, arg.FinishedAt, arg.ID)
+	_, err := q.exec(ctx, q.updateMessageStmt, updateMessage,
+		arg.Parts,
+		arg.Model,
+		arg.FinishedAt,
+		arg.InputTokens,
+		arg.OutputTokens,
+		arg.CacheReadTokens,
+		arg.CacheCreationTokens,
+		arg.Cost,
+		arg.LatencyMs,
+		arg.FirstTokenMs,
+		arg.ID,
+	)
 	return err
 }
@@ -21,12 +21,31 @@ type Querier interface {
 	GetFileByPathAndSession(ctx context.Context, arg GetFileByPathAndSessionParams) (File, error)
 	GetMessage(ctx context.Context, id string) (Message, error)
 	GetSessionByID(ctx context.Context, id string) (Session, error)
+	// Task sessions are left out as their cost is added to their parent session.
+	// Sessions created before the project was recorded are not counted.
+	GetUsageTotals(ctx context.Context, arg GetUsageTotalsParams) (GetUsageTotalsRow, error)
+	// Lists the most expensive assistant messages answered in a time range, in
+	// the top level sessions and their task sessions. A negative limit lists all
+	// of them.
+	ListExpensiveTurns(ctx context.Context, arg ListExpensiveTurnsParams) ([]ListExpensiveTurnsRow, error)
 	ListFilesByPath(ctx context.Context, path string) ([]File, error)
 	ListFilesBySession(ctx context.Context, sessionID string) ([]File, error)
 	ListLatestSessionFiles(ctx context.Context, sessionID string) ([]File, error)
 	ListMessagesBySession(ctx context.Context, sessionID string) ([]Message, error)
 	ListNewFiles(ctx context.Context) ([]File, error)
+	// Sums the usage of the assistant messages of each model answered in a time
+	// range, in the top level sessions and their task sessions. Messages from
+	// before their usage was recorded are left out.
+	ListModelUsage(ctx context.Context, arg ListModelUsageParams) ([]ListModelUsageRow, error)
+	// Lists the models that answered in each top level session and its task
+	// sessions.
+	ListSessionModels(ctx context.Context) ([]ListSessionModelsRow, error)
 	ListSessions(ctx context.Context) ([]Session, error)
+	// Lists the top level sessions created in a time range, whose usage includes
+	// the usage of their task sessions. An empty project or model matches all
+	// sessions.
+	ListUsageSessions(ctx context.Context, arg ListUsageSessionsParams) ([]Session, error)
+	RenameSessionFile(ctx context.Context, arg RenameSessionFileParams) error
 	UpdateFile(ctx context.Context, arg UpdateFileParams) (File, error)
 	UpdateMessage(ctx context.Context, arg UpdateMessageParams) error
 	UpdateSession(ctx context.Context, arg UpdateSessionParams) (Session, error)
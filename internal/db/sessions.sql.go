@@ -20,6 +20,7 @@ INSERT INTO sessions (
     completion_tokens,
     cost,
     summary_message_id,
+    project,
     updated_at,
     created_at
 ) VALUES (
@@ -31,9 +32,10 @@ INSERT INTO sessions (
     ?,
     ?,
     null,
+    ?,
     strftime('%s', 'now'),
     strftime('%s', 'now')
-) RETURNING id, parent_session_id, title, message_count, prompt_tokens, completion_tokens, cost, updated_at, created_at, summary_message_id
+) RETURNING id, parent_session_id, title, message_count, prompt_tokens, completion_tokens, cost, updated_at, created_at, summary_message_id, input_tokens, cache_read_tokens, cache_creation_tokens, output_tokens, project
 `
 
 type CreateSessionParams struct {
@@ -44,6 +46,7 @@ type CreateSessionParams struct {
 	PromptTokens     int64          `json:"prompt_tokens"`
 	CompletionTokens int64          `json:"completion_tokens"`
 	Cost             float64        `json:"cost"`
+	Project          string         `json:"project"`
 }
 
 func (q *Queries) CreateSession(ctx context.Context, arg CreateSessionParams) (Session, error) {
@@ -55,6 +58,7 @@ func (q *Queries) CreateSession(ctx context.Context, arg CreateSessionParams) (S
 		arg.PromptTokens,
 		arg.CompletionTokens,
 		arg.Cost,
+		arg.Project,
 	)
 	var i Session
 	err := row.Scan(
@@ -68,6 +72,11 @@ func (q *Queries) CreateSession(ctx context.Context, arg CreateSessionParams) (S
 		&i.UpdatedAt,
 		&i.CreatedAt,
 		&i.SummaryMessageID,
+		&i.InputTokens,
+		&i.CacheReadTokens,
+		&i.CacheCreationTokens,
+		&i.OutputTokens,
+		&i.Project,
 	)
 	return i, err
 }
@@ -83,7 +92,7 @@ func (q *Queries) DeleteSession(ctx context.Context, id string) error {
 }
 
 const getSessionByID = `-- name: GetSessionByID :one
-SELECT id, parent_session_id, title, message_count, prompt_tokens, completion_tokens, cost, updated_at, created_at, summary_message_id
+SELECT id, parent_session_id, title, message_count, prompt_tokens, completion_tokens, cost, updated_at, created_at, summary_message_id, input_tokens, cache_read_tokens, cache_creation_tokens, output_tokens, project
 FROM sessions
 WHERE id = ? LIMIT 1
 `
@@ -102,12 +111,46 @@ func (q *Queries) GetSessionByID(ctx context.Context, id string) (Session, error
 		&i.UpdatedAt,
 		&i.CreatedAt,
 		&i.SummaryMessageID,
+		&i.InputTokens,
+		&i.CacheReadTokens,
+		&i.CacheCreationTokens,
+		&i.OutputTokens,
+		&i.Project,
 	)
 	return i, err
 }
 
+const getUsageTotals = `-- name: GetUsageTotals :one
+SELECT
+    CAST(COALESCE(SUM(cost), 0.0) AS REAL) AS cost,
+    CAST(COALESCE(SUM(input_tokens + output_tokens + cache_read_tokens + cache_creation_tokens), 0) AS INTEGER) AS tokens
+FROM sessions
+WHERE parent_session_id IS NULL
+    AND updated_at >= ?1
+    AND project = ?2
+`
+
+type GetUsageTotalsParams struct {
+	Since   int64  `json:"since"`
+	Project string `json:"project"`
+}
+
+type GetUsageTotalsRow struct {
+	Cost   float64 `json:"cost"`
+	Tokens int64   `json:"tokens"`
+}
+
+// Task sessions are left out as their cost is added to their parent session.
+// Sessions created before the project was recorded are not counted.
+func (q *Queries) GetUsageTotals(ctx context.Context, arg GetUsageTotalsParams) (GetUsageTotalsRow, error) {
+	row := q.queryRow(ctx, q.getUsageTotalsStmt, getUsageTotals, arg.Since, arg.Project)
+	var i GetUsageTotalsRow
+	err := row.Scan(&i.Cost, &i.Tokens)
+	return i, err
+}
+
 const listSessions = `-- name: ListSessions :many
-SELECT id, parent_session_id, title, message_count, prompt_tokens, completion_tokens, cost, updated_at, created_at, summary_message_id
+SELECT id, parent_session_id, title, message_count, prompt_tokens, completion_tokens, cost, updated_at, created_at, summary_message_id, input_tokens, cache_read_tokens, cache_creation_tokens, output_tokens, project
 FROM sessions
 WHERE parent_session_id is NULL
 ORDER BY created_at DESC
@@ -133,6 +176,11 @@ func (q *Queries) ListSessions(ctx context.Context) ([]Session, error) {
 			&i.UpdatedAt,
 			&i.CreatedAt,
 			&i.SummaryMessageID,
+			&i.InputTokens,
+			&i.CacheReadTokens,
+			&i.CacheCreationTokens,
+			&i.OutputTokens,
+			&i.Project,
 		); err != nil {
 			return nil, err
 		}
@@ -154,18 +202,26 @@ SET
     prompt_tokens = ?,
     completion_tokens = ?,
     summary_message_id = ?,
-    cost = ?
+    cost = ?,
+    input_tokens = ?,
+    cache_read_tokens = ?,
+    cache_creation_tokens = ?,
+    output_tokens = ?
 WHERE id = ?
-RETURNING id, parent_session_id, title, message_count, prompt_tokens, completion_tokens, cost, updated_at, created_at, summary_message_id
+RETURNING id, parent_session_id, title, message_count, prompt_tokens, completion_tokens, cost, updated_at, created_at, summary_message_id, input_tokens, cache_read_tokens, cache_creation_tokens, output_tokens, project
 `
 
 type UpdateSessionParams struct {
-	Title            string         `json:"title"`
-	PromptTokens     int64          `json:"prompt_tokens"`
-	CompletionTokens int64          `json:"completion_tokens"`
-	SummaryMessageID sql.NullString `json:"summary_message_id"`
-	Cost             float64        `json:"cost"`
-	ID               string         `json:"id"`
+	Title               string         `json:"title"`
+	PromptTokens        int64          `json:"prompt_tokens"`
+	CompletionTokens    int64          `json:"completion_tokens"`
+	SummaryMessageID    sql.NullString `json:"summary_message_id"`
+	Cost                float64        `json:"cost"`
+	InputTokens         int64          `json:"input_tokens"`
+	CacheReadTokens     int64          `json:"cache_read_tokens"`
+	CacheCreationTokens int64          `json:"cache_creation_tokens"`
+	OutputTokens        int64          `json:"output_tokens"`
+	ID                  string         `json:"id"`
 }
 
 func (q *Queries) UpdateSession(ctx context.Context, arg UpdateSessionParams) (Session, error) {
@@ -175,6 +231,10 @@ func (q *Queries) UpdateSession(ctx context.Context, arg UpdateSessionParams) (S
 		arg.CompletionTokens,
 		arg.SummaryMessageID,
 		arg.Cost,
+		arg.InputTokens,
+		arg.CacheReadTokens,
+		arg.CacheCreationTokens,
+		arg.OutputTokens,
 		arg.ID,
 	)
 	var i Session
@@ -189,6 +249,11 @@ func (q *Queries) UpdateSession(ctx context.Context, arg UpdateSessionParams) (S
 		&i.UpdatedAt,
 		&i.CreatedAt,
 		&i.SummaryMessageID,
+		&i.InputTokens,
+		&i.CacheReadTokens,
+		&i.CacheCreationTokens,
+		&i.OutputTokens,
+		&i.Project,
 	)
 	return i, err
 }
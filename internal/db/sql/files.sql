@@ -45,6 +45,13 @@ SET
 WHERE id = ?
 RETURNING *;
 
+-- name: RenameSessionFile :exec
+UPDATE files
+SET
+    path = sqlc.arg(new_path),
+    updated_at = strftime('%s', 'now')
+WHERE session_id = sqlc.arg(session_id) AND path = sqlc.arg(old_path);
+
 -- name: DeleteFile :exec
 DELETE FROM files
 WHERE id = ?;
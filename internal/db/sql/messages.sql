@@ -27,7 +27,15 @@ RETURNING *;
 UPDATE messages
 SET
     parts = ?,
+    model = ?,
     finished_at = ?,
+    input_tokens = ?,
+    output_tokens = ?,
+    cache_read_tokens = ?,
+    cache_creation_tokens = ?,
+    cost = ?,
+    latency_ms = ?,
+    first_token_ms = ?,
     updated_at = strftime('%s', 'now')
 WHERE id = ?;
 
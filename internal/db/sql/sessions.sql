@@ -8,6 +8,7 @@ INSERT INTO sessions (
     completion_tokens,
     cost,
     summary_message_id,
+    project,
     updated_at,
     created_at
 ) VALUES (
@@ -19,6 +20,7 @@ INSERT INTO sessions (
     ?,
     ?,
     null,
+    ?,
     strftime('%s', 'now'),
     strftime('%s', 'now')
 ) RETURNING *;
@@ -41,10 +43,24 @@ SET
     prompt_tokens = ?,
     completion_tokens = ?,
     summary_message_id = ?,
-    cost = ?
+    cost = ?,
+    input_tokens = ?,
+    cache_read_tokens = ?,
+    cache_creation_tokens = ?,
+    output_tokens = ?
 WHERE id = ?
 RETURNING *;
 
+-- name: GetUsageTotals :one
+-- Task sessions are left out as their cost is added to their parent session.
+-- Sessions created before the project was recorded are not counted.
+SELECT
+    CAST(COALESCE(SUM(cost), 0.0) AS REAL) AS cost,
+    CAST(COALESCE(SUM(input_tokens + output_tokens + cache_read_tokens + cache_creation_tokens), 0) AS INTEGER) AS tokens
+FROM sessions
+WHERE parent_session_id IS NULL
+    AND updated_at >= sqlc.arg(since)
+    AND project = sqlc.arg(project);
 
 -- name: DeleteSession :exec
 DELETE FROM sessions
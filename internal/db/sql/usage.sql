@@ -0,0 +1,84 @@
+-- name: ListUsageSessions :many
+-- Lists the top level sessions created in a time range, whose usage includes
+-- the usage of their task sessions. An empty project or model matches all
+-- sessions.
+SELECT *
+FROM sessions
+WHERE parent_session_id IS NULL
+    AND created_at >= sqlc.arg(since)
+    AND created_at < sqlc.arg(until)
+    AND (sqlc.arg(project) = '' OR project = sqlc.arg(project))
+    AND (sqlc.arg(model) = '' OR EXISTS (
+        SELECT 1
+        FROM messages
+        JOIN sessions AS task ON task.id = messages.session_id
+        WHERE (task.id = sessions.id OR task.parent_session_id = sessions.id)
+            AND messages.model = sqlc.arg(model)
+    ))
+ORDER BY cost DESC, created_at DESC;
+
+-- name: ListSessionModels :many
+-- Lists the models that answered in each top level session and its task
+-- sessions.
+SELECT DISTINCT
+    CAST(COALESCE(sessions.parent_session_id, sessions.id) AS TEXT) AS session_id,
+    CAST(messages.model AS TEXT) AS model
+FROM messages
+JOIN sessions ON sessions.id = messages.session_id
+WHERE messages.role = 'assistant' AND messages.model != ''
+ORDER BY session_id, model;
+
+-- name: ListModelUsage :many
+-- Sums the usage of the assistant messages of each model answered in a time
+-- range, in the top level sessions and their task sessions. Messages from
+-- before their usage was recorded are left out.
+SELECT
+    CAST(messages.model AS TEXT) AS model,
+    COUNT(*) AS turns,
+    CAST(SUM(messages.input_tokens) AS INTEGER) AS input_tokens,
+    CAST(SUM(messages.output_tokens) AS INTEGER) AS output_tokens,
+    CAST(SUM(messages.cache_read_tokens) AS INTEGER) AS cache_read_tokens,
+    CAST(SUM(messages.cache_creation_tokens) AS INTEGER) AS cache_creation_tokens,
+    CAST(SUM(messages.cost) AS REAL) AS cost,
+    CAST(AVG(messages.latency_ms) AS INTEGER) AS average_latency_ms,
+    CAST(COALESCE(AVG(NULLIF(messages.first_token_ms, 0)), 0) AS INTEGER) AS average_first_token_ms
+FROM messages
+JOIN sessions ON sessions.id = messages.session_id
+JOIN sessions AS top ON top.id = COALESCE(sessions.parent_session_id, sessions.id)
+WHERE messages.role = 'assistant'
+    AND messages.input_tokens + messages.output_tokens + messages.cache_read_tokens + messages.cache_creation_tokens > 0
+    AND messages.created_at >= sqlc.arg(since)
+    AND messages.created_at < sqlc.arg(until)
+    AND (sqlc.arg(project) = '' OR top.project = sqlc.arg(project))
+    AND (sqlc.arg(model) = '' OR messages.model = sqlc.arg(model))
+GROUP BY messages.model
+ORDER BY cost DESC;
+
+-- name: ListExpensiveTurns :many
+-- Lists the most expensive assistant messages answered in a time range, in
+-- the top level sessions and their task sessions. A negative limit lists all
+-- of them.
+SELECT
+    messages.id,
+    top.id AS session_id,
+    top.title AS session_title,
+    CAST(messages.model AS TEXT) AS model,
+    messages.created_at,
+    messages.input_tokens,
+    messages.output_tokens,
+    messages.cache_read_tokens,
+    messages.cache_creation_tokens,
+    messages.cost,
+    messages.latency_ms,
+    messages.first_token_ms
+FROM messages
+JOIN sessions ON sessions.id = messages.session_id
+JOIN sessions AS top ON top.id = COALESCE(sessions.parent_session_id, sessions.id)
+WHERE messages.role = 'assistant'
+    AND messages.input_tokens + messages.output_tokens + messages.cache_read_tokens + messages.cache_creation_tokens > 0
+    AND messages.created_at >= sqlc.arg(since)
+    AND messages.created_at < sqlc.arg(until)
+    AND (sqlc.arg(project) = '' OR top.project = sqlc.arg(project))
+    AND (sqlc.arg(model) = '' OR messages.model = sqlc.arg(model))
+ORDER BY messages.cost DESC, messages.created_at DESC
+LIMIT sqlc.arg(max_turns);
@@ -0,0 +1,311 @@
+package diff
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Match describes where a search string was located inside a file's content.
+type Match struct {
+	Start int // byte offset of the first matched character
+	End   int // byte offset just past the last matched character
+	Fuzz  int // 0 for an exact match, higher values for looser matches
+
+	crlf        bool   // the matched content uses CRLF line endings
+	addIndent   string // indentation the file has in addition to the search text
+	dropIndent  string // indentation the search text has in addition to the file
+	lineMatched bool   // the match was found by the line based matcher
+}
+
+// Candidate is a region of a file that resembles a search string that could
+// not be matched.
+type Candidate struct {
+	StartLine  int     // 1-based first line of the region
+	EndLine    int     // 1-based last line of the region
+	Similarity float64 // 0..1, how closely the region resembles the search
+	Text       string  // the region's content
+}
+
+// MatchError is returned by FindMatch when the search string is not found
+// exactly once.
+type MatchError struct {
+	Occurrences int
+	Candidates  []Candidate
+}
+
+func (e *MatchError) Error() string {
+	if e.Occurrences > 1 {
+		return fmt.Sprintf("found %d matches", e.Occurrences)
+	}
+	return "no match found"
+}
+
+const maxMatchCandidates = 3
+
+// FindMatch locates search inside content and returns its position. An exact
+// match is preferred; when none exists, line endings, trailing whitespace and
+// indentation are progressively ignored. The match must be unique at the
+// first level that finds anything, otherwise a *MatchError is returned.
+func FindMatch(content, search string) (Match, error) {
+	if search == "" {
+		return Match{}, &MatchError{}
+	}
+
+	// Replacements follow the file's line endings at every level
+	crlf := strings.Contains(content, "\r\n")
+	if m, n := findExact(content, search); n == 1 {
+		m.crlf = crlf
+		return m, nil
+	} else if n > 1 {
+		return Match{}, &MatchError{Occurrences: n}
+	}
+
+	// Line ending mismatch: compare with the file's own line endings.
+	if crlf {
+		normalized := strings.ReplaceAll(strings.ReplaceAll(search, "\r\n", "\n"), "\n", "\r\n")
+		if m, n := findExact(content, normalized); n == 1 {
+			m.Fuzz = 1
+			m.crlf = true
+			return m, nil
+		} else if n > 1 {
+			return Match{}, &MatchError{Occurrences: n}
+		}
+	} else if strings.Contains(search, "\r\n") {
+		normalized := strings.ReplaceAll(search, "\r\n", "\n")
+		if m, n := findExact(content, normalized); n == 1 {
+			m.Fuzz = 1
+			return m, nil
+		} else if n > 1 {
+			return Match{}, &MatchError{Occurrences: n}
+		}
+	}
+
+	fileLines, offsets := splitLinesWithOffsets(content)
+	searchLines := strings.Split(strings.ReplaceAll(search, "\r\n", "\n"), "\n")
+	trailingNewline := len(searchLines) > 1 && searchLines[len(searchLines)-1] == ""
+	if trailingNewline {
+		searchLines = searchLines[:len(searchLines)-1]
+	}
+
+	matchers := []struct {
+		fuzz    int
+		compare func(a, b string) bool
+	}{
+		{1, func(a, b string) bool {
+			return strings.TrimRight(a, " \t\r") == strings.TrimRight(b, " \t\r")
+		}},
+		{100, func(a, b string) bool {
+			return strings.TrimSpace(a) == strings.TrimSpace(b)
+		}},
+	}
+
+	for _, matcher := range matchers {
+		starts := findAllLineMatches(fileLines, searchLines, matcher.compare)
+		if len(starts) > 1 {
+			return Match{}, &MatchError{Occurrences: len(starts)}
+		}
+		if len(starts) == 1 {
+			first := starts[0]
+			last := first + len(searchLines) - 1
+			end := offsets[last] + len(strings.TrimRight(fileLines[last], "\r"))
+			if trailingNewline {
+				end = offsets[last] + len(fileLines[last])
+				if end < len(content) {
+					end++
+				}
+			}
+			m := Match{
+				Start:       offsets[first],
+				End:         end,
+				Fuzz:        matcher.fuzz,
+				crlf:        crlf,
+				lineMatched: true,
+			}
+			m.addIndent, m.dropIndent = indentDelta(fileLines[first:last+1], searchLines)
+			return m, nil
+		}
+	}
+
+	return Match{}, &MatchError{Candidates: closestCandidates(fileLines, searchLines, maxMatchCandidates)}
+}
+
+// Adapt rewrites a replacement string so it fits the region described by the
+// match: line endings follow the file, and when the match was found by
+// ignoring indentation the same indentation shift is applied to replacement.
+func (m Match) Adapt(replacement string) string {
+	if replacement == "" {
+		return replacement
+	}
+	if m.lineMatched && (m.addIndent != "" || m.dropIndent != "") {
+		lines := strings.Split(strings.ReplaceAll(replacement, "\r\n", "\n"), "\n")
+		for i, line := range lines {
+			if strings.TrimSpace(line) == "" {
+				continue
+			}
+			if m.dropIndent != "" {
+				line = strings.TrimPrefix(line, m.dropIndent)
+			}
+			lines[i] = m.addIndent + line
+		}
+		replacement = strings.Join(lines, "\n")
+	}
+	if m.crlf {
+		replacement = strings.ReplaceAll(strings.ReplaceAll(replacement, "\r\n", "\n"), "\n", "\r\n")
+	}
+	return replacement
+}
+
+// Replace returns content with the matched region replaced by replacement,
+// after adapting the replacement to the file's formatting.
+func (m Match) Replace(content, replacement string) string {
+	return content[:m.Start] + m.Adapt(replacement) + content[m.End:]
+}
+
+// StartLine returns the 1-based line number where the match begins.
+func (m Match) StartLine(content string) int {
+	return strings.Count(content[:m.Start], "\n") + 1
+}
+
+func findExact(content, search string) (Match, int) {
+	index := strings.Index(content, search)
+	if index == -1 {
+		return Match{}, 0
+	}
+	count := strings.Count(content, search)
+	return Match{Start: index, End: index + len(search)}, count
+}
+
+// splitLinesWithOffsets splits content on "\n" and returns each line (which
+// may still carry a trailing "\r") together with its starting byte offset.
+func splitLinesWithOffsets(content string) ([]string, []int) {
+	lines := strings.Split(content, "\n")
+	offsets := make([]int, len(lines))
+	offset := 0
+	for i, line := range lines {
+		offsets[i] = offset
+		offset += len(line) + 1
+	}
+	return lines, offsets
+}
+
+func findAllLineMatches(lines []string, context []string, compare func(string, string) bool) []int {
+	var starts []int
+	for i := 0; i+len(context) <= len(lines); i++ {
+		match := true
+		for j := range context {
+			if !compare(lines[i+j], context[j]) {
+				match = false
+				break
+			}
+		}
+		if match {
+			starts = append(starts, i)
+		}
+	}
+	return starts
+}
+
+// indentDelta compares the indentation of the first non blank line of the
+// matched file region with that of the search text.
+func indentDelta(fileLines, searchLines []string) (add, drop string) {
+	for i := range searchLines {
+		if strings.TrimSpace(searchLines[i]) == "" {
+			continue
+		}
+		fileIndent := leadingWhitespace(fileLines[i])
+		searchIndent := leadingWhitespace(searchLines[i])
+		switch {
+		case fileIndent == searchIndent:
+			return "", ""
+		case strings.HasPrefix(fileIndent, searchIndent):
+			return fileIndent[len(searchIndent):], ""
+		case strings.HasPrefix(searchIndent, fileIndent):
+			return "", searchIndent[len(fileIndent):]
+		default:
+			// Mixed tabs and spaces, replace the whole prefix.
+			return fileIndent, searchIndent
+		}
+	}
+	return "", ""
+}
+
+func leadingWhitespace(s string) string {
+	return s[:len(s)-len(strings.TrimLeft(s, " \t"))]
+}
+
+// closestCandidates scores every window of the file that has as many lines
+// as the search text and returns the best ones.
+func closestCandidates(fileLines, searchLines []string, limit int) []Candidate {
+	if len(searchLines) == 0 || len(fileLines) == 0 {
+		return nil
+	}
+	window := min(len(searchLines), len(fileLines))
+
+	type scored struct {
+		start int
+		score float64
+	}
+	scores := make([]scored, 0, len(fileLines))
+	for i := 0; i+window <= len(fileLines); i++ {
+		total := 0.0
+		for j := range window {
+			total += lineSimilarity(fileLines[i+j], searchLines[j])
+		}
+		scores = append(scores, scored{start: i, score: total / float64(len(searchLines))})
+	}
+	sort.SliceStable(scores, func(a, b int) bool {
+		return scores[a].score > scores[b].score
+	})
+
+	candidates := make([]Candidate, 0, limit)
+	for _, s := range scores {
+		if len(candidates) == limit || s.score == 0 {
+			break
+		}
+		overlaps := false
+		for _, c := range candidates {
+			if s.start+1 <= c.EndLine && s.start+window >= c.StartLine {
+				overlaps = true
+				break
+			}
+		}
+		if overlaps {
+			continue
+		}
+		region := make([]string, window)
+		for j := range window {
+			region[j] = strings.TrimRight(fileLines[s.start+j], "\r")
+		}
+		candidates = append(candidates, Candidate{
+			StartLine:  s.start + 1,
+			EndLine:    s.start + window,
+			Similarity: s.score,
+			Text:       strings.Join(region, "\n"),
+		})
+	}
+	return candidates
+}
+
+// lineSimilarity is a cheap similarity measure between two lines based on
+// their common prefix and suffix once surrounding whitespace is removed.
+func lineSimilarity(a, b string) float64 {
+	a = strings.TrimSpace(a)
+	b = strings.TrimSpace(b)
+	if a == b {
+		return 1
+	}
+	longest := max(len(a), len(b))
+	if longest == 0 {
+		return 1
+	}
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	return float64(prefix+suffix) / float64(longest)
+}
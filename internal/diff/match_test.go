@@ -0,0 +1,92 @@
+package diff
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFindMatch(t *testing.T) {
+	t.Run("exact match", func(t *testing.T) {
+		content := "func a() {\n\treturn 1\n}\n"
+		m, err := FindMatch(content, "\treturn 1")
+		require.NoError(t, err)
+		assert.Equal(t, 0, m.Fuzz)
+		assert.Equal(t, "func a() {\n\treturn 2\n}\n", m.Replace(content, "\treturn 2"))
+	})
+
+	t.Run("multiple exact matches", func(t *testing.T) {
+		_, err := FindMatch("x\nx\n", "x")
+		var matchErr *MatchError
+		require.ErrorAs(t, err, &matchErr)
+		assert.Equal(t, 2, matchErr.Occurrences)
+	})
+
+	t.Run("crlf file with lf search", func(t *testing.T) {
+		content := "one\r\ntwo\r\nthree\r\n"
+		m, err := FindMatch(content, "one\ntwo")
+		require.NoError(t, err)
+		assert.Equal(t, 1, m.Fuzz)
+		assert.Equal(t, "uno\r\ndos\r\nthree\r\n", m.Replace(content, "uno\ndos"))
+	})
+
+	t.Run("crlf file keeps its line endings", func(t *testing.T) {
+		content := "one  \r\ntwo\r\nthree\r\n"
+		tests := []struct {
+			name   string
+			search string
+			fuzz   int
+		}{
+			{name: "exact match", search: "three", fuzz: 0},
+			{name: "trailing whitespace", search: "one\ntwo", fuzz: 1},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				m, err := FindMatch(content, tt.search)
+				require.NoError(t, err)
+				assert.Equal(t, tt.fuzz, m.Fuzz)
+				got := m.Replace(content, "uno\ndos")
+				assert.NotContains(t, strings.ReplaceAll(got, "\r\n", ""), "\n")
+			})
+		}
+	})
+
+	t.Run("trailing whitespace", func(t *testing.T) {
+		content := "a := 1   \nb := 2\n"
+		m, err := FindMatch(content, "a := 1\nb := 2")
+		require.NoError(t, err)
+		assert.Equal(t, 1, m.Fuzz)
+		assert.Equal(t, "a := 3\nb := 4\n", m.Replace(content, "a := 3\nb := 4"))
+	})
+
+	t.Run("indentation is adapted", func(t *testing.T) {
+		content := "func a() {\n\t\tif x {\n\t\t\ty()\n\t\t}\n}\n"
+		m, err := FindMatch(content, "if x {\n\ty()\n}")
+		require.NoError(t, err)
+		assert.Equal(t, 100, m.Fuzz)
+		assert.Equal(t,
+			"func a() {\n\t\tif z {\n\t\t\tw()\n\t\t}\n}\n",
+			m.Replace(content, "if z {\n\tw()\n}"),
+		)
+	})
+
+	t.Run("trailing newline in search", func(t *testing.T) {
+		content := "a  \nb\nc\n"
+		m, err := FindMatch(content, "a\nb\n")
+		require.NoError(t, err)
+		assert.Equal(t, "c\n", m.Replace(content, ""))
+	})
+
+	t.Run("no match reports candidates", func(t *testing.T) {
+		content := "alpha\nbeta\ngamma\ndelta\n"
+		_, err := FindMatch(content, "betta\ngamma")
+		var matchErr *MatchError
+		require.ErrorAs(t, err, &matchErr)
+		require.NotEmpty(t, matchErr.Candidates)
+		assert.Equal(t, 2, matchErr.Candidates[0].StartLine)
+		assert.Equal(t, 3, matchErr.Candidates[0].EndLine)
+		assert.Equal(t, "beta\ngamma", matchErr.Candidates[0].Text)
+	})
+}
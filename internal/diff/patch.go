@@ -14,6 +14,7 @@ const (
 	ActionAdd    ActionType = "add"
 	ActionDelete ActionType = "delete"
 	ActionUpdate ActionType = "update"
+	ActionMove   ActionType = "move"
 )
 
 type FileChange struct {
@@ -21,6 +22,7 @@ type FileChange struct {
 	OldContent *string
 	NewContent *string
 	MovePath   *string
+	Mode       *os.FileMode // new permission bits, only set by git style patches
 }
 
 type Commit struct {
@@ -38,6 +40,7 @@ type PatchAction struct {
 	NewFile  *string
 	Chunks   []Chunk
 	MovePath *string
+	Mode     *os.FileMode
 }
 
 type Patch struct {
@@ -152,12 +155,35 @@ func (p *Parser) Parse() error {
 				return err
 			}
 			if moveTo != "" {
+				action.Type = ActionMove
 				action.MovePath = &moveTo
 			}
 			p.patch.Actions[path] = action
 			continue
 		}
 
+		path = p.readStr("*** Move File: ", false)
+		if path != "" {
+			if _, exists := p.patch.Actions[path]; exists {
+				return fileError("Move", "Duplicate Path", path)
+			}
+			moveTo := p.readStr("*** Move to: ", false)
+			if moveTo == "" {
+				return fileError("Move", "Missing Move to", path)
+			}
+			if _, exists := p.currentFiles[path]; !exists {
+				return fileError("Move", "Missing File", path)
+			}
+			action, err := p.parseUpdateFile(p.currentFiles[path])
+			if err != nil {
+				return err
+			}
+			action.Type = ActionMove
+			action.MovePath = &moveTo
+			p.patch.Actions[path] = action
+			continue
+		}
+
 		path = p.readStr("*** Delete File: ", false)
 		if path != "" {
 			if _, exists := p.patch.Actions[path]; exists {
@@ -205,6 +231,7 @@ func (p *Parser) parseUpdateFile(text string) (PatchAction, error) {
 	endPrefixes := []string{
 		"*** End Patch",
 		"*** Update File:",
+		"*** Move File:",
 		"*** Delete File:",
 		"*** Add File:",
 		"*** End of File",
@@ -283,6 +310,7 @@ func (p *Parser) parseAddFile() (PatchAction, error) {
 	endPrefixes := []string{
 		"*** End Patch",
 		"*** Update File:",
+		"*** Move File:",
 		"*** Delete File:",
 		"*** Add File:",
 	}
@@ -386,6 +414,7 @@ func peekNextSection(lines []string, initialIndex int) ([]string, []Chunk, int,
 		return strings.HasPrefix(s, "@@") ||
 			strings.HasPrefix(s, "*** End Patch") ||
 			strings.HasPrefix(s, "*** Update File:") ||
+			strings.HasPrefix(s, "*** Move File:") ||
 			strings.HasPrefix(s, "*** Delete File:") ||
 			strings.HasPrefix(s, "*** Add File:") ||
 			strings.HasPrefix(s, "*** End of File") ||
@@ -457,7 +486,12 @@ func peekNextSection(lines []string, initialIndex int) ([]string, []Chunk, int,
 	return old, chunks, index, false
 }
 
+// TextToPatch parses a patch in either the "*** Begin Patch" format or the
+// standard unified / git diff format.
 func TextToPatch(text string, orig map[string]string) (Patch, int, error) {
+	if IsUnifiedDiff(text) {
+		return unifiedToPatch(text, orig)
+	}
 	text = strings.TrimSpace(text)
 	lines := strings.Split(text, "\n")
 	if len(lines) < 2 || !strings.HasPrefix(lines[0], "*** Begin Patch") || lines[len(lines)-1] != "*** End Patch" {
@@ -472,6 +506,10 @@ func TextToPatch(text string, orig map[string]string) (Patch, int, error) {
 }
 
 func IdentifyFilesNeeded(text string) []string {
+	if IsUnifiedDiff(text) {
+		needed, _ := identifyUnifiedFiles(text)
+		return needed
+	}
 	text = strings.TrimSpace(text)
 	lines := strings.Split(text, "\n")
 	result := make(map[string]bool)
@@ -480,6 +518,9 @@ func IdentifyFilesNeeded(text string) []string {
 		if strings.HasPrefix(line, "*** Update File: ") {
 			result[line[len("*** Update File: "):]] = true
 		}
+		if strings.HasPrefix(line, "*** Move File: ") {
+			result[line[len("*** Move File: "):]] = true
+		}
 		if strings.HasPrefix(line, "*** Delete File: ") {
 			result[line[len("*** Delete File: "):]] = true
 		}
@@ -493,6 +534,10 @@ func IdentifyFilesNeeded(text string) []string {
 }
 
 func IdentifyFilesAdded(text string) []string {
+	if IsUnifiedDiff(text) {
+		_, added := identifyUnifiedFiles(text)
+		return added
+	}
 	text = strings.TrimSpace(text)
 	lines := strings.Split(text, "\n")
 	result := make(map[string]bool)
@@ -510,9 +555,37 @@ func IdentifyFilesAdded(text string) []string {
 	return files
 }
 
+// IdentifyFilesMoved returns the files a patch moves, mapped to their
+// destination paths.
+func IdentifyFilesMoved(text string) map[string]string {
+	if IsUnifiedDiff(text) {
+		return identifyUnifiedMoves(text)
+	}
+	text = strings.TrimSpace(text)
+	lines := strings.Split(text, "\n")
+	result := make(map[string]string)
+
+	for i, line := range lines {
+		if i+1 >= len(lines) || !strings.HasPrefix(lines[i+1], "*** Move to: ") {
+			continue
+		}
+		var from string
+		switch {
+		case strings.HasPrefix(line, "*** Update File: "):
+			from = line[len("*** Update File: "):]
+		case strings.HasPrefix(line, "*** Move File: "):
+			from = line[len("*** Move File: "):]
+		default:
+			continue
+		}
+		result[from] = lines[i+1][len("*** Move to: "):]
+	}
+	return result
+}
+
 func getUpdatedFile(text string, action PatchAction, path string) (string, error) {
-	if action.Type != ActionUpdate {
-		return "", errors.New("expected UPDATE action")
+	if action.Type != ActionUpdate && action.Type != ActionMove {
+		return "", errors.New("expected UPDATE or MOVE action")
 	}
 	origLines := strings.Split(text, "\n")
 	destLines := make([]string, 0, len(origLines)) // Preallocate with capacity
@@ -553,17 +626,19 @@ func PatchToCommit(patch Patch, orig map[string]string) (Commit, error) {
 			commit.Changes[pathKey] = FileChange{
 				Type:       ActionAdd,
 				NewContent: action.NewFile,
+				Mode:       action.Mode,
 			}
-		case ActionUpdate:
+		case ActionUpdate, ActionMove:
 			newContent, err := getUpdatedFile(orig[pathKey], action, pathKey)
 			if err != nil {
 				return Commit{}, err
 			}
 			oldContent := orig[pathKey]
 			fileChange := FileChange{
-				Type:       ActionUpdate,
+				Type:       action.Type,
 				OldContent: &oldContent,
 				NewContent: &newContent,
+				Mode:       action.Mode,
 			}
 			if action.MovePath != nil {
 				fileChange.MovePath = action.MovePath
@@ -631,6 +706,20 @@ func ApplyCommit(commit Commit, writeFn func(string, string) error, removeFn fun
 			if err := writeFn(p, *change.NewContent); err != nil {
 				return err
 			}
+		case ActionMove:
+			if change.NewContent == nil || change.MovePath == nil {
+				return NewDiffError(fmt.Sprintf("Move action for %s has nil new_content or move_path", p))
+			}
+			if err := writeFn(*change.MovePath, *change.NewContent); err != nil {
+				return err
+			}
+			if err := removeFn(p); err != nil {
+				// Don't leave the file in both places
+				if rollbackErr := removeFn(*change.MovePath); rollbackErr != nil {
+					return fmt.Errorf("%w (and failed to roll back %s: %v)", err, *change.MovePath, rollbackErr)
+				}
+				return err
+			}
 		case ActionUpdate:
 			if change.NewContent == nil {
 				return NewDiffError(fmt.Sprintf("Update action for %s has nil new_content", p))
@@ -653,8 +742,8 @@ func ApplyCommit(commit Commit, writeFn func(string, string) error, removeFn fun
 }
 
 func ProcessPatch(text string, openFn func(string) (string, error), writeFn func(string, string) error, removeFn func(string) error) (string, error) {
-	if !strings.HasPrefix(text, "*** Begin Patch") {
-		return "", NewDiffError("Patch must start with *** Begin Patch")
+	if !strings.HasPrefix(text, "*** Begin Patch") && !IsUnifiedDiff(text) {
+		return "", NewDiffError("Patch must start with *** Begin Patch or be a unified diff")
 	}
 	paths := IdentifyFilesNeeded(text)
 	orig, err := LoadFiles(paths, openFn)
@@ -711,8 +800,8 @@ func RemoveFile(p string) error {
 }
 
 func ValidatePatch(patchText string, files map[string]string) (bool, string, error) {
-	if !strings.HasPrefix(patchText, "*** Begin Patch") {
-		return false, "Patch must start with *** Begin Patch", nil
+	if !strings.HasPrefix(patchText, "*** Begin Patch") && !IsUnifiedDiff(patchText) {
+		return false, "Patch must start with *** Begin Patch or be a unified diff", nil
 	}
 
 	neededFiles := IdentifyFilesNeeded(patchText)
@@ -0,0 +1,61 @@
+package diff
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMoveFile(t *testing.T) {
+	orig := map[string]string{"old.go": "package a\n\nfunc A() {}\n"}
+
+	t.Run("pure move", func(t *testing.T) {
+		text := "*** Begin Patch\n*** Move File: old.go\n*** Move to: new.go\n*** End Patch"
+		assert.Equal(t, []string{"old.go"}, IdentifyFilesNeeded(text))
+		assert.Equal(t, map[string]string{"old.go": "new.go"}, IdentifyFilesMoved(text))
+
+		patch, _, err := TextToPatch(text, orig)
+		require.NoError(t, err)
+		assert.Equal(t, ActionMove, patch.Actions["old.go"].Type)
+
+		result := applyForTest(t, text, orig)
+		assert.Equal(t, map[string]string{"new.go": orig["old.go"]}, result)
+	})
+
+	t.Run("move with changes", func(t *testing.T) {
+		text := "*** Begin Patch\n*** Move File: old.go\n*** Move to: b/new.go\n@@\n-package a\n+package b\n*** End Patch"
+		result := applyForTest(t, text, orig)
+		assert.Equal(t, map[string]string{"b/new.go": "package b\n\nfunc A() {}\n"}, result)
+	})
+
+	t.Run("update with move to", func(t *testing.T) {
+		text := "*** Begin Patch\n*** Update File: old.go\n*** Move to: new.go\n*** End Patch"
+		patch, _, err := TextToPatch(text, orig)
+		require.NoError(t, err)
+		assert.Equal(t, ActionMove, patch.Actions["old.go"].Type)
+	})
+
+	t.Run("failed remove rolls back", func(t *testing.T) {
+		written := map[string]string{}
+		commit := Commit{Changes: map[string]FileChange{
+			"old.go": {Type: ActionMove, OldContent: strPtr("x"), NewContent: strPtr("x"), MovePath: strPtr("new.go")},
+		}}
+		err := ApplyCommit(commit, func(p, c string) error {
+			written[p] = c
+			return nil
+		}, func(p string) error {
+			if p == "old.go" {
+				return NewDiffError("busy")
+			}
+			delete(written, p)
+			return nil
+		})
+		require.Error(t, err)
+		assert.Empty(t, written)
+	})
+}
+
+func strPtr(s string) *string {
+	return &s
+}
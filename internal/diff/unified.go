@@ -0,0 +1,444 @@
+package diff
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// unifiedFile is one file section of a standard unified or git diff.
+type unifiedFile struct {
+	oldPath  string // empty when the file is created
+	newPath  string // empty when the file is deleted
+	mode     *os.FileMode
+	binary   bool
+	hunks    []unifiedHunk
+	gitStyle bool
+}
+
+type unifiedHunk struct {
+	oldStart int
+	lines    []string // hunk body without "\ No newline" markers
+	noEOL    bool     // the new side does not end with a newline
+	oldNoEOL bool     // the old side does not end with a newline
+}
+
+var hunkHeaderRegex = regexp.MustCompile(`^@@ -(\d+)(?:,(\d+))? \+(\d+)(?:,(\d+))? @@`)
+
+// IsUnifiedDiff reports whether text looks like a standard unified diff or a
+// git diff rather than a "*** Begin Patch" patch.
+func IsUnifiedDiff(text string) bool {
+	text = strings.TrimSpace(text)
+	if strings.HasPrefix(text, "*** Begin Patch") {
+		return false
+	}
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		if strings.HasPrefix(line, "diff --git ") {
+			return true
+		}
+		if strings.HasPrefix(line, "--- ") && i+1 < len(lines) && strings.HasPrefix(lines[i+1], "+++ ") {
+			return true
+		}
+	}
+	return false
+}
+
+func (f unifiedFile) isAdd() bool    { return f.oldPath == "" }
+func (f unifiedFile) isDelete() bool { return f.newPath == "" }
+func (f unifiedFile) isRename() bool {
+	return f.oldPath != "" && f.newPath != "" && f.oldPath != f.newPath
+}
+
+// parseUnifiedDiff splits a unified or git diff into per file sections. It
+// only looks at the text; matching hunks against file contents happens in
+// unifiedToPatch.
+func parseUnifiedDiff(text string) ([]unifiedFile, error) {
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	var files []unifiedFile
+	var current *unifiedFile
+	// sawHeader tracks whether the current file already had its ---/+++ pair.
+	sawHeader := false
+
+	flush := func() {
+		if current != nil {
+			files = append(files, *current)
+		}
+		current = nil
+		sawHeader = false
+	}
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		switch {
+		case strings.HasPrefix(line, "diff --git "):
+			flush()
+			oldPath, newPath := parseGitHeaderPaths(strings.TrimPrefix(line, "diff --git "))
+			current = &unifiedFile{oldPath: oldPath, newPath: newPath, gitStyle: true}
+
+		case current != nil && current.gitStyle && !sawHeader && len(current.hunks) == 0 && isGitExtendedHeader(line):
+			applyGitExtendedHeader(current, line)
+
+		case strings.HasPrefix(line, "--- ") && i+1 < len(lines) && strings.HasPrefix(lines[i+1], "+++ "):
+			if current == nil || sawHeader || len(current.hunks) > 0 {
+				flush()
+				current = &unifiedFile{}
+			}
+			oldPath := parseHeaderPath(strings.TrimPrefix(line, "--- "))
+			newPath := parseHeaderPath(strings.TrimPrefix(lines[i+1], "+++ "))
+			current.oldPath, current.newPath = stripDiffPrefixes(oldPath, newPath)
+			sawHeader = true
+			i++
+
+		case strings.HasPrefix(line, "@@ "):
+			if current == nil {
+				return nil, NewDiffError(fmt.Sprintf("Hunk without file header: %s", line))
+			}
+			hunk, next, err := parseHunk(lines, i)
+			if err != nil {
+				return nil, err
+			}
+			current.hunks = append(current.hunks, hunk)
+			i = next - 1
+
+		case strings.HasPrefix(line, "Binary files ") || line == "GIT binary patch":
+			if current != nil {
+				current.binary = true
+			}
+
+		default:
+			// Anything else (commit messages, "index" lines of plain diffs,
+			// "Only in" notices) carries no change information.
+		}
+	}
+	flush()
+
+	if len(files) == 0 {
+		return nil, NewDiffError("Invalid patch text: no file changes found")
+	}
+	return files, nil
+}
+
+func isGitExtendedHeader(line string) bool {
+	for _, prefix := range []string{
+		"old mode ", "new mode ", "deleted file mode ", "new file mode ",
+		"rename from ", "rename to ", "similarity index ", "dissimilarity index ",
+		"index ", "copy from ", "copy to ",
+	} {
+		if strings.HasPrefix(line, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func applyGitExtendedHeader(f *unifiedFile, line string) {
+	switch {
+	case strings.HasPrefix(line, "new file mode "):
+		f.oldPath = ""
+		f.mode = parseFileMode(strings.TrimPrefix(line, "new file mode "))
+	case strings.HasPrefix(line, "deleted file mode "):
+		f.newPath = ""
+	case strings.HasPrefix(line, "new mode "):
+		f.mode = parseFileMode(strings.TrimPrefix(line, "new mode "))
+	case strings.HasPrefix(line, "rename from "):
+		f.oldPath = unquotePath(strings.TrimPrefix(line, "rename from "))
+	case strings.HasPrefix(line, "rename to "):
+		f.newPath = unquotePath(strings.TrimPrefix(line, "rename to "))
+	}
+}
+
+func parseFileMode(s string) *os.FileMode {
+	mode, err := strconv.ParseUint(strings.TrimSpace(s), 8, 32)
+	if err != nil {
+		return nil
+	}
+	m := os.FileMode(mode & 0o777)
+	return &m
+}
+
+// parseGitHeaderPaths extracts the two paths from "a/foo b/foo". Paths with
+// spaces are ambiguous here; the ---/+++ or rename headers that follow take
+// precedence when present.
+func parseGitHeaderPaths(s string) (string, string) {
+	if strings.HasPrefix(s, `"`) {
+		if end := strings.Index(s[1:], `" `); end >= 0 {
+			return stripDiffPrefixes(unquotePath(s[:end+2]), unquotePath(s[end+3:]))
+		}
+	}
+	if idx := strings.Index(s, " b/"); idx >= 0 {
+		return stripDiffPrefixes(s[:idx], s[idx+1:])
+	}
+	parts := strings.SplitN(s, " ", 2)
+	if len(parts) != 2 {
+		return s, s
+	}
+	return stripDiffPrefixes(parts[0], parts[1])
+}
+
+// parseHeaderPath parses the path of a ---/+++ line, dropping the optional
+// timestamp that diff(1) appends after a tab.
+func parseHeaderPath(s string) string {
+	if idx := strings.Index(s, "\t"); idx >= 0 {
+		s = s[:idx]
+	}
+	s = unquotePath(strings.TrimSpace(s))
+	if s == "/dev/null" {
+		return ""
+	}
+	return s
+}
+
+func unquotePath(s string) string {
+	if strings.HasPrefix(s, `"`) {
+		if unquoted, err := strconv.Unquote(s); err == nil {
+			return unquoted
+		}
+	}
+	return s
+}
+
+func stripDiffPrefixes(oldPath, newPath string) (string, string) {
+	oldPrefixed := oldPath == "" || strings.HasPrefix(oldPath, "a/")
+	newPrefixed := newPath == "" || strings.HasPrefix(newPath, "b/")
+	if oldPrefixed && newPrefixed && (oldPath != "" || newPath != "") {
+		oldPath = strings.TrimPrefix(oldPath, "a/")
+		newPath = strings.TrimPrefix(newPath, "b/")
+	}
+	return oldPath, newPath
+}
+
+func parseHunk(lines []string, index int) (unifiedHunk, int, error) {
+	header := lines[index]
+	m := hunkHeaderRegex.FindStringSubmatch(header)
+	if m == nil {
+		return unifiedHunk{}, 0, NewDiffError(fmt.Sprintf("Invalid hunk header: %s", header))
+	}
+	oldStart, _ := strconv.Atoi(m[1])
+	oldCount, newCount := 1, 1
+	if m[2] != "" {
+		oldCount, _ = strconv.Atoi(m[2])
+	}
+	if m[4] != "" {
+		newCount, _ = strconv.Atoi(m[4])
+	}
+
+	hunk := unifiedHunk{oldStart: oldStart}
+	i := index + 1
+	for ; i < len(lines) && (oldCount > 0 || newCount > 0); i++ {
+		line := lines[i]
+		if line == "" {
+			// Some editors strip the single space of empty context lines.
+			line = " "
+		}
+		switch line[0] {
+		case ' ':
+			oldCount--
+			newCount--
+		case '-':
+			oldCount--
+		case '+':
+			newCount--
+		case '\\':
+			hunk.markNoEOL()
+			continue
+		default:
+			return unifiedHunk{}, 0, NewDiffError(fmt.Sprintf("Invalid hunk line: %s", line))
+		}
+		hunk.lines = append(hunk.lines, line)
+	}
+	if oldCount > 0 || newCount > 0 {
+		return unifiedHunk{}, 0, NewDiffError(fmt.Sprintf("Truncated hunk: %s", header))
+	}
+	// A "\ No newline at end of file" marker right after the hunk applies to
+	// its last line.
+	if i < len(lines) && strings.HasPrefix(lines[i], `\`) {
+		hunk.markNoEOL()
+		i++
+	}
+	return hunk, i, nil
+}
+
+// markNoEOL records a "\ No newline at end of file" marker, which applies to
+// the side of the line before it
+func (h *unifiedHunk) markNoEOL() {
+	if len(h.lines) == 0 {
+		return
+	}
+	switch h.lines[len(h.lines)-1][0] {
+	case ' ':
+		h.oldNoEOL, h.noEOL = true, true
+	case '-':
+		h.oldNoEOL = true
+	case '+':
+		h.noEOL = true
+	}
+}
+
+// identifyUnifiedFiles returns the paths a unified diff needs to read and the
+// paths it creates.
+func identifyUnifiedFiles(text string) (needed []string, added []string) {
+	files, err := parseUnifiedDiff(text)
+	if err != nil {
+		return nil, nil
+	}
+	for _, f := range files {
+		if f.isAdd() {
+			added = append(added, f.newPath)
+		} else {
+			needed = append(needed, f.oldPath)
+		}
+	}
+	return needed, added
+}
+
+func identifyUnifiedMoves(text string) map[string]string {
+	result := make(map[string]string)
+	files, err := parseUnifiedDiff(text)
+	if err != nil {
+		return result
+	}
+	for _, f := range files {
+		if f.isRename() {
+			result[f.oldPath] = f.newPath
+		}
+	}
+	return result
+}
+
+// unifiedToPatch converts a unified or git diff into a Patch, locating each
+// hunk in the original file. Hunks are first tried at the line number from
+// their header and otherwise searched for, like patch(1) does.
+func unifiedToPatch(text string, orig map[string]string) (Patch, int, error) {
+	files, err := parseUnifiedDiff(text)
+	if err != nil {
+		return Patch{}, 0, err
+	}
+
+	patch := Patch{Actions: make(map[string]PatchAction, len(files))}
+	fuzz := 0
+	for _, f := range files {
+		if f.binary {
+			return Patch{}, 0, fileError("Update", "Binary patches are not supported", f.newPath)
+		}
+
+		if f.isAdd() {
+			if _, exists := patch.Actions[f.newPath]; exists {
+				return Patch{}, 0, fileError("Add", "Duplicate Path", f.newPath)
+			}
+			if _, exists := orig[f.newPath]; exists {
+				return Patch{}, 0, fileError("Add", "File already exists", f.newPath)
+			}
+			newFile := addedFileContent(f.hunks)
+			patch.Actions[f.newPath] = PatchAction{Type: ActionAdd, NewFile: &newFile, Chunks: []Chunk{}, Mode: f.mode}
+			continue
+		}
+
+		path := f.oldPath
+		if _, exists := patch.Actions[path]; exists {
+			return Patch{}, 0, fileError("Update", "Duplicate Path", path)
+		}
+		text, exists := orig[path]
+		if !exists {
+			return Patch{}, 0, fileError("Update", "Missing File", path)
+		}
+
+		if f.isDelete() {
+			patch.Actions[path] = PatchAction{Type: ActionDelete, Chunks: []Chunk{}}
+			continue
+		}
+
+		action := PatchAction{Type: ActionUpdate, Chunks: []Chunk{}, Mode: f.mode}
+		if f.isRename() {
+			movePath := f.newPath
+			action.Type = ActionMove
+			action.MovePath = &movePath
+		}
+
+		fileLines := strings.Split(text, "\n")
+		index := 0
+		for _, hunk := range f.hunks {
+			context, chunks, _, _ := peekNextSection(hunk.lines, 0)
+			start := -1
+			hinted := hunk.oldStart - 1
+			if len(context) == 0 {
+				hinted = hunk.oldStart
+			}
+			if hinted >= index && linesEqualAt(fileLines, context, hinted) {
+				start = hinted
+			}
+			if start == -1 {
+				var hunkFuzz int
+				start, hunkFuzz = findContext(fileLines, context, index, false)
+				if start == -1 {
+					return Patch{}, 0, contextError(hunk.oldStart, strings.Join(context, "\n"), false)
+				}
+				fuzz += hunkFuzz
+			}
+			for _, ch := range chunks {
+				ch.OrigIndex += start
+				action.Chunks = append(action.Chunks, ch)
+			}
+			index = start + len(context)
+			applyFinalNewline(&action, hunk, fileLines, index)
+		}
+		patch.Actions[path] = action
+	}
+	return patch, fuzz, nil
+}
+
+// applyFinalNewline adds or removes the newline ending the file when a hunk
+// that ends at index changes it. A file that ends with a newline has an
+// empty last element in fileLines.
+func applyFinalNewline(action *PatchAction, hunk unifiedHunk, fileLines []string, index int) {
+	if hunk.noEOL == hunk.oldNoEOL || len(action.Chunks) == 0 {
+		return
+	}
+	last := &action.Chunks[len(action.Chunks)-1]
+	if last.OrigIndex+len(last.DelLines) != index {
+		return
+	}
+	hasEOL := len(fileLines) > 0 && fileLines[len(fileLines)-1] == ""
+	switch {
+	case hunk.noEOL && hasEOL && index == len(fileLines)-1:
+		last.DelLines = append(last.DelLines, "")
+	case hunk.oldNoEOL && !hasEOL && index == len(fileLines):
+		last.InsLines = append(last.InsLines, "")
+	}
+}
+
+func linesEqualAt(lines []string, context []string, start int) bool {
+	if start < 0 || start+len(context) > len(lines) {
+		return false
+	}
+	for j := range context {
+		if lines[start+j] != context[j] {
+			return false
+		}
+	}
+	return true
+}
+
+// addedFileContent rebuilds the content of a created file from its hunks.
+func addedFileContent(hunks []unifiedHunk) string {
+	var lines []string
+	noEOL := false
+	for _, hunk := range hunks {
+		for _, line := range hunk.lines {
+			if line[0] == '+' {
+				lines = append(lines, line[1:])
+			}
+		}
+		noEOL = hunk.noEOL
+	}
+	if len(lines) == 0 {
+		return ""
+	}
+	content := strings.Join(lines, "\n")
+	if !noEOL {
+		content += "\n"
+	}
+	return content
+}
@@ -0,0 +1,165 @@
+package diff
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func applyForTest(t *testing.T, text string, orig map[string]string) map[string]string {
+	t.Helper()
+	patch, fuzz, err := TextToPatch(text, orig)
+	require.NoError(t, err)
+	assert.Equal(t, 0, fuzz)
+	commit, err := PatchToCommit(patch, orig)
+	require.NoError(t, err)
+
+	result := make(map[string]string, len(orig))
+	for k, v := range orig {
+		result[k] = v
+	}
+	err = ApplyCommit(commit, func(p, content string) error {
+		result[p] = content
+		return nil
+	}, func(p string) error {
+		delete(result, p)
+		return nil
+	})
+	require.NoError(t, err)
+	return result
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	t.Run("detects formats", func(t *testing.T) {
+		assert.True(t, IsUnifiedDiff("--- a/x\n+++ b/x\n@@ -1 +1 @@\n-a\n+b\n"))
+		assert.True(t, IsUnifiedDiff("diff --git a/x b/y\nrename from x\nrename to y\n"))
+		assert.False(t, IsUnifiedDiff("*** Begin Patch\n*** Delete File: x\n*** End Patch"))
+	})
+
+	t.Run("updates a file", func(t *testing.T) {
+		orig := map[string]string{"main.go": "package main\n\nfunc a() {}\n\nfunc b() {}\n"}
+		text := `--- a/main.go
++++ b/main.go
+@@ -3,3 +3,4 @@
+ func a() {}
+
+-func b() {}
++func b() { a() }
++func c() {}
+`
+		assert.Equal(t, []string{"main.go"}, IdentifyFilesNeeded(text))
+		result := applyForTest(t, text, orig)
+		assert.Equal(t, "package main\n\nfunc a() {}\n\nfunc b() { a() }\nfunc c() {}\n", result["main.go"])
+	})
+
+	t.Run("hunk with wrong line numbers is located by context", func(t *testing.T) {
+		orig := map[string]string{"f.txt": "one\ntwo\nthree\nfour\n"}
+		text := "--- f.txt\n+++ f.txt\n@@ -10,2 +10,2 @@\n three\n-four\n+FOUR\n"
+		result := applyForTest(t, text, orig)
+		assert.Equal(t, "one\ntwo\nthree\nFOUR\n", result["f.txt"])
+	})
+
+	t.Run("git create, delete, rename and mode change", func(t *testing.T) {
+		orig := map[string]string{
+			"old.txt":  "keep\n",
+			"gone.txt": "bye\n",
+			"run.sh":   "echo hi\n",
+		}
+		text := `diff --git a/new.txt b/new.txt
+new file mode 100644
+index 0000000..e69de29
+--- /dev/null
++++ b/new.txt
+@@ -0,0 +1,2 @@
++hello
++world
+diff --git a/gone.txt b/gone.txt
+deleted file mode 100644
+--- a/gone.txt
++++ /dev/null
+@@ -1 +0,0 @@
+-bye
+diff --git a/old.txt b/renamed.txt
+similarity index 100%
+rename from old.txt
+rename to renamed.txt
+diff --git a/run.sh b/run.sh
+old mode 100644
+new mode 100755
+`
+		assert.ElementsMatch(t, []string{"old.txt", "gone.txt", "run.sh"}, IdentifyFilesNeeded(text))
+		assert.Equal(t, []string{"new.txt"}, IdentifyFilesAdded(text))
+
+		patch, _, err := TextToPatch(text, orig)
+		require.NoError(t, err)
+		require.NotNil(t, patch.Actions["run.sh"].Mode)
+		assert.Equal(t, os.FileMode(0o755), *patch.Actions["run.sh"].Mode)
+
+		result := applyForTest(t, text, orig)
+		assert.Equal(t, map[string]string{
+			"new.txt":     "hello\nworld\n",
+			"renamed.txt": "keep\n",
+			"run.sh":      "echo hi\n",
+		}, result)
+	})
+
+	t.Run("no newline at end of added file", func(t *testing.T) {
+		text := "--- /dev/null\n+++ b/a.txt\n@@ -0,0 +1 @@\n+x\n\\ No newline at end of file\n"
+		result := applyForTest(t, text, map[string]string{})
+		assert.Equal(t, "x", result["a.txt"])
+	})
+
+	t.Run("no newline at end of updated file", func(t *testing.T) {
+		tests := []struct {
+			name string
+			orig string
+			text string
+			want string
+		}{
+			{
+				name: "removes the final newline",
+				orig: "one\ntwo\n",
+				text: "--- a.txt\n+++ a.txt\n@@ -1,2 +1,2 @@\n one\n-two\n+TWO\n\\ No newline at end of file\n",
+				want: "one\nTWO",
+			},
+			{
+				name: "appends to a file without a final newline",
+				orig: "one\ntwo",
+				text: "--- a.txt\n+++ a.txt\n@@ -1,2 +1,3 @@\n one\n-two\n\\ No newline at end of file\n+two\n+three\n\\ No newline at end of file\n",
+				want: "one\ntwo\nthree",
+			},
+			{
+				name: "adds the missing final newline",
+				orig: "one\ntwo",
+				text: "--- a.txt\n+++ a.txt\n@@ -1,2 +1,2 @@\n one\n-two\n\\ No newline at end of file\n+two\n",
+				want: "one\ntwo\n",
+			},
+			{
+				name: "removes the last line of a file without a final newline",
+				orig: "one\ntwo",
+				text: "--- a.txt\n+++ a.txt\n@@ -1,2 +1 @@\n one\n-two\n\\ No newline at end of file\n",
+				want: "one\n",
+			},
+			{
+				name: "keeps a missing newline in the context",
+				orig: "one\ntwo",
+				text: "--- a.txt\n+++ a.txt\n@@ -1,2 +1,2 @@\n-one\n+ONE\n two\n\\ No newline at end of file\n",
+				want: "ONE\ntwo",
+			},
+			{
+				name: "keeps the newline of a hunk before the end",
+				orig: "one\ntwo\nthree\n",
+				text: "--- a.txt\n+++ a.txt\n@@ -1,2 +1,2 @@\n-one\n+ONE\n two\n",
+				want: "ONE\ntwo\nthree\n",
+			},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				result := applyForTest(t, tt.text, map[string]string{"a.txt": tt.orig})
+				assert.Equal(t, tt.want, result["a.txt"])
+			})
+		}
+	})
+}
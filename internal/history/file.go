@@ -36,6 +36,7 @@ type Service interface {
 	ListBySession(ctx context.Context, sessionID string) ([]File, error)
 	ListLatestSessionFiles(ctx context.Context, sessionID string) ([]File, error)
 	Update(ctx context.Context, file File) (File, error)
+	Rename(ctx context.Context, sessionID, oldPath, newPath string) error
 	Delete(ctx context.Context, id string) error
 	DeleteSessionFiles(ctx context.Context, sessionID string) error
 }
@@ -212,6 +213,57 @@ func (s *service) Update(ctx context.Context, file File) (File, error) {
 	return updatedFile, nil
 }
 
+// Rename moves every version of a file recorded in the session to a new
+// path, so the file keeps its history after being moved. History already
+// recorded for the new path belongs to the file the move replaced and is
+// deleted, as the versions of both files would collide.
+func (s *service) Rename(ctx context.Context, sessionID, oldPath, newPath string) error {
+	dbFiles, err := s.q.ListFilesByPath(ctx, newPath)
+	if err != nil {
+		return err
+	}
+	var replaced []File
+	for _, dbFile := range dbFiles {
+		if dbFile.SessionID == sessionID {
+			replaced = append(replaced, s.fromDBItem(dbFile))
+		}
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	qtx := s.q.WithTx(tx)
+	for _, file := range replaced {
+		if err := qtx.DeleteFile(ctx, file.ID); err != nil {
+			return err
+		}
+	}
+	err = qtx.RenameSessionFile(ctx, db.RenameSessionFileParams{
+		NewPath:   newPath,
+		SessionID: sessionID,
+		OldPath:   oldPath,
+	})
+	if err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	for _, file := range replaced {
+		s.Publish(pubsub.DeletedEvent, file)
+	}
+	file, err := s.GetByPathAndSession(ctx, newPath, sessionID)
+	if err != nil {
+		return err
+	}
+	s.Publish(pubsub.UpdatedEvent, file)
+	return nil
+}
+
 func (s *service) Delete(ctx context.Context, id string) error {
 	file, err := s.Get(ctx, id)
 	if err != nil {
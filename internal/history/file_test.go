@@ -0,0 +1,60 @@
+package history
+
+import (
+	"context"
+	"testing"
+
+	"github.com/omnitrix-sh/cli/internal/config"
+	"github.com/omnitrix-sh/cli/internal/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRename(t *testing.T) {
+	dir := t.TempDir()
+	cfg, err := config.Load(dir, false)
+	require.NoError(t, err)
+	cfg.Data.Directory = dir
+	conn, err := db.Connect()
+	require.NoError(t, err)
+	defer conn.Close()
+
+	ctx := context.Background()
+	q := db.New(conn)
+	for _, id := range []string{"session", "other"} {
+		_, err := q.CreateSession(ctx, db.CreateSessionParams{ID: id, Title: id})
+		require.NoError(t, err)
+	}
+	s := NewService(q, conn)
+
+	versions := func(sessionID, path string) []string {
+		files, err := s.ListBySession(ctx, sessionID)
+		require.NoError(t, err)
+		var result []string
+		for _, f := range files {
+			if f.Path == path {
+				result = append(result, f.Version+" "+f.Content)
+			}
+		}
+		return result
+	}
+
+	_, err = s.Create(ctx, "session", "old.go", "old")
+	require.NoError(t, err)
+	_, err = s.CreateVersion(ctx, "session", "old.go", "old v1")
+	require.NoError(t, err)
+	// The target of the move already has history in the session and in
+	// another session
+	_, err = s.Create(ctx, "session", "new.go", "replaced")
+	require.NoError(t, err)
+	_, err = s.CreateVersion(ctx, "session", "new.go", "replaced v1")
+	require.NoError(t, err)
+	_, err = s.Create(ctx, "other", "new.go", "other")
+	require.NoError(t, err)
+
+	require.NoError(t, s.Rename(ctx, "session", "old.go", "new.go"))
+
+	assert.Empty(t, versions("session", "old.go"))
+	assert.ElementsMatch(t, []string{"initial old", "v1 old v1"}, versions("session", "new.go"))
+	assert.Equal(t, []string{"initial other"}, versions("other", "new.go"))
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 
+	"github.com/omnitrix-sh/cli/internal/budget"
 	"github.com/omnitrix-sh/cli/internal/config"
 	"github.com/omnitrix-sh/cli/internal/llm/tools"
 	"github.com/omnitrix-sh/cli/internal/lsp"
@@ -15,7 +16,8 @@ import (
 type agentTool struct {
 	sessions   session.Service
 	messages   message.Service
-	lspClients map[string]*lsp.Client
+	budgets    budget.Service
+	lspClients *lsp.Clients
 }
 
 const (
@@ -54,7 +56,7 @@ func (b *agentTool) Run(ctx context.Context, call tools.ToolCall) (tools.ToolRes
 		return tools.ToolResponse{}, fmt.Errorf("session_id and message_id are required")
 	}
 
-	agent, err := NewAgent(config.AgentTask, b.sessions, b.messages, TaskAgentTools(b.lspClients))
+	agent, err := NewAgent(config.AgentTask, b.sessions, b.messages, b.budgets, TaskAgentTools(b.lspClients))
 	if err != nil {
 		return tools.ToolResponse{}, fmt.Errorf("error creating agent: %s", err)
 	}
@@ -88,6 +90,10 @@ func (b *agentTool) Run(ctx context.Context, call tools.ToolCall) (tools.ToolRes
 	}
 
 	parentSession.Cost += updatedSession.Cost
+	parentSession.InputTokens += updatedSession.InputTokens
+	parentSession.OutputTokens += updatedSession.OutputTokens
+	parentSession.CacheReadTokens += updatedSession.CacheReadTokens
+	parentSession.CacheCreationTokens += updatedSession.CacheCreationTokens
 
 	_, err = b.sessions.Save(ctx, parentSession)
 	if err != nil {
@@ -99,11 +105,13 @@ func (b *agentTool) Run(ctx context.Context, call tools.ToolCall) (tools.ToolRes
 func NewAgentTool(
 	Sessions session.Service,
 	Messages message.Service,
-	LspClients map[string]*lsp.Client,
+	Budgets budget.Service,
+	LspClients *lsp.Clients,
 ) tools.BaseTool {
 	return &agentTool{
 		sessions:   Sessions,
 		messages:   Messages,
+		budgets:    Budgets,
 		lspClients: LspClients,
 	}
 }
@@ -8,6 +8,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/omnitrix-sh/cli/internal/budget"
 	"github.com/omnitrix-sh/cli/internal/config"
 	"github.com/omnitrix-sh/cli/internal/llm/models"
 	"github.com/omnitrix-sh/cli/internal/llm/prompt"
@@ -31,6 +32,9 @@ const (
 	AgentEventTypeError     AgentEventType = "error"
 	AgentEventTypeResponse  AgentEventType = "response"
 	AgentEventTypeSummarize AgentEventType = "summarize"
+	// AgentEventTypeContext reports the tokens the conversation of a session
+	// takes in the context window
+	AgentEventTypeContext AgentEventType = "context"
 )
 
 type AgentEvent struct {
@@ -42,6 +46,9 @@ type AgentEvent struct {
 	SessionID string
 	Progress  string
 	Done      bool
+
+	// ContextTokens is the estimated size of the conversation
+	ContextTokens int64
 }
 
 type Service interface {
@@ -52,13 +59,18 @@ type Service interface {
 	IsSessionBusy(sessionID string) bool
 	IsBusy() bool
 	Update(agentName config.AgentName, modelID models.ModelID) (models.Model, error)
+	// UpdateTools replaces the tools of the agent, such as when language
+	// servers were enabled after it was created
+	UpdateTools(agentTools []tools.BaseTool) error
 	Summarize(ctx context.Context, sessionID string) error
 }
 
 type agent struct {
 	*pubsub.Broker[AgentEvent]
+	name     config.AgentName
 	sessions session.Service
 	messages message.Service
+	budgets  budget.Service
 
 	tools    []tools.BaseTool
 	provider provider.Provider
@@ -73,6 +85,7 @@ func NewAgent(
 	agentName config.AgentName,
 	sessions session.Service,
 	messages message.Service,
+	budgets budget.Service,
 	agentTools []tools.BaseTool,
 ) (Service, error) {
 	agentProvider, err := createAgentProvider(agentName)
@@ -97,9 +110,11 @@ func NewAgent(
 
 	agent := &agent{
 		Broker:            pubsub.NewBroker[AgentEvent](),
+		name:              agentName,
 		provider:          agentProvider,
 		messages:          messages,
 		sessions:          sessions,
+		budgets:           budgets,
 		tools:             agentTools,
 		titleProvider:     titleProvider,
 		summarizeProvider: summarizeProvider,
@@ -275,6 +290,13 @@ func (a *agent) processGeneration(ctx context.Context, sessionID, content string
 		default:
 			// Continue processing
 		}
+		msgHistory = a.fitContext(sessionID, msgHistory)
+		if err := a.checkBudget(ctx, sessionID, msgHistory); err != nil {
+			if errors.Is(err, context.Canceled) {
+				return a.err(ErrRequestCancelled)
+			}
+			return a.err(err)
+		}
 		agentMessage, toolResults, err := a.streamAndHandleEvents(ctx, sessionID, msgHistory)
 		if err != nil {
 			if errors.Is(err, context.Canceled) {
@@ -296,6 +318,7 @@ func (a *agent) processGeneration(ctx context.Context, sessionID, content string
 			msgHistory = append(msgHistory, agentMessage, *toolResults)
 			continue
 		}
+		a.countContext(sessionID, msgHistory, agentMessage)
 		return AgentEvent{
 			Type:    AgentEventTypeResponse,
 			Message: agentMessage,
@@ -315,6 +338,7 @@ func (a *agent) createUserMessage(ctx context.Context, sessionID, content string
 
 func (a *agent) streamAndHandleEvents(ctx context.Context, sessionID string, msgHistory []message.Message) (message.Message, *message.Message, error) {
 	ctx = context.WithValue(ctx, tools.SessionIDContextKey, sessionID)
+	start := time.Now()
 	eventChan := a.provider.StreamResponse(ctx, msgHistory, a.tools)
 
 	assistantMsg, err := a.messages.Create(ctx, sessionID, message.CreateMessageParams{
@@ -329,6 +353,14 @@ func (a *agent) streamAndHandleEvents(ctx context.Context, sessionID string, msg
 	ctx = context.WithValue(ctx, tools.MessageIDContextKey, assistantMsg.ID)
 
 	for event := range eventChan {
+		switch event.Type {
+		case provider.EventThinkingDelta, provider.EventContentDelta, provider.EventToolUseStart:
+			if assistantMsg.Usage.TimeToFirstToken == 0 {
+				assistantMsg.Usage.TimeToFirstToken = time.Since(start)
+			}
+		case provider.EventComplete:
+			assistantMsg.Usage.Latency = time.Since(start)
+		}
 		if processErr := a.processEvent(ctx, sessionID, &assistantMsg, event); processErr != nil {
 			a.finishMessage(ctx, &assistantMsg, message.FinishReasonCanceled)
 			return assistantMsg, nil, processErr
@@ -442,7 +474,7 @@ func (a *agent) processEvent(ctx context.Context, sessionID string, assistantMsg
 
 	switch event.Type {
 	case provider.EventThinkingDelta:
-		assistantMsg.AppendReasoningContent(event.Content)
+		assistantMsg.AppendReasoningContent(event.Thinking)
 		return a.messages.Update(ctx, *assistantMsg)
 	case provider.EventContentDelta:
 		assistantMsg.AppendContent(event.Content)
@@ -470,31 +502,60 @@ func (a *agent) processEvent(ctx context.Context, sessionID string, assistantMsg
 		logging.ErrorPersist(event.Error.Error())
 		return event.Error
 	case provider.EventComplete:
+		model := answeringModel(a.provider, event.Response)
+		assistantMsg.Model = model.ID
 		assistantMsg.SetToolCalls(event.Response.ToolCalls)
+		if len(event.Response.EncryptedReasoning) > 0 {
+			assistantMsg.SetEncryptedReasoning(event.Response.EncryptedReasoning)
+		}
 		assistantMsg.AddFinish(event.Response.FinishReason)
+		usage := event.Response.Usage
+		assistantMsg.Usage.InputTokens = usage.InputTokens
+		assistantMsg.Usage.OutputTokens = usage.OutputTokens
+		assistantMsg.Usage.CacheReadTokens = usage.CacheReadTokens
+		assistantMsg.Usage.CacheCreationTokens = usage.CacheCreationTokens
+		assistantMsg.Usage.Cost = usageCost(model, usage)
 		if err := a.messages.Update(ctx, *assistantMsg); err != nil {
 			return fmt.Errorf("failed to update message: %w", err)
 		}
-		return a.TrackUsage(ctx, sessionID, a.provider.Model(), event.Response.Usage)
+		return a.TrackUsage(ctx, sessionID, model, usage)
 	}
 
 	return nil
 }
 
-func (a *agent) TrackUsage(ctx context.Context, sessionID string, model models.Model, usage provider.TokenUsage) error {
-	sess, err := a.sessions.Get(ctx, sessionID)
-	if err != nil {
-		return fmt.Errorf("failed to get session: %w", err)
+// answeringModel returns the model that produced response, which is not the
+// provider's current model when a fallback model answered
+func answeringModel(p provider.Provider, response *provider.ProviderResponse) models.Model {
+	if response != nil {
+		if model, ok := models.SupportedModels[response.Model]; ok {
+			return model
+		}
 	}
+	return p.Model()
+}
 
-	cost := model.CostPer1MInCached/1e6*float64(usage.CacheCreationTokens) +
+// usageCost returns the cost of a request to a model
+func usageCost(model models.Model, usage provider.TokenUsage) float64 {
+	return model.CostPer1MInCached/1e6*float64(usage.CacheCreationTokens) +
 		model.CostPer1MOutCached/1e6*float64(usage.CacheReadTokens) +
 		model.CostPer1MIn/1e6*float64(usage.InputTokens) +
 		model.CostPer1MOut/1e6*float64(usage.OutputTokens)
+}
 
-	sess.Cost += cost
+func (a *agent) TrackUsage(ctx context.Context, sessionID string, model models.Model, usage provider.TokenUsage) error {
+	sess, err := a.sessions.Get(ctx, sessionID)
+	if err != nil {
+		return fmt.Errorf("failed to get session: %w", err)
+	}
+
+	sess.Cost += usageCost(model, usage)
 	sess.CompletionTokens = usage.OutputTokens + usage.CacheReadTokens
 	sess.PromptTokens = usage.InputTokens + usage.CacheCreationTokens
+	sess.InputTokens += usage.InputTokens
+	sess.OutputTokens += usage.OutputTokens
+	sess.CacheReadTokens += usage.CacheReadTokens
+	sess.CacheCreationTokens += usage.CacheCreationTokens
 
 	_, err = a.sessions.Save(ctx, sess)
 	if err != nil {
@@ -503,6 +564,28 @@ func (a *agent) TrackUsage(ctx context.Context, sessionID string, model models.M
 	return nil
 }
 
+// checkBudget asks for confirmation when the next request would exceed a
+// budget cap. Its cost is estimated from the tokens of the history and a
+// response of the most tokens the agent allows.
+func (a *agent) checkBudget(ctx context.Context, sessionID string, history []message.Message) error {
+	model := a.provider.Model()
+	tokens := a.provider.CountTokens(history, a.tools)
+	maxTokens := responseMaxTokens(config.Get().Agents[a.name], model)
+	return a.budgets.Check(ctx, sessionID, session.Usage{
+		Cost:   model.CostPer1MIn/1e6*float64(tokens) + model.CostPer1MOut/1e6*float64(maxTokens),
+		Tokens: tokens,
+	})
+}
+
+// responseMaxTokens returns the most tokens a response of a model may have
+func responseMaxTokens(agentConfig config.Agent, model models.Model) int64 {
+	maxTokens := model.DefaultMaxTokens
+	if agentConfig.MaxTokens > 0 {
+		maxTokens = agentConfig.MaxTokens
+	}
+	return maxTokens
+}
+
 func (a *agent) Update(agentName config.AgentName, modelID models.ModelID) (models.Model, error) {
 	if a.IsBusy() {
 		return models.Model{}, fmt.Errorf("cannot change model while processing requests")
@@ -522,6 +605,14 @@ func (a *agent) Update(agentName config.AgentName, modelID models.ModelID) (mode
 	return a.provider.Model(), nil
 }
 
+func (a *agent) UpdateTools(agentTools []tools.BaseTool) error {
+	if a.IsBusy() {
+		return fmt.Errorf("cannot change tools while processing requests")
+	}
+	a.tools = agentTools
+	return nil
+}
+
 func (a *agent) Summarize(ctx context.Context, sessionID string) error {
 	if a.summarizeProvider == nil {
 		return fmt.Errorf("summarize provider not available")
@@ -596,11 +687,13 @@ func (a *agent) Summarize(ctx context.Context, sessionID string) error {
 		a.Publish(pubsub.CreatedEvent, event)
 
 		// Send the messages to the summarize provider
+		start := time.Now()
 		response, err := a.summarizeProvider.SendMessages(
 			summarizeCtx,
 			msgsWithPrompt,
 			make([]tools.BaseTool, 0),
 		)
+		latency := time.Since(start)
 		if err != nil {
 			event = AgentEvent{
 				Type:  AgentEventTypeError,
@@ -648,7 +741,7 @@ func (a *agent) Summarize(ctx context.Context, sessionID string) error {
 					Time:   time.Now().Unix(),
 				},
 			},
-			Model: a.summarizeProvider.Model().ID,
+			Model: answeringModel(a.summarizeProvider, response).ID,
 		})
 		if err != nil {
 			event = AgentEvent{
@@ -660,16 +753,28 @@ func (a *agent) Summarize(ctx context.Context, sessionID string) error {
 			a.Publish(pubsub.CreatedEvent, event)
 			return
 		}
+		model := answeringModel(a.summarizeProvider, response)
+		usage := response.Usage
+		// The summary is not streamed, its first token comes with the rest
+		msg.Usage = message.Usage{
+			InputTokens:         usage.InputTokens,
+			OutputTokens:        usage.OutputTokens,
+			CacheReadTokens:     usage.CacheReadTokens,
+			CacheCreationTokens: usage.CacheCreationTokens,
+			Cost:                usageCost(model, usage),
+			Latency:             latency,
+		}
+		if err := a.messages.Update(summarizeCtx, msg); err != nil {
+			logging.Warn("Failed to record the usage of the summary", "error", err)
+		}
 		oldSession.SummaryMessageID = msg.ID
 		oldSession.CompletionTokens = response.Usage.OutputTokens
 		oldSession.PromptTokens = 0
-		model := a.summarizeProvider.Model()
-		usage := response.Usage
-		cost := model.CostPer1MInCached/1e6*float64(usage.CacheCreationTokens) +
-			model.CostPer1MOutCached/1e6*float64(usage.CacheReadTokens) +
-			model.CostPer1MIn/1e6*float64(usage.InputTokens) +
-			model.CostPer1MOut/1e6*float64(usage.OutputTokens)
-		oldSession.Cost += cost
+		oldSession.Cost += usageCost(model, usage)
+		oldSession.InputTokens += usage.InputTokens
+		oldSession.OutputTokens += usage.OutputTokens
+		oldSession.CacheReadTokens += usage.CacheReadTokens
+		oldSession.CacheCreationTokens += usage.CacheCreationTokens
 		_, err = a.sessions.Save(summarizeCtx, oldSession)
 		if err != nil {
 			event = AgentEvent{
@@ -703,7 +808,31 @@ func createAgentProvider(agentName config.AgentName) (provider.Provider, error)
 	if !ok {
 		return nil, fmt.Errorf("model %s not supported", agentConfig.Model)
 	}
+	agentProvider, err := createModelProvider(agentName, agentConfig, model)
+	if err != nil {
+		return nil, err
+	}
+
+	providers := []provider.Provider{agentProvider}
+	for _, modelID := range agentConfig.Fallbacks {
+		fallbackModel, ok := models.SupportedModels[modelID]
+		if !ok {
+			continue
+		}
+		fallbackProvider, err := createModelProvider(agentName, agentConfig, fallbackModel)
+		if err != nil {
+			logging.Warn("Skipping fallback model", "agent", agentName, "model", modelID, "error", err)
+			continue
+		}
+		providers = append(providers, fallbackProvider)
+	}
+
+	return provider.NewFallbackProvider(providers...), nil
+}
 
+// createModelProvider creates the provider for one of the models of an agent
+func createModelProvider(agentName config.AgentName, agentConfig config.Agent, model models.Model) (provider.Provider, error) {
+	cfg := config.Get()
 	providerCfg, ok := cfg.Providers[model.Provider]
 	if !ok {
 		return nil, fmt.Errorf("provider %s not supported", model.Provider)
@@ -711,24 +840,25 @@ func createAgentProvider(agentName config.AgentName) (provider.Provider, error)
 	if providerCfg.Disabled {
 		return nil, fmt.Errorf("provider %s is not enabled", model.Provider)
 	}
-	maxTokens := model.DefaultMaxTokens
-	if agentConfig.MaxTokens > 0 {
-		maxTokens = agentConfig.MaxTokens
-	}
+	maxTokens := responseMaxTokens(agentConfig, model)
 	opts := []provider.ProviderClientOption{
 		provider.WithAPIKey(providerCfg.APIKey),
 		provider.WithModel(model),
 		provider.WithSystemMessage(prompt.GetAgentPrompt(agentName, model.Provider)),
 		provider.WithMaxTokens(maxTokens),
+		provider.WithMaxRetries(providerCfg.MaxRetries),
+		provider.WithRequestsPerMinute(providerCfg.RequestsPerMinute),
 	}
-	if model.Provider == models.ProviderOpenAI || model.Provider == models.ProviderLocal && model.CanReason {
-		opts = append(
-			opts,
-			provider.WithOpenAIOptions(
-				provider.WithReasoningEffort(agentConfig.ReasoningEffort),
-			),
-		)
-	} else if model.Provider == models.ProviderAnthropic && model.CanReason && agentName == config.AgentCoder {
+	customType := cfg.CustomProviders[model.Provider].Type
+	if model.Provider == models.ProviderOpenAI || (model.Provider == models.ProviderLocal || customType == config.CustomProviderOpenAI) && model.CanReason {
+		openaiOpts := []provider.OpenAIOption{
+			provider.WithReasoningEffort(agentConfig.ReasoningEffort),
+		}
+		if model.Provider == models.ProviderOpenAI && providerCfg.ResponsesAPI {
+			openaiOpts = append(openaiOpts, provider.WithOpenAIResponsesAPI())
+		}
+		opts = append(opts, provider.WithOpenAIOptions(openaiOpts...))
+	} else if (model.Provider == models.ProviderAnthropic || customType == config.CustomProviderAnthropic) && model.CanReason && agentName == config.AgentCoder {
 		opts = append(
 			opts,
 			provider.WithAnthropicOptions(
@@ -736,7 +866,7 @@ func createAgentProvider(agentName config.AgentName) (provider.Provider, error)
 			),
 		)
 	}
-	agentProvider, err := provider.NewProvider(
+	modelProvider, err := provider.NewProvider(
 		model.Provider,
 		opts...,
 	)
@@ -744,5 +874,5 @@ func createAgentProvider(agentName config.AgentName) (provider.Provider, error)
 		return nil, fmt.Errorf("could not create provider: %v", err)
 	}
 
-	return agentProvider, nil
+	return modelProvider, nil
 }
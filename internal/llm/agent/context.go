@@ -0,0 +1,111 @@
+package agent
+
+import (
+	"slices"
+
+	"github.com/omnitrix-sh/cli/internal/config"
+	"github.com/omnitrix-sh/cli/internal/logging"
+	"github.com/omnitrix-sh/cli/internal/message"
+	"github.com/omnitrix-sh/cli/internal/pubsub"
+)
+
+const (
+	// contextTrimThreshold is the share of the context budget above which old
+	// tool results are removed from the history sent to the model
+	contextTrimThreshold = 0.9
+	// contextTrimTarget is the share of the budget the history is trimmed
+	// down to, so that it is not trimmed again on every request, which would
+	// defeat prompt caching
+	contextTrimTarget = 0.7
+	// keepRecentMessages is the number of latest messages whose tool results
+	// are never removed, as the model is working with them
+	keepRecentMessages = 6
+)
+
+// trimmedToolResult replaces the content of the tool results removed from the
+// history
+const trimmedToolResult = "[Output removed to fit the context window, run the tool again if it is still needed]"
+
+// contextBudget returns the number of tokens the history can use, the context
+// window of the model less the tokens kept for its response. At most half the
+// window is kept, as the max tokens of the agent's model may not fit the
+// window of a fallback model.
+func (a *agent) contextBudget() int64 {
+	model := a.provider.Model()
+	maxTokens := responseMaxTokens(config.Get().Agents[a.name], model)
+	if maxTokens <= 0 || maxTokens > model.ContextWindow/2 {
+		maxTokens = model.ContextWindow / 2
+	}
+	return model.ContextWindow - maxTokens
+}
+
+// fitContext counts the tokens of the history before it is sent and, when
+// it nears the context window, removes the output of old tool calls from it.
+// The stored messages are left untouched. The count is an estimate of the
+// provider's tokenizer, so the thresholds leave a margin, and the history is
+// not summarized: a conversation still too long once the tool results are
+// removed is sent as is, and may be rejected by the provider.
+func (a *agent) fitContext(sessionID string, history []message.Message) []message.Message {
+	budget := a.contextBudget()
+	tokens := a.provider.CountTokens(history, a.tools)
+	if budget <= 0 || tokens <= int64(float64(budget)*contextTrimThreshold) {
+		a.publishContextTokens(sessionID, tokens)
+		return history
+	}
+
+	target := int64(float64(budget) * contextTrimTarget)
+	base := a.provider.CountTokens(nil, nil)
+	trimmed := slices.Clone(history)
+	removed := 0
+	for i := 0; i < len(trimmed)-keepRecentMessages && tokens > target; i++ {
+		if trimmed[i].Role != message.Tool {
+			continue
+		}
+		before := a.provider.CountTokens(trimmed[i:i+1], nil) - base
+		trimmed[i] = trimToolResults(trimmed[i])
+		after := a.provider.CountTokens(trimmed[i:i+1], nil) - base
+		tokens -= before - after
+		removed++
+	}
+
+	if removed > 0 {
+		logging.Info("Removed old tool results to fit the context window", "session", sessionID, "messages", removed, "tokens", tokens, "budget", budget)
+	}
+	if tokens > budget {
+		logging.Warn("The conversation exceeds the context window of the model", "session", sessionID, "tokens", tokens, "budget", budget)
+	}
+	a.publishContextTokens(sessionID, tokens)
+	return trimmed
+}
+
+// trimToolResults returns a copy of a tool message whose results are
+// replaced with a note that they were removed
+func trimToolResults(msg message.Message) message.Message {
+	parts := make([]message.ContentPart, len(msg.Parts))
+	for i, part := range msg.Parts {
+		if result, ok := part.(message.ToolResult); ok && result.Content != trimmedToolResult {
+			result.Content = trimmedToolResult
+			result.Metadata = ""
+			part = result
+		}
+		parts[i] = part
+	}
+	msg.Parts = parts
+	return msg
+}
+
+// publishContextTokens publishes the number of tokens the history of a
+// session takes in the context window
+func (a *agent) publishContextTokens(sessionID string, tokens int64) {
+	a.Publish(pubsub.UpdatedEvent, AgentEvent{
+		Type:          AgentEventTypeContext,
+		SessionID:     sessionID,
+		ContextTokens: tokens,
+	})
+}
+
+// countContext publishes the tokens of the history once the response to it is
+// complete
+func (a *agent) countContext(sessionID string, history []message.Message, response message.Message) {
+	a.publishContextTokens(sessionID, a.provider.CountTokens(append(slices.Clip(history), response), a.tools))
+}
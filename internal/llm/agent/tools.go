@@ -3,6 +3,8 @@ package agent
 import (
 	"context"
 
+	"github.com/omnitrix-sh/cli/internal/budget"
+	"github.com/omnitrix-sh/cli/internal/config"
 	"github.com/omnitrix-sh/cli/internal/history"
 	"github.com/omnitrix-sh/cli/internal/llm/tools"
 	"github.com/omnitrix-sh/cli/internal/lsp"
@@ -15,13 +17,23 @@ func CoderAgentTools(
 	permissions permission.Service,
 	sessions session.Service,
 	messages message.Service,
+	budgets budget.Service,
 	history history.Service,
-	lspClients map[string]*lsp.Client,
+	lspClients *lsp.Clients,
 ) []tools.BaseTool {
 	ctx := context.Background()
 	otherTools := GetMcpTools(ctx, permissions)
-	if len(lspClients) > 0 {
-		otherTools = append(otherTools, tools.NewDiagnosticsTool(lspClients))
+	if lspEnabled(lspClients) {
+		otherTools = append(otherTools,
+			tools.NewDiagnosticsTool(lspClients),
+			tools.NewDefinitionTool(lspClients),
+			tools.NewReferencesTool(lspClients),
+			tools.NewHoverTool(lspClients),
+			tools.NewSymbolsTool(lspClients),
+			tools.NewHierarchyTool(lspClients),
+			tools.NewRenameSymbolTool(lspClients, permissions, history),
+			tools.NewCodeActionsTool(lspClients, permissions, history),
+		)
 	}
 	return append(
 		[]tools.BaseTool{
@@ -35,17 +47,46 @@ func CoderAgentTools(
 			tools.NewViewTool(lspClients),
 			tools.NewPatchTool(lspClients, permissions, history),
 			tools.NewWriteTool(lspClients, permissions, history),
-			NewAgentTool(sessions, messages, lspClients),
+			NewAgentTool(sessions, messages, budgets, lspClients),
 		}, otherTools...,
 	)
 }
 
-func TaskAgentTools(lspClients map[string]*lsp.Client) []tools.BaseTool {
-	return []tools.BaseTool{
+func TaskAgentTools(lspClients *lsp.Clients) []tools.BaseTool {
+	taskTools := []tools.BaseTool{
 		tools.NewGlobTool(),
 		tools.NewGrepTool(),
 		tools.NewLsTool(),
 		tools.NewSourcegraphTool(),
 		tools.NewViewTool(lspClients),
 	}
+	if lspEnabled(lspClients) {
+		taskTools = append(taskTools,
+			tools.NewDefinitionTool(lspClients),
+			tools.NewReferencesTool(lspClients),
+			tools.NewHoverTool(lspClients),
+			tools.NewSymbolsTool(lspClients),
+			tools.NewHierarchyTool(lspClients),
+		)
+	}
+	return taskTools
+}
+
+// lspEnabled reports whether LSP backed tools should be offered. The clients
+// are started in the background and may not be in the registry yet when the agent
+// is created, so the configuration is checked as well.
+func lspEnabled(lspClients *lsp.Clients) bool {
+	if lspClients.Len() > 0 {
+		return true
+	}
+	cfg := config.Get()
+	if cfg == nil {
+		return false
+	}
+	for _, l := range cfg.LSP {
+		if !l.Disabled {
+			return true
+		}
+	}
+	return false
 }
@@ -0,0 +1,333 @@
+package models
+
+import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/omnitrix-sh/cli/internal/logging"
+	"github.com/omnitrix-sh/cli/internal/pubsub"
+	"github.com/spf13/viper"
+)
+
+const (
+	ProviderOllama ModelProvider = "ollama"
+
+	ollamaDefaultHost = "http://localhost:11434"
+	// ollamaDefaultContextWindow caps the context of models supporting very
+	// long ones, as Ollama allocates memory for the whole context. Set
+	// OLLAMA_CONTEXT_LENGTH to use another limit.
+	ollamaDefaultContextWindow = 32768
+	// ollamaFallbackContextWindow is used when a model does not report its
+	// context length
+	ollamaFallbackContextWindow = 4096
+)
+
+// LoadOllamaModels adds the models installed in Ollama to the supported
+// models and enables the provider when Ollama answers. Configuration loading
+// calls it when OLLAMA_HOST is set or the ollama provider is configured, so
+// Ollama is not probed on every start.
+func LoadOllamaModels() {
+	host := OllamaHost()
+	client := &http.Client{Timeout: 2 * time.Second}
+	names, err := listOllamaModels(client, host)
+	if err != nil {
+		logging.Debug("Ollama not available", "host", host, "error", err)
+		return
+	}
+
+	// The provider is enabled while Ollama runs, even without models, so that
+	// models can be pulled from the model dialog
+	viper.SetDefault("providers.ollama.apiKey", "ollama")
+	ProviderPopularity[ProviderOllama] = 0
+
+	ollamaModels := make([]Model, len(names))
+	var wg sync.WaitGroup
+	for i, name := range names {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			model, err := showOllamaModel(context.Background(), client, host, name)
+			if err != nil {
+				logging.Debug("Failed to read Ollama model", "model", name, "error", err)
+				return
+			}
+			ollamaModels[i] = model
+		}()
+	}
+	wg.Wait()
+
+	defaultSet := false
+	for _, model := range ollamaModels {
+		if model.ID == "" {
+			continue
+		}
+		SupportedModels[model.ID] = model
+		if !defaultSet {
+			viper.SetDefault("agents.coder.model", model.ID)
+			viper.SetDefault("agents.summarizer.model", model.ID)
+			viper.SetDefault("agents.task.model", model.ID)
+			viper.SetDefault("agents.title.model", model.ID)
+			defaultSet = true
+		}
+	}
+}
+
+// OllamaHost returns the URL of the Ollama server, read from OLLAMA_HOST like
+// the Ollama CLI does.
+func OllamaHost() string {
+	host := strings.TrimSpace(os.Getenv("OLLAMA_HOST"))
+	if host == "" {
+		return ollamaDefaultHost
+	}
+	if !strings.Contains(host, "://") {
+		host = "http://" + host
+	}
+	return strings.TrimSuffix(host, "/")
+}
+
+// OllamaModelID returns the ID of the Ollama model with the given name, such
+// as "qwen3:8b".
+func OllamaModelID(name string) ModelID {
+	return ModelID("ollama." + name)
+}
+
+// ollamaError is an error response of the Ollama API
+type ollamaError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *ollamaError) Error() string {
+	return fmt.Sprintf("ollama: %s (status %d)", e.Message, e.StatusCode)
+}
+
+// OllamaErrorStatus returns the HTTP status of an error returned by the
+// Ollama API, 0 for other errors.
+func OllamaErrorStatus(err error) int {
+	var ollamaErr *ollamaError
+	if errors.As(err, &ollamaErr) {
+		return ollamaErr.StatusCode
+	}
+	return 0
+}
+
+// OllamaRequest sends a request to the Ollama API and returns the response,
+// or an error if it failed.
+func OllamaRequest(ctx context.Context, client *http.Client, host, method, path string, body any) (*http.Response, error) {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, host+path, reader)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode != http.StatusOK {
+		defer res.Body.Close()
+		var errorBody struct {
+			Error string `json:"error"`
+		}
+		_ = json.NewDecoder(res.Body).Decode(&errorBody)
+		return nil, &ollamaError{
+			StatusCode: res.StatusCode,
+			Message:    cmp.Or(errorBody.Error, http.StatusText(res.StatusCode)),
+		}
+	}
+	return res, nil
+}
+
+// listOllamaModels returns the names of the models installed in Ollama
+func listOllamaModels(client *http.Client, host string) ([]string, error) {
+	res, err := OllamaRequest(context.Background(), client, host, http.MethodGet, "/api/tags", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	var tags struct {
+		Models []struct {
+			Name string `json:"name"`
+		} `json:"models"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&tags); err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(tags.Models))
+	for _, model := range tags.Models {
+		names = append(names, model.Name)
+	}
+	return names, nil
+}
+
+// ollamaShowResponse holds the parts of the /api/show response used to
+// describe a model
+type ollamaShowResponse struct {
+	// Parameters are the Modelfile parameters, one "name value" per line
+	Parameters   string         `json:"parameters"`
+	ModelInfo    map[string]any `json:"model_info"`
+	Capabilities []string       `json:"capabilities"`
+}
+
+// showOllamaModel reads the metadata of an installed model
+func showOllamaModel(ctx context.Context, client *http.Client, host, name string) (Model, error) {
+	res, err := OllamaRequest(ctx, client, host, http.MethodPost, "/api/show", map[string]string{"model": name})
+	if err != nil {
+		return Model{}, err
+	}
+	defer res.Body.Close()
+
+	var show ollamaShowResponse
+	if err := json.NewDecoder(res.Body).Decode(&show); err != nil {
+		return Model{}, err
+	}
+	model, ok := convertOllamaModel(name, show)
+	if !ok {
+		return Model{}, errors.New("model does not support chat completion")
+	}
+	return model, nil
+}
+
+// convertOllamaModel describes a model from its metadata, false for models
+// that cannot chat such as embedding models
+func convertOllamaModel(name string, show ollamaShowResponse) (Model, bool) {
+	// Older Ollama versions do not report capabilities
+	if len(show.Capabilities) > 0 && !slices.Contains(show.Capabilities, "completion") {
+		return Model{}, false
+	}
+
+	contextWindow := ollamaContextWindow(show)
+	return Model{
+		ID:                  OllamaModelID(name),
+		Name:                name,
+		Provider:            ProviderOllama,
+		APIModel:            name,
+		ContextWindow:       contextWindow,
+		DefaultMaxTokens:    contextWindow / 4,
+		CanReason:           slices.Contains(show.Capabilities, "thinking"),
+		SupportsAttachments: slices.Contains(show.Capabilities, "vision"),
+	}, true
+}
+
+// ollamaContextWindow returns the context to run a model with: the num_ctx
+// of its Modelfile if set, else its context length up to the limit
+func ollamaContextWindow(show ollamaShowResponse) int64 {
+	for _, line := range strings.Split(show.Parameters, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 2 && fields[0] == "num_ctx" {
+			if numCtx, err := strconv.ParseInt(fields[1], 10, 64); err == nil && numCtx > 0 {
+				return numCtx
+			}
+		}
+	}
+
+	var contextLength int64
+	if arch, ok := show.ModelInfo["general.architecture"].(string); ok {
+		if length, ok := show.ModelInfo[arch+".context_length"].(float64); ok {
+			contextLength = int64(length)
+		}
+	}
+	if contextLength <= 0 {
+		return ollamaFallbackContextWindow
+	}
+
+	limit := int64(ollamaDefaultContextWindow)
+	if value, err := strconv.ParseInt(os.Getenv("OLLAMA_CONTEXT_LENGTH"), 10, 64); err == nil && value > 0 {
+		limit = value
+	}
+	return min(contextLength, limit)
+}
+
+// OllamaPullStatus is published while a model is pulled.
+type OllamaPullStatus struct {
+	Model string
+	// Status is the step Ollama is at, such as "pulling manifest"
+	Status    string
+	Completed int64
+	Total     int64
+}
+
+// Message describes the status for display, for example "pulling qwen3:8b:
+// 42%".
+func (s OllamaPullStatus) Message() string {
+	if s.Total > 0 {
+		return fmt.Sprintf("pulling %s: %d%%", s.Model, s.Completed*100/s.Total)
+	}
+	return fmt.Sprintf("pulling %s: %s", s.Model, s.Status)
+}
+
+var ollamaPullBroker = pubsub.NewBroker[OllamaPullStatus]()
+
+// SubscribeOllamaPulls returns the progress of the models being pulled.
+func SubscribeOllamaPulls(ctx context.Context) <-chan pubsub.Event[OllamaPullStatus] {
+	return ollamaPullBroker.Subscribe(ctx)
+}
+
+// PullOllamaModel downloads a model into Ollama, publishing its progress,
+// and returns it once it can be used. The model is not added to the supported
+// models.
+func PullOllamaModel(ctx context.Context, name string) (Model, error) {
+	host := OllamaHost()
+	client := &http.Client{}
+	res, err := OllamaRequest(ctx, client, host, http.MethodPost, "/api/pull", map[string]any{"model": name, "stream": true})
+	if err != nil {
+		return Model{}, fmt.Errorf("failed to pull %s: %w", name, err)
+	}
+	defer res.Body.Close()
+
+	// Progress is streamed as a JSON object per line, published at most every
+	// half second to not flood the status bar
+	var lastPublished time.Time
+	scanner := bufio.NewScanner(res.Body)
+	for scanner.Scan() {
+		var progress struct {
+			Status    string `json:"status"`
+			Error     string `json:"error"`
+			Completed int64  `json:"completed"`
+			Total     int64  `json:"total"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &progress); err != nil {
+			continue
+		}
+		if progress.Error != "" {
+			return Model{}, fmt.Errorf("failed to pull %s: %s", name, progress.Error)
+		}
+		if time.Since(lastPublished) > 500*time.Millisecond || progress.Status == "success" {
+			lastPublished = time.Now()
+			ollamaPullBroker.Publish(pubsub.UpdatedEvent, OllamaPullStatus{
+				Model:     name,
+				Status:    progress.Status,
+				Completed: progress.Completed,
+				Total:     progress.Total,
+			})
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return Model{}, fmt.Errorf("failed to pull %s: %w", name, err)
+	}
+
+	return showOllamaModel(ctx, client, host, name)
+}
@@ -0,0 +1,53 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConvertOllamaModel(t *testing.T) {
+	t.Setenv("OLLAMA_CONTEXT_LENGTH", "")
+
+	show := ollamaShowResponse{
+		ModelInfo: map[string]any{
+			"general.architecture": "qwen3",
+			"qwen3.context_length": float64(40960),
+		},
+		Capabilities: []string{"completion", "tools", "thinking"},
+	}
+
+	t.Run("caps the context length", func(t *testing.T) {
+		model, ok := convertOllamaModel("qwen3:8b", show)
+		assert.True(t, ok)
+		assert.Equal(t, ModelID("ollama.qwen3:8b"), model.ID)
+		assert.Equal(t, int64(ollamaDefaultContextWindow), model.ContextWindow)
+		assert.True(t, model.CanReason)
+		assert.False(t, model.SupportsAttachments)
+	})
+
+	t.Run("uses the num_ctx of the Modelfile", func(t *testing.T) {
+		withNumCtx := show
+		withNumCtx.Parameters = "stop \"<|im_end|>\"\nnum_ctx 8192"
+		model, _ := convertOllamaModel("qwen3:8b", withNumCtx)
+		assert.Equal(t, int64(8192), model.ContextWindow)
+	})
+
+	t.Run("uses OLLAMA_CONTEXT_LENGTH as limit", func(t *testing.T) {
+		t.Setenv("OLLAMA_CONTEXT_LENGTH", "65536")
+		model, _ := convertOllamaModel("qwen3:8b", show)
+		assert.Equal(t, int64(40960), model.ContextWindow)
+	})
+
+	t.Run("skips embedding models", func(t *testing.T) {
+		_, ok := convertOllamaModel("nomic-embed-text", ollamaShowResponse{Capabilities: []string{"embedding"}})
+		assert.False(t, ok)
+	})
+}
+
+func TestOllamaHost(t *testing.T) {
+	t.Setenv("OLLAMA_HOST", "")
+	assert.Equal(t, "http://localhost:11434", OllamaHost())
+	t.Setenv("OLLAMA_HOST", "0.0.0.0:11434")
+	assert.Equal(t, "http://0.0.0.0:11434", OllamaHost())
+}
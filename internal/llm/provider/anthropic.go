@@ -4,10 +4,8 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
-	"fmt"
 	"io"
 	"strings"
-	"time"
 
 	"github.com/anthropics/anthropic-sdk-go"
 	"github.com/anthropics/anthropic-sdk-go/bedrock"
@@ -23,6 +21,8 @@ type anthropicOptions struct {
 	useBedrock   bool
 	disableCache bool
 	shouldThink  func(userMessage string) bool
+	baseURL      string
+	extraHeaders map[string]string
 }
 
 type AnthropicOption func(*anthropicOptions)
@@ -48,6 +48,12 @@ func newAnthropicClient(opts providerClientOptions) AnthropicClient {
 	if anthropicOpts.useBedrock {
 		anthropicClientOptions = append(anthropicClientOptions, bedrock.WithLoadDefaultConfig(context.Background()))
 	}
+	if anthropicOpts.baseURL != "" {
+		anthropicClientOptions = append(anthropicClientOptions, option.WithBaseURL(anthropicOpts.baseURL))
+	}
+	for key, value := range anthropicOpts.extraHeaders {
+		anthropicClientOptions = append(anthropicClientOptions, option.WithHeader(key, value))
+	}
 
 	client := anthropic.NewClient(anthropicClientOptions...)
 	return &anthropicClient{
@@ -57,20 +63,22 @@ func newAnthropicClient(opts providerClientOptions) AnthropicClient {
 	}
 }
 
+// anthropicCachedMessages is how many of the last messages get a cache
+// breakpoint. With the system prompt and the tools this uses the four
+// breakpoints allowed per request.
+const anthropicCachedMessages = 2
+
 func (a *anthropicClient) convertMessages(messages []message.Message) (anthropicMessages []anthropic.MessageParam) {
-	for i, msg := range messages {
-		cache := false
-		if i > len(messages)-3 {
-			cache = true
+	defer func() {
+		if !a.options.disableCache {
+			addCacheBreakpoints(anthropicMessages)
 		}
+	}()
+
+	for _, msg := range messages {
 		switch msg.Role {
 		case message.User:
 			content := anthropic.NewTextBlock(msg.Content().String())
-			if cache && !a.options.disableCache {
-				content.OfText.CacheControl = anthropic.CacheControlEphemeralParam{
-					Type: "ephemeral",
-				}
-			}
 			var contentBlocks []anthropic.ContentBlockParamUnion
 			contentBlocks = append(contentBlocks, content)
 			for _, binaryContent := range msg.BinaryContent() {
@@ -83,13 +91,7 @@ func (a *anthropicClient) convertMessages(messages []message.Message) (anthropic
 		case message.Assistant:
 			blocks := []anthropic.ContentBlockParamUnion{}
 			if msg.Content().String() != "" {
-				content := anthropic.NewTextBlock(msg.Content().String())
-				if cache && !a.options.disableCache {
-					content.OfText.CacheControl = anthropic.CacheControlEphemeralParam{
-						Type: "ephemeral",
-					}
-				}
-				blocks = append(blocks, content)
+				blocks = append(blocks, anthropic.NewTextBlock(msg.Content().String()))
 			}
 
 			for _, toolCall := range msg.ToolCalls() {
@@ -118,6 +120,24 @@ func (a *anthropicClient) convertMessages(messages []message.Message) (anthropic
 	return
 }
 
+// addCacheBreakpoints marks the last block of the last messages, whatever its
+// type, so that the next request reads the conversation up to them from the
+// cache. Agentic turns mostly end with tool uses and tool results, which
+// have to be marked as well as text.
+func addCacheBreakpoints(messages []anthropic.MessageParam) {
+	for i := max(len(messages)-anthropicCachedMessages, 0); i < len(messages); i++ {
+		content := messages[i].Content
+		if len(content) == 0 {
+			continue
+		}
+		if cacheControl := content[len(content)-1].GetCacheControl(); cacheControl != nil {
+			*cacheControl = anthropic.CacheControlEphemeralParam{
+				Type: "ephemeral",
+			}
+		}
+	}
+}
+
 func (a *anthropicClient) convertTools(tools []toolsPkg.BaseTool) []anthropic.ToolUnionParam {
 	anthropicTools := make([]anthropic.ToolUnionParam, len(tools))
 
@@ -177,6 +197,13 @@ func (a *anthropicClient) preparedMessages(messages []anthropic.MessageParam, to
 		}
 	}
 
+	system := anthropic.TextBlockParam{Text: a.providerOptions.systemMessage}
+	if !a.options.disableCache {
+		system.CacheControl = anthropic.CacheControlEphemeralParam{
+			Type: "ephemeral",
+		}
+	}
+
 	return anthropic.MessageNewParams{
 		Model:       anthropic.Model(a.providerOptions.model.APIModel),
 		MaxTokens:   a.providerOptions.maxTokens,
@@ -184,14 +211,7 @@ func (a *anthropicClient) preparedMessages(messages []anthropic.MessageParam, to
 		Messages:    messages,
 		Tools:       tools,
 		Thinking:    thinkingParam,
-		System: []anthropic.TextBlockParam{
-			{
-				Text: a.providerOptions.systemMessage,
-				CacheControl: anthropic.CacheControlEphemeralParam{
-					Type: "ephemeral",
-				},
-			},
-		},
+		System:      []anthropic.TextBlockParam{system},
 	}
 }
 
@@ -203,45 +223,27 @@ func (a *anthropicClient) send(ctx context.Context, messages []message.Message,
 		logging.Debug("Prepared messages", "messages", string(jsonData))
 	}
 
-	attempts := 0
-	for {
-		attempts++
-		anthropicResponse, err := a.client.Messages.New(
-			ctx,
-			preparedMessages,
-		)
-		// If there is an error we are going to see if we can retry the call
-		if err != nil {
-			logging.Error("Error in Anthropic API call", "error", err)
-			retry, after, retryErr := a.shouldRetry(attempts, err)
-			if retryErr != nil {
-				return nil, retryErr
-			}
-			if retry {
-				logging.WarnPersist(fmt.Sprintf("Retrying due to rate limit... attempt %d of %d", attempts, maxRetries), logging.PersistTimeArg, time.Millisecond*time.Duration(after+100))
-				select {
-				case <-ctx.Done():
-					return nil, ctx.Err()
-				case <-time.After(time.Duration(after) * time.Millisecond):
-					continue
-				}
-			}
-			return nil, retryErr
-		}
+	anthropicResponse, err := a.client.Messages.New(
+		ctx,
+		preparedMessages,
+	)
+	if err != nil {
+		logging.Error("Error in Anthropic API call", "error", err)
+		return nil, err
+	}
 
-		content := ""
-		for _, block := range anthropicResponse.Content {
-			if text, ok := block.AsAny().(anthropic.TextBlock); ok {
-				content += text.Text
-			}
+	content := ""
+	for _, block := range anthropicResponse.Content {
+		if text, ok := block.AsAny().(anthropic.TextBlock); ok {
+			content += text.Text
 		}
-
-		return &ProviderResponse{
-			Content:   content,
-			ToolCalls: a.toolCalls(*anthropicResponse),
-			Usage:     a.usage(*anthropicResponse),
-		}, nil
 	}
+
+	return &ProviderResponse{
+		Content:   content,
+		ToolCalls: a.toolCalls(*anthropicResponse),
+		Usage:     a.usage(*anthropicResponse),
+	}, nil
 }
 
 func (a *anthropicClient) stream(ctx context.Context, messages []message.Message, tools []toolsPkg.BaseTool) <-chan ProviderEvent {
@@ -263,163 +265,105 @@ func (a *anthropicClient) stream(ctx context.Context, messages []message.Message
 		}
 
 	}
-	attempts := 0
 	eventChan := make(chan ProviderEvent)
 	go func() {
-		for {
-			attempts++
-			anthropicStream := a.client.Messages.NewStreaming(
-				ctx,
-				preparedMessages,
-			)
-			accumulatedMessage := anthropic.Message{}
-
-			currentToolCallID := ""
-			for anthropicStream.Next() {
-				event := anthropicStream.Current()
-				err := accumulatedMessage.Accumulate(event)
-				if err != nil {
-					logging.Warn("Error accumulating message", "error", err)
-					continue
-				}
+		defer close(eventChan)
 
-				switch event := event.AsAny().(type) {
-				case anthropic.ContentBlockStartEvent:
-					if event.ContentBlock.Type == "text" {
-						eventChan <- ProviderEvent{Type: EventContentStart}
-					} else if event.ContentBlock.Type == "tool_use" {
-						currentToolCallID = event.ContentBlock.ID
-						eventChan <- ProviderEvent{
-							Type: EventToolUseStart,
-							ToolCall: &message.ToolCall{
-								ID:       event.ContentBlock.ID,
-								Name:     event.ContentBlock.Name,
-								Finished: false,
-							},
-						}
+		anthropicStream := a.client.Messages.NewStreaming(
+			ctx,
+			preparedMessages,
+		)
+		accumulatedMessage := anthropic.Message{}
+
+		currentToolCallID := ""
+		for anthropicStream.Next() {
+			event := anthropicStream.Current()
+			err := accumulatedMessage.Accumulate(event)
+			if err != nil {
+				logging.Warn("Error accumulating message", "error", err)
+				continue
+			}
+
+			switch event := event.AsAny().(type) {
+			case anthropic.ContentBlockStartEvent:
+				if event.ContentBlock.Type == "text" {
+					eventChan <- ProviderEvent{Type: EventContentStart}
+				} else if event.ContentBlock.Type == "tool_use" {
+					currentToolCallID = event.ContentBlock.ID
+					eventChan <- ProviderEvent{
+						Type: EventToolUseStart,
+						ToolCall: &message.ToolCall{
+							ID:       event.ContentBlock.ID,
+							Name:     event.ContentBlock.Name,
+							Finished: false,
+						},
 					}
+				}
 
-				case anthropic.ContentBlockDeltaEvent:
-					if event.Delta.Type == "thinking_delta" && event.Delta.Thinking != "" {
-						eventChan <- ProviderEvent{
-							Type:     EventThinkingDelta,
-							Thinking: event.Delta.Thinking,
-						}
-					} else if event.Delta.Type == "text_delta" && event.Delta.Text != "" {
-						eventChan <- ProviderEvent{
-							Type:    EventContentDelta,
-							Content: event.Delta.Text,
-						}
-					} else if event.Delta.Type == "input_json_delta" {
-						if currentToolCallID != "" {
-							eventChan <- ProviderEvent{
-								Type: EventToolUseDelta,
-								ToolCall: &message.ToolCall{
-									ID:       currentToolCallID,
-									Finished: false,
-									Input:    event.Delta.JSON.PartialJSON.Raw(),
-								},
-							}
-						}
+			case anthropic.ContentBlockDeltaEvent:
+				if event.Delta.Type == "thinking_delta" && event.Delta.Thinking != "" {
+					eventChan <- ProviderEvent{
+						Type:     EventThinkingDelta,
+						Thinking: event.Delta.Thinking,
+					}
+				} else if event.Delta.Type == "text_delta" && event.Delta.Text != "" {
+					eventChan <- ProviderEvent{
+						Type:    EventContentDelta,
+						Content: event.Delta.Text,
 					}
-				case anthropic.ContentBlockStopEvent:
+				} else if event.Delta.Type == "input_json_delta" {
 					if currentToolCallID != "" {
 						eventChan <- ProviderEvent{
-							Type: EventToolUseStop,
+							Type: EventToolUseDelta,
 							ToolCall: &message.ToolCall{
-								ID: currentToolCallID,
+								ID:       currentToolCallID,
+								Finished: false,
+								Input:    event.Delta.JSON.PartialJSON.Raw(),
 							},
 						}
-						currentToolCallID = ""
-					} else {
-						eventChan <- ProviderEvent{Type: EventContentStop}
-					}
-
-				case anthropic.MessageStopEvent:
-					content := ""
-					for _, block := range accumulatedMessage.Content {
-						if text, ok := block.AsAny().(anthropic.TextBlock); ok {
-							content += text.Text
-						}
 					}
-
+				}
+			case anthropic.ContentBlockStopEvent:
+				if currentToolCallID != "" {
 					eventChan <- ProviderEvent{
-						Type: EventComplete,
-						Response: &ProviderResponse{
-							Content:      content,
-							ToolCalls:    a.toolCalls(accumulatedMessage),
-							Usage:        a.usage(accumulatedMessage),
-							FinishReason: a.finishReason(string(accumulatedMessage.StopReason)),
+						Type: EventToolUseStop,
+						ToolCall: &message.ToolCall{
+							ID: currentToolCallID,
 						},
 					}
+					currentToolCallID = ""
+				} else {
+					eventChan <- ProviderEvent{Type: EventContentStop}
 				}
-			}
 
-			err := anthropicStream.Err()
-			if err == nil || errors.Is(err, io.EOF) {
-				close(eventChan)
-				return
-			}
-			// If there is an error we are going to see if we can retry the call
-			retry, after, retryErr := a.shouldRetry(attempts, err)
-			if retryErr != nil {
-				eventChan <- ProviderEvent{Type: EventError, Error: retryErr}
-				close(eventChan)
-				return
-			}
-			if retry {
-				logging.WarnPersist(fmt.Sprintf("Retrying due to rate limit... attempt %d of %d", attempts, maxRetries), logging.PersistTimeArg, time.Millisecond*time.Duration(after+100))
-				select {
-				case <-ctx.Done():
-					// context cancelled
-					if ctx.Err() != nil {
-						eventChan <- ProviderEvent{Type: EventError, Error: ctx.Err()}
+			case anthropic.MessageStopEvent:
+				content := ""
+				for _, block := range accumulatedMessage.Content {
+					if text, ok := block.AsAny().(anthropic.TextBlock); ok {
+						content += text.Text
 					}
-					close(eventChan)
-					return
-				case <-time.After(time.Duration(after) * time.Millisecond):
-					continue
+				}
+
+				eventChan <- ProviderEvent{
+					Type: EventComplete,
+					Response: &ProviderResponse{
+						Content:      content,
+						ToolCalls:    a.toolCalls(accumulatedMessage),
+						Usage:        a.usage(accumulatedMessage),
+						FinishReason: a.finishReason(string(accumulatedMessage.StopReason)),
+					},
 				}
 			}
-			if ctx.Err() != nil {
-				eventChan <- ProviderEvent{Type: EventError, Error: ctx.Err()}
-			}
+		}
 
-			close(eventChan)
-			return
+		err := anthropicStream.Err()
+		if err != nil && !errors.Is(err, io.EOF) {
+			eventChan <- ProviderEvent{Type: EventError, Error: err}
 		}
 	}()
 	return eventChan
 }
 
-func (a *anthropicClient) shouldRetry(attempts int, err error) (bool, int64, error) {
-	var apierr *anthropic.Error
-	if !errors.As(err, &apierr) {
-		return false, 0, err
-	}
-
-	if apierr.StatusCode != 429 && apierr.StatusCode != 529 {
-		return false, 0, err
-	}
-
-	if attempts > maxRetries {
-		return false, 0, fmt.Errorf("maximum retry attempts reached for rate limit: %d retries", maxRetries)
-	}
-
-	retryMs := 0
-	retryAfterValues := apierr.Response.Header.Values("Retry-After")
-
-	backoffMs := 2000 * (1 << (attempts - 1))
-	jitterMs := int(float64(backoffMs) * 0.2)
-	retryMs = backoffMs + jitterMs
-	if len(retryAfterValues) > 0 {
-		if _, err := fmt.Sscanf(retryAfterValues[0], "%d", &retryMs); err == nil {
-			retryMs = retryMs * 1000
-		}
-	}
-	return true, int64(retryMs), nil
-}
-
 func (a *anthropicClient) toolCalls(msg anthropic.Message) []message.ToolCall {
 	var toolCalls []message.ToolCall
 
@@ -455,6 +399,18 @@ func WithAnthropicBedrock(useBedrock bool) AnthropicOption {
 	}
 }
 
+func WithAnthropicBaseURL(baseURL string) AnthropicOption {
+	return func(options *anthropicOptions) {
+		options.baseURL = baseURL
+	}
+}
+
+func WithAnthropicExtraHeaders(headers map[string]string) AnthropicOption {
+	return func(options *anthropicOptions) {
+		options.extraHeaders = headers
+	}
+}
+
 func WithAnthropicDisableCache() AnthropicOption {
 	return func(options *anthropicOptions) {
 		options.disableCache = true
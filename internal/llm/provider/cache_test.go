@@ -0,0 +1,75 @@
+package provider
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/genai"
+)
+
+func TestAddCacheBreakpoints(t *testing.T) {
+	messages := []anthropic.MessageParam{
+		anthropic.NewUserMessage(anthropic.NewTextBlock("fix the build")),
+		anthropic.NewAssistantMessage(
+			anthropic.NewTextBlock("Let me look"),
+			anthropic.NewToolUseBlock("toolu_1", map[string]any{"path": "main.go"}, "view"),
+		),
+		anthropic.NewUserMessage(anthropic.NewToolResultBlock("toolu_1", "package main", false)),
+	}
+
+	addCacheBreakpoints(messages)
+
+	cached := func(block anthropic.ContentBlockParamUnion) bool {
+		return block.GetCacheControl().Type == "ephemeral"
+	}
+	assert.False(t, cached(messages[0].Content[0]))
+	assert.False(t, cached(messages[1].Content[0]))
+	assert.True(t, cached(messages[1].Content[1]), "tool use is marked")
+	assert.True(t, cached(messages[2].Content[0]), "tool result is marked")
+}
+
+func TestGeminiCacheReuse(t *testing.T) {
+	newConfig := func() *genai.GenerateContentConfig {
+		return &genai.GenerateContentConfig{
+			SystemInstruction: &genai.Content{Parts: []*genai.Part{{Text: "system"}}},
+		}
+	}
+	history := []*genai.Content{
+		{Role: "user", Parts: []*genai.Part{{Text: "first"}}},
+		{Role: "model", Parts: []*genai.Part{{Text: "answer"}}},
+		{Role: "user", Parts: []*genai.Part{{Text: "second"}}},
+	}
+
+	g := &geminiClient{}
+	g.cache.name = "cachedContents/abc"
+	g.cache.key = geminiCacheKey(newConfig(), history[:2])
+	g.cache.messages = 2
+	g.cache.expires = time.Now().Add(geminiCacheTTL)
+	// Keeps the test from creating caches
+	g.cache.retryAt = time.Now().Add(time.Hour)
+
+	t.Run("uses the cache for the cached messages", func(t *testing.T) {
+		config := newConfig()
+		uncached, written := g.useCache(context.Background(), config, history)
+		assert.Equal(t, history[2:], uncached)
+		assert.Zero(t, written)
+		assert.Equal(t, "cachedContents/abc", config.CachedContent)
+		assert.Nil(t, config.SystemInstruction)
+	})
+
+	t.Run("ignores the cache when the history changed", func(t *testing.T) {
+		changed := []*genai.Content{
+			{Role: "user", Parts: []*genai.Part{{Text: "other"}}},
+			history[1],
+			history[2],
+		}
+		config := newConfig()
+		uncached, _ := g.useCache(context.Background(), config, changed)
+		assert.Equal(t, changed, uncached)
+		assert.Empty(t, config.CachedContent)
+		assert.NotNil(t, config.SystemInstruction)
+	})
+}
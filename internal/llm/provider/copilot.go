@@ -326,51 +326,32 @@ func (c *copilotClient) send(ctx context.Context, messages []message.Message, to
 		}
 	}
 
-	attempts := 0
-	for {
-		attempts++
-		copilotResponse, err := c.client.Chat.Completions.New(
-			ctx,
-			params,
-		)
-
-		// If there is an error we are going to see if we can retry the call
-		if err != nil {
-			retry, after, retryErr := c.shouldRetry(attempts, err)
-			if retryErr != nil {
-				return nil, retryErr
-			}
-			if retry {
-				logging.WarnPersist(fmt.Sprintf("Retrying due to rate limit... attempt %d of %d", attempts, maxRetries), logging.PersistTimeArg, time.Millisecond*time.Duration(after+100))
-				select {
-				case <-ctx.Done():
-					return nil, ctx.Err()
-				case <-time.After(time.Duration(after) * time.Millisecond):
-					continue
-				}
-			}
-			return nil, retryErr
-		}
-
-		content := ""
-		if copilotResponse.Choices[0].Message.Content != "" {
-			content = copilotResponse.Choices[0].Message.Content
-		}
+	copilotResponse, err := c.client.Chat.Completions.New(
+		ctx,
+		params,
+	)
+	if err != nil {
+		return nil, err
+	}
 
-		toolCalls := c.toolCalls(*copilotResponse)
-		finishReason := c.finishReason(string(copilotResponse.Choices[0].FinishReason))
+	content := ""
+	if copilotResponse.Choices[0].Message.Content != "" {
+		content = copilotResponse.Choices[0].Message.Content
+	}
 
-		if len(toolCalls) > 0 {
-			finishReason = message.FinishReasonToolUse
-		}
+	toolCalls := c.toolCalls(*copilotResponse)
+	finishReason := c.finishReason(string(copilotResponse.Choices[0].FinishReason))
 
-		return &ProviderResponse{
-			Content:      content,
-			ToolCalls:    toolCalls,
-			Usage:        c.usage(*copilotResponse),
-			FinishReason: finishReason,
-		}, nil
+	if len(toolCalls) > 0 {
+		finishReason = message.FinishReasonToolUse
 	}
+
+	return &ProviderResponse{
+		Content:      content,
+		ToolCalls:    toolCalls,
+		Usage:        c.usage(*copilotResponse),
+		FinishReason: finishReason,
+	}, nil
 }
 
 func (c *copilotClient) stream(ctx context.Context, messages []message.Message, tools []toolsPkg.BaseTool) <-chan ProviderEvent {
@@ -396,50 +377,67 @@ func (c *copilotClient) stream(ctx context.Context, messages []message.Message,
 
 	}
 
-	attempts := 0
 	eventChan := make(chan ProviderEvent)
 
 	go func() {
-		for {
-			attempts++
-			copilotStream := c.client.Chat.Completions.NewStreaming(
-				ctx,
-				params,
-			)
-
-			acc := openai.ChatCompletionAccumulator{}
-			currentContent := ""
-			toolCalls := make([]message.ToolCall, 0)
-
-			var currentToolCallId string
-			var currentToolCall openai.ChatCompletionMessageToolCall
-			var msgToolCalls []openai.ChatCompletionMessageToolCall
-			for copilotStream.Next() {
-				chunk := copilotStream.Current()
-				acc.AddChunk(chunk)
-
-				if cfg.Debug {
-					logging.AppendToStreamSessionLogJson(sessionId, requestSeqId, chunk)
-				}
+		defer close(eventChan)
 
-				for _, choice := range chunk.Choices {
-					if choice.Delta.Content != "" {
-						eventChan <- ProviderEvent{
-							Type:    EventContentDelta,
-							Content: choice.Delta.Content,
-						}
-						currentContent += choice.Delta.Content
+		copilotStream := c.client.Chat.Completions.NewStreaming(
+			ctx,
+			params,
+		)
+
+		acc := openai.ChatCompletionAccumulator{}
+		currentContent := ""
+		toolCalls := make([]message.ToolCall, 0)
+
+		var currentToolCallId string
+		var currentToolCall openai.ChatCompletionMessageToolCall
+		var msgToolCalls []openai.ChatCompletionMessageToolCall
+		for copilotStream.Next() {
+			chunk := copilotStream.Current()
+			acc.AddChunk(chunk)
+
+			if cfg.Debug {
+				logging.AppendToStreamSessionLogJson(sessionId, requestSeqId, chunk)
+			}
+
+			for _, choice := range chunk.Choices {
+				if choice.Delta.Content != "" {
+					eventChan <- ProviderEvent{
+						Type:    EventContentDelta,
+						Content: choice.Delta.Content,
 					}
+					currentContent += choice.Delta.Content
 				}
+			}
 
-				if c.isAnthropicModel() {
-					// Monkeypatch adapter for Sonnet-4 multi-tool use
-					for _, choice := range chunk.Choices {
-						if choice.Delta.ToolCalls != nil && len(choice.Delta.ToolCalls) > 0 {
-							toolCall := choice.Delta.ToolCalls[0]
-							// Detect tool use start
-							if currentToolCallId == "" {
-								if toolCall.ID != "" {
+			if c.isAnthropicModel() {
+				// Monkeypatch adapter for Sonnet-4 multi-tool use
+				for _, choice := range chunk.Choices {
+					if choice.Delta.ToolCalls != nil && len(choice.Delta.ToolCalls) > 0 {
+						toolCall := choice.Delta.ToolCalls[0]
+						// Detect tool use start
+						if currentToolCallId == "" {
+							if toolCall.ID != "" {
+								currentToolCallId = toolCall.ID
+								currentToolCall = openai.ChatCompletionMessageToolCall{
+									ID:   toolCall.ID,
+									Type: "function",
+									Function: openai.ChatCompletionMessageToolCallFunction{
+										Name:      toolCall.Function.Name,
+										Arguments: toolCall.Function.Arguments,
+									},
+								}
+							}
+						} else {
+							// Delta tool use
+							if toolCall.ID == "" {
+								currentToolCall.Function.Arguments += toolCall.Function.Arguments
+							} else {
+								// Detect new tool use
+								if toolCall.ID != currentToolCallId {
+									msgToolCalls = append(msgToolCalls, currentToolCall)
 									currentToolCallId = toolCall.ID
 									currentToolCall = openai.ChatCompletionMessageToolCall{
 										ID:   toolCall.ID,
@@ -450,167 +448,85 @@ func (c *copilotClient) stream(ctx context.Context, messages []message.Message,
 										},
 									}
 								}
-							} else {
-								// Delta tool use
-								if toolCall.ID == "" {
-									currentToolCall.Function.Arguments += toolCall.Function.Arguments
-								} else {
-									// Detect new tool use
-									if toolCall.ID != currentToolCallId {
-										msgToolCalls = append(msgToolCalls, currentToolCall)
-										currentToolCallId = toolCall.ID
-										currentToolCall = openai.ChatCompletionMessageToolCall{
-											ID:   toolCall.ID,
-											Type: "function",
-											Function: openai.ChatCompletionMessageToolCallFunction{
-												Name:      toolCall.Function.Name,
-												Arguments: toolCall.Function.Arguments,
-											},
-										}
-									}
-								}
 							}
 						}
-						if choice.FinishReason == "tool_calls" {
-							msgToolCalls = append(msgToolCalls, currentToolCall)
-							acc.ChatCompletion.Choices[0].Message.ToolCalls = msgToolCalls
-						}
+					}
+					if choice.FinishReason == "tool_calls" {
+						msgToolCalls = append(msgToolCalls, currentToolCall)
+						acc.ChatCompletion.Choices[0].Message.ToolCalls = msgToolCalls
 					}
 				}
 			}
+		}
 
-			err := copilotStream.Err()
-			if err == nil || errors.Is(err, io.EOF) {
-				if cfg.Debug {
-					respFilepath := logging.WriteChatResponseJson(sessionId, requestSeqId, acc.ChatCompletion)
-					logging.Debug("Chat completion response", "filepath", respFilepath)
-				}
-				// Stream completed successfully
-				finishReason := c.finishReason(string(acc.ChatCompletion.Choices[0].FinishReason))
-				if len(acc.ChatCompletion.Choices[0].Message.ToolCalls) > 0 {
-					toolCalls = append(toolCalls, c.toolCalls(acc.ChatCompletion)...)
-				}
-				if len(toolCalls) > 0 {
-					finishReason = message.FinishReasonToolUse
-				}
-
-				eventChan <- ProviderEvent{
-					Type: EventComplete,
-					Response: &ProviderResponse{
-						Content:      currentContent,
-						ToolCalls:    toolCalls,
-						Usage:        c.usage(acc.ChatCompletion),
-						FinishReason: finishReason,
-					},
-				}
-				close(eventChan)
-				return
+		err := copilotStream.Err()
+		if err == nil || errors.Is(err, io.EOF) {
+			if cfg.Debug {
+				respFilepath := logging.WriteChatResponseJson(sessionId, requestSeqId, acc.ChatCompletion)
+				logging.Debug("Chat completion response", "filepath", respFilepath)
 			}
-
-			// If there is an error we are going to see if we can retry the call
-			retry, after, retryErr := c.shouldRetry(attempts, err)
-			if retryErr != nil {
-				eventChan <- ProviderEvent{Type: EventError, Error: retryErr}
-				close(eventChan)
-				return
+			// Stream completed successfully
+			finishReason := c.finishReason(string(acc.ChatCompletion.Choices[0].FinishReason))
+			if len(acc.ChatCompletion.Choices[0].Message.ToolCalls) > 0 {
+				toolCalls = append(toolCalls, c.toolCalls(acc.ChatCompletion)...)
 			}
-			// shouldRetry is not catching the max retries...
-			// TODO: Figure out why
-			if attempts > maxRetries {
-				logging.Warn("Maximum retry attempts reached for rate limit", "attempts", attempts, "max_retries", maxRetries)
-				retry = false
+			if len(toolCalls) > 0 {
+				finishReason = message.FinishReasonToolUse
 			}
-			if retry {
-				logging.WarnPersist(fmt.Sprintf("Retrying due to rate limit... attempt %d of %d (paused for %d ms)", attempts, maxRetries, after), logging.PersistTimeArg, time.Millisecond*time.Duration(after+100))
-				select {
-				case <-ctx.Done():
-					// context cancelled
-					if ctx.Err() == nil {
-						eventChan <- ProviderEvent{Type: EventError, Error: ctx.Err()}
-					}
-					close(eventChan)
-					return
-				case <-time.After(time.Duration(after) * time.Millisecond):
-					continue
-				}
+
+			eventChan <- ProviderEvent{
+				Type: EventComplete,
+				Response: &ProviderResponse{
+					Content:      currentContent,
+					ToolCalls:    toolCalls,
+					Usage:        c.usage(acc.ChatCompletion),
+					FinishReason: finishReason,
+				},
 			}
-			eventChan <- ProviderEvent{Type: EventError, Error: retryErr}
-			close(eventChan)
 			return
 		}
+
+		eventChan <- ProviderEvent{Type: EventError, Error: err}
 	}()
 
 	return eventChan
 }
 
-func (c *copilotClient) shouldRetry(attempts int, err error) (bool, int64, error) {
-	var apierr *openai.Error
-	if !errors.As(err, &apierr) {
-		return false, 0, err
-	}
-
-	// Check for token expiration (401 Unauthorized)
-	if apierr.StatusCode == 401 {
-		// Try to refresh the bearer token
-		var githubToken string
-
-		// 1. Environment variable
-		githubToken = os.Getenv("GITHUB_TOKEN")
+// refreshCredentials exchanges the GitHub token for a new bearer token when
+// the current one expired
+func (c *copilotClient) refreshCredentials() error {
+	var githubToken string
 
-		// 2. API key from options
-		if githubToken == "" {
-			githubToken = c.providerOptions.apiKey
-		}
+	// 1. Environment variable
+	githubToken = os.Getenv("GITHUB_TOKEN")
 
-		// 3. Standard GitHub CLI/Copilot locations
-		if githubToken == "" {
-			var err error
-			githubToken, err = config.LoadGitHubToken()
-			if err != nil {
-				logging.Debug("Failed to load GitHub token from standard locations during retry", "error", err)
-			}
-		}
-
-		if githubToken != "" {
-			newBearerToken, tokenErr := c.exchangeGitHubToken(githubToken)
-			if tokenErr == nil {
-				c.options.bearerToken = newBearerToken
-				// Update the client with the new token
-				// Note: This is a simplified approach. In a production system,
-				// you might want to recreate the entire client with the new token
-				logging.Info("Refreshed Copilot bearer token")
-				return true, 1000, nil // Retry immediately with new token
-			}
-			logging.Error("Failed to refresh Copilot bearer token", "error", tokenErr)
-		}
-		return false, 0, fmt.Errorf("authentication failed: %w", err)
+	// 2. API key from options
+	if githubToken == "" {
+		githubToken = c.providerOptions.apiKey
 	}
-	logging.Debug("Copilot API Error", "status", apierr.StatusCode, "headers", apierr.Response.Header, "body", apierr.RawJSON())
 
-	if apierr.StatusCode != 429 && apierr.StatusCode != 500 {
-		return false, 0, err
-	}
-
-	if apierr.StatusCode == 500 {
-		logging.Warn("Copilot API returned 500 error, retrying", "error", err)
-	}
-
-	if attempts > maxRetries {
-		return false, 0, fmt.Errorf("maximum retry attempts reached for rate limit: %d retries", maxRetries)
+	// 3. Standard GitHub CLI/Copilot locations
+	if githubToken == "" {
+		var err error
+		githubToken, err = config.LoadGitHubToken()
+		if err != nil {
+			logging.Debug("Failed to load GitHub token from standard locations during retry", "error", err)
+		}
 	}
 
-	retryMs := 0
-	retryAfterValues := apierr.Response.Header.Values("Retry-After")
-
-	backoffMs := 2000 * (1 << (attempts - 1))
-	jitterMs := int(float64(backoffMs) * 0.2)
-	retryMs = backoffMs + jitterMs
-	if len(retryAfterValues) > 0 {
-		if _, err := fmt.Sscanf(retryAfterValues[0], "%d", &retryMs); err == nil {
-			retryMs = retryMs * 1000
-		}
+	if githubToken == "" {
+		return fmt.Errorf("authentication failed: no GitHub token found")
 	}
-	return true, int64(retryMs), nil
+	newBearerToken, err := c.exchangeGitHubToken(githubToken)
+	if err != nil {
+		return fmt.Errorf("authentication failed: %w", err)
+	}
+	c.options.bearerToken = newBearerToken
+	// Update the client with the new token
+	// Note: This is a simplified approach. In a production system,
+	// you might want to recreate the entire client with the new token
+	logging.Info("Refreshed Copilot bearer token")
+	return nil
 }
 
 func (c *copilotClient) toolCalls(completion openai.ChatCompletion) []message.ToolCall {
@@ -0,0 +1,217 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/omnitrix-sh/cli/internal/llm/models"
+	"github.com/omnitrix-sh/cli/internal/llm/tools"
+	"github.com/omnitrix-sh/cli/internal/logging"
+	"github.com/omnitrix-sh/cli/internal/message"
+)
+
+// fallbackCooldown is how long a provider that failed is skipped before it is
+// tried again
+const fallbackCooldown = 5 * time.Minute
+
+// fallbackProvider sends requests to the first of its providers that answers.
+// A provider that fails is skipped for fallbackCooldown, so a session keeps
+// using the fallback instead of waiting for the failing provider every turn.
+type fallbackProvider struct {
+	providers []Provider
+
+	mu       sync.Mutex
+	failedAt []time.Time
+}
+
+// NewFallbackProvider returns a provider that fails over to the next provider
+// in order when a request fails, before any output was received from it.
+// Responses name the model that actually answered.
+func NewFallbackProvider(providers ...Provider) Provider {
+	if len(providers) == 1 {
+		return providers[0]
+	}
+	return &fallbackProvider{
+		providers: providers,
+		failedAt:  make([]time.Time, len(providers)),
+	}
+}
+
+// Model returns the model of the provider the next request is sent to first,
+// so the context window and costs of the request are those of the model
+// CountTokens counts for.
+func (p *fallbackProvider) Model() models.Model {
+	return p.providers[p.order()[0]].Model()
+}
+
+// CountTokens counts the tokens for the provider the next request is sent
+// to first.
+func (p *fallbackProvider) CountTokens(messages []message.Message, tools []tools.BaseTool) int64 {
+	provider := p.providers[p.order()[0]]
+	return provider.CountTokens(convertHistory(messages, provider.Model()), tools)
+}
+
+func (p *fallbackProvider) SendMessages(ctx context.Context, messages []message.Message, tools []tools.BaseTool) (*ProviderResponse, error) {
+	var lastErr error
+	for _, i := range p.order() {
+		provider := p.providers[i]
+		response, err := provider.SendMessages(ctx, convertHistory(messages, provider.Model()), tools)
+		if err == nil {
+			p.answered(i)
+			response.Model = provider.Model().ID
+			return response, nil
+		}
+		if !shouldFailOver(ctx, err) {
+			return nil, err
+		}
+		p.failed(i, err)
+		lastErr = err
+	}
+	return nil, lastErr
+}
+
+func (p *fallbackProvider) StreamResponse(ctx context.Context, messages []message.Message, tools []tools.BaseTool) <-chan ProviderEvent {
+	eventChan := make(chan ProviderEvent)
+
+	go func() {
+		defer close(eventChan)
+
+		var lastErr error
+		for _, i := range p.order() {
+			provider := p.providers[i]
+			// Events before the first output are held back, so that they are
+			// not repeated by the next provider when this one fails
+			var failErr error
+			var pending []ProviderEvent
+			started := false
+			for event := range provider.StreamResponse(ctx, convertHistory(messages, provider.Model()), tools) {
+				if failErr != nil {
+					// Drain the stream of the failed provider
+					continue
+				}
+				switch event.Type {
+				case EventError:
+					if !started && shouldFailOver(ctx, event.Error) {
+						failErr = event.Error
+						continue
+					}
+					started = true
+				case EventThinkingDelta, EventContentDelta, EventToolUseStart, EventToolUseStop:
+					started = true
+				case EventComplete:
+					started = true
+					p.answered(i)
+					if event.Response != nil {
+						event.Response.Model = provider.Model().ID
+					}
+				}
+				if !started {
+					pending = append(pending, event)
+					continue
+				}
+				for _, held := range pending {
+					eventChan <- held
+				}
+				pending = nil
+				eventChan <- event
+			}
+			if failErr == nil {
+				for _, held := range pending {
+					eventChan <- held
+				}
+				return
+			}
+			p.failed(i, failErr)
+			lastErr = failErr
+		}
+		eventChan <- ProviderEvent{Type: EventError, Error: lastErr}
+	}()
+
+	return eventChan
+}
+
+// order returns the indexes of the providers to try: the ones that did not
+// fail recently, then the others as a last resort
+func (p *fallbackProvider) order() []int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var available, coolingDown []int
+	for i, failedAt := range p.failedAt {
+		if time.Since(failedAt) < fallbackCooldown {
+			coolingDown = append(coolingDown, i)
+		} else {
+			available = append(available, i)
+		}
+	}
+	return append(available, coolingDown...)
+}
+
+func (p *fallbackProvider) answered(i int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.failedAt[i] = time.Time{}
+}
+
+func (p *fallbackProvider) failed(i int, err error) {
+	p.mu.Lock()
+	p.failedAt[i] = time.Now()
+	p.mu.Unlock()
+
+	logging.WarnPersist(fmt.Sprintf("%s failed, trying the next fallback model: %s", p.providers[i].Model().Name, err))
+}
+
+// shouldFailOver reports whether err is a failure of the provider that
+// another provider may not have: a server error, an overloaded provider, or a
+// transient error that persisted after all the retries. Invalid requests and
+// cancellations are returned as they are.
+func shouldFailOver(ctx context.Context, err error) bool {
+	if err == nil || ctx.Err() != nil {
+		return false
+	}
+	if errors.Is(err, errMaxRetries) {
+		return true
+	}
+	status, _ := errorStatus(err)
+	return status >= http.StatusInternalServerError
+}
+
+// invalidToolCallIDChars matches characters not accepted in tool call IDs by
+// all providers
+var invalidToolCallIDChars = regexp.MustCompile(`[^a-zA-Z0-9_-]`)
+
+// convertHistory adapts a message history, possibly created with another
+// provider, to model: attachments are dropped when the model does not support
+// them and tool call IDs are limited to the characters all providers accept.
+// The messages passed in are not modified.
+func convertHistory(messages []message.Message, model models.Model) []message.Message {
+	converted := make([]message.Message, len(messages))
+	for i, msg := range messages {
+		parts := make([]message.ContentPart, 0, len(msg.Parts))
+		for _, part := range msg.Parts {
+			switch part := part.(type) {
+			case message.BinaryContent, message.ImageURLContent:
+				if !model.SupportsAttachments {
+					continue
+				}
+			case message.ToolCall:
+				part.ID = invalidToolCallIDChars.ReplaceAllString(part.ID, "_")
+				parts = append(parts, part)
+				continue
+			case message.ToolResult:
+				part.ToolCallID = invalidToolCallIDChars.ReplaceAllString(part.ToolCallID, "_")
+				parts = append(parts, part)
+				continue
+			}
+			parts = append(parts, part)
+		}
+		msg.Parts = parts
+		converted[i] = msg
+	}
+	return converted
+}
@@ -0,0 +1,207 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/omnitrix-sh/cli/internal/llm/models"
+	"github.com/omnitrix-sh/cli/internal/llm/tools"
+	"github.com/omnitrix-sh/cli/internal/message"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeProvider struct {
+	model  models.Model
+	events []ProviderEvent
+	calls  int
+	tokens int64
+}
+
+func (f *fakeProvider) SendMessages(ctx context.Context, messages []message.Message, tools []tools.BaseTool) (*ProviderResponse, error) {
+	f.calls++
+	for _, event := range f.events {
+		if event.Type == EventError {
+			return nil, event.Error
+		}
+	}
+	return &ProviderResponse{Content: string(f.model.ID)}, nil
+}
+
+func (f *fakeProvider) StreamResponse(ctx context.Context, messages []message.Message, tools []tools.BaseTool) <-chan ProviderEvent {
+	f.calls++
+	eventChan := make(chan ProviderEvent, len(f.events))
+	for _, event := range f.events {
+		eventChan <- event
+	}
+	close(eventChan)
+	return eventChan
+}
+
+func (f *fakeProvider) CountTokens(messages []message.Message, tools []tools.BaseTool) int64 {
+	return f.tokens
+}
+
+func (f *fakeProvider) Model() models.Model {
+	return f.model
+}
+
+func collectEvents(events <-chan ProviderEvent) []ProviderEvent {
+	var collected []ProviderEvent
+	for event := range events {
+		collected = append(collected, event)
+	}
+	return collected
+}
+
+func TestFallbackProvider(t *testing.T) {
+	errUnavailable := apiError(http.StatusServiceUnavailable, nil)
+	complete := ProviderEvent{Type: EventComplete, Response: &ProviderResponse{}}
+
+	t.Run("fails over before any output", func(t *testing.T) {
+		primary := &fakeProvider{
+			model:  models.Model{ID: "primary"},
+			events: []ProviderEvent{{Type: EventError, Error: errUnavailable}},
+		}
+		fallback := &fakeProvider{
+			model:  models.Model{ID: "fallback"},
+			events: []ProviderEvent{{Type: EventContentDelta, Content: "hi"}, complete},
+		}
+		p := NewFallbackProvider(primary, fallback)
+
+		events := collectEvents(p.StreamResponse(context.Background(), nil, nil))
+		require.Len(t, events, 2)
+		assert.Equal(t, EventComplete, events[1].Type)
+		assert.Equal(t, models.ModelID("fallback"), events[1].Response.Model)
+		assert.Equal(t, models.ModelID("fallback"), p.Model().ID)
+
+		// The failed provider is skipped while cooling down
+		collectEvents(p.StreamResponse(context.Background(), nil, nil))
+		assert.Equal(t, 1, primary.calls)
+		assert.Equal(t, 2, fallback.calls)
+	})
+
+	t.Run("does not repeat the events of the failed provider", func(t *testing.T) {
+		start := ProviderEvent{Type: EventContentStart}
+		p := NewFallbackProvider(
+			&fakeProvider{model: models.Model{ID: "primary"}, events: []ProviderEvent{start, {Type: EventError, Error: errUnavailable}}},
+			&fakeProvider{model: models.Model{ID: "fallback"}, events: []ProviderEvent{start, complete}},
+		)
+
+		events := collectEvents(p.StreamResponse(context.Background(), nil, nil))
+		require.Len(t, events, 2)
+		assert.Equal(t, EventContentStart, events[0].Type)
+		assert.Equal(t, EventComplete, events[1].Type)
+	})
+
+	t.Run("sizes requests for the provider tried first", func(t *testing.T) {
+		primary := &fakeProvider{
+			model:  models.Model{ID: "primary"},
+			events: []ProviderEvent{{Type: EventError, Error: errUnavailable}},
+			tokens: 100,
+		}
+		fallback := &fakeProvider{model: models.Model{ID: "fallback"}, events: []ProviderEvent{complete}, tokens: 200}
+		p := NewFallbackProvider(primary, fallback)
+		assert.Equal(t, models.ModelID("primary"), p.Model().ID)
+		assert.Equal(t, int64(100), p.CountTokens(nil, nil))
+
+		collectEvents(p.StreamResponse(context.Background(), nil, nil))
+		assert.Equal(t, models.ModelID("fallback"), p.Model().ID)
+		assert.Equal(t, int64(200), p.CountTokens(nil, nil))
+
+		// Once the cooldown is over the primary provider is tried first again
+		p.(*fallbackProvider).failedAt[0] = time.Now().Add(-fallbackCooldown)
+		assert.Equal(t, models.ModelID("primary"), p.Model().ID)
+		assert.Equal(t, int64(100), p.CountTokens(nil, nil))
+	})
+
+	t.Run("does not fail over after output", func(t *testing.T) {
+		primary := &fakeProvider{
+			model: models.Model{ID: "primary"},
+			events: []ProviderEvent{
+				{Type: EventContentDelta, Content: "partial"},
+				{Type: EventError, Error: errUnavailable},
+			},
+		}
+		fallback := &fakeProvider{model: models.Model{ID: "fallback"}}
+		p := NewFallbackProvider(primary, fallback)
+
+		events := collectEvents(p.StreamResponse(context.Background(), nil, nil))
+		require.Len(t, events, 2)
+		assert.Equal(t, EventError, events[1].Type)
+		assert.Equal(t, 0, fallback.calls)
+	})
+
+	t.Run("returns the last error when all fail", func(t *testing.T) {
+		errLast := fmt.Errorf("%w (8 retries): %w", errMaxRetries, apiError(http.StatusTooManyRequests, nil))
+		p := NewFallbackProvider(
+			&fakeProvider{model: models.Model{ID: "a"}, events: []ProviderEvent{{Type: EventError, Error: errUnavailable}}},
+			&fakeProvider{model: models.Model{ID: "b"}, events: []ProviderEvent{{Type: EventError, Error: errLast}}},
+		)
+
+		_, err := p.SendMessages(context.Background(), nil, nil)
+		assert.ErrorIs(t, err, errLast)
+	})
+
+	t.Run("does not fail over invalid requests", func(t *testing.T) {
+		errInvalid := apiError(http.StatusBadRequest, nil)
+		fallback := &fakeProvider{model: models.Model{ID: "fallback"}}
+		p := NewFallbackProvider(
+			&fakeProvider{model: models.Model{ID: "primary"}, events: []ProviderEvent{{Type: EventError, Error: errInvalid}}},
+			fallback,
+		)
+
+		_, err := p.SendMessages(context.Background(), nil, nil)
+		assert.ErrorIs(t, err, errInvalid)
+		events := collectEvents(p.StreamResponse(context.Background(), nil, nil))
+		require.Len(t, events, 1)
+		assert.ErrorIs(t, events[0].Error, errInvalid)
+		assert.Equal(t, 0, fallback.calls)
+	})
+
+	t.Run("does not fail over when canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		fallback := &fakeProvider{model: models.Model{ID: "fallback"}}
+		p := NewFallbackProvider(
+			&fakeProvider{model: models.Model{ID: "primary"}, events: []ProviderEvent{{Type: EventError, Error: context.Canceled}}},
+			fallback,
+		)
+
+		_, err := p.SendMessages(ctx, nil, nil)
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.Equal(t, 0, fallback.calls)
+	})
+}
+
+func TestConvertHistory(t *testing.T) {
+	messages := []message.Message{
+		{
+			Role: message.User,
+			Parts: []message.ContentPart{
+				message.TextContent{Text: "look"},
+				message.BinaryContent{Path: "a.png", MIMEType: "image/png"},
+			},
+		},
+		{
+			Role:  message.Assistant,
+			Parts: []message.ContentPart{message.ToolCall{ID: "functions.view:0", Name: "view"}},
+		},
+		{
+			Role:  message.Tool,
+			Parts: []message.ContentPart{message.ToolResult{ToolCallID: "functions.view:0"}},
+		},
+	}
+
+	converted := convertHistory(messages, models.Model{})
+	assert.Len(t, converted[0].Parts, 1)
+	assert.Equal(t, "functions_view_0", converted[1].ToolCalls()[0].ID)
+	assert.Equal(t, "functions_view_0", converted[2].ToolResults()[0].ToolCallID)
+
+	// The original history is left untouched
+	assert.Len(t, messages[0].Parts, 2)
+	assert.Equal(t, "functions.view:0", messages[1].ToolCalls()[0].ID)
+}
@@ -0,0 +1,136 @@
+package provider
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/omnitrix-sh/cli/internal/logging"
+	"google.golang.org/genai"
+)
+
+const (
+	// geminiCacheTTL is how long a context cache is kept by Gemini
+	geminiCacheTTL = 10 * time.Minute
+	// geminiCacheMinTokens is the smallest context worth caching, Gemini
+	// rejects caches below a model dependent minimum of 1024 to 4096 tokens
+	geminiCacheMinTokens = 4096
+	// geminiCacheMaxUncached is how many messages can follow the cached ones
+	// before the cache is recreated with the longer history
+	geminiCacheMaxUncached = 6
+)
+
+// geminiCache is a context cache holding the system instruction, the tools
+// and the start of the conversation, shared by the requests of a client
+type geminiCache struct {
+	mu   sync.Mutex
+	name string
+	// key identifies the cached contents
+	key      string
+	messages int
+	expires  time.Time
+	// retryAt delays creating a cache after creating one failed, for example
+	// because the model does not support context caching
+	retryAt time.Time
+}
+
+// useCache moves the system instruction, the tools and as much of history as
+// possible into a context cache, which config is changed to refer to. It
+// returns the part of history that is not cached and the number of tokens
+// written to a new cache, if one was created.
+func (g *geminiClient) useCache(ctx context.Context, config *genai.GenerateContentConfig, history []*genai.Content) ([]*genai.Content, int64) {
+	if g.options.disableCache {
+		return history, 0
+	}
+
+	c := &g.cache
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Reuse the cache while the history starts with the cached messages and
+	// not too many messages were added after them
+	if c.name != "" && time.Until(c.expires) > time.Minute &&
+		c.messages <= len(history) && len(history)-c.messages <= geminiCacheMaxUncached &&
+		c.key == geminiCacheKey(config, history[:c.messages]) {
+		applyGeminiCache(config, c.name)
+		return history[c.messages:], 0
+	}
+
+	if time.Now().Before(c.retryAt) || estimateGeminiTokens(config, history) < geminiCacheMinTokens {
+		return history, 0
+	}
+
+	cached, err := g.client.Caches.Create(ctx, g.providerOptions.model.APIModel, &genai.CreateCachedContentConfig{
+		TTL:               geminiCacheTTL,
+		Contents:          history,
+		SystemInstruction: config.SystemInstruction,
+		Tools:             config.Tools,
+	})
+	if err != nil {
+		logging.Debug("Failed to create Gemini context cache", "model", g.providerOptions.model.APIModel, "error", err)
+		c.retryAt = time.Now().Add(geminiCacheTTL)
+		return history, 0
+	}
+
+	g.deleteCache(c.name)
+	c.name = cached.Name
+	c.key = geminiCacheKey(config, history)
+	c.messages = len(history)
+	c.expires = time.Now().Add(geminiCacheTTL)
+	applyGeminiCache(config, c.name)
+
+	var written int64
+	if cached.UsageMetadata != nil {
+		written = int64(cached.UsageMetadata.TotalTokenCount)
+	}
+	return nil, written
+}
+
+// deleteCache deletes a context cache that was replaced, in the background
+func (g *geminiClient) deleteCache(name string) {
+	if name == "" {
+		return
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		if _, err := g.client.Caches.Delete(ctx, name, nil); err != nil {
+			logging.Debug("Failed to delete Gemini context cache", "name", name, "error", err)
+		}
+	}()
+}
+
+// applyGeminiCache makes config use the cache, which holds the system
+// instruction and tools that must not be sent with the request anymore
+func applyGeminiCache(config *genai.GenerateContentConfig, name string) {
+	config.CachedContent = name
+	config.SystemInstruction = nil
+	config.Tools = nil
+}
+
+// geminiCacheKey identifies a cache holding the system instruction and tools
+// of config and the given contents
+func geminiCacheKey(config *genai.GenerateContentConfig, contents []*genai.Content) string {
+	hash := sha256.New()
+	encoder := json.NewEncoder(hash)
+	_ = encoder.Encode(config.SystemInstruction)
+	_ = encoder.Encode(config.Tools)
+	for _, content := range contents {
+		_ = encoder.Encode(content)
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// estimateGeminiTokens roughly estimates the tokens of a request at four
+// bytes per token
+func estimateGeminiTokens(config *genai.GenerateContentConfig, contents []*genai.Content) int {
+	size := 0
+	for _, v := range []any{config.SystemInstruction, config.Tools, contents} {
+		data, _ := json.Marshal(v)
+		size += len(data)
+	}
+	return size / 4
+}
@@ -0,0 +1,290 @@
+package provider
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/omnitrix-sh/cli/internal/config"
+	"github.com/omnitrix-sh/cli/internal/llm/models"
+	"github.com/omnitrix-sh/cli/internal/llm/tools"
+	"github.com/omnitrix-sh/cli/internal/logging"
+	"github.com/omnitrix-sh/cli/internal/message"
+)
+
+// ollamaClient talks to Ollama through its native chat API, which unlike its
+// OpenAI compatible API lets the context size of the model be set
+type ollamaClient struct {
+	providerOptions providerClientOptions
+	host            string
+	client          *http.Client
+}
+
+type OllamaClient ProviderClient
+
+func newOllamaClient(opts providerClientOptions) OllamaClient {
+	return &ollamaClient{
+		providerOptions: opts,
+		host:            models.OllamaHost(),
+		client:          &http.Client{},
+	}
+}
+
+type ollamaMessage struct {
+	Role     string           `json:"role"`
+	Content  string           `json:"content"`
+	Thinking string           `json:"thinking,omitempty"`
+	Images   []string         `json:"images,omitempty"`
+	ToolName string           `json:"tool_name,omitempty"`
+	Calls    []ollamaToolCall `json:"tool_calls,omitempty"`
+}
+
+type ollamaToolCall struct {
+	Function struct {
+		Name      string          `json:"name"`
+		Arguments json.RawMessage `json:"arguments"`
+	} `json:"function"`
+}
+
+type ollamaTool struct {
+	Type     string `json:"type"`
+	Function struct {
+		Name        string         `json:"name"`
+		Description string         `json:"description"`
+		Parameters  map[string]any `json:"parameters"`
+	} `json:"function"`
+}
+
+type ollamaChatRequest struct {
+	Model    string          `json:"model"`
+	Messages []ollamaMessage `json:"messages"`
+	Tools    []ollamaTool    `json:"tools,omitempty"`
+	Stream   bool            `json:"stream"`
+	Think    bool            `json:"think,omitempty"`
+	Options  map[string]any  `json:"options"`
+}
+
+type ollamaChatResponse struct {
+	Message         ollamaMessage `json:"message"`
+	Done            bool          `json:"done"`
+	DoneReason      string        `json:"done_reason"`
+	PromptEvalCount int64         `json:"prompt_eval_count"`
+	EvalCount       int64         `json:"eval_count"`
+	Error           string        `json:"error"`
+}
+
+func (o *ollamaClient) convertMessages(messages []message.Message) []ollamaMessage {
+	ollamaMessages := []ollamaMessage{{Role: "system", Content: o.providerOptions.systemMessage}}
+
+	for _, msg := range messages {
+		switch msg.Role {
+		case message.User:
+			userMsg := ollamaMessage{Role: "user", Content: msg.Content().String()}
+			for _, binaryContent := range msg.BinaryContent() {
+				userMsg.Images = append(userMsg.Images, base64.StdEncoding.EncodeToString(binaryContent.Data))
+			}
+			ollamaMessages = append(ollamaMessages, userMsg)
+
+		case message.Assistant:
+			assistantMsg := ollamaMessage{Role: "assistant", Content: msg.Content().String()}
+			for _, call := range msg.ToolCalls() {
+				var toolCall ollamaToolCall
+				toolCall.Function.Name = call.Name
+				toolCall.Function.Arguments = json.RawMessage(call.Input)
+				if !json.Valid(toolCall.Function.Arguments) {
+					toolCall.Function.Arguments = json.RawMessage("{}")
+				}
+				assistantMsg.Calls = append(assistantMsg.Calls, toolCall)
+			}
+			ollamaMessages = append(ollamaMessages, assistantMsg)
+
+		case message.Tool:
+			for _, result := range msg.ToolResults() {
+				ollamaMessages = append(ollamaMessages, ollamaMessage{
+					Role:     "tool",
+					Content:  result.Content,
+					ToolName: result.Name,
+				})
+			}
+		}
+	}
+
+	return ollamaMessages
+}
+
+func (o *ollamaClient) convertTools(tools []tools.BaseTool) []ollamaTool {
+	ollamaTools := make([]ollamaTool, len(tools))
+
+	for i, tool := range tools {
+		info := tool.Info()
+		ollamaTools[i].Type = "function"
+		ollamaTools[i].Function.Name = info.Name
+		ollamaTools[i].Function.Description = info.Description
+		ollamaTools[i].Function.Parameters = map[string]any{
+			"type":       "object",
+			"properties": info.Parameters,
+			"required":   info.Required,
+		}
+	}
+
+	return ollamaTools
+}
+
+func (o *ollamaClient) preparedRequest(messages []message.Message, tools []tools.BaseTool, stream bool) ollamaChatRequest {
+	model := o.providerOptions.model
+	return ollamaChatRequest{
+		Model:    model.APIModel,
+		Messages: o.convertMessages(messages),
+		Tools:    o.convertTools(tools),
+		Stream:   stream,
+		Think:    model.CanReason,
+		Options: map[string]any{
+			// Ollama runs models with a small context by default, silently
+			// dropping the start of longer conversations
+			"num_ctx":     model.ContextWindow,
+			"num_predict": o.providerOptions.maxTokens,
+		},
+	}
+}
+
+func (o *ollamaClient) chat(ctx context.Context, request ollamaChatRequest) (*http.Response, error) {
+	cfg := config.Get()
+	if cfg.Debug {
+		jsonData, _ := json.Marshal(request)
+		logging.Debug("Prepared messages", "messages", string(jsonData))
+	}
+	return models.OllamaRequest(ctx, o.client, o.host, http.MethodPost, "/api/chat", request)
+}
+
+func (o *ollamaClient) send(ctx context.Context, messages []message.Message, tools []tools.BaseTool) (*ProviderResponse, error) {
+	res, err := o.chat(ctx, o.preparedRequest(messages, tools, false))
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	var chatResponse ollamaChatResponse
+	if err := json.NewDecoder(res.Body).Decode(&chatResponse); err != nil {
+		return nil, err
+	}
+	if chatResponse.Error != "" {
+		return nil, errors.New(chatResponse.Error)
+	}
+
+	toolCalls := o.toolCalls(chatResponse.Message)
+	return &ProviderResponse{
+		Content:      chatResponse.Message.Content,
+		ToolCalls:    toolCalls,
+		Usage:        o.usage(chatResponse),
+		FinishReason: o.finishReason(chatResponse.DoneReason, toolCalls),
+	}, nil
+}
+
+func (o *ollamaClient) stream(ctx context.Context, messages []message.Message, tools []tools.BaseTool) <-chan ProviderEvent {
+	eventChan := make(chan ProviderEvent)
+
+	go func() {
+		defer close(eventChan)
+
+		res, err := o.chat(ctx, o.preparedRequest(messages, tools, true))
+		if err != nil {
+			eventChan <- ProviderEvent{Type: EventError, Error: err}
+			return
+		}
+		defer res.Body.Close()
+
+		content := ""
+		var toolCalls []message.ToolCall
+		// The response is streamed as a JSON object per line
+		scanner := bufio.NewScanner(res.Body)
+		scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+		for scanner.Scan() {
+			var chunk ollamaChatResponse
+			if err := json.Unmarshal(scanner.Bytes(), &chunk); err != nil {
+				eventChan <- ProviderEvent{Type: EventError, Error: fmt.Errorf("invalid response from Ollama: %w", err)}
+				return
+			}
+			if chunk.Error != "" {
+				eventChan <- ProviderEvent{Type: EventError, Error: errors.New(chunk.Error)}
+				return
+			}
+
+			if chunk.Message.Thinking != "" {
+				eventChan <- ProviderEvent{Type: EventThinkingDelta, Thinking: chunk.Message.Thinking}
+			}
+			if chunk.Message.Content != "" {
+				eventChan <- ProviderEvent{Type: EventContentDelta, Content: chunk.Message.Content}
+				content += chunk.Message.Content
+			}
+			// Tool calls arrive complete in a single chunk
+			for _, call := range o.toolCalls(chunk.Message) {
+				eventChan <- ProviderEvent{Type: EventToolUseStart, ToolCall: &call}
+				eventChan <- ProviderEvent{Type: EventToolUseStop, ToolCall: &call}
+				toolCalls = append(toolCalls, call)
+			}
+
+			if chunk.Done {
+				eventChan <- ProviderEvent{
+					Type: EventComplete,
+					Response: &ProviderResponse{
+						Content:      content,
+						ToolCalls:    toolCalls,
+						Usage:        o.usage(chunk),
+						FinishReason: o.finishReason(chunk.DoneReason, toolCalls),
+					},
+				}
+				return
+			}
+		}
+
+		err = scanner.Err()
+		if err == nil {
+			err = errors.New("response stream from Ollama ended before the response was completed")
+		}
+		eventChan <- ProviderEvent{Type: EventError, Error: err}
+	}()
+
+	return eventChan
+}
+
+// toolCalls returns the tool calls of a message, which Ollama does not give
+// IDs to
+func (o *ollamaClient) toolCalls(msg ollamaMessage) []message.ToolCall {
+	var toolCalls []message.ToolCall
+	for _, call := range msg.Calls {
+		toolCalls = append(toolCalls, message.ToolCall{
+			ID:       "call_" + uuid.NewString(),
+			Name:     call.Function.Name,
+			Input:    string(call.Function.Arguments),
+			Type:     "function",
+			Finished: true,
+		})
+	}
+	return toolCalls
+}
+
+func (o *ollamaClient) finishReason(reason string, toolCalls []message.ToolCall) message.FinishReason {
+	if len(toolCalls) > 0 {
+		return message.FinishReasonToolUse
+	}
+	switch reason {
+	case "stop":
+		return message.FinishReasonEndTurn
+	case "length":
+		return message.FinishReasonMaxTokens
+	default:
+		return message.FinishReasonUnknown
+	}
+}
+
+func (o *ollamaClient) usage(response ollamaChatResponse) TokenUsage {
+	return TokenUsage{
+		InputTokens:  response.PromptEvalCount,
+		OutputTokens: response.EvalCount,
+	}
+}
@@ -0,0 +1,60 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/omnitrix-sh/cli/internal/config"
+	"github.com/omnitrix-sh/cli/internal/llm/models"
+	"github.com/omnitrix-sh/cli/internal/message"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOllamaClient(t *testing.T) {
+	_, err := config.Load(t.TempDir(), false)
+	require.NoError(t, err)
+
+	var request ollamaChatRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/api/chat", r.URL.Path)
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		for _, chunk := range []string{
+			`{"message":{"role":"assistant","content":"","thinking":"Let me look"},"done":false}`,
+			`{"message":{"role":"assistant","content":"","tool_calls":[{"function":{"name":"view","arguments":{"path":"main.go"}}}]},"done":false}`,
+			`{"message":{"role":"assistant","content":""},"done":true,"done_reason":"stop","prompt_eval_count":120,"eval_count":30}`,
+		} {
+			fmt.Fprintln(w, chunk)
+		}
+	}))
+	defer server.Close()
+	t.Setenv("OLLAMA_HOST", server.URL)
+
+	o := newOllamaClient(providerClientOptions{
+		model:     models.Model{APIModel: "qwen3:8b", ContextWindow: 32768, CanReason: true},
+		maxTokens: 4096,
+	})
+	messages := []message.Message{
+		{Role: message.User, Parts: []message.ContentPart{message.TextContent{Text: "open main.go"}}},
+	}
+	events := collectEvents(o.stream(context.Background(), messages, nil))
+
+	assert.Equal(t, float64(32768), request.Options["num_ctx"])
+	assert.True(t, request.Think)
+
+	require.Len(t, events, 4)
+	assert.Equal(t, EventThinkingDelta, events[0].Type)
+	assert.Equal(t, EventToolUseStart, events[1].Type)
+	assert.Equal(t, EventToolUseStop, events[2].Type)
+
+	response := events[3].Response
+	require.NotNil(t, response)
+	require.Len(t, response.ToolCalls, 1)
+	assert.JSONEq(t, `{"path":"main.go"}`, response.ToolCalls[0].Input)
+	assert.Equal(t, message.FinishReasonToolUse, response.FinishReason)
+	assert.Equal(t, TokenUsage{InputTokens: 120, OutputTokens: 30}, response.Usage)
+}
@@ -4,9 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
-	"fmt"
 	"io"
-	"time"
 
 	"github.com/openai/openai-go"
 	"github.com/openai/openai-go/option"
@@ -23,6 +21,7 @@ type openaiOptions struct {
 	disableCache    bool
 	reasoningEffort string
 	extraHeaders    map[string]string
+	responsesAPI    bool
 }
 
 type OpenAIOption func(*openaiOptions)
@@ -35,17 +34,31 @@ type openaiClient struct {
 
 type OpenAIClient ProviderClient
 
-func newOpenAIClient(opts providerClientOptions) OpenAIClient {
+func newOpenAIOptions(opts providerClientOptions) openaiOptions {
 	openaiOpts := openaiOptions{
 		reasoningEffort: "medium",
 	}
 	for _, o := range opts.openaiOptions {
 		o(&openaiOpts)
 	}
+	return openaiOpts
+}
 
+func newOpenAIClient(opts providerClientOptions) OpenAIClient {
+	openaiOpts := newOpenAIOptions(opts)
+	return &openaiClient{
+		providerOptions: opts,
+		options:         openaiOpts,
+		client:          newOpenAISDKClient(opts.apiKey, openaiOpts),
+	}
+}
+
+// newOpenAISDKClient creates the SDK client shared by the Chat Completions
+// and Responses clients
+func newOpenAISDKClient(apiKey string, openaiOpts openaiOptions) openai.Client {
 	openaiClientOptions := []option.RequestOption{}
-	if opts.apiKey != "" {
-		openaiClientOptions = append(openaiClientOptions, option.WithAPIKey(opts.apiKey))
+	if apiKey != "" {
+		openaiClientOptions = append(openaiClientOptions, option.WithAPIKey(apiKey))
 	}
 	if openaiOpts.baseURL != "" {
 		openaiClientOptions = append(openaiClientOptions, option.WithBaseURL(openaiOpts.baseURL))
@@ -57,12 +70,7 @@ func newOpenAIClient(opts providerClientOptions) OpenAIClient {
 		}
 	}
 
-	client := openai.NewClient(openaiClientOptions...)
-	return &openaiClient{
-		providerOptions: opts,
-		options:         openaiOpts,
-		client:          client,
-	}
+	return openai.NewClient(openaiClientOptions...)
 }
 
 func (o *openaiClient) convertMessages(messages []message.Message) (openaiMessages []openai.ChatCompletionMessageParamUnion) {
@@ -192,50 +200,32 @@ func (o *openaiClient) send(ctx context.Context, messages []message.Message, too
 		jsonData, _ := json.Marshal(params)
 		logging.Debug("Prepared messages", "messages", string(jsonData))
 	}
-	attempts := 0
-	for {
-		attempts++
-		openaiResponse, err := o.client.Chat.Completions.New(
-			ctx,
-			params,
-		)
-		// If there is an error we are going to see if we can retry the call
-		if err != nil {
-			retry, after, retryErr := o.shouldRetry(attempts, err)
-			if retryErr != nil {
-				return nil, retryErr
-			}
-			if retry {
-				logging.WarnPersist(fmt.Sprintf("Retrying due to rate limit... attempt %d of %d", attempts, maxRetries), logging.PersistTimeArg, time.Millisecond*time.Duration(after+100))
-				select {
-				case <-ctx.Done():
-					return nil, ctx.Err()
-				case <-time.After(time.Duration(after) * time.Millisecond):
-					continue
-				}
-			}
-			return nil, retryErr
-		}
-
-		content := ""
-		if openaiResponse.Choices[0].Message.Content != "" {
-			content = openaiResponse.Choices[0].Message.Content
-		}
+	openaiResponse, err := o.client.Chat.Completions.New(
+		ctx,
+		params,
+	)
+	if err != nil {
+		return nil, err
+	}
 
-		toolCalls := o.toolCalls(*openaiResponse)
-		finishReason := o.finishReason(string(openaiResponse.Choices[0].FinishReason))
+	content := ""
+	if openaiResponse.Choices[0].Message.Content != "" {
+		content = openaiResponse.Choices[0].Message.Content
+	}
 
-		if len(toolCalls) > 0 {
-			finishReason = message.FinishReasonToolUse
-		}
+	toolCalls := o.toolCalls(*openaiResponse)
+	finishReason := o.finishReason(string(openaiResponse.Choices[0].FinishReason))
 
-		return &ProviderResponse{
-			Content:      content,
-			ToolCalls:    toolCalls,
-			Usage:        o.usage(*openaiResponse),
-			FinishReason: finishReason,
-		}, nil
+	if len(toolCalls) > 0 {
+		finishReason = message.FinishReasonToolUse
 	}
+
+	return &ProviderResponse{
+		Content:      content,
+		ToolCalls:    toolCalls,
+		Usage:        o.usage(*openaiResponse),
+		FinishReason: finishReason,
+	}, nil
 }
 
 func (o *openaiClient) stream(ctx context.Context, messages []message.Message, tools []tools.BaseTool) <-chan ProviderEvent {
@@ -250,118 +240,64 @@ func (o *openaiClient) stream(ctx context.Context, messages []message.Message, t
 		logging.Debug("Prepared messages", "messages", string(jsonData))
 	}
 
-	attempts := 0
 	eventChan := make(chan ProviderEvent)
 
 	go func() {
-		for {
-			attempts++
-			openaiStream := o.client.Chat.Completions.NewStreaming(
-				ctx,
-				params,
-			)
-
-			acc := openai.ChatCompletionAccumulator{}
-			currentContent := ""
-			toolCalls := make([]message.ToolCall, 0)
-
-			for openaiStream.Next() {
-				chunk := openaiStream.Current()
-				acc.AddChunk(chunk)
-
-				for _, choice := range chunk.Choices {
-					if choice.Delta.Content != "" {
-						eventChan <- ProviderEvent{
-							Type:    EventContentDelta,
-							Content: choice.Delta.Content,
-						}
-						currentContent += choice.Delta.Content
-					}
-				}
-			}
+		defer close(eventChan)
 
-			err := openaiStream.Err()
-			if err == nil || errors.Is(err, io.EOF) {
-				// Stream completed successfully
-				finishReason := o.finishReason(string(acc.ChatCompletion.Choices[0].FinishReason))
-				if len(acc.ChatCompletion.Choices[0].Message.ToolCalls) > 0 {
-					toolCalls = append(toolCalls, o.toolCalls(acc.ChatCompletion)...)
-				}
-				if len(toolCalls) > 0 {
-					finishReason = message.FinishReasonToolUse
-				}
+		openaiStream := o.client.Chat.Completions.NewStreaming(
+			ctx,
+			params,
+		)
 
-				eventChan <- ProviderEvent{
-					Type: EventComplete,
-					Response: &ProviderResponse{
-						Content:      currentContent,
-						ToolCalls:    toolCalls,
-						Usage:        o.usage(acc.ChatCompletion),
-						FinishReason: finishReason,
-					},
+		acc := openai.ChatCompletionAccumulator{}
+		currentContent := ""
+		toolCalls := make([]message.ToolCall, 0)
+
+		for openaiStream.Next() {
+			chunk := openaiStream.Current()
+			acc.AddChunk(chunk)
+
+			for _, choice := range chunk.Choices {
+				if choice.Delta.Content != "" {
+					eventChan <- ProviderEvent{
+						Type:    EventContentDelta,
+						Content: choice.Delta.Content,
+					}
+					currentContent += choice.Delta.Content
 				}
-				close(eventChan)
-				return
 			}
+		}
 
-			// If there is an error we are going to see if we can retry the call
-			retry, after, retryErr := o.shouldRetry(attempts, err)
-			if retryErr != nil {
-				eventChan <- ProviderEvent{Type: EventError, Error: retryErr}
-				close(eventChan)
-				return
+		err := openaiStream.Err()
+		if err == nil || errors.Is(err, io.EOF) {
+			// Stream completed successfully
+			finishReason := o.finishReason(string(acc.ChatCompletion.Choices[0].FinishReason))
+			if len(acc.ChatCompletion.Choices[0].Message.ToolCalls) > 0 {
+				toolCalls = append(toolCalls, o.toolCalls(acc.ChatCompletion)...)
 			}
-			if retry {
-				logging.WarnPersist(fmt.Sprintf("Retrying due to rate limit... attempt %d of %d", attempts, maxRetries), logging.PersistTimeArg, time.Millisecond*time.Duration(after+100))
-				select {
-				case <-ctx.Done():
-					// context cancelled
-					if ctx.Err() == nil {
-						eventChan <- ProviderEvent{Type: EventError, Error: ctx.Err()}
-					}
-					close(eventChan)
-					return
-				case <-time.After(time.Duration(after) * time.Millisecond):
-					continue
-				}
+			if len(toolCalls) > 0 {
+				finishReason = message.FinishReasonToolUse
+			}
+
+			eventChan <- ProviderEvent{
+				Type: EventComplete,
+				Response: &ProviderResponse{
+					Content:      currentContent,
+					ToolCalls:    toolCalls,
+					Usage:        o.usage(acc.ChatCompletion),
+					FinishReason: finishReason,
+				},
 			}
-			eventChan <- ProviderEvent{Type: EventError, Error: retryErr}
-			close(eventChan)
 			return
 		}
+
+		eventChan <- ProviderEvent{Type: EventError, Error: err}
 	}()
 
 	return eventChan
 }
 
-func (o *openaiClient) shouldRetry(attempts int, err error) (bool, int64, error) {
-	var apierr *openai.Error
-	if !errors.As(err, &apierr) {
-		return false, 0, err
-	}
-
-	if apierr.StatusCode != 429 && apierr.StatusCode != 500 {
-		return false, 0, err
-	}
-
-	if attempts > maxRetries {
-		return false, 0, fmt.Errorf("maximum retry attempts reached for rate limit: %d retries", maxRetries)
-	}
-
-	retryMs := 0
-	retryAfterValues := apierr.Response.Header.Values("Retry-After")
-
-	backoffMs := 2000 * (1 << (attempts - 1))
-	jitterMs := int(float64(backoffMs) * 0.2)
-	retryMs = backoffMs + jitterMs
-	if len(retryAfterValues) > 0 {
-		if _, err := fmt.Sscanf(retryAfterValues[0], "%d", &retryMs); err == nil {
-			retryMs = retryMs * 1000
-		}
-	}
-	return true, int64(retryMs), nil
-}
-
 func (o *openaiClient) toolCalls(completion openai.ChatCompletion) []message.ToolCall {
 	var toolCalls []message.ToolCall
 
@@ -411,6 +347,14 @@ func WithOpenAIDisableCache() OpenAIOption {
 	}
 }
 
+// WithOpenAIResponsesAPI uses the Responses API instead of Chat Completions,
+// which returns reasoning summaries for reasoning models.
+func WithOpenAIResponsesAPI() OpenAIOption {
+	return func(options *openaiOptions) {
+		options.responsesAPI = true
+	}
+}
+
 func WithReasoningEffort(effort string) OpenAIOption {
 	return func(options *openaiOptions) {
 		defaultReasoningEffort := "medium"
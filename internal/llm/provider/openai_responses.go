@@ -0,0 +1,311 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/omnitrix-sh/cli/internal/config"
+	"github.com/omnitrix-sh/cli/internal/llm/models"
+	"github.com/omnitrix-sh/cli/internal/llm/tools"
+	"github.com/omnitrix-sh/cli/internal/logging"
+	"github.com/omnitrix-sh/cli/internal/message"
+	"github.com/openai/openai-go"
+	"github.com/openai/openai-go/responses"
+	"github.com/openai/openai-go/shared"
+)
+
+// openaiResponsesClient talks to OpenAI through the Responses API, which
+// unlike Chat Completions returns summaries of the reasoning of reasoning
+// models. Responses are not stored by OpenAI, the encrypted reasoning is
+// kept with the messages and sent back with the following requests instead.
+type openaiResponsesClient struct {
+	providerOptions providerClientOptions
+	options         openaiOptions
+	client          openai.Client
+}
+
+func newOpenAIResponsesClient(opts providerClientOptions) OpenAIClient {
+	openaiOpts := newOpenAIOptions(opts)
+	return &openaiResponsesClient{
+		providerOptions: opts,
+		options:         openaiOpts,
+		client:          newOpenAISDKClient(opts.apiKey, openaiOpts),
+	}
+}
+
+func (o *openaiResponsesClient) convertMessages(messages []message.Message) responses.ResponseInputParam {
+	var input responses.ResponseInputParam
+
+	for _, msg := range messages {
+		switch msg.Role {
+		case message.User:
+			content := responses.ResponseInputMessageContentListParam{
+				{OfInputText: &responses.ResponseInputTextParam{Text: msg.Content().String()}},
+			}
+			for _, binaryContent := range msg.BinaryContent() {
+				content = append(content, responses.ResponseInputContentUnionParam{
+					OfInputImage: &responses.ResponseInputImageParam{
+						ImageURL: openai.String(binaryContent.String(models.ProviderOpenAI)),
+						Detail:   responses.ResponseInputImageDetailAuto,
+					},
+				})
+			}
+			input = append(input, responses.ResponseInputItemUnionParam{
+				OfMessage: &responses.EasyInputMessageParam{
+					Role:    responses.EasyInputMessageRoleUser,
+					Content: responses.EasyInputMessageContentUnionParam{OfInputItemContentList: content},
+				},
+			})
+
+		case message.Assistant:
+			// Encrypted reasoning can only be read by the model that wrote it
+			if msg.Model == o.providerOptions.model.ID {
+				for _, reasoning := range msg.ReasoningContent().Encrypted {
+					item := responses.ResponseReasoningItemParam{
+						ID:      reasoning.ID,
+						Summary: []responses.ResponseReasoningItemSummaryParam{},
+					}
+					item.WithExtraFields(map[string]any{"encrypted_content": reasoning.Content})
+					input = append(input, responses.ResponseInputItemUnionParam{OfReasoning: &item})
+				}
+			}
+
+			if msg.Content().String() != "" {
+				input = append(input, responses.ResponseInputItemUnionParam{
+					OfMessage: &responses.EasyInputMessageParam{
+						Role:    responses.EasyInputMessageRoleAssistant,
+						Content: responses.EasyInputMessageContentUnionParam{OfString: openai.String(msg.Content().String())},
+					},
+				})
+			}
+
+			for _, call := range msg.ToolCalls() {
+				input = append(input, responses.ResponseInputItemUnionParam{
+					OfFunctionCall: &responses.ResponseFunctionToolCallParam{
+						CallID:    call.ID,
+						Name:      call.Name,
+						Arguments: call.Input,
+					},
+				})
+			}
+
+		case message.Tool:
+			for _, result := range msg.ToolResults() {
+				input = append(input, responses.ResponseInputItemUnionParam{
+					OfFunctionCallOutput: &responses.ResponseInputItemFunctionCallOutputParam{
+						CallID: result.ToolCallID,
+						Output: result.Content,
+					},
+				})
+			}
+		}
+	}
+
+	return input
+}
+
+func (o *openaiResponsesClient) convertTools(tools []tools.BaseTool) []responses.ToolUnionParam {
+	openaiTools := make([]responses.ToolUnionParam, len(tools))
+
+	for i, tool := range tools {
+		info := tool.Info()
+		openaiTools[i] = responses.ToolUnionParam{
+			OfFunction: &responses.FunctionToolParam{
+				Name:        info.Name,
+				Description: openai.String(info.Description),
+				Parameters: map[string]any{
+					"type":       "object",
+					"properties": info.Parameters,
+					"required":   info.Required,
+				},
+			},
+		}
+	}
+
+	return openaiTools
+}
+
+func (o *openaiResponsesClient) preparedParams(input responses.ResponseInputParam, tools []responses.ToolUnionParam) responses.ResponseNewParams {
+	params := responses.ResponseNewParams{
+		Model:           shared.ResponsesModel(o.providerOptions.model.APIModel),
+		Instructions:    openai.String(o.providerOptions.systemMessage),
+		Input:           responses.ResponseNewParamsInputUnion{OfInputItemList: input},
+		Tools:           tools,
+		MaxOutputTokens: openai.Int(o.providerOptions.maxTokens),
+		Store:           openai.Bool(false),
+	}
+
+	if o.providerOptions.model.CanReason {
+		params.Reasoning = shared.ReasoningParam{Effort: shared.ReasoningEffortMedium}
+		switch o.options.reasoningEffort {
+		case "low":
+			params.Reasoning.Effort = shared.ReasoningEffortLow
+		case "high":
+			params.Reasoning.Effort = shared.ReasoningEffortHigh
+		}
+		params.Reasoning.WithExtraFields(map[string]any{"summary": "auto"})
+		params.Include = []responses.ResponseIncludable{"reasoning.encrypted_content"}
+	}
+
+	return params
+}
+
+func (o *openaiResponsesClient) send(ctx context.Context, messages []message.Message, tools []tools.BaseTool) (*ProviderResponse, error) {
+	params := o.preparedParams(o.convertMessages(messages), o.convertTools(tools))
+	cfg := config.Get()
+	if cfg.Debug {
+		jsonData, _ := json.Marshal(params)
+		logging.Debug("Prepared messages", "messages", string(jsonData))
+	}
+
+	openaiResponse, err := o.client.Responses.New(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	if openaiResponse.Status == responses.ResponseStatusFailed {
+		return nil, fmt.Errorf("response failed: %s", openaiResponse.Error.Message)
+	}
+	return o.response(*openaiResponse), nil
+}
+
+func (o *openaiResponsesClient) stream(ctx context.Context, messages []message.Message, tools []tools.BaseTool) <-chan ProviderEvent {
+	params := o.preparedParams(o.convertMessages(messages), o.convertTools(tools))
+	cfg := config.Get()
+	if cfg.Debug {
+		jsonData, _ := json.Marshal(params)
+		logging.Debug("Prepared messages", "messages", string(jsonData))
+	}
+
+	eventChan := make(chan ProviderEvent)
+
+	go func() {
+		defer close(eventChan)
+
+		openaiStream := o.client.Responses.NewStreaming(ctx, params)
+
+		// Summaries can have several parts, separated when streamed
+		summaryPartDone := false
+		for openaiStream.Next() {
+			event := openaiStream.Current()
+
+			switch event.Type {
+			case "response.reasoning_summary_text.delta":
+				thinking := event.Delta
+				if summaryPartDone {
+					thinking = "\n\n" + thinking
+					summaryPartDone = false
+				}
+				eventChan <- ProviderEvent{Type: EventThinkingDelta, Thinking: thinking}
+
+			case "response.reasoning_summary_text.done":
+				summaryPartDone = true
+
+			case "response.output_text.delta":
+				eventChan <- ProviderEvent{Type: EventContentDelta, Content: event.Delta}
+
+			case "response.output_item.added":
+				if event.Item.Type == "function_call" {
+					eventChan <- ProviderEvent{
+						Type: EventToolUseStart,
+						ToolCall: &message.ToolCall{
+							ID:       event.Item.CallID,
+							Name:     event.Item.Name,
+							Finished: false,
+						},
+					}
+				}
+
+			case "response.output_item.done":
+				if event.Item.Type == "function_call" {
+					eventChan <- ProviderEvent{
+						Type:     EventToolUseStop,
+						ToolCall: &message.ToolCall{ID: event.Item.CallID},
+					}
+				}
+
+			case "response.completed", "response.incomplete":
+				eventChan <- ProviderEvent{Type: EventComplete, Response: o.response(event.Response)}
+				return
+
+			case "response.failed":
+				eventChan <- ProviderEvent{Type: EventError, Error: fmt.Errorf("response failed: %s", event.Response.Error.Message)}
+				return
+
+			case "error":
+				eventChan <- ProviderEvent{Type: EventError, Error: fmt.Errorf("response failed: %s (%s)", event.Message, event.Code)}
+				return
+			}
+		}
+
+		err := openaiStream.Err()
+		if err == nil || errors.Is(err, io.EOF) {
+			err = errors.New("response stream ended before the response was completed")
+		}
+		eventChan <- ProviderEvent{Type: EventError, Error: err}
+	}()
+
+	return eventChan
+}
+
+// response converts a completed response, whose output holds the reasoning,
+// the text and the tool calls of the model
+func (o *openaiResponsesClient) response(r responses.Response) *ProviderResponse {
+	response := &ProviderResponse{
+		FinishReason: message.FinishReasonEndTurn,
+		Usage:        o.usage(r),
+	}
+
+	for _, item := range r.Output {
+		switch item.Type {
+		case "message":
+			for _, content := range item.Content {
+				if content.Type == "output_text" {
+					response.Content += content.Text
+				}
+			}
+		case "function_call":
+			response.ToolCalls = append(response.ToolCalls, message.ToolCall{
+				ID:       item.CallID,
+				Name:     item.Name,
+				Input:    item.Arguments,
+				Type:     "function",
+				Finished: true,
+			})
+		case "reasoning":
+			var reasoning struct {
+				EncryptedContent string `json:"encrypted_content"`
+			}
+			if err := json.Unmarshal([]byte(item.RawJSON()), &reasoning); err == nil && reasoning.EncryptedContent != "" {
+				response.EncryptedReasoning = append(response.EncryptedReasoning, message.EncryptedReasoning{
+					ID:      item.ID,
+					Content: reasoning.EncryptedContent,
+				})
+			}
+		}
+	}
+
+	if len(response.ToolCalls) > 0 {
+		response.FinishReason = message.FinishReasonToolUse
+	} else if r.Status == responses.ResponseStatusIncomplete {
+		if r.IncompleteDetails.Reason == "max_output_tokens" {
+			response.FinishReason = message.FinishReasonMaxTokens
+		} else {
+			response.FinishReason = message.FinishReasonUnknown
+		}
+	}
+
+	return response
+}
+
+func (o *openaiResponsesClient) usage(r responses.Response) TokenUsage {
+	cachedTokens := r.Usage.InputTokensDetails.CachedTokens
+
+	return TokenUsage{
+		InputTokens:     r.Usage.InputTokens - cachedTokens,
+		OutputTokens:    r.Usage.OutputTokens,
+		CacheReadTokens: cachedTokens,
+	}
+}
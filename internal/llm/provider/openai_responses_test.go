@@ -0,0 +1,100 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/omnitrix-sh/cli/internal/config"
+	"github.com/omnitrix-sh/cli/internal/llm/models"
+	"github.com/omnitrix-sh/cli/internal/message"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenAIResponsesClient(t *testing.T) {
+	_, err := config.Load(t.TempDir(), false)
+	require.NoError(t, err)
+
+	model := models.Model{ID: "o4-mini", APIModel: "o4-mini", CanReason: true}
+
+	t.Run("sends encrypted reasoning back to the same model", func(t *testing.T) {
+		o := newOpenAIResponsesClient(providerClientOptions{model: model}).(*openaiResponsesClient)
+		reasoning := message.ReasoningContent{Encrypted: []message.EncryptedReasoning{{ID: "rs_1", Content: "gAAA"}}}
+		messages := []message.Message{
+			{Role: message.User, Parts: []message.ContentPart{message.TextContent{Text: "fix it"}}},
+			{Role: message.Assistant, Model: model.ID, Parts: []message.ContentPart{reasoning, message.ToolCall{ID: "call_1", Name: "view", Input: "{}"}}},
+			{Role: message.Tool, Parts: []message.ContentPart{message.ToolResult{ToolCallID: "call_1", Content: "package main"}}},
+			{Role: message.Assistant, Model: "o3", Parts: []message.ContentPart{reasoning, message.TextContent{Text: "done"}}},
+		}
+
+		data, err := json.Marshal(o.preparedParams(o.convertMessages(messages), nil))
+		require.NoError(t, err)
+		var params struct {
+			Input []map[string]any `json:"input"`
+			Store bool             `json:"store"`
+			// Reasoning and Include ask for the summary and encrypted reasoning
+			Reasoning map[string]any `json:"reasoning"`
+			Include   []string       `json:"include"`
+		}
+		require.NoError(t, json.Unmarshal(data, &params))
+
+		var types []string
+		for _, item := range params.Input {
+			types = append(types, fmt.Sprint(item["type"], item["role"]))
+		}
+		assert.Equal(t, []string{"<nil>user", "reasoning<nil>", "function_call<nil>", "function_call_output<nil>", "<nil>assistant"}, types)
+		assert.Equal(t, "gAAA", params.Input[1]["encrypted_content"])
+		assert.False(t, params.Store)
+		assert.Equal(t, "auto", params.Reasoning["summary"])
+		assert.Equal(t, []string{"reasoning.encrypted_content"}, params.Include)
+	})
+
+	t.Run("streams reasoning summaries", func(t *testing.T) {
+		events := []string{
+			`{"type":"response.reasoning_summary_text.delta","delta":"Reading"}`,
+			`{"type":"response.reasoning_summary_text.done","text":"Reading"}`,
+			`{"type":"response.reasoning_summary_text.delta","delta":"Editing"}`,
+			`{"type":"response.output_item.added","item":{"type":"function_call","call_id":"call_1","name":"edit"}}`,
+			`{"type":"response.output_item.done","item":{"type":"function_call","call_id":"call_1","name":"edit"}}`,
+			`{"type":"response.completed","response":{"status":"completed","output":[` +
+				`{"type":"reasoning","id":"rs_1","summary":[],"encrypted_content":"gAAA"},` +
+				`{"type":"function_call","call_id":"call_1","name":"edit","arguments":"{}"}],` +
+				`"usage":{"input_tokens":100,"input_tokens_details":{"cached_tokens":40},"output_tokens":20}}}`,
+		}
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/event-stream")
+			for _, event := range events {
+				fmt.Fprintf(w, "data: %s\n\n", event)
+			}
+		}))
+		defer server.Close()
+
+		o := newOpenAIResponsesClient(providerClientOptions{
+			apiKey:        "test",
+			model:         model,
+			openaiOptions: []OpenAIOption{WithOpenAIBaseURL(server.URL)},
+		})
+		collected := collectEvents(o.stream(context.Background(), nil, nil))
+		require.Len(t, collected, 5)
+
+		var thinking strings.Builder
+		for _, event := range collected[:2] {
+			assert.Equal(t, EventThinkingDelta, event.Type)
+			thinking.WriteString(event.Thinking)
+		}
+		assert.Equal(t, "Reading\n\nEditing", thinking.String())
+		assert.Equal(t, EventToolUseStart, collected[2].Type)
+		assert.Equal(t, EventToolUseStop, collected[3].Type)
+
+		response := collected[4].Response
+		require.NotNil(t, response)
+		assert.Equal(t, message.FinishReasonToolUse, response.FinishReason)
+		assert.Equal(t, []message.EncryptedReasoning{{ID: "rs_1", Content: "gAAA"}}, response.EncryptedReasoning)
+		assert.Equal(t, TokenUsage{InputTokens: 60, OutputTokens: 20, CacheReadTokens: 40}, response.Usage)
+	})
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/omnitrix-sh/cli/internal/config"
 	"github.com/omnitrix-sh/cli/internal/llm/models"
 	"github.com/omnitrix-sh/cli/internal/llm/tools"
 	"github.com/omnitrix-sh/cli/internal/message"
@@ -12,8 +13,6 @@ import (
 
 type EventType string
 
-const maxRetries = 8
-
 const (
 	EventContentStart  EventType = "content_start"
 	EventToolUseStart  EventType = "tool_use_start"
@@ -39,6 +38,12 @@ type ProviderResponse struct {
 	ToolCalls    []message.ToolCall
 	Usage        TokenUsage
 	FinishReason message.FinishReason
+	// Model is the model that answered when it can differ from the
+	// provider's model, as with fallback models
+	Model models.ModelID
+	// EncryptedReasoning is the reasoning to send back with the next
+	// request, for providers that do not keep it themselves
+	EncryptedReasoning []message.EncryptedReasoning
 }
 
 type ProviderEvent struct {
@@ -55,6 +60,10 @@ type Provider interface {
 
 	StreamResponse(ctx context.Context, messages []message.Message, tools []tools.BaseTool) <-chan ProviderEvent
 
+	// CountTokens estimates the input tokens of a request with the given
+	// messages and tools, including the system prompt
+	CountTokens(messages []message.Message, tools []tools.BaseTool) int64
+
 	Model() models.Model
 }
 
@@ -64,6 +73,9 @@ type providerClientOptions struct {
 	maxTokens     int64
 	systemMessage string
 
+	maxRetries        int
+	requestsPerMinute int
+
 	anthropicOptions []AnthropicOption
 	openaiOptions    []OpenAIOption
 	geminiOptions    []GeminiOption
@@ -79,8 +91,21 @@ type ProviderClient interface {
 }
 
 type baseProvider[C ProviderClient] struct {
-	options providerClientOptions
-	client  C
+	options     providerClientOptions
+	client      C
+	retrier     *retrier
+	tokenizer   tokenizerFamily
+	calibration *tokenCalibration
+}
+
+func newBaseProvider[C ProviderClient](providerName models.ModelProvider, options providerClientOptions, client C) *baseProvider[C] {
+	return &baseProvider[C]{
+		options:     options,
+		client:      client,
+		retrier:     newRetrier(providerName, options, client),
+		tokenizer:   tokenizerFor(providerName),
+		calibration: modelCalibration(options.model.ID),
+	}
 }
 
 func NewProvider(providerName models.ModelProvider, opts ...ProviderClientOption) (Provider, error) {
@@ -90,48 +115,27 @@ func NewProvider(providerName models.ModelProvider, opts ...ProviderClientOption
 	}
 	switch providerName {
 	case models.ProviderCopilot:
-		return &baseProvider[CopilotClient]{
-			options: clientOptions,
-			client:  newCopilotClient(clientOptions),
-		}, nil
+		return newBaseProvider(providerName, clientOptions, newCopilotClient(clientOptions)), nil
 	case models.ProviderAnthropic:
-		return &baseProvider[AnthropicClient]{
-			options: clientOptions,
-			client:  newAnthropicClient(clientOptions),
-		}, nil
+		return newBaseProvider(providerName, clientOptions, newAnthropicClient(clientOptions)), nil
 	case models.ProviderOpenAI:
-		return &baseProvider[OpenAIClient]{
-			options: clientOptions,
-			client:  newOpenAIClient(clientOptions),
-		}, nil
+		if newOpenAIOptions(clientOptions).responsesAPI {
+			return newBaseProvider(providerName, clientOptions, newOpenAIResponsesClient(clientOptions)), nil
+		}
+		return newBaseProvider(providerName, clientOptions, newOpenAIClient(clientOptions)), nil
 	case models.ProviderGemini:
-		return &baseProvider[GeminiClient]{
-			options: clientOptions,
-			client:  newGeminiClient(clientOptions),
-		}, nil
+		return newBaseProvider(providerName, clientOptions, newGeminiClient(clientOptions)), nil
 	case models.ProviderBedrock:
-		return &baseProvider[BedrockClient]{
-			options: clientOptions,
-			client:  newBedrockClient(clientOptions),
-		}, nil
+		return newBaseProvider(providerName, clientOptions, newBedrockClient(clientOptions)), nil
 	case models.ProviderGROQ:
 		clientOptions.openaiOptions = append(clientOptions.openaiOptions,
 			WithOpenAIBaseURL("https://api.groq.com/openai/v1"),
 		)
-		return &baseProvider[OpenAIClient]{
-			options: clientOptions,
-			client:  newOpenAIClient(clientOptions),
-		}, nil
+		return newBaseProvider(providerName, clientOptions, newOpenAIClient(clientOptions)), nil
 	case models.ProviderAzure:
-		return &baseProvider[AzureClient]{
-			options: clientOptions,
-			client:  newAzureClient(clientOptions),
-		}, nil
+		return newBaseProvider(providerName, clientOptions, newAzureClient(clientOptions)), nil
 	case models.ProviderVertexAI:
-		return &baseProvider[VertexAIClient]{
-			options: clientOptions,
-			client:  newVertexAIClient(clientOptions),
-		}, nil
+		return newBaseProvider(providerName, clientOptions, newVertexAIClient(clientOptions)), nil
 	case models.ProviderOpenRouter:
 		clientOptions.openaiOptions = append(clientOptions.openaiOptions,
 			WithOpenAIBaseURL("https://openrouter.ai/api/v1"),
@@ -140,33 +144,48 @@ func NewProvider(providerName models.ModelProvider, opts ...ProviderClientOption
 				"X-Title":      "Omnitrix",
 			}),
 		)
-		return &baseProvider[OpenAIClient]{
-			options: clientOptions,
-			client:  newOpenAIClient(clientOptions),
-		}, nil
+		return newBaseProvider(providerName, clientOptions, newOpenAIClient(clientOptions)), nil
 	case models.ProviderXAI:
 		clientOptions.openaiOptions = append(clientOptions.openaiOptions,
 			WithOpenAIBaseURL("https://api.x.ai/v1"),
 		)
-		return &baseProvider[OpenAIClient]{
-			options: clientOptions,
-			client:  newOpenAIClient(clientOptions),
-		}, nil
+		return newBaseProvider(providerName, clientOptions, newOpenAIClient(clientOptions)), nil
 	case models.ProviderLocal:
 		clientOptions.openaiOptions = append(clientOptions.openaiOptions,
 			WithOpenAIBaseURL(os.Getenv("LOCAL_ENDPOINT")),
 		)
-		return &baseProvider[OpenAIClient]{
-			options: clientOptions,
-			client:  newOpenAIClient(clientOptions),
-		}, nil
+		return newBaseProvider(providerName, clientOptions, newOpenAIClient(clientOptions)), nil
+	case models.ProviderOllama:
+		return newBaseProvider(providerName, clientOptions, newOllamaClient(clientOptions)), nil
 	case models.ProviderMock:
 		// TODO: implement mock client for test
 		panic("not implemented")
 	}
+	if cfg := config.Get(); cfg != nil {
+		if custom, ok := cfg.CustomProviders[providerName]; ok {
+			return newCustomProvider(providerName, custom, clientOptions), nil
+		}
+	}
 	return nil, fmt.Errorf("provider not supported: %s", providerName)
 }
 
+// newCustomProvider creates a provider for an OpenAI or Anthropic compatible
+// endpoint declared in the configuration
+func newCustomProvider(providerName models.ModelProvider, custom config.CustomProvider, clientOptions providerClientOptions) Provider {
+	if custom.Type == config.CustomProviderAnthropic {
+		clientOptions.anthropicOptions = append(clientOptions.anthropicOptions,
+			WithAnthropicBaseURL(custom.BaseURL),
+			WithAnthropicExtraHeaders(custom.Headers),
+		)
+		return newBaseProvider(providerName, clientOptions, newAnthropicClient(clientOptions))
+	}
+	clientOptions.openaiOptions = append(clientOptions.openaiOptions,
+		WithOpenAIBaseURL(custom.BaseURL),
+		WithOpenAIExtraHeaders(custom.Headers),
+	)
+	return newBaseProvider(providerName, clientOptions, newOpenAIClient(clientOptions))
+}
+
 func (p *baseProvider[C]) cleanMessages(messages []message.Message) (cleaned []message.Message) {
 	for _, msg := range messages {
 		// The message has no content
@@ -178,18 +197,120 @@ func (p *baseProvider[C]) cleanMessages(messages []message.Message) (cleaned []m
 	return
 }
 
+// SendMessages sends the request, retrying it when it fails with a transient
+// error such as a rate limit.
 func (p *baseProvider[C]) SendMessages(ctx context.Context, messages []message.Message, tools []tools.BaseTool) (*ProviderResponse, error) {
 	messages = p.cleanMessages(messages)
-	return p.client.send(ctx, messages, tools)
+	estimated := p.tokenizer.countMessages(p.options.systemMessage, messages, tools)
+	refreshed := false
+	for attempt := 1; ; attempt++ {
+		if err := p.retrier.wait(ctx); err != nil {
+			return nil, err
+		}
+		response, err := p.client.send(ctx, messages, tools)
+		if err == nil {
+			p.calibration.update(estimated, response.Usage.inputTokens())
+			return response, nil
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		delay, retry := p.retrier.retryDelay(attempt, err, &refreshed)
+		if !retry {
+			return nil, p.retrier.giveUp(attempt, err)
+		}
+		if err := sleep(ctx, delay); err != nil {
+			return nil, err
+		}
+	}
 }
 
 func (p *baseProvider[C]) Model() models.Model {
 	return p.options.model
 }
 
+// CountTokens estimates the tokens with the tokenizer family of the
+// provider, corrected by the tokens counted for the previous requests.
+func (p *baseProvider[C]) CountTokens(messages []message.Message, tools []tools.BaseTool) int64 {
+	messages = p.cleanMessages(messages)
+	return p.calibration.apply(p.tokenizer.countMessages(p.options.systemMessage, messages, tools))
+}
+
+// StreamResponse streams the response, retrying the request when it fails
+// with a transient error before any output was received. Events of a failed
+// attempt are not forwarded.
 func (p *baseProvider[C]) StreamResponse(ctx context.Context, messages []message.Message, tools []tools.BaseTool) <-chan ProviderEvent {
 	messages = p.cleanMessages(messages)
-	return p.client.stream(ctx, messages, tools)
+	estimated := p.tokenizer.countMessages(p.options.systemMessage, messages, tools)
+	eventChan := make(chan ProviderEvent)
+
+	go func() {
+		defer close(eventChan)
+
+		refreshed := false
+		for attempt := 1; ; attempt++ {
+			if err := p.retrier.wait(ctx); err != nil {
+				eventChan <- ProviderEvent{Type: EventError, Error: err}
+				return
+			}
+
+			// Events before the first output are held back, so that a failed
+			// attempt is retried without repeating them
+			var streamErr error
+			var pending []ProviderEvent
+			started := false
+			for event := range p.client.stream(ctx, messages, tools) {
+				if streamErr != nil {
+					continue
+				}
+				switch event.Type {
+				case EventError:
+					if !started {
+						streamErr = event.Error
+						continue
+					}
+				case EventThinkingDelta, EventContentDelta, EventToolUseStart, EventToolUseStop:
+					started = true
+				case EventComplete:
+					started = true
+					if event.Response != nil {
+						p.calibration.update(estimated, event.Response.Usage.inputTokens())
+					}
+				}
+				if !started {
+					pending = append(pending, event)
+					continue
+				}
+				for _, held := range pending {
+					eventChan <- held
+				}
+				pending = nil
+				eventChan <- event
+			}
+			if streamErr == nil {
+				for _, held := range pending {
+					eventChan <- held
+				}
+				return
+			}
+			if ctx.Err() != nil {
+				eventChan <- ProviderEvent{Type: EventError, Error: ctx.Err()}
+				return
+			}
+
+			delay, retry := p.retrier.retryDelay(attempt, streamErr, &refreshed)
+			if !retry {
+				eventChan <- ProviderEvent{Type: EventError, Error: p.retrier.giveUp(attempt, streamErr)}
+				return
+			}
+			if err := sleep(ctx, delay); err != nil {
+				eventChan <- ProviderEvent{Type: EventError, Error: err}
+				return
+			}
+		}
+	}()
+
+	return eventChan
 }
 
 func WithAPIKey(apiKey string) ProviderClientOption {
@@ -216,6 +337,22 @@ func WithSystemMessage(systemMessage string) ProviderClientOption {
 	}
 }
 
+// WithMaxRetries sets how many times a request failing with a transient error
+// is retried, 0 keeps the default.
+func WithMaxRetries(maxRetries int) ProviderClientOption {
+	return func(options *providerClientOptions) {
+		options.maxRetries = maxRetries
+	}
+}
+
+// WithRequestsPerMinute limits the requests sent to the provider by all its
+// clients, 0 for no limit.
+func WithRequestsPerMinute(requestsPerMinute int) ProviderClientOption {
+	return func(options *providerClientOptions) {
+		options.requestsPerMinute = requestsPerMinute
+	}
+}
+
 func WithAnthropicOptions(anthropicOptions ...AnthropicOption) ProviderClientOption {
 	return func(options *providerClientOptions) {
 		options.anthropicOptions = anthropicOptions
@@ -0,0 +1,290 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/omnitrix-sh/cli/internal/llm/models"
+	"github.com/omnitrix-sh/cli/internal/logging"
+	"github.com/omnitrix-sh/cli/internal/pubsub"
+	"github.com/openai/openai-go"
+	"google.golang.org/genai"
+)
+
+const (
+	// defaultMaxRetries is how many times a failed request is retried when
+	// the provider configuration does not say otherwise
+	defaultMaxRetries = 8
+	// retryBackoff is the delay before the first retry, doubled for every
+	// further attempt up to maxRetryBackoff
+	retryBackoff    = 2 * time.Second
+	maxRetryBackoff = time.Minute
+)
+
+// RetryStatus is published when a request to a provider is delayed, either
+// to retry it after a failure or to stay within the configured rate limit.
+type RetryStatus struct {
+	Provider models.ModelProvider
+	Model    models.ModelID
+	// Reason says why the request is delayed, such as "rate limited"
+	Reason string
+	// Attempt is the number of the retry, 0 when the request is throttled
+	// before being sent
+	Attempt    int
+	MaxRetries int
+	Delay      time.Duration
+}
+
+// Message describes the status for display, for example "rate limited,
+// retrying in 8s (attempt 1 of 8)".
+func (s RetryStatus) Message() string {
+	delay := s.Delay.Round(time.Second)
+	if s.Attempt == 0 {
+		return fmt.Sprintf("%s, sending in %s", s.Reason, delay)
+	}
+	return fmt.Sprintf("%s, retrying in %s (attempt %d of %d)", s.Reason, delay, s.Attempt, s.MaxRetries)
+}
+
+var retryBroker = pubsub.NewBroker[RetryStatus]()
+
+// errMaxRetries is wrapped by the error of a request that still failed after
+// all its retries
+var errMaxRetries = errors.New("maximum retry attempts reached")
+
+// SubscribeRetryStatus returns the delayed requests to all providers.
+func SubscribeRetryStatus(ctx context.Context) <-chan pubsub.Event[RetryStatus] {
+	return retryBroker.Subscribe(ctx)
+}
+
+// credentialRefresher is implemented by clients whose credentials expire
+// during a session, so that a request rejected as unauthorized can be retried
+// with fresh ones
+type credentialRefresher interface {
+	refreshCredentials() error
+}
+
+// retrier retries the requests of a provider that fail with a transient
+// error and throttles them to the provider's rate limit
+type retrier struct {
+	provider   models.ModelProvider
+	model      models.ModelID
+	maxRetries int
+	limiter    *tokenBucket
+	refresher  credentialRefresher
+}
+
+func newRetrier(providerName models.ModelProvider, options providerClientOptions, client ProviderClient) *retrier {
+	r := &retrier{
+		provider:   providerName,
+		model:      options.model.ID,
+		maxRetries: defaultMaxRetries,
+		limiter:    providerLimiter(providerName, options.requestsPerMinute),
+	}
+	if options.maxRetries > 0 {
+		r.maxRetries = options.maxRetries
+	}
+	r.refresher, _ = client.(credentialRefresher)
+	return r
+}
+
+// wait blocks until the rate limit allows sending a request
+func (r *retrier) wait(ctx context.Context) error {
+	if r.limiter == nil {
+		return nil
+	}
+	if delay := r.limiter.reserve(); delay > 0 {
+		r.publish(RetryStatus{Reason: "requests per minute limit reached", Delay: delay})
+		return sleep(ctx, delay)
+	}
+	return nil
+}
+
+// retryDelay decides whether the request that failed with err on the given
+// attempt is retried, and after how long. refreshed records whether the
+// credentials were refreshed for the request already.
+func (r *retrier) retryDelay(attempt int, err error, refreshed *bool) (time.Duration, bool) {
+	status, header := errorStatus(err)
+	if status == http.StatusUnauthorized && r.refresher != nil && !*refreshed {
+		*refreshed = true
+		if refreshErr := r.refresher.refreshCredentials(); refreshErr != nil {
+			logging.Error("Failed to refresh credentials", "provider", r.provider, "error", refreshErr)
+			return 0, false
+		}
+		return 0, true
+	}
+
+	reason := retryReason(status, err)
+	if reason == "" || attempt > r.maxRetries {
+		return 0, false
+	}
+
+	delay := retryAfter(header)
+	if delay == 0 {
+		delay = min(retryBackoff<<(attempt-1), maxRetryBackoff)
+		delay += time.Duration(rand.Int64N(int64(delay / 5)))
+	}
+	logging.Warn("Retrying request", "provider", r.provider, "reason", reason, "attempt", attempt, "delay", delay, "error", err)
+	r.publish(RetryStatus{Reason: reason, Attempt: attempt, MaxRetries: r.maxRetries, Delay: delay})
+	return delay, true
+}
+
+// giveUp returns the error for a request that will not be retried
+func (r *retrier) giveUp(attempt int, err error) error {
+	status, _ := errorStatus(err)
+	if attempt > r.maxRetries && retryReason(status, err) != "" {
+		return fmt.Errorf("%w (%d retries): %w", errMaxRetries, r.maxRetries, err)
+	}
+	return err
+}
+
+func (r *retrier) publish(status RetryStatus) {
+	status.Provider = r.provider
+	status.Model = r.model
+	retryBroker.Publish(pubsub.UpdatedEvent, status)
+}
+
+// errorStatus returns the HTTP status and headers of the response an error
+// of one of the provider SDKs was created from
+func errorStatus(err error) (int, http.Header) {
+	var openaiErr *openai.Error
+	if errors.As(err, &openaiErr) {
+		return openaiErr.StatusCode, responseHeader(openaiErr.Response)
+	}
+	var anthropicErr *anthropic.Error
+	if errors.As(err, &anthropicErr) {
+		return anthropicErr.StatusCode, responseHeader(anthropicErr.Response)
+	}
+	var genaiErr genai.APIError
+	if errors.As(err, &genaiErr) {
+		return genaiErr.Code, nil
+	}
+	return models.OllamaErrorStatus(err), nil
+}
+
+func responseHeader(resp *http.Response) http.Header {
+	if resp == nil {
+		return nil
+	}
+	return resp.Header
+}
+
+// retryReason returns why a request that failed with the given status should
+// be retried, or an empty string when retrying would not help
+func retryReason(status int, err error) string {
+	switch status {
+	case http.StatusTooManyRequests:
+		return "rate limited"
+	case 529:
+		return "provider overloaded"
+	case http.StatusRequestTimeout:
+		return "request timed out"
+	case http.StatusConflict, http.StatusInternalServerError, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return fmt.Sprintf("server error %d", status)
+	case 0:
+		// Errors without a status, for example from wrapped clients, are
+		// recognised by their message
+		if err != nil && contains(err.Error(), "rate limit", "quota exceeded", "too many requests") {
+			return "rate limited"
+		}
+	}
+	return ""
+}
+
+// retryAfter returns the delay asked for by the provider in the
+// retry-after-ms or Retry-After headers, 0 if none
+func retryAfter(header http.Header) time.Duration {
+	if header == nil {
+		return 0
+	}
+	if ms, err := strconv.ParseFloat(header.Get("retry-after-ms"), 64); err == nil && ms > 0 {
+		return time.Duration(ms * float64(time.Millisecond))
+	}
+	value := strings.TrimSpace(header.Get("Retry-After"))
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil && seconds > 0 {
+		return time.Duration(seconds * float64(time.Second))
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return max(time.Until(at), 0)
+	}
+	return 0
+}
+
+// sleep waits for d or until ctx is done
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// tokenBucket limits requests to a number per minute, allowing bursts of up
+// to that number
+type tokenBucket struct {
+	mu       sync.Mutex
+	capacity float64
+	tokens   float64
+	// rate is the number of tokens added per second
+	rate float64
+	last time.Time
+}
+
+func newTokenBucket(perMinute int) *tokenBucket {
+	return &tokenBucket{
+		capacity: float64(perMinute),
+		tokens:   float64(perMinute),
+		rate:     float64(perMinute) / 60,
+		last:     time.Now(),
+	}
+}
+
+// reserve takes a token and returns how long to wait before it can be used
+func (b *tokenBucket) reserve() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	b.tokens = min(b.capacity, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+var (
+	limitersMu sync.Mutex
+	limiters   = make(map[models.ModelProvider]*tokenBucket)
+)
+
+// providerLimiter returns the rate limiter shared by all the agents using a
+// provider, nil when its requests are not limited
+func providerLimiter(providerName models.ModelProvider, requestsPerMinute int) *tokenBucket {
+	if requestsPerMinute <= 0 {
+		return nil
+	}
+	limitersMu.Lock()
+	defer limitersMu.Unlock()
+	limiter, ok := limiters[providerName]
+	if !ok || limiter.capacity != float64(requestsPerMinute) {
+		limiter = newTokenBucket(requestsPerMinute)
+		limiters[providerName] = limiter
+	}
+	return limiter
+}
@@ -0,0 +1,155 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/omnitrix-sh/cli/internal/llm/models"
+	"github.com/omnitrix-sh/cli/internal/llm/tools"
+	"github.com/omnitrix-sh/cli/internal/message"
+	"github.com/openai/openai-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// scriptedClient answers each request with the next of its results
+type scriptedClient struct {
+	results [][]ProviderEvent
+	calls   int
+}
+
+func (c *scriptedClient) next() []ProviderEvent {
+	events := c.results[min(c.calls, len(c.results)-1)]
+	c.calls++
+	return events
+}
+
+func (c *scriptedClient) send(ctx context.Context, messages []message.Message, tools []tools.BaseTool) (*ProviderResponse, error) {
+	for _, event := range c.next() {
+		if event.Type == EventError {
+			return nil, event.Error
+		}
+	}
+	return &ProviderResponse{Content: "ok"}, nil
+}
+
+func (c *scriptedClient) stream(ctx context.Context, messages []message.Message, tools []tools.BaseTool) <-chan ProviderEvent {
+	events := c.next()
+	eventChan := make(chan ProviderEvent, len(events))
+	for _, event := range events {
+		eventChan <- event
+	}
+	close(eventChan)
+	return eventChan
+}
+
+func apiError(status int, header http.Header) error {
+	return &openai.Error{
+		StatusCode: status,
+		Request:    httptest.NewRequest(http.MethodPost, "https://api.example.com/v1/chat/completions", nil),
+		Response:   &http.Response{StatusCode: status, Header: header},
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	tests := []struct {
+		name   string
+		header http.Header
+		want   time.Duration
+	}{
+		{"none", http.Header{}, 0},
+		{"seconds", http.Header{"Retry-After": {"8"}}, 8 * time.Second},
+		{"milliseconds", http.Header{"Retry-After-Ms": {"250"}, "Retry-After": {"1"}}, 250 * time.Millisecond},
+		{"invalid", http.Header{"Retry-After": {"soon"}}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, retryAfter(tt.header))
+		})
+	}
+}
+
+func TestRetryReason(t *testing.T) {
+	assert.Equal(t, "rate limited", retryReason(http.StatusTooManyRequests, nil))
+	assert.Equal(t, "provider overloaded", retryReason(529, nil))
+	assert.Equal(t, "server error 503", retryReason(http.StatusServiceUnavailable, nil))
+	assert.Equal(t, "rate limited", retryReason(0, errors.New("Quota exceeded for this project")))
+	assert.Empty(t, retryReason(http.StatusBadRequest, nil))
+	assert.Empty(t, retryReason(0, errors.New("connection refused")))
+}
+
+func TestTokenBucket(t *testing.T) {
+	bucket := newTokenBucket(2)
+	assert.Zero(t, bucket.reserve())
+	assert.Zero(t, bucket.reserve())
+	// The third request waits for a token, added every 30s
+	assert.InDelta(t, 30*time.Second, bucket.reserve(), float64(time.Second))
+}
+
+func TestBaseProviderRetry(t *testing.T) {
+	rateLimited := ProviderEvent{Type: EventError, Error: apiError(http.StatusTooManyRequests, http.Header{"Retry-After-Ms": {"1"}})}
+	complete := ProviderEvent{Type: EventComplete, Response: &ProviderResponse{Content: "ok"}}
+
+	t.Run("retries a rate limited stream", func(t *testing.T) {
+		client := &scriptedClient{results: [][]ProviderEvent{{rateLimited}, {complete}}}
+		p := newBaseProvider(models.ProviderOpenAI, providerClientOptions{}, client)
+
+		events := collectEvents(p.StreamResponse(context.Background(), nil, nil))
+		require.Len(t, events, 1)
+		assert.Equal(t, EventComplete, events[0].Type)
+		assert.Equal(t, 2, client.calls)
+	})
+
+	t.Run("does not repeat the events of a failed attempt", func(t *testing.T) {
+		start := ProviderEvent{Type: EventContentStart}
+		client := &scriptedClient{results: [][]ProviderEvent{
+			{start, rateLimited},
+			{start, {Type: EventContentDelta, Content: "ok"}, complete},
+		}}
+		p := newBaseProvider(models.ProviderOpenAI, providerClientOptions{}, client)
+
+		events := collectEvents(p.StreamResponse(context.Background(), nil, nil))
+		require.Len(t, events, 3)
+		assert.Equal(t, EventContentStart, events[0].Type)
+		assert.Equal(t, EventContentDelta, events[1].Type)
+		assert.Equal(t, EventComplete, events[2].Type)
+		assert.Equal(t, 2, client.calls)
+	})
+
+	t.Run("does not retry after output", func(t *testing.T) {
+		client := &scriptedClient{results: [][]ProviderEvent{
+			{{Type: EventContentDelta, Content: "partial"}, rateLimited},
+			{complete},
+		}}
+		p := newBaseProvider(models.ProviderOpenAI, providerClientOptions{}, client)
+
+		events := collectEvents(p.StreamResponse(context.Background(), nil, nil))
+		require.Len(t, events, 2)
+		assert.Equal(t, EventError, events[1].Type)
+		assert.Equal(t, 1, client.calls)
+	})
+
+	t.Run("does not retry client errors", func(t *testing.T) {
+		client := &scriptedClient{results: [][]ProviderEvent{
+			{{Type: EventError, Error: apiError(http.StatusBadRequest, nil)}},
+		}}
+		p := newBaseProvider(models.ProviderOpenAI, providerClientOptions{}, client)
+
+		_, err := p.SendMessages(context.Background(), nil, nil)
+		assert.Error(t, err)
+		assert.Equal(t, 1, client.calls)
+	})
+
+	t.Run("gives up after max retries", func(t *testing.T) {
+		client := &scriptedClient{results: [][]ProviderEvent{{rateLimited}}}
+		p := newBaseProvider(models.ProviderOpenAI, providerClientOptions{maxRetries: 2}, client)
+
+		_, err := p.SendMessages(context.Background(), nil, nil)
+		assert.ErrorContains(t, err, "maximum retry attempts reached")
+		assert.Equal(t, 3, client.calls)
+	})
+}
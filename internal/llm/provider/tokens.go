@@ -0,0 +1,183 @@
+package provider
+
+import (
+	"encoding/json"
+	"math"
+	"sync"
+	"unicode"
+
+	"github.com/omnitrix-sh/cli/internal/llm/models"
+	"github.com/omnitrix-sh/cli/internal/llm/tools"
+	"github.com/omnitrix-sh/cli/internal/message"
+)
+
+const (
+	// messageTokenOverhead is the role and separator tokens around a message
+	messageTokenOverhead = 4
+	// imageTokens is a typical cost of an image attachment
+	imageTokens = 1000
+)
+
+// tokenizerFamily describes how the tokenizers of a family of models split
+// text. Words are split into pieces of about lettersPerToken letters, most
+// common words being a single token.
+type tokenizerFamily struct {
+	lettersPerToken float64
+}
+
+var (
+	openaiTokenizer    = tokenizerFamily{lettersPerToken: 6}
+	geminiTokenizer    = tokenizerFamily{lettersPerToken: 6}
+	anthropicTokenizer = tokenizerFamily{lettersPerToken: 5}
+	// defaultTokenizer errs on the side of more tokens for unknown models
+	defaultTokenizer = tokenizerFamily{lettersPerToken: 4}
+)
+
+// tokenizerFor returns the tokenizer family of the models of a provider
+func tokenizerFor(providerName models.ModelProvider) tokenizerFamily {
+	switch providerName {
+	case models.ProviderOpenAI, models.ProviderAzure, models.ProviderCopilot, models.ProviderGROQ, models.ProviderXAI:
+		return openaiTokenizer
+	case models.ProviderGemini, models.ProviderVertexAI:
+		return geminiTokenizer
+	case models.ProviderAnthropic, models.ProviderBedrock:
+		return anthropicTokenizer
+	default:
+		return defaultTokenizer
+	}
+}
+
+// count estimates the number of tokens of text. Runs of letters are split
+// into pieces, numbers into groups of three digits, and other characters
+// such as punctuation and line breaks are a token each. A single space before
+// a word is part of the word's token.
+func (f tokenizerFamily) count(text string) int64 {
+	var tokens float64
+	letters, digits := 0, 0
+	flush := func() {
+		if letters > 0 {
+			tokens += math.Ceil(float64(letters) / f.lettersPerToken)
+			letters = 0
+		}
+		if digits > 0 {
+			tokens += math.Ceil(float64(digits) / 3)
+			digits = 0
+		}
+	}
+
+	for _, r := range text {
+		switch {
+		case r > unicode.MaxLatin1 && unicode.IsLetter(r) && !unicode.In(r, unicode.Latin, unicode.Greek, unicode.Cyrillic):
+			// Characters of scripts like CJK are about a token each
+			flush()
+			tokens++
+		case unicode.IsLetter(r):
+			if digits > 0 {
+				flush()
+			}
+			letters++
+		case unicode.IsDigit(r):
+			if letters > 0 {
+				flush()
+			}
+			digits++
+		case r == ' ':
+			flush()
+		default:
+			flush()
+			tokens++
+		}
+	}
+	flush()
+	return int64(tokens)
+}
+
+// countMessages estimates the tokens of a request with the given system
+// prompt, history and tools
+func (f tokenizerFamily) countMessages(systemMessage string, messages []message.Message, tools []tools.BaseTool) int64 {
+	tokens := f.count(systemMessage)
+	for _, msg := range messages {
+		tokens += messageTokenOverhead
+		for _, part := range msg.Parts {
+			switch part := part.(type) {
+			case message.TextContent:
+				tokens += f.count(part.Text)
+			case message.ReasoningContent:
+				tokens += f.count(part.Thinking)
+			case message.ToolCall:
+				tokens += f.count(part.Name) + f.count(part.Input)
+			case message.ToolResult:
+				tokens += f.count(part.Content)
+			case message.BinaryContent, message.ImageURLContent:
+				tokens += imageTokens
+			}
+		}
+	}
+	for _, tool := range tools {
+		info := tool.Info()
+		schema, _ := json.Marshal(info.Parameters)
+		tokens += messageTokenOverhead + f.count(info.Name) + f.count(info.Description) + f.count(string(schema))
+	}
+	return tokens
+}
+
+// tokenCalibration corrects the estimates for a model with the number of
+// input tokens the provider reported for the previous requests
+type tokenCalibration struct {
+	mu sync.Mutex
+	// ratio is the actual number of tokens per estimated token
+	ratio float64
+}
+
+// maxCalibrationRatio bounds the correction, in case usage reported by the
+// provider does not match the request, for example with a context cache
+const maxCalibrationRatio = 2
+
+func (c *tokenCalibration) apply(estimated int64) int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.ratio == 0 {
+		return estimated
+	}
+	return int64(float64(estimated) * c.ratio)
+}
+
+// update records the number of tokens a request was estimated at and the
+// number the provider counted, smoothing the ratio over requests
+func (c *tokenCalibration) update(estimated, actual int64) {
+	if estimated <= 0 || actual <= 0 {
+		return
+	}
+	ratio := min(max(float64(actual)/float64(estimated), 1/maxCalibrationRatio), maxCalibrationRatio)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.ratio == 0 {
+		c.ratio = ratio
+		return
+	}
+	c.ratio = 0.7*c.ratio + 0.3*ratio
+}
+
+var (
+	calibrationsMu sync.Mutex
+	calibrations   = make(map[models.ModelID]*tokenCalibration)
+)
+
+// modelCalibration returns the calibration shared by the clients of a model
+func modelCalibration(modelID models.ModelID) *tokenCalibration {
+	calibrationsMu.Lock()
+	defer calibrationsMu.Unlock()
+	calibration, ok := calibrations[modelID]
+	if !ok {
+		calibration = &tokenCalibration{}
+		calibrations[modelID] = calibration
+	}
+	return calibration
+}
+
+// inputTokens returns the number of tokens a request was counted at by the
+// provider
+func (u TokenUsage) inputTokens() int64 {
+	return u.InputTokens + u.CacheReadTokens + u.CacheCreationTokens
+}
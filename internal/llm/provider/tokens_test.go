@@ -0,0 +1,48 @@
+package provider
+
+import (
+	"testing"
+
+	"github.com/omnitrix-sh/cli/internal/message"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTokenizerCount(t *testing.T) {
+	tokenizer := tokenizerFamily{lettersPerToken: 4}
+
+	t.Run("splits words into pieces", func(t *testing.T) {
+		assert.Equal(t, int64(2), tokenizer.count("read the"))
+		assert.Equal(t, int64(4), tokenizer.count("implementation"))
+	})
+
+	t.Run("counts punctuation and numbers", func(t *testing.T) {
+		assert.Equal(t, int64(7), tokenizer.count("f(x) = 1234"))
+	})
+
+	t.Run("counts CJK characters one by one", func(t *testing.T) {
+		assert.Equal(t, int64(4), tokenizer.count("日本語だ"))
+	})
+}
+
+func TestCountMessages(t *testing.T) {
+	tokenizer := tokenizerFamily{lettersPerToken: 4}
+	messages := []message.Message{
+		{Role: message.User, Parts: []message.ContentPart{message.TextContent{Text: "read the"}}},
+		{Role: message.Tool, Parts: []message.ContentPart{message.ToolResult{Content: "done"}}},
+	}
+	assert.Equal(t, int64(2+4+2+4+1), tokenizer.countMessages("system", messages, nil))
+}
+
+func TestTokenCalibration(t *testing.T) {
+	var calibration tokenCalibration
+	assert.Equal(t, int64(100), calibration.apply(100))
+
+	calibration.update(100, 150)
+	assert.Equal(t, int64(150), calibration.apply(100))
+
+	calibration.update(100, 1000)
+	assert.Equal(t, int64(165), calibration.apply(100), "the ratio is smoothed and bounded")
+
+	calibration.update(0, 100)
+	assert.Equal(t, int64(165), calibration.apply(100))
+}
@@ -0,0 +1,341 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/omnitrix-sh/cli/internal/config"
+	"github.com/omnitrix-sh/cli/internal/history"
+	"github.com/omnitrix-sh/cli/internal/logging"
+	"github.com/omnitrix-sh/cli/internal/lsp"
+	"github.com/omnitrix-sh/cli/internal/lsp/protocol"
+	"github.com/omnitrix-sh/cli/internal/permission"
+)
+
+type CodeActionsParams struct {
+	FilePath string `json:"file_path"`
+	Line     int    `json:"line"`
+	EndLine  int    `json:"end_line"`
+	Kind     string `json:"kind"`
+	Apply    int    `json:"apply"`
+}
+
+type codeActionsTool struct {
+	lspClients  *lsp.Clients
+	permissions permission.Service
+	files       history.Service
+}
+
+// codeActionItem is a code action together with the server that offered it,
+// which is the one that has to resolve it
+type codeActionItem struct {
+	client *lsp.Client
+	action protocol.CodeAction
+}
+
+const (
+	CodeActionsToolName    = "code_actions"
+	codeActionsDescription = `List and apply code actions and quick fixes offered by the language server.
+WHEN TO USE THIS TOOL:
+- Use when diagnostics report an error the language server may know how to fix, such as a missing import or an unused variable
+- Use for refactorings the language server provides, such as organizing imports or extracting a function
+HOW TO USE:
+- Provide the file and, optionally, the line (and end_line) the diagnostic or code is on
+- Without a line, actions for the whole file are listed
+- Optionally restrict the list to a kind, e.g. "quickfix", "refactor" or "source.organizeImports"
+- The actions are returned as a numbered list. Call the tool again with the same file, lines and kind and set apply to the number of the action to apply it
+FEATURES:
+- Shows which diagnostics each action fixes and which action the server prefers
+- Applied changes are shown to the user as a diff first and are recorded in the file history
+- Diagnostics for the changed files are returned after applying an action
+LIMITATIONS:
+- Requires a language server configured for the file's language
+- Actions that only run a server command, without describing their changes, cannot be applied
+- The list is only valid until the file changes, list the actions again after editing
+TIPS:
+- Prefer quick fixes over hand-written edits for mechanical fixes like imports
+- Check the diagnostics after applying an action
+`
+)
+
+func NewCodeActionsTool(lspClients *lsp.Clients, permissions permission.Service, files history.Service) BaseTool {
+	return &codeActionsTool{
+		lspClients:  lspClients,
+		permissions: permissions,
+		files:       files,
+	}
+}
+
+func (c *codeActionsTool) Info() ToolInfo {
+	return ToolInfo{
+		Name:        CodeActionsToolName,
+		Description: codeActionsDescription,
+		Parameters: map[string]any{
+			"file_path": map[string]any{
+				"type":        "string",
+				"description": "The path to the file",
+			},
+			"line": map[string]any{
+				"type":        "integer",
+				"description": "The first line of the range to get actions for (1-based, optional)",
+			},
+			"end_line": map[string]any{
+				"type":        "integer",
+				"description": "The last line of the range (1-based, defaults to line)",
+			},
+			"kind": map[string]any{
+				"type":        "string",
+				"description": "Only return actions of this kind, e.g. quickfix, refactor, source",
+			},
+			"apply": map[string]any{
+				"type":        "integer",
+				"description": "The number of the action to apply, from a previous call with the same parameters",
+			},
+		},
+		Required: []string{"file_path"},
+	}
+}
+
+func (c *codeActionsTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error) {
+	lspClients := c.lspClients.Snapshot()
+	var params CodeActionsParams
+	if err := json.Unmarshal([]byte(call.Input), &params); err != nil {
+		return NewTextErrorResponse(fmt.Sprintf("error parsing parameters: %s", err)), nil
+	}
+
+	if params.FilePath == "" {
+		return NewTextErrorResponse("file_path is required"), nil
+	}
+
+	if len(lspClients) == 0 {
+		return NewTextErrorResponse("no LSP clients available"), nil
+	}
+
+	path := absolutePath(params.FilePath)
+	content, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return NewTextErrorResponse(fmt.Sprintf("file not found: %s", path)), nil
+		}
+		return ToolResponse{}, fmt.Errorf("failed to read file: %w", err)
+	}
+
+	lines := strings.Split(string(content), "\n")
+	rng, msg := codeActionRange(lines, params.Line, params.EndLine)
+	if msg != "" {
+		return NewTextErrorResponse(msg), nil
+	}
+
+	notifyLspOpenFile(ctx, path, lspClients)
+	waitForLspDiagnostics(ctx, path, lspClients)
+
+	actions := c.codeActions(ctx, path, rng, params.Kind)
+
+	if params.Apply == 0 {
+		return NewTextResponse(formatCodeActions(path, rng, actions)), nil
+	}
+	if params.Apply < 0 || params.Apply > len(actions) {
+		return NewTextErrorResponse(fmt.Sprintf("there is no action %d, list the actions again to get their numbers", params.Apply)), nil
+	}
+	return c.apply(ctx, actions[params.Apply-1])
+}
+
+// codeActions asks every language server for the actions available in a
+// range, passing the diagnostics that overlap it
+func (c *codeActionsTool) codeActions(ctx context.Context, path string, rng protocol.Range, kind string) []codeActionItem {
+	lspClients := c.lspClients.Snapshot()
+	uri := protocol.URIFromPath(path)
+
+	var only []protocol.CodeActionKind
+	if kind != "" {
+		only = []protocol.CodeActionKind{protocol.CodeActionKind(kind)}
+	}
+
+	var items []codeActionItem
+	for _, name := range clientsForFile(lspClients, path) {
+		client := lspClients[name]
+
+		diagnostics := []protocol.Diagnostic{}
+		for _, d := range client.GetFileDiagnostics(uri) {
+			if d.Range.Start.Line <= rng.End.Line && d.Range.End.Line >= rng.Start.Line {
+				diagnostics = append(diagnostics, d)
+			}
+		}
+
+		result, err := client.CodeAction(ctx, protocol.CodeActionParams{
+			TextDocument: protocol.TextDocumentIdentifier{URI: uri},
+			Range:        rng,
+			Context: protocol.CodeActionContext{
+				Diagnostics: diagnostics,
+				Only:        only,
+			},
+		})
+		if err != nil {
+			logging.Debug("LSP codeAction failed", "lsp", name, "error", err)
+			continue
+		}
+
+		for _, r := range result {
+			switch v := r.Value.(type) {
+			case protocol.CodeAction:
+				items = append(items, codeActionItem{client: client, action: v})
+			case protocol.Command:
+				items = append(items, codeActionItem{client: client, action: protocol.CodeAction{
+					Title:   v.Title,
+					Command: &v,
+				}})
+			}
+		}
+	}
+	return items
+}
+
+func (c *codeActionsTool) apply(ctx context.Context, item codeActionItem) (ToolResponse, error) {
+	lspClients := c.lspClients.Snapshot()
+	sessionID, messageID := GetContextValues(ctx)
+	if sessionID == "" || messageID == "" {
+		return ToolResponse{}, fmt.Errorf("session ID and message ID are required for applying a code action")
+	}
+
+	action := item.action
+	if action.Disabled != nil {
+		return NewTextErrorResponse(fmt.Sprintf("action %q is disabled: %s", action.Title, action.Disabled.Reason)), nil
+	}
+
+	if action.Edit == nil && action.Data != nil {
+		resolved, err := item.client.ResolveCodeAction(ctx, action)
+		if err != nil {
+			return NewTextErrorResponse(fmt.Sprintf("failed to resolve action %q: %s", action.Title, err)), nil
+		}
+		action = resolved
+	}
+	if action.Edit == nil {
+		return NewTextErrorResponse(fmt.Sprintf("action %q runs a server command and does not describe its changes, so it cannot be applied", action.Title)), nil
+	}
+
+	edits, err := resolveWorkspaceEdit(*action.Edit)
+	if err != nil {
+		return NewTextErrorResponse(err.Error()), nil
+	}
+	if len(edits) == 0 {
+		return NewTextErrorResponse(fmt.Sprintf("action %q changes nothing", action.Title)), nil
+	}
+
+	for _, e := range edits {
+		if msg := externalChangeMessage(e.path); msg != "" {
+			return NewTextErrorResponse(msg), nil
+		}
+	}
+
+	actionDiff, additions, removals := combinedDiff(edits)
+	p := c.permissions.Request(
+		permission.CreatePermissionRequest{
+			SessionID:   sessionID,
+			Path:        config.WorkingDirectory(),
+			ToolName:    CodeActionsToolName,
+			Action:      "apply",
+			Description: fmt.Sprintf("Apply code action %q to %d files", action.Title, len(edits)),
+			Params: EditPermissionsParams{
+				FilePath: edits[0].path,
+				Diff:     actionDiff,
+			},
+		},
+	)
+	if !p {
+		return ToolResponse{}, permission.ErrorPermissionDenied
+	}
+
+	if err := writeFileEdits(ctx, c.files, sessionID, edits); err != nil {
+		return ToolResponse{}, err
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Applied %q. %d files changed, %d additions, %d removals:\n", action.Title, len(edits), additions, removals)
+	for _, e := range edits {
+		sb.WriteString("- " + displayPath(e.path) + "\n")
+	}
+
+	diagnosticsText := ""
+	for _, e := range edits {
+		waitForLspDiagnostics(ctx, e.path, lspClients)
+		diagnosticsText += getDiagnostics(e.path, lspClients)
+	}
+	if diagnosticsText != "" {
+		sb.WriteString("\nDiagnostics:\n" + diagnosticsText)
+	}
+
+	return WithResponseMetadata(
+		NewTextResponse(sb.String()),
+		EditResponseMetadata{
+			Diff:      actionDiff,
+			Additions: additions,
+			Removals:  removals,
+		},
+	), nil
+}
+
+// codeActionRange converts 1-based lines into a range covering them, or the
+// whole file when no line is given
+func codeActionRange(lines []string, line, endLine int) (protocol.Range, string) {
+	if line <= 0 {
+		last := len(lines) - 1
+		return protocol.Range{
+			End: protocol.Position{Line: uint32(last), Character: uint32(utf16Len(lines[last]))},
+		}, ""
+	}
+	if endLine < line {
+		endLine = line
+	}
+	if line > len(lines) {
+		return protocol.Range{}, fmt.Sprintf("line %d is out of range, the file has %d lines", line, len(lines))
+	}
+	endLine = min(endLine, len(lines))
+	return protocol.Range{
+		Start: protocol.Position{Line: uint32(line - 1)},
+		End:   protocol.Position{Line: uint32(endLine - 1), Character: uint32(utf16Len(lines[endLine-1]))},
+	}, ""
+}
+
+func formatCodeActions(path string, rng protocol.Range, actions []codeActionItem) string {
+	location := displayPath(path)
+	if rng.Start.Line != 0 || rng.End.Line != 0 {
+		location += fmt.Sprintf(" %s", lineRange(rng))
+	}
+	if len(actions) == 0 {
+		return fmt.Sprintf("No code actions available for %s", location)
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Code actions for %s:\n", location)
+	for i, item := range actions {
+		action := item.action
+		fmt.Fprintf(&sb, "%d. ", i+1)
+		if action.Kind != "" {
+			fmt.Fprintf(&sb, "[%s] ", action.Kind)
+		}
+		sb.WriteString(action.Title)
+
+		var notes []string
+		if action.IsPreferred {
+			notes = append(notes, "preferred")
+		}
+		if action.Disabled != nil {
+			notes = append(notes, "disabled: "+action.Disabled.Reason)
+		} else if action.Edit == nil && action.Data == nil && action.Command != nil {
+			notes = append(notes, "runs a server command, cannot be applied")
+		}
+		if len(notes) > 0 {
+			fmt.Fprintf(&sb, " (%s)", strings.Join(notes, ", "))
+		}
+		sb.WriteString("\n")
+
+		for _, d := range action.Diagnostics {
+			fmt.Fprintf(&sb, "   fixes line %d: %s\n", d.Range.Start.Line+1, strings.Join(strings.Fields(d.Message), " "))
+		}
+	}
+	sb.WriteString("\nCall this tool again with the same parameters and apply set to the number of an action to apply it.\n")
+	return sb.String()
+}
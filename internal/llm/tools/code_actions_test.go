@@ -0,0 +1,60 @@
+package tools
+
+import (
+	"testing"
+
+	"github.com/omnitrix-sh/cli/internal/lsp/protocol"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCodeActionRange(t *testing.T) {
+	lines := []string{"package main", "", "func main() {", "\tprintln(\"héllo\")", "}"}
+
+	tests := []struct {
+		name    string
+		line    int
+		endLine int
+		want    protocol.Range
+		wantMsg string
+	}{
+		{
+			name: "whole file",
+			want: protocol.Range{End: protocol.Position{Line: 4, Character: 1}},
+		},
+		{
+			name: "single line",
+			line: 4,
+			want: protocol.Range{Start: protocol.Position{Line: 3}, End: protocol.Position{Line: 3, Character: 17}},
+		},
+		{
+			name:    "line range",
+			line:    3,
+			endLine: 5,
+			want:    protocol.Range{Start: protocol.Position{Line: 2}, End: protocol.Position{Line: 4, Character: 1}},
+		},
+		{
+			name:    "end line before line",
+			line:    3,
+			endLine: 1,
+			want:    protocol.Range{Start: protocol.Position{Line: 2}, End: protocol.Position{Line: 2, Character: 13}},
+		},
+		{
+			name:    "end line past the end of the file",
+			line:    3,
+			endLine: 20,
+			want:    protocol.Range{Start: protocol.Position{Line: 2}, End: protocol.Position{Line: 4, Character: 1}},
+		},
+		{
+			name:    "line out of range",
+			line:    6,
+			wantMsg: "line 6 is out of range, the file has 5 lines",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, msg := codeActionRange(lines, tt.line, tt.endLine)
+			assert.Equal(t, tt.wantMsg, msg)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
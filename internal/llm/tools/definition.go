@@ -0,0 +1,139 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/omnitrix-sh/cli/internal/logging"
+	"github.com/omnitrix-sh/cli/internal/lsp"
+	"github.com/omnitrix-sh/cli/internal/lsp/protocol"
+)
+
+type DefinitionParams struct {
+	SymbolPositionParams
+	Kind string `json:"kind"`
+}
+
+type definitionTool struct {
+	lspClients *lsp.Clients
+}
+
+const (
+	DefinitionToolName = "definition"
+
+	definitionKindDefinition     = "definition"
+	definitionKindTypeDefinition = "type_definition"
+	definitionKindImplementation = "implementation"
+
+	definitionContextLines = 3
+
+	definitionDescription = `Find where a symbol is defined using the language server.
+WHEN TO USE THIS TOOL:
+- Use when you need to jump to the definition of a function, type, variable or method
+- Prefer it over grep when the name is common or overloaded, as the language server resolves the exact symbol
+HOW TO USE:
+- Provide the file that uses the symbol
+- Give the symbol name, a line and column, or both a symbol name and the line it appears on
+- Set kind to "type_definition" to find the type of a variable, or "implementation" to find the implementations of an interface or abstract method
+FEATURES:
+- Returns every location with a snippet of the surrounding code and line numbers
+- Works across files and dependencies the language server knows about
+LIMITATIONS:
+- Requires a language server configured for the file's language
+- Without a line, the first occurrence of the symbol in the file is used
+TIPS:
+- Use the references tool to find where a symbol is used
+- Use the hover tool to read a symbol's signature and documentation
+`
+)
+
+func NewDefinitionTool(lspClients *lsp.Clients) BaseTool {
+	return &definitionTool{
+		lspClients,
+	}
+}
+
+func (d *definitionTool) Info() ToolInfo {
+	parameters := symbolPositionSchema()
+	parameters["kind"] = map[string]any{
+		"type":        "string",
+		"description": "What to look up: definition (default), type_definition or implementation",
+		"enum":        []string{definitionKindDefinition, definitionKindTypeDefinition, definitionKindImplementation},
+	}
+	return ToolInfo{
+		Name:        DefinitionToolName,
+		Description: definitionDescription,
+		Parameters:  parameters,
+		Required:    []string{"file_path"},
+	}
+}
+
+func (d *definitionTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error) {
+	lspClients := d.lspClients.Snapshot()
+	var params DefinitionParams
+	if err := json.Unmarshal([]byte(call.Input), &params); err != nil {
+		return NewTextErrorResponse(fmt.Sprintf("error parsing parameters: %s", err)), nil
+	}
+	if params.Kind == "" {
+		params.Kind = definitionKindDefinition
+	}
+	if params.Kind != definitionKindDefinition && params.Kind != definitionKindTypeDefinition && params.Kind != definitionKindImplementation {
+		return NewTextErrorResponse(fmt.Sprintf("unknown kind %q", params.Kind)), nil
+	}
+
+	if len(lspClients) == 0 {
+		return NewTextErrorResponse("no LSP clients available"), nil
+	}
+
+	pos, msg, err := resolveSymbolPosition(ctx, params.SymbolPositionParams, lspClients)
+	if err != nil {
+		return ToolResponse{}, err
+	}
+	if msg != "" {
+		return NewTextErrorResponse(msg), nil
+	}
+
+	var locations []protocol.Location
+	for _, name := range clientsForFile(lspClients, pos.path) {
+		locations, err = d.lookup(ctx, lspClients[name], params.Kind, pos)
+		if err != nil {
+			logging.Debug("LSP definition lookup failed", "lsp", name, "kind", params.Kind, "error", err)
+			continue
+		}
+		if len(locations) > 0 {
+			break
+		}
+	}
+
+	if len(locations) == 0 {
+		return NewTextResponse(fmt.Sprintf("No %s found for the symbol at %s", kindLabel(params.Kind), pos)), nil
+	}
+
+	output := fmt.Sprintf("Found %d %s location(s) for the symbol at %s:\n\n", len(locations), kindLabel(params.Kind), pos)
+	output += formatLocations(locations, definitionContextLines)
+	return NewTextResponse(output), nil
+}
+
+func (d *definitionTool) lookup(ctx context.Context, client *lsp.Client, kind string, pos lspPosition) ([]protocol.Location, error) {
+	switch kind {
+	case definitionKindTypeDefinition:
+		result, err := client.TypeDefinition(ctx, protocol.TypeDefinitionParams{TextDocumentPositionParams: pos.textDocumentPosition()})
+		return locationsFromDefinition(result.Value), err
+	case definitionKindImplementation:
+		result, err := client.Implementation(ctx, protocol.ImplementationParams{TextDocumentPositionParams: pos.textDocumentPosition()})
+		return locationsFromDefinition(result.Value), err
+	default:
+		result, err := client.Definition(ctx, protocol.DefinitionParams{TextDocumentPositionParams: pos.textDocumentPosition()})
+		return locationsFromDefinition(result.Value), err
+	}
+}
+
+func kindLabel(kind string) string {
+	switch kind {
+	case definitionKindTypeDefinition:
+		return "type definition"
+	default:
+		return kind
+	}
+}
@@ -17,7 +17,7 @@ type DiagnosticsParams struct {
 	FilePath string `json:"file_path"`
 }
 type diagnosticsTool struct {
-	lspClients map[string]*lsp.Client
+	lspClients *lsp.Clients
 }
 
 const (
@@ -45,7 +45,7 @@ TIPS:
 `
 )
 
-func NewDiagnosticsTool(lspClients map[string]*lsp.Client) BaseTool {
+func NewDiagnosticsTool(lspClients *lsp.Clients) BaseTool {
 	return &diagnosticsTool{
 		lspClients,
 	}
@@ -71,7 +71,7 @@ func (b *diagnosticsTool) Run(ctx context.Context, call ToolCall) (ToolResponse,
 		return NewTextErrorResponse(fmt.Sprintf("error parsing parameters: %s", err)), nil
 	}
 
-	lsps := b.lspClients
+	lsps := b.lspClients.Snapshot()
 
 	if len(lsps) == 0 {
 		return NewTextErrorResponse("no LSP clients available"), nil
@@ -89,6 +89,9 @@ func (b *diagnosticsTool) Run(ctx context.Context, call ToolCall) (ToolResponse,
 
 func notifyLspOpenFile(ctx context.Context, filePath string, lsps map[string]*lsp.Client) {
 	for _, client := range lsps {
+		if !client.HandlesFile(filePath) {
+			continue
+		}
 		err := client.OpenFile(ctx, filePath)
 		if err != nil {
 			continue
@@ -97,13 +100,15 @@ func notifyLspOpenFile(ctx context.Context, filePath string, lsps map[string]*ls
 }
 
 func waitForLspDiagnostics(ctx context.Context, filePath string, lsps map[string]*lsp.Client) {
-	if len(lsps) == 0 {
+	names := clientsForFile(lsps, filePath)
+	if len(names) == 0 {
 		return
 	}
 
 	diagChan := make(chan struct{}, 1)
 
-	for _, client := range lsps {
+	for _, name := range names {
+		client := lsps[name]
 		originalDiags := make(map[protocol.DocumentUri][]protocol.Diagnostic)
 		maps.Copy(originalDiags, client.GetDiagnostics())
 
@@ -18,9 +18,18 @@ import (
 )
 
 type EditParams struct {
-	FilePath  string `json:"file_path"`
-	OldString string `json:"old_string"`
-	NewString string `json:"new_string"`
+	FilePath   string          `json:"file_path"`
+	OldString  string          `json:"old_string"`
+	NewString  string          `json:"new_string"`
+	ReplaceAll bool            `json:"replace_all,omitempty"`
+	Edits      []EditOperation `json:"edits,omitempty"`
+}
+
+// EditOperation is a single replacement inside a multi-edit call.
+type EditOperation struct {
+	OldString  string `json:"old_string"`
+	NewString  string `json:"new_string"`
+	ReplaceAll bool   `json:"replace_all,omitempty"`
 }
 
 type EditPermissionsParams struct {
@@ -35,7 +44,7 @@ type EditResponseMetadata struct {
 }
 
 type editTool struct {
-	lspClients  map[string]*lsp.Client
+	lspClients  *lsp.Clients
 	permissions permission.Service
 	files       history.Service
 }
@@ -53,14 +62,19 @@ Before using this tool:
 
 To make a file edit, provide the following:
 1. file_path: The absolute path to the file to modify (must be absolute, not relative)
-2. old_string: The text to replace (must be unique within the file, and must match the file contents exactly, including all whitespace and indentation)
+2. old_string: The text to replace (must be unique within the file, and should match the file contents exactly, including all whitespace and indentation)
 3. new_string: The edited text to replace the old_string
 
 Special cases:
 - To create a new file: provide file_path and new_string, leave old_string empty
 - To delete content: provide file_path and old_string, leave new_string empty
 
-The tool will replace ONE occurrence of old_string with new_string in the specified file.
+The tool will replace ONE occurrence of old_string with new_string in the specified file, unless replace_all is set, in which case every exact occurrence is replaced.
+
+MULTIPLE EDITS: To make several changes to the same file in one call, provide file_path and an "edits" list instead of old_string/new_string. Each entry has its own old_string, new_string and optional replace_all.
+   - Edits are applied in order, each one to the result of the previous edits
+   - The call is atomic: if any edit fails to match, no changes are written
+   - Prefer this over repeated calls when refactoring several places in one file
 
 CRITICAL REQUIREMENTS FOR USING THIS TOOL:
 
@@ -69,29 +83,34 @@ CRITICAL REQUIREMENTS FOR USING THIS TOOL:
    - Include AT LEAST 3-5 lines of context AFTER the change point
    - Include all whitespace, indentation, and surrounding code exactly as it appears in the file
 
-2. SINGLE INSTANCE: This tool can only change ONE instance at a time. If you need to change multiple instances:
-   - Make separate calls to this tool for each instance
-   - Each call must uniquely identify its specific instance using extensive context
+2. SINGLE INSTANCE: Without replace_all, each old_string changes ONE instance. If you need to change multiple instances:
+   - Set replace_all to change every occurrence of the same text, or
+   - Use the edits list with one entry per instance, each uniquely identifying its specific instance
 
 3. VERIFICATION: Before using this tool:
    - Check how many instances of the target text exist in the file
    - If multiple instances exist, gather enough context to uniquely identify each one
-   - Plan separate tool calls for each instance
+   - Plan one edit per instance
 
 WARNING: If you do not follow these requirements:
    - The tool will fail if old_string matches multiple locations
-   - The tool will fail if old_string doesn't match exactly (including whitespace)
+   - The tool will fail if old_string can't be found in the file
    - You may change the wrong instance if you don't include enough context
 
+MATCHING: When old_string doesn't match exactly, the tool falls back to a match that ignores
+line ending (CRLF/LF) differences, trailing whitespace and indentation. When the fallback is used,
+new_string is re-indented and converted to the file's line endings to fit the matched text.
+If no match is found, the closest candidate regions are returned with their line numbers.
+
 When making edits:
    - Ensure the edit results in idiomatic, correct code
    - Do not leave the code in a broken state
    - Always use absolute file paths (starting with /)
 
-Remember: when making multiple file edits in a row to the same file, you should prefer to send all edits in a single message with multiple calls to this tool, rather than multiple messages with a single call each.`
+Remember: when making multiple file edits in a row to the same file, you should prefer a single call with an edits list, rather than multiple calls with a single edit each.`
 )
 
-func NewEditTool(lspClients map[string]*lsp.Client, permissions permission.Service, files history.Service) BaseTool {
+func NewEditTool(lspClients *lsp.Clients, permissions permission.Service, files history.Service) BaseTool {
 	return &editTool{
 		lspClients:  lspClients,
 		permissions: permissions,
@@ -116,12 +135,39 @@ func (e *editTool) Info() ToolInfo {
 				"type":        "string",
 				"description": "The text to replace it with",
 			},
+			"replace_all": map[string]any{
+				"type":        "boolean",
+				"description": "Replace every occurrence of old_string instead of requiring a unique match (default false)",
+			},
+			"edits": map[string]any{
+				"type":        "array",
+				"description": "Ordered list of replacements to apply atomically to the file. When set, old_string and new_string are ignored",
+				"items": map[string]any{
+					"type": "object",
+					"properties": map[string]any{
+						"old_string": map[string]any{
+							"type":        "string",
+							"description": "The text to replace",
+						},
+						"new_string": map[string]any{
+							"type":        "string",
+							"description": "The text to replace it with",
+						},
+						"replace_all": map[string]any{
+							"type":        "boolean",
+							"description": "Replace every occurrence of old_string (default false)",
+						},
+					},
+					"required": []string{"old_string", "new_string"},
+				},
+			},
 		},
-		Required: []string{"file_path", "old_string", "new_string"},
+		Required: []string{"file_path"},
 	}
 }
 
 func (e *editTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error) {
+	lspClients := e.lspClients.Snapshot()
 	var params EditParams
 	if err := json.Unmarshal([]byte(call.Input), &params); err != nil {
 		return NewTextErrorResponse("invalid parameters"), nil
@@ -139,38 +185,36 @@ func (e *editTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error)
 	var response ToolResponse
 	var err error
 
-	if params.OldString == "" {
+	switch {
+	case len(params.Edits) > 0:
+		response, err = e.applyEdits(ctx, params.FilePath, params.Edits)
+	case params.OldString == "":
 		response, err = e.createNewFile(ctx, params.FilePath, params.NewString)
-		if err != nil {
-			return response, err
-		}
-	}
-
-	if params.NewString == "" {
-		response, err = e.deleteContent(ctx, params.FilePath, params.OldString)
-		if err != nil {
-			return response, err
-		}
+	default:
+		response, err = e.applyEdits(ctx, params.FilePath, []EditOperation{{
+			OldString:  params.OldString,
+			NewString:  params.NewString,
+			ReplaceAll: params.ReplaceAll,
+		}})
 	}
-
-	response, err = e.replaceContent(ctx, params.FilePath, params.OldString, params.NewString)
 	if err != nil {
 		return response, err
 	}
 	if response.IsError {
-		// Return early if there was an error during content replacement
+		// Return early if there was an error during the edit
 		// This prevents unnecessary LSP diagnostics processing
 		return response, nil
 	}
 
-	waitForLspDiagnostics(ctx, params.FilePath, e.lspClients)
+	waitForLspDiagnostics(ctx, params.FilePath, lspClients)
 	text := fmt.Sprintf("<result>\n%s\n</result>\n", response.Content)
-	text += getDiagnostics(params.FilePath, e.lspClients)
+	text += getDiagnostics(params.FilePath, lspClients)
 	response.Content = text
 	return response, nil
 }
 
 func (e *editTool) createNewFile(ctx context.Context, filePath, content string) (ToolResponse, error) {
+	lspClients := e.lspClients.Snapshot()
 	fileInfo, err := os.Stat(filePath)
 	if err == nil {
 		if fileInfo.IsDir() {
@@ -191,6 +235,13 @@ func (e *editTool) createNewFile(ctx context.Context, filePath, content string)
 		return ToolResponse{}, fmt.Errorf("session ID and message ID are required for creating a new file")
 	}
 
+	formatted := formatContent(ctx, filePath, content, lspClients)
+	result := "File created: " + filePath
+	if formatted != content {
+		result += "\n" + formattedNote
+		content = formatted
+	}
+
 	diff, additions, removals := diff.GenerateDiff(
 		"",
 		content,
@@ -241,7 +292,7 @@ func (e *editTool) createNewFile(ctx context.Context, filePath, content string)
 	recordFileRead(filePath)
 
 	return WithResponseMetadata(
-		NewTextResponse("File created: "+filePath),
+		NewTextResponse(result),
 		EditResponseMetadata{
 			Diff:      diff,
 			Additions: additions,
@@ -250,7 +301,8 @@ func (e *editTool) createNewFile(ctx context.Context, filePath, content string)
 	), nil
 }
 
-func (e *editTool) deleteContent(ctx context.Context, filePath, oldString string) (ToolResponse, error) {
+func (e *editTool) applyEdits(ctx context.Context, filePath string, edits []EditOperation) (ToolResponse, error) {
+	lspClients := e.lspClients.Snapshot()
 	fileInfo, err := os.Stat(filePath)
 	if err != nil {
 		if os.IsNotExist(err) {
@@ -276,6 +328,10 @@ func (e *editTool) deleteContent(ctx context.Context, filePath, oldString string
 			)), nil
 	}
 
+	if msg := externalChangeMessage(filePath); msg != "" {
+		return NewTextErrorResponse(msg), nil
+	}
+
 	content, err := os.ReadFile(filePath)
 	if err != nil {
 		return ToolResponse{}, fmt.Errorf("failed to read file: %w", err)
@@ -283,23 +339,54 @@ func (e *editTool) deleteContent(ctx context.Context, filePath, oldString string
 
 	oldContent := string(content)
 
-	index := strings.Index(oldContent, oldString)
-	if index == -1 {
-		return NewTextErrorResponse("old_string not found in file. Make sure it matches exactly, including whitespace and line breaks"), nil
-	}
+	// Edits are applied in order to an in-memory copy, so nothing is written
+	// unless every one of them succeeds.
+	newContent := oldContent
+	fuzzyLines := []int{}
+	for i, edit := range edits {
+		if edit.OldString == "" {
+			return NewTextErrorResponse(editError(edits, i, "old_string is required")), nil
+		}
+		if edit.OldString == edit.NewString {
+			return NewTextErrorResponse(editError(edits, i, "old_string and new_string are identical")), nil
+		}
 
-	lastIndex := strings.LastIndex(oldContent, oldString)
-	if index != lastIndex {
-		return NewTextErrorResponse("old_string appears multiple times in the file. Please provide more context to ensure a unique match"), nil
-	}
+		// Whitespace tolerant matching only finds a single occurrence
+		if edit.ReplaceAll {
+			if !strings.Contains(newContent, edit.OldString) {
+				return NewTextErrorResponse(editError(edits, i, "old_string not found in file. replace_all requires an exact match, including whitespace")), nil
+			}
+			newContent = strings.ReplaceAll(newContent, edit.OldString, edit.NewString)
+			continue
+		}
 
-	newContent := oldContent[:index] + oldContent[index+len(oldString):]
+		match, err := diff.FindMatch(newContent, edit.OldString)
+		if err != nil {
+			response := matchErrorResponse(err)
+			response.Content = editError(edits, i, response.Content)
+			return response, nil
+		}
+		if match.Fuzz > 0 {
+			fuzzyLines = append(fuzzyLines, match.StartLine(newContent))
+		}
+		newContent = match.Replace(newContent, edit.NewString)
+	}
 
+	if oldContent == newContent {
+		return NewTextErrorResponse("new content is the same as old content. No changes made."), nil
+	}
 	sessionID, messageID := GetContextValues(ctx)
 
 	if sessionID == "" || messageID == "" {
-		return ToolResponse{}, fmt.Errorf("session ID and message ID are required for creating a new file")
+		return ToolResponse{}, fmt.Errorf("session ID and message ID are required for editing a file")
+	}
+
+	formatted := formatContent(ctx, filePath, newContent, lspClients)
+	if formatted == oldContent {
+		return NewTextErrorResponse("the edit only changes formatting, which the configured formatter reverts. No changes made."), nil
 	}
+	wasFormatted := formatted != newContent
+	newContent = formatted
 
 	diff, additions, removals := diff.GenerateDiff(
 		oldContent,
@@ -307,6 +394,17 @@ func (e *editTool) deleteContent(ctx context.Context, filePath, oldString string
 		filePath,
 	)
 
+	description := fmt.Sprintf("Replace content in file %s", filePath)
+	result := "Content replaced in file: " + filePath
+	switch {
+	case len(edits) > 1:
+		description = fmt.Sprintf("Apply %d edits to file %s", len(edits), filePath)
+		result = fmt.Sprintf("Applied %d edits to file: %s", len(edits), filePath)
+	case edits[0].NewString == "":
+		description = fmt.Sprintf("Delete content from file %s", filePath)
+		result = "Content deleted from file: " + filePath
+	}
+
 	rootDir := config.WorkingDirectory()
 	permissionPath := filepath.Dir(filePath)
 	if strings.HasPrefix(filePath, rootDir) {
@@ -318,7 +416,7 @@ func (e *editTool) deleteContent(ctx context.Context, filePath, oldString string
 			Path:        permissionPath,
 			ToolName:    EditToolName,
 			Action:      "write",
-			Description: fmt.Sprintf("Delete content from file %s", filePath),
+			Description: description,
 			Params: EditPermissionsParams{
 				FilePath: filePath,
 				Diff:     diff,
@@ -351,7 +449,7 @@ func (e *editTool) deleteContent(ctx context.Context, filePath, oldString string
 		}
 	}
 	// Store the new version
-	_, err = e.files.CreateVersion(ctx, sessionID, filePath, "")
+	_, err = e.files.CreateVersion(ctx, sessionID, filePath, newContent)
 	if err != nil {
 		logging.Debug("Error creating file history version", "error", err)
 	}
@@ -359,131 +457,55 @@ func (e *editTool) deleteContent(ctx context.Context, filePath, oldString string
 	recordFileWrite(filePath)
 	recordFileRead(filePath)
 
+	for _, line := range fuzzyLines {
+		result += fmt.Sprintf("\n(old_string matched at line %d after ignoring whitespace or line ending differences)", line)
+	}
+	if wasFormatted {
+		result += "\n" + formattedNote
+	}
 	return WithResponseMetadata(
-		NewTextResponse("Content deleted from file: "+filePath),
+		NewTextResponse(result),
 		EditResponseMetadata{
 			Diff:      diff,
 			Additions: additions,
 			Removals:  removals,
-		},
-	), nil
+		}), nil
 }
 
-func (e *editTool) replaceContent(ctx context.Context, filePath, oldString, newString string) (ToolResponse, error) {
-	fileInfo, err := os.Stat(filePath)
-	if err != nil {
-		if os.IsNotExist(err) {
-			return NewTextErrorResponse(fmt.Sprintf("file not found: %s", filePath)), nil
-		}
-		return ToolResponse{}, fmt.Errorf("failed to access file: %w", err)
-	}
-
-	if fileInfo.IsDir() {
-		return NewTextErrorResponse(fmt.Sprintf("path is a directory, not a file: %s", filePath)), nil
-	}
-
-	if getLastReadTime(filePath).IsZero() {
-		return NewTextErrorResponse("you must read the file before editing it. Use the View tool first"), nil
-	}
-
-	modTime := fileInfo.ModTime()
-	lastRead := getLastReadTime(filePath)
-	if modTime.After(lastRead) {
-		return NewTextErrorResponse(
-			fmt.Sprintf("file %s has been modified since it was last read (mod time: %s, last read: %s)",
-				filePath, modTime.Format(time.RFC3339), lastRead.Format(time.RFC3339),
-			)), nil
-	}
-
-	content, err := os.ReadFile(filePath)
-	if err != nil {
-		return ToolResponse{}, fmt.Errorf("failed to read file: %w", err)
-	}
-
-	oldContent := string(content)
-
-	index := strings.Index(oldContent, oldString)
-	if index == -1 {
-		return NewTextErrorResponse("old_string not found in file. Make sure it matches exactly, including whitespace and line breaks"), nil
-	}
-
-	lastIndex := strings.LastIndex(oldContent, oldString)
-	if index != lastIndex {
-		return NewTextErrorResponse("old_string appears multiple times in the file. Please provide more context to ensure a unique match"), nil
-	}
-
-	newContent := oldContent[:index] + newString + oldContent[index+len(oldString):]
-
-	if oldContent == newContent {
-		return NewTextErrorResponse("new content is the same as old content. No changes made."), nil
+// editError prefixes an error with the position of the failing edit when
+// several edits were requested, so the model knows which one to fix.
+func editError(edits []EditOperation, index int, msg string) string {
+	if len(edits) == 1 {
+		return msg
 	}
-	sessionID, messageID := GetContextValues(ctx)
+	return fmt.Sprintf("edit %d of %d failed, no changes were made: %s", index+1, len(edits), msg)
+}
 
-	if sessionID == "" || messageID == "" {
-		return ToolResponse{}, fmt.Errorf("session ID and message ID are required for creating a new file")
-	}
-	diff, additions, removals := diff.GenerateDiff(
-		oldContent,
-		newContent,
-		filePath,
-	)
-	rootDir := config.WorkingDirectory()
-	permissionPath := filepath.Dir(filePath)
-	if strings.HasPrefix(filePath, rootDir) {
-		permissionPath = rootDir
-	}
-	p := e.permissions.Request(
-		permission.CreatePermissionRequest{
-			SessionID:   sessionID,
-			Path:        permissionPath,
-			ToolName:    EditToolName,
-			Action:      "write",
-			Description: fmt.Sprintf("Replace content in file %s", filePath),
-			Params: EditPermissionsParams{
-				FilePath: filePath,
-				Diff:     diff,
-			},
-		},
-	)
-	if !p {
-		return ToolResponse{}, permission.ErrorPermissionDenied
+// matchErrorResponse turns a failed diff.FindMatch into a tool error that
+// points the model at the regions of the file closest to what it asked for.
+func matchErrorResponse(err error) ToolResponse {
+	matchErr, ok := err.(*diff.MatchError)
+	if !ok {
+		return NewTextErrorResponse(fmt.Sprintf("failed to match old_string: %s", err))
 	}
-
-	err = os.WriteFile(filePath, []byte(newContent), 0o644)
-	if err != nil {
-		return ToolResponse{}, fmt.Errorf("failed to write file: %w", err)
+	if matchErr.Occurrences > 1 {
+		return NewTextErrorResponse(fmt.Sprintf("old_string appears %d times in the file. Please provide more context to ensure a unique match", matchErr.Occurrences))
 	}
 
-	// Check if file exists in history
-	file, err := e.files.GetByPathAndSession(ctx, filePath, sessionID)
-	if err != nil {
-		_, err = e.files.Create(ctx, sessionID, filePath, oldContent)
-		if err != nil {
-			// Log error but don't fail the operation
-			return ToolResponse{}, fmt.Errorf("error creating file history: %w", err)
-		}
+	var sb strings.Builder
+	sb.WriteString("old_string not found in file, even when ignoring whitespace, indentation and line ending differences.")
+	if len(matchErr.Candidates) == 0 {
+		return NewTextErrorResponse(sb.String())
 	}
-	if file.Content != oldContent {
-		// User Manually changed the content store an intermediate version
-		_, err = e.files.CreateVersion(ctx, sessionID, filePath, oldContent)
-		if err != nil {
-			logging.Debug("Error creating file history version", "error", err)
+	sb.WriteString(" The closest matches are:\n")
+	for _, c := range matchErr.Candidates {
+		fmt.Fprintf(&sb, "\n<candidate lines=\"%d-%d\" similarity=\"%.0f%%\">\n", c.StartLine, c.EndLine, c.Similarity*100)
+		lines := strings.Split(c.Text, "\n")
+		for i, line := range lines {
+			fmt.Fprintf(&sb, "%6d|%s\n", c.StartLine+i, line)
 		}
+		sb.WriteString("</candidate>\n")
 	}
-	// Store the new version
-	_, err = e.files.CreateVersion(ctx, sessionID, filePath, newContent)
-	if err != nil {
-		logging.Debug("Error creating file history version", "error", err)
-	}
-
-	recordFileWrite(filePath)
-	recordFileRead(filePath)
-
-	return WithResponseMetadata(
-		NewTextResponse("Content replaced in file: "+filePath),
-		EditResponseMetadata{
-			Diff:      diff,
-			Additions: additions,
-			Removals:  removals,
-		}), nil
+	sb.WriteString("\nRead the file again if needed and retry with old_string copied from the file.")
+	return NewTextErrorResponse(sb.String())
 }
@@ -0,0 +1,113 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/omnitrix-sh/cli/internal/history"
+	"github.com/omnitrix-sh/cli/internal/lsp"
+	"github.com/omnitrix-sh/cli/internal/permission"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type allowPermissions struct {
+	permission.Service
+}
+
+func (allowPermissions) Request(opts permission.CreatePermissionRequest) bool {
+	return true
+}
+
+type memoryHistory struct {
+	history.Service
+}
+
+func (memoryHistory) Create(ctx context.Context, sessionID, path, content string) (history.File, error) {
+	return history.File{SessionID: sessionID, Path: path, Content: content}, nil
+}
+
+func (memoryHistory) CreateVersion(ctx context.Context, sessionID, path, content string) (history.File, error) {
+	return history.File{SessionID: sessionID, Path: path, Content: content}, nil
+}
+
+func (memoryHistory) GetByPathAndSession(ctx context.Context, path, sessionID string) (history.File, error) {
+	return history.File{}, os.ErrNotExist
+}
+
+func TestEditTool_Run(t *testing.T) {
+	dir := t.TempDir()
+	loadTestConfig(t, dir)
+
+	tool := NewEditTool(lsp.NewClients(), allowPermissions{}, memoryHistory{})
+	ctx := context.WithValue(context.Background(), SessionIDContextKey, "session")
+	ctx = context.WithValue(ctx, MessageIDContextKey, "message")
+
+	const content = "func a() {\n\treturn 1\n}\n\nfunc b() {\n\treturn 1\n}\n"
+	tests := []struct {
+		name    string
+		edits   []EditOperation
+		want    string
+		wantErr string
+	}{
+		{
+			name: "applies edits in order",
+			edits: []EditOperation{
+				{OldString: "func a()", NewString: "func c()"},
+				{OldString: "func c() {\n\treturn 1", NewString: "func c() {\n\treturn 2"},
+			},
+			want: "func c() {\n\treturn 2\n}\n\nfunc b() {\n\treturn 1\n}\n",
+		},
+		{
+			name: "leaves the file unchanged when an edit fails",
+			edits: []EditOperation{
+				{OldString: "func a()", NewString: "func c()"},
+				{OldString: "func missing()", NewString: "func d()"},
+			},
+			want:    content,
+			wantErr: "edit 2 of 2 failed, no changes were made",
+		},
+		{
+			name:    "requires a unique match",
+			edits:   []EditOperation{{OldString: "return 1", NewString: "return 2"}},
+			want:    content,
+			wantErr: "appears 2 times",
+		},
+		{
+			name:  "replaces every occurrence",
+			edits: []EditOperation{{OldString: "return 1", NewString: "return 2", ReplaceAll: true}},
+			want:  "func a() {\n\treturn 2\n}\n\nfunc b() {\n\treturn 2\n}\n",
+		},
+		{
+			name:    "replaces every occurrence only on an exact match",
+			edits:   []EditOperation{{OldString: "return   1", NewString: "return 2", ReplaceAll: true}},
+			want:    content,
+			wantErr: "replace_all requires an exact match",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, "main.go")
+			require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+			recordFileRead(path)
+
+			input, err := json.Marshal(EditParams{FilePath: path, Edits: tt.edits})
+			require.NoError(t, err)
+			response, err := tool.Run(ctx, ToolCall{Name: EditToolName, Input: string(input)})
+			require.NoError(t, err)
+			if tt.wantErr != "" {
+				assert.True(t, response.IsError)
+				assert.Contains(t, response.Content, tt.wantErr)
+			} else {
+				assert.False(t, response.IsError, response.Content)
+			}
+
+			got, err := os.ReadFile(path)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, string(got))
+		})
+	}
+}
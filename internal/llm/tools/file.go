@@ -1,8 +1,16 @@
 package tools
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"sort"
 	"sync"
 	"time"
+
+	"github.com/omnitrix-sh/cli/internal/pubsub"
 )
 
 // File record to track when files were read/written
@@ -10,23 +18,45 @@ type fileRecord struct {
 	path      string
 	readTime  time.Time
 	writeTime time.Time
+	// hash of the content the agent last saw, used to tell external edits
+	// apart from the agent's own writes
+	hash string
+	// drifted is set when the file changed outside of the session after it
+	// was last read
+	drifted bool
+}
+
+// FileDrift is published when a file the agent has read is changed outside of
+// the session (CreatedEvent) and when the agent reads it again (DeletedEvent)
+type FileDrift struct {
+	Path string
 }
 
 var (
 	fileRecords     = make(map[string]fileRecord)
 	fileRecordMutex sync.RWMutex
+
+	driftBroker = pubsub.NewBroker[FileDrift]()
 )
 
 func recordFileRead(path string) {
-	fileRecordMutex.Lock()
-	defer fileRecordMutex.Unlock()
+	hash := hashFile(path)
 
+	fileRecordMutex.Lock()
 	record, exists := fileRecords[path]
 	if !exists {
 		record = fileRecord{path: path}
 	}
+	wasDrifted := record.drifted
 	record.readTime = time.Now()
+	record.hash = hash
+	record.drifted = false
 	fileRecords[path] = record
+	fileRecordMutex.Unlock()
+
+	if wasDrifted {
+		driftBroker.Publish(pubsub.DeletedEvent, FileDrift{Path: path})
+	}
 }
 
 func getLastReadTime(path string) time.Time {
@@ -51,3 +81,88 @@ func recordFileWrite(path string) {
 	record.writeTime = time.Now()
 	fileRecords[path] = record
 }
+
+// forgetFile drops the record of a path the agent moved away, so its removal
+// is not reported as an external change
+func forgetFile(path string) {
+	fileRecordMutex.Lock()
+	defer fileRecordMutex.Unlock()
+
+	delete(fileRecords, path)
+}
+
+// HandleExternalChange is called by the workspace change watcher whenever a
+// file on disk changes. Changes to files the agent has read are compared with
+// the content it last saw, so the agent's own writes are not reported.
+func HandleExternalChange(path string) {
+	fileRecordMutex.RLock()
+	record, exists := fileRecords[path]
+	fileRecordMutex.RUnlock()
+	if !exists || record.drifted || record.hash == "" {
+		return
+	}
+
+	if hashFile(path) == record.hash {
+		return
+	}
+
+	fileRecordMutex.Lock()
+	record, exists = fileRecords[path]
+	if !exists || record.drifted {
+		fileRecordMutex.Unlock()
+		return
+	}
+	record.drifted = true
+	fileRecords[path] = record
+	fileRecordMutex.Unlock()
+
+	driftBroker.Publish(pubsub.CreatedEvent, FileDrift{Path: path})
+}
+
+// DriftedFiles returns the files that changed outside of the session since the
+// agent last read them
+func DriftedFiles() []string {
+	fileRecordMutex.RLock()
+	defer fileRecordMutex.RUnlock()
+
+	var paths []string
+	for path, record := range fileRecords {
+		if record.drifted {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// SubscribeFileDrift subscribes to external change notifications
+func SubscribeFileDrift(ctx context.Context) <-chan pubsub.Event[FileDrift] {
+	return driftBroker.Subscribe(ctx)
+}
+
+// externalChangeMessage returns an explanation for the model when path was
+// changed outside of the session after it was last read, or an empty string
+// when it is safe to modify the file. The content hash is checked as well as
+// the watcher flag, in case the change has not been reported yet.
+func externalChangeMessage(path string) string {
+	fileRecordMutex.RLock()
+	record, exists := fileRecords[path]
+	fileRecordMutex.RUnlock()
+	if !exists || record.hash == "" {
+		return ""
+	}
+	if !record.drifted && hashFile(path) == record.hash {
+		return ""
+	}
+	return fmt.Sprintf("file %s was modified outside of this session after it was last read. "+
+		"Read it again with the View tool before changing it, so the external changes are not overwritten", path)
+}
+
+func hashFile(path string) string {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,87 @@
+package tools
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExternalChange(t *testing.T) {
+	dir := t.TempDir()
+
+	tests := []struct {
+		name string
+		// change is applied to the file after it was read
+		change      func(t *testing.T, path string)
+		wantDrifted bool
+	}{
+		{
+			name:   "unchanged file",
+			change: func(t *testing.T, path string) {},
+		},
+		{
+			name: "rewritten with the same content",
+			change: func(t *testing.T, path string) {
+				require.NoError(t, os.WriteFile(path, []byte("package main\n"), 0o644))
+			},
+		},
+		{
+			name: "edited outside of the session",
+			change: func(t *testing.T, path string) {
+				require.NoError(t, os.WriteFile(path, []byte("package other\n"), 0o644))
+			},
+			wantDrifted: true,
+		},
+		{
+			name: "deleted outside of the session",
+			change: func(t *testing.T, path string) {
+				require.NoError(t, os.Remove(path))
+			},
+			wantDrifted: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, tt.name+".go")
+			require.NoError(t, os.WriteFile(path, []byte("package main\n"), 0o644))
+			recordFileRead(path)
+			t.Cleanup(func() { forgetFile(path) })
+
+			tt.change(t, path)
+			// The hash is checked before the watcher reports the change
+			assert.Equal(t, tt.wantDrifted, externalChangeMessage(path) != "")
+
+			HandleExternalChange(path)
+			assert.Equal(t, tt.wantDrifted, externalChangeMessage(path) != "")
+			assert.Equal(t, tt.wantDrifted, slices.Contains(DriftedFiles(), path))
+		})
+	}
+
+	t.Run("read again after an external edit", func(t *testing.T) {
+		path := filepath.Join(dir, "reread.go")
+		require.NoError(t, os.WriteFile(path, []byte("package main\n"), 0o644))
+		recordFileRead(path)
+		t.Cleanup(func() { forgetFile(path) })
+
+		require.NoError(t, os.WriteFile(path, []byte("package other\n"), 0o644))
+		HandleExternalChange(path)
+		require.Contains(t, DriftedFiles(), path)
+
+		recordFileRead(path)
+		assert.Empty(t, externalChangeMessage(path))
+		assert.NotContains(t, DriftedFiles(), path)
+	})
+
+	t.Run("unread file", func(t *testing.T) {
+		path := filepath.Join(dir, "unread.go")
+		require.NoError(t, os.WriteFile(path, []byte("package main\n"), 0o644))
+
+		HandleExternalChange(path)
+		assert.Empty(t, externalChangeMessage(path))
+		assert.NotContains(t, DriftedFiles(), path)
+	})
+}
@@ -0,0 +1,120 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/omnitrix-sh/cli/internal/config"
+	"github.com/omnitrix-sh/cli/internal/logging"
+	"github.com/omnitrix-sh/cli/internal/lsp"
+	"github.com/omnitrix-sh/cli/internal/lsp/protocol"
+)
+
+const (
+	formatTimeout = 10 * time.Second
+
+	formattedNote = "(the file was formatted after the change, view it again before editing the reformatted lines)"
+)
+
+// formatContent formats content about to be written to path when format on
+// write is enabled for its language, using the configured formatter command
+// or else the language server. Formatting is best effort: on failure the
+// content is returned unchanged.
+func formatContent(ctx context.Context, path, content string, lspClients map[string]*lsp.Client) string {
+	name, lspCfg, ok := formatOnWriteConfig(path)
+	if !ok {
+		return content
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, formatTimeout)
+	defer cancel()
+
+	var formatted string
+	var err error
+	if len(lspCfg.Formatter) > 0 {
+		formatted, err = runFormatter(ctx, lspCfg.Formatter, path, content)
+	} else {
+		client, ok := lspClients[name]
+		if !ok || !client.FormattingSupport() {
+			return content
+		}
+		formatted, err = client.FormatContent(ctx, path, content, formattingOptions(content))
+	}
+	if err != nil {
+		logging.Warn("Failed to format file, writing it unformatted", "file", path, "lsp", name, "error", err)
+		return content
+	}
+	return formatted
+}
+
+// formatOnWriteConfig returns the LSP configuration with format on write
+// enabled that handles path
+func formatOnWriteConfig(path string) (string, config.LSPConfig, bool) {
+	cfg := config.Get()
+	if cfg == nil {
+		return "", config.LSPConfig{}, false
+	}
+
+	names := make([]string, 0, len(cfg.LSP))
+	for name := range cfg.LSP {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		lspCfg := cfg.LSP[name]
+		if lspCfg.FormatOnWrite && lsp.HandlesFile(name, lspCfg, path) {
+			return name, lspCfg, true
+		}
+	}
+	return "", config.LSPConfig{}, false
+}
+
+// runFormatter pipes content through an external formatter command
+func runFormatter(ctx context.Context, command []string, path, content string) (string, error) {
+	args := make([]string, 0, len(command)-1)
+	for _, arg := range command[1:] {
+		args = append(args, strings.ReplaceAll(arg, "{file}", path))
+	}
+
+	cmd := exec.CommandContext(ctx, command[0], args...)
+	cmd.Dir = config.WorkingDirectory()
+	cmd.Stdin = strings.NewReader(content)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("%s: %w: %s", command[0], err, msg)
+		}
+		return "", fmt.Errorf("%s: %w", command[0], err)
+	}
+	if stdout.Len() == 0 && content != "" {
+		return "", errors.New(command[0] + " produced no output")
+	}
+	return stdout.String(), nil
+}
+
+// formattingOptions follows the indentation already used by the content,
+// servers that have their own style ignore it
+func formattingOptions(content string) protocol.FormattingOptions {
+	options := protocol.FormattingOptions{TabSize: 4}
+	for line := range strings.SplitSeq(content, "\n") {
+		switch {
+		case strings.HasPrefix(line, "\t"):
+			return options
+		case strings.HasPrefix(line, "  "):
+			options.InsertSpaces = true
+			options.TabSize = uint32(min(len(line)-len(strings.TrimLeft(line, " ")), 8))
+			return options
+		}
+	}
+	return options
+}
@@ -0,0 +1,108 @@
+package tools
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/omnitrix-sh/cli/internal/config"
+	"github.com/omnitrix-sh/cli/internal/lsp/protocol"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatContent(t *testing.T) {
+	dir := t.TempDir()
+	cfg := loadTestConfig(t, dir)
+
+	goFile := filepath.Join(dir, "main.go")
+	const content = "package main\n"
+	tests := []struct {
+		name   string
+		lspCfg config.LSPConfig
+		path   string
+		want   string
+	}{
+		{
+			name:   "pipes the content through the formatter",
+			lspCfg: config.LSPConfig{FormatOnWrite: true, Formatter: []string{"tr", "a-z", "A-Z"}},
+			path:   goFile,
+			want:   "PACKAGE MAIN\n",
+		},
+		{
+			name:   "replaces {file} in the formatter arguments",
+			lspCfg: config.LSPConfig{FormatOnWrite: true, Formatter: []string{"sh", "-c", `echo "$0"`, "{file}"}},
+			path:   goFile,
+			want:   goFile + "\n",
+		},
+		{
+			name:   "format on write disabled",
+			lspCfg: config.LSPConfig{Formatter: []string{"tr", "a-z", "A-Z"}},
+			path:   goFile,
+			want:   content,
+		},
+		{
+			name:   "file of another language",
+			lspCfg: config.LSPConfig{FormatOnWrite: true, Formatter: []string{"tr", "a-z", "A-Z"}},
+			path:   filepath.Join(dir, "main.py"),
+			want:   content,
+		},
+		{
+			name:   "formatter fails",
+			lspCfg: config.LSPConfig{FormatOnWrite: true, Formatter: []string{"false"}},
+			path:   goFile,
+			want:   content,
+		},
+		{
+			name:   "formatter produces no output",
+			lspCfg: config.LSPConfig{FormatOnWrite: true, Formatter: []string{"true"}},
+			path:   goFile,
+			want:   content,
+		},
+		{
+			name:   "no formatter and no running server",
+			lspCfg: config.LSPConfig{FormatOnWrite: true, Command: "gopls"},
+			path:   goFile,
+			want:   content,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg.LSP = map[string]config.LSPConfig{"go": tt.lspCfg}
+			assert.Equal(t, tt.want, formatContent(context.Background(), tt.path, content, nil))
+		})
+	}
+}
+
+func TestFormattingOptions(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    protocol.FormattingOptions
+	}{
+		{
+			name:    "tabs",
+			content: "func main() {\n\treturn\n}\n",
+			want:    protocol.FormattingOptions{TabSize: 4},
+		},
+		{
+			name:    "two spaces",
+			content: "def main():\n  return\n",
+			want:    protocol.FormattingOptions{TabSize: 2, InsertSpaces: true},
+		},
+		{
+			name:    "indentation capped at eight spaces",
+			content: "x:\n            y: 1\n",
+			want:    protocol.FormattingOptions{TabSize: 8, InsertSpaces: true},
+		},
+		{
+			name:    "no indentation",
+			content: "package main\n",
+			want:    protocol.FormattingOptions{TabSize: 4},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, formattingOptions(tt.content))
+		})
+	}
+}
@@ -0,0 +1,343 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/omnitrix-sh/cli/internal/logging"
+	"github.com/omnitrix-sh/cli/internal/lsp"
+	"github.com/omnitrix-sh/cli/internal/lsp/protocol"
+)
+
+type HierarchyParams struct {
+	SymbolPositionParams
+	Direction string `json:"direction"`
+	Depth     int    `json:"depth"`
+}
+
+type hierarchyTool struct {
+	lspClients *lsp.Clients
+}
+
+// hierarchyNode is an item of a call or type hierarchy
+type hierarchyNode struct {
+	name      string
+	kind      protocol.SymbolKind
+	detail    string
+	uri       protocol.DocumentUri
+	rng       protocol.Range
+	callSites []protocol.Range
+
+	callItem protocol.CallHierarchyItem
+	typeItem protocol.TypeHierarchyItem
+}
+
+func (n hierarchyNode) key() string {
+	return fmt.Sprintf("%s:%d:%d:%s", n.uri, n.rng.Start.Line, n.rng.Start.Character, n.name)
+}
+
+const (
+	HierarchyToolName = "hierarchy"
+
+	hierarchyIncoming   = "incoming"
+	hierarchyOutgoing   = "outgoing"
+	hierarchySupertypes = "supertypes"
+	hierarchySubtypes   = "subtypes"
+
+	defaultHierarchyDepth = 2
+	maxHierarchyDepth     = 5
+	maxHierarchyNodes     = 200
+
+	hierarchyDescription = `Explore the call hierarchy or type hierarchy of a symbol using the language server.
+WHEN TO USE THIS TOOL:
+- Use before changing a function to find everything that calls it, directly or indirectly
+- Use to understand what a function depends on
+- Use to find the interfaces a type implements, or the types that implement an interface
+HOW TO USE:
+- Provide the file containing the symbol
+- Give the symbol name, a line and column, or both a symbol name and the line it appears on
+- Set direction to one of:
+  - "incoming": functions that call the symbol (default)
+  - "outgoing": functions the symbol calls
+  - "supertypes": types the symbol extends or implements
+  - "subtypes": types that extend or implement the symbol
+- Set depth to follow the hierarchy further (default 2, maximum 5)
+FEATURES:
+- Returns an indented tree with the location of every item
+- Incoming calls list the lines the calls are made from
+- Recursion is detected and shown once
+LIMITATIONS:
+- Requires a language server that supports call or type hierarchies
+- The tree is limited to 200 items
+- Calls through interfaces or function values may not be found
+TIPS:
+- Start with depth 1 for widely used symbols and increase it if needed
+- Use the references tool for non-call uses such as assignments
+`
+)
+
+func NewHierarchyTool(lspClients *lsp.Clients) BaseTool {
+	return &hierarchyTool{
+		lspClients,
+	}
+}
+
+func (h *hierarchyTool) Info() ToolInfo {
+	parameters := symbolPositionSchema()
+	parameters["direction"] = map[string]any{
+		"type":        "string",
+		"description": "Which hierarchy to return: incoming (default), outgoing, supertypes or subtypes",
+		"enum":        []string{hierarchyIncoming, hierarchyOutgoing, hierarchySupertypes, hierarchySubtypes},
+	}
+	parameters["depth"] = map[string]any{
+		"type":        "integer",
+		"description": "How many levels to follow (default 2, maximum 5)",
+	}
+	return ToolInfo{
+		Name:        HierarchyToolName,
+		Description: hierarchyDescription,
+		Parameters:  parameters,
+		Required:    []string{"file_path"},
+	}
+}
+
+func (h *hierarchyTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error) {
+	lspClients := h.lspClients.Snapshot()
+	var params HierarchyParams
+	if err := json.Unmarshal([]byte(call.Input), &params); err != nil {
+		return NewTextErrorResponse(fmt.Sprintf("error parsing parameters: %s", err)), nil
+	}
+
+	if params.Direction == "" {
+		params.Direction = hierarchyIncoming
+	}
+	switch params.Direction {
+	case hierarchyIncoming, hierarchyOutgoing, hierarchySupertypes, hierarchySubtypes:
+	default:
+		return NewTextErrorResponse(fmt.Sprintf("unknown direction %q", params.Direction)), nil
+	}
+	if params.Depth <= 0 {
+		params.Depth = defaultHierarchyDepth
+	}
+	params.Depth = min(params.Depth, maxHierarchyDepth)
+
+	if len(lspClients) == 0 {
+		return NewTextErrorResponse("no LSP clients available"), nil
+	}
+
+	pos, msg, err := resolveSymbolPosition(ctx, params.SymbolPositionParams, lspClients)
+	if err != nil {
+		return ToolResponse{}, err
+	}
+	if msg != "" {
+		return NewTextErrorResponse(msg), nil
+	}
+
+	for _, name := range clientsForFile(lspClients, pos.path) {
+		client := lspClients[name]
+		roots, err := h.prepare(ctx, client, params.Direction, pos)
+		if err != nil {
+			logging.Debug("LSP hierarchy prepare failed", "lsp", name, "direction", params.Direction, "error", err)
+			continue
+		}
+		if len(roots) == 0 {
+			continue
+		}
+
+		tree := &hierarchyTree{
+			children: func(ctx context.Context, node hierarchyNode) ([]hierarchyNode, error) {
+				return h.children(ctx, client, params.Direction, node)
+			},
+			direction: params.Direction,
+			maxDepth:  params.Depth,
+			visited:   make(map[string]bool),
+			ancestors: make(map[string]bool),
+		}
+		for _, root := range roots {
+			tree.walk(ctx, root, 0)
+		}
+		return NewTextResponse(tree.String()), nil
+	}
+
+	return NewTextResponse(fmt.Sprintf("No %s hierarchy found for the symbol at %s", hierarchyKind(params.Direction), pos)), nil
+}
+
+func (h *hierarchyTool) prepare(ctx context.Context, client *lsp.Client, direction string, pos lspPosition) ([]hierarchyNode, error) {
+	var nodes []hierarchyNode
+	switch direction {
+	case hierarchySupertypes, hierarchySubtypes:
+		items, err := client.PrepareTypeHierarchy(ctx, protocol.TypeHierarchyPrepareParams{
+			TextDocumentPositionParams: pos.textDocumentPosition(),
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range items {
+			nodes = append(nodes, typeHierarchyNode(item))
+		}
+	default:
+		items, err := client.PrepareCallHierarchy(ctx, protocol.CallHierarchyPrepareParams{
+			TextDocumentPositionParams: pos.textDocumentPosition(),
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range items {
+			nodes = append(nodes, callHierarchyNode(item, nil))
+		}
+	}
+	return nodes, nil
+}
+
+func (h *hierarchyTool) children(ctx context.Context, client *lsp.Client, direction string, node hierarchyNode) ([]hierarchyNode, error) {
+	var nodes []hierarchyNode
+	switch direction {
+	case hierarchyIncoming:
+		calls, err := client.IncomingCalls(ctx, protocol.CallHierarchyIncomingCallsParams{Item: node.callItem})
+		if err != nil {
+			return nil, err
+		}
+		for _, call := range calls {
+			nodes = append(nodes, callHierarchyNode(call.From, call.FromRanges))
+		}
+	case hierarchyOutgoing:
+		calls, err := client.OutgoingCalls(ctx, protocol.CallHierarchyOutgoingCallsParams{Item: node.callItem})
+		if err != nil {
+			return nil, err
+		}
+		for _, call := range calls {
+			nodes = append(nodes, callHierarchyNode(call.To, nil))
+		}
+	case hierarchySupertypes:
+		items, err := client.Supertypes(ctx, protocol.TypeHierarchySupertypesParams{Item: node.typeItem})
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range items {
+			nodes = append(nodes, typeHierarchyNode(item))
+		}
+	case hierarchySubtypes:
+		items, err := client.Subtypes(ctx, protocol.TypeHierarchySubtypesParams{Item: node.typeItem})
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range items {
+			nodes = append(nodes, typeHierarchyNode(item))
+		}
+	}
+	return nodes, nil
+}
+
+func callHierarchyNode(item protocol.CallHierarchyItem, callSites []protocol.Range) hierarchyNode {
+	return hierarchyNode{
+		name:      item.Name,
+		kind:      item.Kind,
+		detail:    item.Detail,
+		uri:       item.URI,
+		rng:       item.SelectionRange,
+		callSites: callSites,
+		callItem:  item,
+	}
+}
+
+func typeHierarchyNode(item protocol.TypeHierarchyItem) hierarchyNode {
+	return hierarchyNode{
+		name:     item.Name,
+		kind:     item.Kind,
+		detail:   item.Detail,
+		uri:      item.URI,
+		rng:      item.SelectionRange,
+		typeItem: item,
+	}
+}
+
+// hierarchyTree renders a hierarchy depth first, bounded by depth and by the
+// total number of items
+type hierarchyTree struct {
+	children  func(ctx context.Context, node hierarchyNode) ([]hierarchyNode, error)
+	direction string
+	maxDepth  int
+
+	sb        strings.Builder
+	nodes     int
+	truncated bool
+	// visited holds the items already shown and ancestors those on the path
+	// to the current item, which call or extend themselves when repeated
+	visited   map[string]bool
+	ancestors map[string]bool
+}
+
+func (t *hierarchyTree) walk(ctx context.Context, node hierarchyNode, depth int) {
+	if t.nodes >= maxHierarchyNodes {
+		t.truncated = true
+		return
+	}
+	t.nodes++
+
+	t.sb.WriteString(strings.Repeat("  ", depth))
+	if depth > 0 {
+		t.sb.WriteString("- ")
+	}
+	fmt.Fprintf(&t.sb, "%s %s", symbolKindName(node.kind), node.name)
+	if node.detail != "" {
+		fmt.Fprintf(&t.sb, " (%s)", strings.Join(strings.Fields(node.detail), " "))
+	}
+	fmt.Fprintf(&t.sb, " %s:%d", displayPath(node.uri.Path()), node.rng.Start.Line+1)
+	if len(node.callSites) > 0 {
+		lines := make([]string, 0, len(node.callSites))
+		for _, r := range node.callSites {
+			lines = append(lines, fmt.Sprintf("%d", r.Start.Line+1))
+		}
+		fmt.Fprintf(&t.sb, ", calls at line %s", strings.Join(lines, ", "))
+	}
+
+	key := node.key()
+	switch {
+	case t.ancestors[key]:
+		t.sb.WriteString(" (recursive, see above)\n")
+		return
+	case t.visited[key]:
+		t.sb.WriteString(" (see above)\n")
+		return
+	}
+	t.visited[key] = true
+	t.sb.WriteString("\n")
+
+	if depth >= t.maxDepth {
+		return
+	}
+
+	t.ancestors[key] = true
+	defer delete(t.ancestors, key)
+	children, err := t.children(ctx, node)
+	if err != nil {
+		logging.Debug("LSP hierarchy request failed", "direction", t.direction, "item", node.name, "error", err)
+		return
+	}
+	for _, child := range children {
+		t.walk(ctx, child, depth+1)
+	}
+}
+
+func (t *hierarchyTree) String() string {
+	title := "Call"
+	if hierarchyKind(t.direction) == "type" {
+		title = "Type"
+	}
+	result := fmt.Sprintf("%s hierarchy (%s, depth %d):\n\n", title, t.direction, t.maxDepth) + t.sb.String()
+	if t.truncated {
+		result += fmt.Sprintf("\n(Results truncated to %d items. Use a smaller depth or start from a more specific symbol)\n", maxHierarchyNodes)
+	}
+	return result
+}
+
+func hierarchyKind(direction string) string {
+	switch direction {
+	case hierarchySupertypes, hierarchySubtypes:
+		return "type"
+	default:
+		return "call"
+	}
+}
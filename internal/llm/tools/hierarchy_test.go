@@ -0,0 +1,59 @@
+package tools
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/omnitrix-sh/cli/internal/lsp/protocol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHierarchyTree(t *testing.T) {
+	dir := t.TempDir()
+	loadTestConfig(t, dir)
+
+	node := func(name string, line uint32) hierarchyNode {
+		return hierarchyNode{
+			name: name,
+			kind: protocol.Function,
+			uri:  protocol.DocumentUri("file://" + dir + "/main.go"),
+			rng:  protocol.Range{Start: protocol.Position{Line: line}},
+		}
+	}
+	// main calls a and b, which both call shared, which calls main back
+	calls := map[string][]hierarchyNode{
+		"main":   {node("a", 10), node("b", 20)},
+		"a":      {node("shared", 30)},
+		"b":      {node("shared", 30)},
+		"shared": {node("main", 0)},
+	}
+	tree := &hierarchyTree{
+		children: func(ctx context.Context, n hierarchyNode) ([]hierarchyNode, error) {
+			return calls[n.name], nil
+		},
+		direction: hierarchyOutgoing,
+		maxDepth:  5,
+		visited:   make(map[string]bool),
+		ancestors: make(map[string]bool),
+	}
+	tree.walk(context.Background(), node("main", 0), 0)
+
+	lines := strings.Split(strings.TrimSpace(tree.sb.String()), "\n")
+	tests := []struct {
+		line  string
+		label string
+	}{
+		{"function main main.go:1", ""},
+		{"  - function a main.go:11", ""},
+		{"    - function shared main.go:31", ""},
+		{"      - function main main.go:1", " (recursive, see above)"},
+		{"  - function b main.go:21", ""},
+		{"    - function shared main.go:31", " (see above)"},
+	}
+	require.Len(t, lines, len(tests))
+	for i, tt := range tests {
+		assert.Equal(t, tt.line+tt.label, lines[i])
+	}
+}
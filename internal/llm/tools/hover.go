@@ -0,0 +1,101 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/omnitrix-sh/cli/internal/logging"
+	"github.com/omnitrix-sh/cli/internal/lsp"
+	"github.com/omnitrix-sh/cli/internal/lsp/protocol"
+)
+
+type HoverParams struct {
+	SymbolPositionParams
+}
+
+type hoverTool struct {
+	lspClients *lsp.Clients
+}
+
+const (
+	HoverToolName    = "hover"
+	hoverDescription = `Show the type, signature and documentation of a symbol using the language server.
+WHEN TO USE THIS TOOL:
+- Use when you need the signature of a function or method before calling it
+- Use to find the type of a variable or expression
+- Use to read the documentation of a symbol from a dependency without opening its source
+HOW TO USE:
+- Provide the file containing the symbol
+- Give the symbol name, a line and column, or both a symbol name and the line it appears on
+FEATURES:
+- Returns the hover information exactly as an editor would display it
+- Includes the line the symbol is on for reference
+LIMITATIONS:
+- Requires a language server configured for the file's language
+- The amount of documentation depends on the language server
+- Without a line, the first occurrence of the symbol in the file is used
+TIPS:
+- Use the definition tool to read the full implementation
+`
+)
+
+func NewHoverTool(lspClients *lsp.Clients) BaseTool {
+	return &hoverTool{
+		lspClients,
+	}
+}
+
+func (h *hoverTool) Info() ToolInfo {
+	return ToolInfo{
+		Name:        HoverToolName,
+		Description: hoverDescription,
+		Parameters:  symbolPositionSchema(),
+		Required:    []string{"file_path"},
+	}
+}
+
+func (h *hoverTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error) {
+	lspClients := h.lspClients.Snapshot()
+	var params HoverParams
+	if err := json.Unmarshal([]byte(call.Input), &params); err != nil {
+		return NewTextErrorResponse(fmt.Sprintf("error parsing parameters: %s", err)), nil
+	}
+
+	if len(lspClients) == 0 {
+		return NewTextErrorResponse("no LSP clients available"), nil
+	}
+
+	pos, msg, err := resolveSymbolPosition(ctx, params.SymbolPositionParams, lspClients)
+	if err != nil {
+		return ToolResponse{}, err
+	}
+	if msg != "" {
+		return NewTextErrorResponse(msg), nil
+	}
+
+	contents := ""
+	for _, name := range clientsForFile(lspClients, pos.path) {
+		hover, err := lspClients[name].Hover(ctx, protocol.HoverParams{
+			TextDocumentPositionParams: pos.textDocumentPosition(),
+		})
+		if err != nil {
+			logging.Debug("LSP hover failed", "lsp", name, "error", err)
+			continue
+		}
+		if contents = strings.TrimSpace(hover.Contents.Value); contents != "" {
+			break
+		}
+	}
+
+	location := formatLocations([]protocol.Location{{
+		URI:   protocol.URIFromPath(pos.path),
+		Range: protocol.Range{Start: pos.position, End: pos.position},
+	}}, 0)
+
+	if contents == "" {
+		return NewTextResponse(fmt.Sprintf("No hover information for the symbol at %s\n\n%s", pos, location)), nil
+	}
+	return NewTextResponse(fmt.Sprintf("Hover information for the symbol at %s:\n\n%s\n%s", pos, location, contents)), nil
+}
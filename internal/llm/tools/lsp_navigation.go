@@ -0,0 +1,266 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/omnitrix-sh/cli/internal/config"
+	"github.com/omnitrix-sh/cli/internal/lsp"
+	"github.com/omnitrix-sh/cli/internal/lsp/protocol"
+)
+
+// SymbolPositionParams identifies a position in a file by symbol name, by
+// line and column, or by a symbol on a given line.
+type SymbolPositionParams struct {
+	FilePath string `json:"file_path"`
+	Symbol   string `json:"symbol"`
+	Line     int    `json:"line"`
+	Column   int    `json:"column"`
+}
+
+// symbolPositionSchema is the parameter schema shared by the LSP navigation
+// tools
+func symbolPositionSchema() map[string]any {
+	return map[string]any{
+		"file_path": map[string]any{
+			"type":        "string",
+			"description": "The path to the file containing the symbol",
+		},
+		"symbol": map[string]any{
+			"type":        "string",
+			"description": "The name of the symbol. Without a line, its first occurrence in the file is used",
+		},
+		"line": map[string]any{
+			"type":        "integer",
+			"description": "The line of the symbol (1-based)",
+		},
+		"column": map[string]any{
+			"type":        "integer",
+			"description": "The column of the symbol (1-based). Optional when symbol is given",
+		},
+	}
+}
+
+// lspPosition is a resolved position in an open file
+type lspPosition struct {
+	path     string
+	position protocol.Position
+}
+
+func (p lspPosition) textDocumentPosition() protocol.TextDocumentPositionParams {
+	return protocol.TextDocumentPositionParams{
+		TextDocument: protocol.TextDocumentIdentifier{URI: protocol.URIFromPath(p.path)},
+		Position:     p.position,
+	}
+}
+
+// String describes the position for tool output
+func (p lspPosition) String() string {
+	return fmt.Sprintf("%s:%d:%d", displayPath(p.path), p.position.Line+1, p.position.Character+1)
+}
+
+// resolveSymbolPosition turns the parameters of a navigation tool into an LSP
+// position and opens the file in the language servers. The returned message is
+// meant for the model when the position cannot be resolved.
+func resolveSymbolPosition(ctx context.Context, params SymbolPositionParams, lspClients map[string]*lsp.Client) (lspPosition, string, error) {
+	if params.FilePath == "" {
+		return lspPosition{}, "file_path is required", nil
+	}
+	if params.Symbol == "" && params.Line <= 0 {
+		return lspPosition{}, "either symbol or line is required", nil
+	}
+
+	path := absolutePath(params.FilePath)
+	content, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return lspPosition{}, fmt.Sprintf("file not found: %s", path), nil
+		}
+		return lspPosition{}, "", fmt.Errorf("failed to read file: %w", err)
+	}
+	lines := strings.Split(string(content), "\n")
+
+	pattern := symbolPattern(params.Symbol)
+	lineIdx, byteCol := -1, -1
+	switch {
+	case params.Line > 0:
+		if params.Line > len(lines) {
+			return lspPosition{}, fmt.Sprintf("line %d is out of range, %s has %d lines", params.Line, path, len(lines)), nil
+		}
+		lineIdx = params.Line - 1
+		line := lines[lineIdx]
+		switch {
+		case params.Column > 0:
+			byteCol = runeOffsetToByte(line, params.Column-1)
+		case params.Symbol != "":
+			byteCol = findSymbol(line, pattern)
+			if byteCol < 0 {
+				return lspPosition{}, fmt.Sprintf("symbol %q not found on line %d of %s", params.Symbol, params.Line, path), nil
+			}
+		default:
+			byteCol = len(line) - len(strings.TrimLeft(line, " \t"))
+		}
+	default:
+		for i, line := range lines {
+			if col := findSymbol(line, pattern); col >= 0 {
+				lineIdx, byteCol = i, col
+				break
+			}
+		}
+		if lineIdx < 0 {
+			return lspPosition{}, fmt.Sprintf("symbol %q not found in %s", params.Symbol, path), nil
+		}
+	}
+
+	notifyLspOpenFile(ctx, path, lspClients)
+
+	line := strings.TrimSuffix(lines[lineIdx], "\r")
+	return lspPosition{
+		path: path,
+		position: protocol.Position{
+			Line:      uint32(lineIdx),
+			Character: uint32(utf16Len(line[:min(byteCol, len(line))])),
+		},
+	}, "", nil
+}
+
+// symbolPattern matches symbol as a whole word
+func symbolPattern(symbol string) *regexp.Regexp {
+	return regexp.MustCompile(`(^|[^\w$])` + regexp.QuoteMeta(symbol) + `($|[^\w$])`)
+}
+
+// findSymbol returns the byte offset of the first match of a symbol pattern in
+// line, or -1
+func findSymbol(line string, pattern *regexp.Regexp) int {
+	loc := pattern.FindStringSubmatchIndex(line)
+	if loc == nil {
+		return -1
+	}
+	return loc[3]
+}
+
+func runeOffsetToByte(line string, runes int) int {
+	offset := 0
+	for i := 0; i < runes && offset < len(line); i++ {
+		_, size := utf8.DecodeRuneInString(line[offset:])
+		offset += size
+	}
+	return offset
+}
+
+// utf16Len returns the length of s in UTF-16 code units, the default position
+// encoding of LSP
+func utf16Len(s string) int {
+	n := 0
+	for _, r := range s {
+		n += utf16.RuneLen(r)
+	}
+	return n
+}
+
+// sortedClients returns the language servers in a stable order
+func sortedClients(lspClients map[string]*lsp.Client) []string {
+	names := make([]string, 0, len(lspClients))
+	for name := range lspClients {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// clientsForFile returns the language servers that handle path in a stable
+// order
+func clientsForFile(lspClients map[string]*lsp.Client, path string) []string {
+	var names []string
+	for _, name := range sortedClients(lspClients) {
+		if lspClients[name].HandlesFile(path) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// locationsFromDefinition flattens the result variants of the definition,
+// type definition and implementation requests
+func locationsFromDefinition(value any) []protocol.Location {
+	switch v := value.(type) {
+	case protocol.Or_Definition:
+		return locationsFromDefinition(v.Value)
+	case protocol.Location:
+		return []protocol.Location{v}
+	case []protocol.Location:
+		return v
+	case []protocol.LocationLink:
+		locations := make([]protocol.Location, 0, len(v))
+		for _, link := range v {
+			locations = append(locations, protocol.Location{URI: link.TargetURI, Range: link.TargetSelectionRange})
+		}
+		return locations
+	}
+	return nil
+}
+
+// formatLocations renders locations grouped by file, with the referenced lines
+// and contextLines of surrounding code
+func formatLocations(locations []protocol.Location, contextLines int) string {
+	sort.Slice(locations, func(i, j int) bool {
+		if locations[i].URI != locations[j].URI {
+			return locations[i].URI < locations[j].URI
+		}
+		return locations[i].Range.Start.Line < locations[j].Range.Start.Line
+	})
+
+	var sb strings.Builder
+	fileLines := make(map[string][]string)
+	currentFile := ""
+	for _, loc := range locations {
+		path := loc.URI.Path()
+		if path != currentFile {
+			if currentFile != "" {
+				sb.WriteString("\n")
+			}
+			currentFile = path
+			sb.WriteString(displayPath(path) + ":\n")
+		}
+
+		lines, ok := fileLines[path]
+		if !ok {
+			content, err := os.ReadFile(path)
+			if err == nil {
+				lines = strings.Split(string(content), "\n")
+			}
+			fileLines[path] = lines
+		}
+
+		start := int(loc.Range.Start.Line)
+		end := int(loc.Range.End.Line)
+		if start >= len(lines) {
+			fmt.Fprintf(&sb, "%6d|(line not available)\n", start+1)
+			continue
+		}
+		from := max(start-contextLines, 0)
+		to := min(max(end, start)+contextLines, len(lines)-1)
+		if contextLines > 0 && from > 0 {
+			sb.WriteString("   ...\n")
+		}
+		for i := from; i <= to; i++ {
+			fmt.Fprintf(&sb, "%6d|%s\n", i+1, strings.TrimSuffix(lines[i], "\r"))
+		}
+	}
+	return sb.String()
+}
+
+// displayPath shortens paths inside the working directory for tool output
+func displayPath(path string) string {
+	if rel, err := filepath.Rel(config.WorkingDirectory(), path); err == nil && !strings.HasPrefix(rel, "..") {
+		return rel
+	}
+	return path
+}
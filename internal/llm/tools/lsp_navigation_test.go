@@ -0,0 +1,102 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/omnitrix-sh/cli/internal/lsp/protocol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveSymbolPosition(t *testing.T) {
+	dir := t.TempDir()
+	loadTestConfig(t, dir)
+
+	path := filepath.Join(dir, "main.go")
+	content := "package main\n" +
+		"\n" +
+		"func handlerFunc() {}\n" +
+		"func handler() {}\n" +
+		"\tvar café = handler()\n" +
+		"x := \"😀\" + handler()\n"
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+
+	tests := []struct {
+		name    string
+		params  SymbolPositionParams
+		want    protocol.Position
+		wantMsg string
+	}{
+		{
+			name:   "first whole word match of a symbol",
+			params: SymbolPositionParams{FilePath: path, Symbol: "handler"},
+			want:   protocol.Position{Line: 3, Character: 5},
+		},
+		{
+			name:   "symbol on a line",
+			params: SymbolPositionParams{FilePath: path, Symbol: "handler", Line: 5},
+			want:   protocol.Position{Line: 4, Character: 12},
+		},
+		{
+			name:   "character in UTF-16 code units",
+			params: SymbolPositionParams{FilePath: path, Symbol: "handler", Line: 6},
+			want:   protocol.Position{Line: 5, Character: 12},
+		},
+		{
+			name:   "column counted in runes",
+			params: SymbolPositionParams{FilePath: path, Line: 5, Column: 11},
+			want:   protocol.Position{Line: 4, Character: 10},
+		},
+		{
+			name:   "line without symbol or column",
+			params: SymbolPositionParams{FilePath: path, Line: 5},
+			want:   protocol.Position{Line: 4, Character: 1},
+		},
+		{
+			name:    "file path is required",
+			params:  SymbolPositionParams{Symbol: "handler"},
+			wantMsg: "file_path is required",
+		},
+		{
+			name:    "symbol or line is required",
+			params:  SymbolPositionParams{FilePath: path},
+			wantMsg: "either symbol or line is required",
+		},
+		{
+			name:    "missing file",
+			params:  SymbolPositionParams{FilePath: filepath.Join(dir, "missing.go"), Symbol: "handler"},
+			wantMsg: "file not found",
+		},
+		{
+			name:    "line out of range",
+			params:  SymbolPositionParams{FilePath: path, Line: 8},
+			wantMsg: "line 8 is out of range",
+		},
+		{
+			name:    "symbol not in file",
+			params:  SymbolPositionParams{FilePath: path, Symbol: "handle"},
+			wantMsg: `symbol "handle" not found in`,
+		},
+		{
+			name:    "symbol not on line",
+			params:  SymbolPositionParams{FilePath: path, Symbol: "handler", Line: 3},
+			wantMsg: `symbol "handler" not found on line 3`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pos, msg, err := resolveSymbolPosition(context.Background(), tt.params, nil)
+			require.NoError(t, err)
+			if tt.wantMsg != "" {
+				assert.Contains(t, msg, tt.wantMsg)
+				return
+			}
+			assert.Empty(t, msg)
+			assert.Equal(t, path, pos.path)
+			assert.Equal(t, tt.want, pos.position)
+		})
+	}
+}
@@ -27,7 +27,7 @@ type PatchResponseMetadata struct {
 }
 
 type patchTool struct {
-	lspClients  map[string]*lsp.Client
+	lspClients  *lsp.Clients
 	permissions permission.Service
 	files       history.Service
 }
@@ -48,8 +48,27 @@ The patch text must follow this format:
 +Content of the new file
 +More content
 *** Delete File: /path/to/file/to/delete
+*** Move File: /path/to/old/location
+*** Move to: /path/to/new/location
 *** End Patch
 
+A "*** Move File:" section renames a file, keeping its history; it may be followed by
+hunks like an update to change the content at the same time. Language servers are told
+about moves so they can update references such as import paths.
+
+Standard unified diffs and git style diffs are also accepted:
+--- a/path/to/file
++++ b/path/to/file
+@@ -10,3 +10,4 @@
+ Line to keep
+-Line to remove
++Line to add
+ Line to keep
+
+Git extended headers are supported for creating (--- /dev/null), deleting (+++ /dev/null),
+renaming (rename from / rename to) and changing the mode of files (new mode 100755).
+Relative paths in unified diffs are resolved against the working directory.
+
 Before using this tool:
 1. Use the FileRead tool to understand the files' contents and context
 2. Verify all file paths are correct (use the LS tool)
@@ -64,7 +83,7 @@ CRITICAL REQUIREMENTS FOR USING THIS TOOL:
 The tool will apply all changes in a single atomic operation.`
 )
 
-func NewPatchTool(lspClients map[string]*lsp.Client, permissions permission.Service, files history.Service) BaseTool {
+func NewPatchTool(lspClients *lsp.Clients, permissions permission.Service, files history.Service) BaseTool {
 	return &patchTool{
 		lspClients:  lspClients,
 		permissions: permissions,
@@ -87,6 +106,7 @@ func (p *patchTool) Info() ToolInfo {
 }
 
 func (p *patchTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error) {
+	lspClients := p.lspClients.Snapshot()
 	var params PatchParams
 	if err := json.Unmarshal([]byte(call.Input), &params); err != nil {
 		return NewTextErrorResponse("invalid parameters"), nil
@@ -129,6 +149,10 @@ func (p *patchTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error
 					absPath, modTime.Format(time.RFC3339), lastRead.Format(time.RFC3339),
 				)), nil
 		}
+
+		if msg := externalChangeMessage(absPath); msg != "" {
+			return NewTextErrorResponse(msg), nil
+		}
 	}
 
 	// Check for new files to ensure they don't already exist
@@ -148,6 +172,23 @@ func (p *patchTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error
 		}
 	}
 
+	// Check that files are not moved onto existing files
+	filesToMove := diff.IdentifyFilesMoved(params.PatchText)
+	for _, target := range filesToMove {
+		absPath := target
+		if !filepath.IsAbs(absPath) {
+			wd := config.WorkingDirectory()
+			absPath = filepath.Join(wd, absPath)
+		}
+
+		_, err := os.Stat(absPath)
+		if err == nil {
+			return NewTextErrorResponse(fmt.Sprintf("file already exists and cannot be the target of a move: %s", absPath)), nil
+		} else if !os.IsNotExist(err) {
+			return ToolResponse{}, fmt.Errorf("failed to check file: %w", err)
+		}
+	}
+
 	// Load all required files
 	currentFiles := make(map[string]string)
 	for _, filePath := range filesToRead {
@@ -186,6 +227,24 @@ func (p *patchTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error
 		return ToolResponse{}, fmt.Errorf("session ID and message ID are required for creating a patch")
 	}
 
+	// Format the new contents so the user approves what will be written
+	formattedFiles := 0
+	for path, change := range commit.Changes {
+		if change.NewContent == nil || change.Type == diff.ActionDelete {
+			continue
+		}
+		target := path
+		if change.MovePath != nil {
+			target = *change.MovePath
+		}
+		formatted := formatContent(ctx, absolutePath(target), *change.NewContent, lspClients)
+		if formatted != *change.NewContent {
+			change.NewContent = &formatted
+			commit.Changes[path] = change
+			formattedFiles++
+		}
+	}
+
 	// Request permission for all changes
 	for path, change := range commit.Changes {
 		switch change.Type {
@@ -235,6 +294,25 @@ func (p *patchTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error
 			if !p {
 				return ToolResponse{}, permission.ErrorPermissionDenied
 			}
+		case diff.ActionMove:
+			patchDiff, _, _ := diff.GenerateDiff(*change.OldContent, *change.NewContent, *change.MovePath)
+			dir := filepath.Dir(path)
+			p := p.permissions.Request(
+				permission.CreatePermissionRequest{
+					SessionID:   sessionID,
+					Path:        dir,
+					ToolName:    PatchToolName,
+					Action:      "move",
+					Description: fmt.Sprintf("Move file %s to %s", path, *change.MovePath),
+					Params: EditPermissionsParams{
+						FilePath: *change.MovePath,
+						Diff:     patchDiff,
+					},
+				},
+			)
+			if !p {
+				return ToolResponse{}, permission.ErrorPermissionDenied
+			}
 		case diff.ActionDelete:
 			dir := filepath.Dir(path)
 			patchDiff, _, _ := diff.GenerateDiff(*change.OldContent, "", path)
@@ -257,6 +335,28 @@ func (p *patchTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error
 		}
 	}
 
+	// Ask the language servers which references need updating for moved files
+	referenceEdits := p.willMoveFiles(ctx, commit)
+	if len(referenceEdits) > 0 {
+		referencesDiff, _, _ := combinedDiff(referenceEdits)
+		granted := p.permissions.Request(
+			permission.CreatePermissionRequest{
+				SessionID:   sessionID,
+				Path:        config.WorkingDirectory(),
+				ToolName:    PatchToolName,
+				Action:      "update",
+				Description: fmt.Sprintf("Update references to moved files in %d files", len(referenceEdits)),
+				Params: EditPermissionsParams{
+					FilePath: referenceEdits[0].path,
+					Diff:     referencesDiff,
+				},
+			},
+		)
+		if !granted {
+			return ToolResponse{}, permission.ErrorPermissionDenied
+		}
+	}
+
 	// Apply the changes to the filesystem
 	err = diff.ApplyCommit(commit, func(path string, content string) error {
 		absPath := path
@@ -284,6 +384,24 @@ func (p *patchTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error
 		return NewTextErrorResponse(fmt.Sprintf("failed to apply patch: %s", err)), nil
 	}
 
+	// Git style patches can carry mode changes, apply them once the content is in place
+	for path, change := range commit.Changes {
+		if change.Mode == nil || change.Type == diff.ActionDelete {
+			continue
+		}
+		if change.MovePath != nil {
+			path = *change.MovePath
+		}
+		absPath := path
+		if !filepath.IsAbs(absPath) {
+			wd := config.WorkingDirectory()
+			absPath = filepath.Join(wd, absPath)
+		}
+		if err := os.Chmod(absPath, *change.Mode); err != nil {
+			return NewTextErrorResponse(fmt.Sprintf("failed to change mode of %s: %s", absPath, err)), nil
+		}
+	}
+
 	// Update file history for all modified files
 	changedFiles := []string{}
 	totalAdditions := 0
@@ -295,8 +413,6 @@ func (p *patchTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error
 			wd := config.WorkingDirectory()
 			absPath = filepath.Join(wd, absPath)
 		}
-		changedFiles = append(changedFiles, absPath)
-
 		oldContent := ""
 		if change.OldContent != nil {
 			oldContent = *change.OldContent
@@ -307,6 +423,23 @@ func (p *patchTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error
 			newContent = *change.NewContent
 		}
 
+		if change.Type == diff.ActionMove {
+			absMovePath := *change.MovePath
+			if !filepath.IsAbs(absMovePath) {
+				wd := config.WorkingDirectory()
+				absMovePath = filepath.Join(wd, absMovePath)
+			}
+			changedFiles = append(changedFiles, absMovePath)
+
+			_, additions, removals := diff.GenerateDiff(oldContent, newContent, absMovePath)
+			totalAdditions += additions
+			totalRemovals += removals
+
+			p.recordMove(ctx, sessionID, absPath, absMovePath, oldContent, newContent)
+			continue
+		}
+		changedFiles = append(changedFiles, absPath)
+
 		// Calculate diff statistics
 		_, additions, removals := diff.GenerateDiff(oldContent, newContent, path)
 		totalAdditions += additions
@@ -345,17 +478,31 @@ func (p *patchTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error
 		recordFileRead(absPath)
 	}
 
+	// Apply the reference updates the language servers asked for
+	if err := writeFileEdits(ctx, p.files, sessionID, referenceEdits); err != nil {
+		return NewTextErrorResponse(fmt.Sprintf("patch applied, but failed to update references to moved files: %s", err)), nil
+	}
+	for _, e := range referenceEdits {
+		_, additions, removals := diff.GenerateDiff(e.oldContent, e.newContent, e.path)
+		totalAdditions += additions
+		totalRemovals += removals
+		changedFiles = append(changedFiles, e.path)
+	}
+
 	// Run LSP diagnostics on all changed files
 	for _, filePath := range changedFiles {
-		waitForLspDiagnostics(ctx, filePath, p.lspClients)
+		waitForLspDiagnostics(ctx, filePath, lspClients)
 	}
 
 	result := fmt.Sprintf("Patch applied successfully. %d files changed, %d additions, %d removals",
 		len(changedFiles), totalAdditions, totalRemovals)
+	if formattedFiles > 0 {
+		result += fmt.Sprintf("\n(%d files were formatted after patching, view them again before editing the reformatted lines)", formattedFiles)
+	}
 
 	diagnosticsText := ""
 	for _, filePath := range changedFiles {
-		diagnosticsText += getDiagnostics(filePath, p.lspClients)
+		diagnosticsText += getDiagnostics(filePath, lspClients)
 	}
 
 	if diagnosticsText != "" {
@@ -370,3 +517,83 @@ func (p *patchTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error
 			Removals:     totalRemovals,
 		}), nil
 }
+
+// willMoveFiles collects the edits the language servers handling a moved file
+// want applied for the moves in a commit. Edits to files the commit itself
+// changes are dropped, as their positions would no longer be valid once the
+// patch is applied.
+func (p *patchTool) willMoveFiles(ctx context.Context, commit diff.Commit) []fileEdit {
+	lspClients := p.lspClients.Snapshot()
+	touched := make(map[string]bool, len(commit.Changes))
+	for path := range commit.Changes {
+		touched[absolutePath(path)] = true
+	}
+
+	var result []fileEdit
+	seen := make(map[string]bool)
+	for path, change := range commit.Changes {
+		if change.Type != diff.ActionMove {
+			continue
+		}
+		oldPath, newPath := absolutePath(path), absolutePath(*change.MovePath)
+		for name, client := range lspClients {
+			if !client.HandlesFile(oldPath) && !client.HandlesFile(newPath) {
+				continue
+			}
+			workspaceEdit, err := client.WillRenameFile(ctx, oldPath, newPath)
+			if err != nil {
+				logging.Debug("LSP willRenameFiles failed", "lsp", name, "error", err)
+				continue
+			}
+			edits, err := resolveWorkspaceEdit(workspaceEdit)
+			if err != nil {
+				logging.Debug("Cannot apply willRenameFiles edit", "lsp", name, "error", err)
+				continue
+			}
+			for _, e := range edits {
+				if touched[e.path] {
+					logging.Debug("Skipping willRenameFiles edit to a patched file", "lsp", name, "file", e.path)
+					continue
+				}
+				if seen[e.path] {
+					continue
+				}
+				seen[e.path] = true
+				result = append(result, e)
+			}
+		}
+	}
+	return result
+}
+
+// recordMove moves the history of a file to its new path, stores the moved
+// content as a new version and tells the language servers handling the file
+// about the move.
+func (p *patchTool) recordMove(ctx context.Context, sessionID, oldPath, newPath, oldContent, newContent string) {
+	lspClients := p.lspClients.Snapshot()
+	if _, err := p.files.GetByPathAndSession(ctx, oldPath, sessionID); err == nil {
+		if err := p.files.Rename(ctx, sessionID, oldPath, newPath); err != nil {
+			logging.Debug("Error renaming file history", "error", err)
+		}
+	}
+	recordFileHistory(ctx, p.files, sessionID, newPath, oldContent, newContent)
+	forgetFile(oldPath)
+	recordFileWrite(newPath)
+	recordFileRead(newPath)
+
+	for name, client := range lspClients {
+		if !client.HandlesFile(oldPath) && !client.HandlesFile(newPath) {
+			continue
+		}
+		if err := client.RenameFile(ctx, oldPath, newPath); err != nil {
+			logging.Debug("LSP didRenameFiles failed", "lsp", name, "error", err)
+		}
+	}
+}
+
+func absolutePath(path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(config.WorkingDirectory(), path)
+}
@@ -0,0 +1,115 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/omnitrix-sh/cli/internal/logging"
+	"github.com/omnitrix-sh/cli/internal/lsp"
+	"github.com/omnitrix-sh/cli/internal/lsp/protocol"
+)
+
+type ReferencesParams struct {
+	SymbolPositionParams
+	IncludeDeclaration bool `json:"include_declaration"`
+}
+
+type referencesTool struct {
+	lspClients *lsp.Clients
+}
+
+const (
+	ReferencesToolName = "references"
+
+	maxReferences = 200
+
+	referencesDescription = `Find all references to a symbol using the language server.
+WHEN TO USE THIS TOOL:
+- Use when you need to know where a function, type, variable or method is used
+- Use before changing a signature to find every caller
+- Prefer it over grep when the name is common, as only real references to the symbol are returned
+HOW TO USE:
+- Provide the file containing the symbol
+- Give the symbol name, a line and column, or both a symbol name and the line it appears on
+- Set include_declaration to also list the declaration itself
+FEATURES:
+- Results are grouped by file and show each referencing line with its line number
+LIMITATIONS:
+- Requires a language server configured for the file's language
+- Results are limited to 200 references
+- Without a line, the first occurrence of the symbol in the file is used
+TIPS:
+- Use the definition tool to jump to the declaration
+- Use the view tool to read more context around a reference
+`
+)
+
+func NewReferencesTool(lspClients *lsp.Clients) BaseTool {
+	return &referencesTool{
+		lspClients,
+	}
+}
+
+func (r *referencesTool) Info() ToolInfo {
+	parameters := symbolPositionSchema()
+	parameters["include_declaration"] = map[string]any{
+		"type":        "boolean",
+		"description": "Include the declaration of the symbol in the results (default false)",
+	}
+	return ToolInfo{
+		Name:        ReferencesToolName,
+		Description: referencesDescription,
+		Parameters:  parameters,
+		Required:    []string{"file_path"},
+	}
+}
+
+func (r *referencesTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error) {
+	lspClients := r.lspClients.Snapshot()
+	var params ReferencesParams
+	if err := json.Unmarshal([]byte(call.Input), &params); err != nil {
+		return NewTextErrorResponse(fmt.Sprintf("error parsing parameters: %s", err)), nil
+	}
+
+	if len(lspClients) == 0 {
+		return NewTextErrorResponse("no LSP clients available"), nil
+	}
+
+	pos, msg, err := resolveSymbolPosition(ctx, params.SymbolPositionParams, lspClients)
+	if err != nil {
+		return ToolResponse{}, err
+	}
+	if msg != "" {
+		return NewTextErrorResponse(msg), nil
+	}
+
+	var locations []protocol.Location
+	for _, name := range clientsForFile(lspClients, pos.path) {
+		locations, err = lspClients[name].References(ctx, protocol.ReferenceParams{
+			TextDocumentPositionParams: pos.textDocumentPosition(),
+			Context: protocol.ReferenceContext{
+				IncludeDeclaration: params.IncludeDeclaration,
+			},
+		})
+		if err != nil {
+			logging.Debug("LSP references lookup failed", "lsp", name, "error", err)
+			continue
+		}
+		if len(locations) > 0 {
+			break
+		}
+	}
+
+	if len(locations) == 0 {
+		return NewTextResponse(fmt.Sprintf("No references found for the symbol at %s", pos)), nil
+	}
+
+	output := fmt.Sprintf("Found %d reference(s) to the symbol at %s", len(locations), pos)
+	if len(locations) > maxReferences {
+		output += fmt.Sprintf(", showing the first %d", maxReferences)
+		locations = locations[:maxReferences]
+	}
+	output += ":\n\n" + formatLocations(locations, 0)
+	return NewTextResponse(output), nil
+}
@@ -0,0 +1,219 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/omnitrix-sh/cli/internal/config"
+	"github.com/omnitrix-sh/cli/internal/history"
+	"github.com/omnitrix-sh/cli/internal/logging"
+	"github.com/omnitrix-sh/cli/internal/lsp"
+	"github.com/omnitrix-sh/cli/internal/lsp/protocol"
+	"github.com/omnitrix-sh/cli/internal/permission"
+)
+
+type RenameSymbolParams struct {
+	SymbolPositionParams
+	NewName string `json:"new_name"`
+}
+
+type renameSymbolTool struct {
+	lspClients  *lsp.Clients
+	permissions permission.Service
+	files       history.Service
+}
+
+const (
+	RenameSymbolToolName    = "rename_symbol"
+	renameSymbolDescription = `Rename a symbol and every reference to it across the project using the language server.
+WHEN TO USE THIS TOOL:
+- Use to rename functions, types, variables, methods, fields or packages
+- Prefer it over edit calls, as the language server updates every reference, including those in other files, and leaves unrelated text with the same name alone
+HOW TO USE:
+- Provide the file containing the symbol
+- Give the symbol name, a line and column, or both a symbol name and the line it appears on
+- Provide the new name
+FEATURES:
+- Every changed file is shown to the user in one diff before anything is written
+- All files are changed together and recorded in the file history
+- Diagnostics for the changed files are returned after the rename
+LIMITATIONS:
+- Requires a language server for the file's language that supports renaming
+- Renames that would create or move files are not supported
+- Without a line, the first occurrence of the symbol in the file is used
+TIPS:
+- Use the references tool first if you want to know how many places will change
+- Check the diagnostics in the result for conflicts the rename introduced
+`
+)
+
+func NewRenameSymbolTool(lspClients *lsp.Clients, permissions permission.Service, files history.Service) BaseTool {
+	return &renameSymbolTool{
+		lspClients:  lspClients,
+		permissions: permissions,
+		files:       files,
+	}
+}
+
+func (r *renameSymbolTool) Info() ToolInfo {
+	parameters := symbolPositionSchema()
+	parameters["new_name"] = map[string]any{
+		"type":        "string",
+		"description": "The new name of the symbol",
+	}
+	return ToolInfo{
+		Name:        RenameSymbolToolName,
+		Description: renameSymbolDescription,
+		Parameters:  parameters,
+		Required:    []string{"file_path", "new_name"},
+	}
+}
+
+func (r *renameSymbolTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error) {
+	lspClients := r.lspClients.Snapshot()
+	var params RenameSymbolParams
+	if err := json.Unmarshal([]byte(call.Input), &params); err != nil {
+		return NewTextErrorResponse(fmt.Sprintf("error parsing parameters: %s", err)), nil
+	}
+
+	params.NewName = strings.TrimSpace(params.NewName)
+	if params.NewName == "" {
+		return NewTextErrorResponse("new_name is required"), nil
+	}
+
+	if len(lspClients) == 0 {
+		return NewTextErrorResponse("no LSP clients available"), nil
+	}
+
+	sessionID, messageID := GetContextValues(ctx)
+	if sessionID == "" || messageID == "" {
+		return ToolResponse{}, fmt.Errorf("session ID and message ID are required for renaming a symbol")
+	}
+
+	pos, msg, err := resolveSymbolPosition(ctx, params.SymbolPositionParams, lspClients)
+	if err != nil {
+		return ToolResponse{}, err
+	}
+	if msg != "" {
+		return NewTextErrorResponse(msg), nil
+	}
+
+	workspaceEdit, err := r.rename(ctx, pos, params.NewName)
+	if err != nil {
+		return NewTextErrorResponse(fmt.Sprintf("cannot rename the symbol at %s: %s", pos, err)), nil
+	}
+
+	edits, err := resolveWorkspaceEdit(workspaceEdit)
+	if err != nil {
+		return NewTextErrorResponse(err.Error()), nil
+	}
+	if len(edits) == 0 {
+		return NewTextErrorResponse(fmt.Sprintf("renaming the symbol at %s to %s changes nothing", pos, params.NewName)), nil
+	}
+
+	for _, e := range edits {
+		if msg := externalChangeMessage(e.path); msg != "" {
+			return NewTextErrorResponse(msg), nil
+		}
+	}
+
+	name := params.Symbol
+	if name == "" {
+		name = "symbol at " + pos.String()
+	}
+
+	renameDiff, additions, removals := combinedDiff(edits)
+	p := r.permissions.Request(
+		permission.CreatePermissionRequest{
+			SessionID:   sessionID,
+			Path:        config.WorkingDirectory(),
+			ToolName:    RenameSymbolToolName,
+			Action:      "rename",
+			Description: fmt.Sprintf("Rename %s to %s in %d files", name, params.NewName, len(edits)),
+			Params: EditPermissionsParams{
+				FilePath: pos.path,
+				Diff:     renameDiff,
+			},
+		},
+	)
+	if !p {
+		return ToolResponse{}, permission.ErrorPermissionDenied
+	}
+
+	if err := writeFileEdits(ctx, r.files, sessionID, edits); err != nil {
+		return ToolResponse{}, err
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Renamed %s to %s. %d files changed, %d additions, %d removals:\n", name, params.NewName, len(edits), additions, removals)
+	for _, e := range edits {
+		sb.WriteString("- " + displayPath(e.path) + "\n")
+	}
+
+	diagnosticsText := ""
+	for _, e := range edits {
+		waitForLspDiagnostics(ctx, e.path, lspClients)
+		diagnosticsText += getDiagnostics(e.path, lspClients)
+	}
+	if diagnosticsText != "" {
+		sb.WriteString("\nDiagnostics:\n" + diagnosticsText)
+	}
+
+	return WithResponseMetadata(
+		NewTextResponse(sb.String()),
+		EditResponseMetadata{
+			Diff:      renameDiff,
+			Additions: additions,
+			Removals:  removals,
+		},
+	), nil
+}
+
+// rename asks the language servers that support renaming for the edit, using
+// the first server that accepts the position
+func (r *renameSymbolTool) rename(ctx context.Context, pos lspPosition, newName string) (protocol.WorkspaceEdit, error) {
+	lspClients := r.lspClients.Snapshot()
+	var lastErr error
+	for _, name := range clientsForFile(lspClients, pos.path) {
+		client := lspClients[name]
+		canRename, canPrepare := client.RenameSupport()
+		if !canRename {
+			continue
+		}
+
+		if canPrepare {
+			prepared, err := client.PrepareRename(ctx, protocol.PrepareRenameParams{
+				TextDocumentPositionParams: pos.textDocumentPosition(),
+			})
+			if err != nil {
+				logging.Debug("LSP prepareRename failed", "lsp", name, "error", err)
+				lastErr = err
+				continue
+			}
+			if prepared.Value == nil {
+				lastErr = errors.New("the language server cannot rename the symbol at this position")
+				continue
+			}
+		}
+
+		edit, err := client.Rename(ctx, protocol.RenameParams{
+			TextDocument: protocol.TextDocumentIdentifier{URI: protocol.URIFromPath(pos.path)},
+			Position:     pos.position,
+			NewName:      newName,
+		})
+		if err != nil {
+			logging.Debug("LSP rename failed", "lsp", name, "error", err)
+			lastErr = err
+			continue
+		}
+		return edit, nil
+	}
+
+	if lastErr == nil {
+		lastErr = errors.New("no language server supports renaming")
+	}
+	return protocol.WorkspaceEdit{}, lastErr
+}
@@ -0,0 +1,384 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/omnitrix-sh/cli/internal/logging"
+	"github.com/omnitrix-sh/cli/internal/lsp"
+	"github.com/omnitrix-sh/cli/internal/lsp/protocol"
+)
+
+type SymbolsParams struct {
+	FilePath string `json:"file_path"`
+	Query    string `json:"query"`
+	Kind     string `json:"kind"`
+}
+
+type symbolsTool struct {
+	lspClients *lsp.Clients
+}
+
+// symbolEntry is a symbol flattened for display
+type symbolEntry struct {
+	name      string
+	detail    string
+	kind      protocol.SymbolKind
+	container string
+	path      string
+	rng       protocol.Range
+	depth     int
+}
+
+const (
+	SymbolsToolName = "symbols"
+
+	maxWorkspaceSymbols = 100
+
+	symbolsDescription = `List the symbols of a file or search for symbols across the project using the language server.
+WHEN TO USE THIS TOOL:
+- Use to get an outline of a file (types, functions, methods and their line ranges) without reading all of it
+- Use to find where a type, function or method is defined when you only know (part of) its name
+HOW TO USE:
+- Provide file_path to get the outline of that file, optionally filtered by query
+- Leave file_path empty and provide query to search the whole project
+- Optionally restrict the results to one kind, e.g. "function", "method", "class", "struct", "interface"
+FEATURES:
+- Outlines are nested, so methods appear under their types
+- Every symbol includes its line range, which can be passed to the view tool as offset and limit
+- Project search is fuzzy, so "UsrSvc" can find "UserService"
+LIMITATIONS:
+- Requires a language server configured for the language
+- Project search returns at most 100 symbols
+TIPS:
+- Use this before viewing a large file to read only the part you need
+- Prefer it over grep when looking for a definition rather than its uses
+`
+)
+
+var symbolKindNames = map[protocol.SymbolKind]string{
+	protocol.File:          "file",
+	protocol.Module:        "module",
+	protocol.Namespace:     "namespace",
+	protocol.Package:       "package",
+	protocol.Class:         "class",
+	protocol.Method:        "method",
+	protocol.Property:      "property",
+	protocol.Field:         "field",
+	protocol.Constructor:   "constructor",
+	protocol.Enum:          "enum",
+	protocol.Interface:     "interface",
+	protocol.Function:      "function",
+	protocol.Variable:      "variable",
+	protocol.Constant:      "constant",
+	protocol.String:        "string",
+	protocol.Number:        "number",
+	protocol.Boolean:       "boolean",
+	protocol.Array:         "array",
+	protocol.Object:        "object",
+	protocol.Key:           "key",
+	protocol.Null:          "null",
+	protocol.EnumMember:    "enum_member",
+	protocol.Struct:        "struct",
+	protocol.Event:         "event",
+	protocol.Operator:      "operator",
+	protocol.TypeParameter: "type_parameter",
+}
+
+func symbolKindName(kind protocol.SymbolKind) string {
+	if name, ok := symbolKindNames[kind]; ok {
+		return name
+	}
+	return "symbol"
+}
+
+func NewSymbolsTool(lspClients *lsp.Clients) BaseTool {
+	return &symbolsTool{
+		lspClients,
+	}
+}
+
+func (s *symbolsTool) Info() ToolInfo {
+	return ToolInfo{
+		Name:        SymbolsToolName,
+		Description: symbolsDescription,
+		Parameters: map[string]any{
+			"file_path": map[string]any{
+				"type":        "string",
+				"description": "The file to outline (leave empty to search the whole project)",
+			},
+			"query": map[string]any{
+				"type":        "string",
+				"description": "The symbol name to search for. Required when file_path is empty",
+			},
+			"kind": map[string]any{
+				"type":        "string",
+				"description": "Only return symbols of this kind, e.g. function, method, class, struct, interface, variable, constant",
+			},
+		},
+		Required: []string{},
+	}
+}
+
+func (s *symbolsTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error) {
+	lspClients := s.lspClients.Snapshot()
+	var params SymbolsParams
+	if err := json.Unmarshal([]byte(call.Input), &params); err != nil {
+		return NewTextErrorResponse(fmt.Sprintf("error parsing parameters: %s", err)), nil
+	}
+
+	if params.FilePath == "" && params.Query == "" {
+		return NewTextErrorResponse("either file_path or query is required"), nil
+	}
+
+	if len(lspClients) == 0 {
+		return NewTextErrorResponse("no LSP clients available"), nil
+	}
+
+	if params.FilePath != "" {
+		return s.outline(ctx, params)
+	}
+	return s.search(ctx, params)
+}
+
+func (s *symbolsTool) outline(ctx context.Context, params SymbolsParams) (ToolResponse, error) {
+	lspClients := s.lspClients.Snapshot()
+	path := absolutePath(params.FilePath)
+	if _, err := os.Stat(path); err != nil {
+		if os.IsNotExist(err) {
+			return NewTextErrorResponse(fmt.Sprintf("file not found: %s", path)), nil
+		}
+		return ToolResponse{}, fmt.Errorf("failed to access file: %w", err)
+	}
+
+	notifyLspOpenFile(ctx, path, lspClients)
+
+	var entries []symbolEntry
+	for _, name := range clientsForFile(lspClients, path) {
+		result, err := lspClients[name].DocumentSymbol(ctx, protocol.DocumentSymbolParams{
+			TextDocument: protocol.TextDocumentIdentifier{URI: protocol.URIFromPath(path)},
+		})
+		if err != nil {
+			logging.Debug("LSP documentSymbol failed", "lsp", name, "error", err)
+			continue
+		}
+		if entries = documentSymbolEntries(result.Value, path); len(entries) > 0 {
+			break
+		}
+	}
+
+	entries = filterSymbols(entries, params.Query, params.Kind)
+	if len(entries) == 0 {
+		return NewTextResponse(fmt.Sprintf("No symbols found in %s", displayPath(path))), nil
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Symbols in %s:\n", displayPath(path))
+	for _, e := range entries {
+		sb.WriteString(strings.Repeat("  ", e.depth))
+		fmt.Fprintf(&sb, "%s %s", symbolKindName(e.kind), e.name)
+		if e.detail != "" {
+			fmt.Fprintf(&sb, " %s", strings.Join(strings.Fields(e.detail), " "))
+		}
+		fmt.Fprintf(&sb, " (%s)\n", lineRange(e.rng))
+	}
+	return NewTextResponse(sb.String()), nil
+}
+
+func (s *symbolsTool) search(ctx context.Context, params SymbolsParams) (ToolResponse, error) {
+	lspClients := s.lspClients.Snapshot()
+	var entries []symbolEntry
+	seen := make(map[string]bool)
+	for _, name := range sortedClients(lspClients) {
+		result, err := lspClients[name].Symbol(ctx, protocol.WorkspaceSymbolParams{Query: params.Query})
+		if err != nil {
+			logging.Debug("LSP workspace/symbol failed", "lsp", name, "error", err)
+			continue
+		}
+		for _, e := range workspaceSymbolEntries(result.Value) {
+			key := fmt.Sprintf("%s:%d:%s", e.path, e.rng.Start.Line, e.name)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			entries = append(entries, e)
+		}
+	}
+
+	entries = filterSymbols(entries, "", params.Kind)
+	if len(entries) == 0 {
+		return NewTextResponse(fmt.Sprintf("No symbols found matching %q", params.Query)), nil
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		ri, rj := matchRank(entries[i].name, params.Query), matchRank(entries[j].name, params.Query)
+		if ri != rj {
+			return ri < rj
+		}
+		return entries[i].path < entries[j].path
+	})
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Found %d symbols matching %q", len(entries), params.Query)
+	if len(entries) > maxWorkspaceSymbols {
+		fmt.Fprintf(&sb, ", showing the first %d", maxWorkspaceSymbols)
+		entries = entries[:maxWorkspaceSymbols]
+	}
+	sb.WriteString(":\n")
+	for _, e := range entries {
+		name := e.name
+		if e.container != "" {
+			name = e.container + "." + e.name
+		}
+		fmt.Fprintf(&sb, "%s %s %s:%d\n", symbolKindName(e.kind), name, displayPath(e.path), e.rng.Start.Line+1)
+	}
+	return NewTextResponse(sb.String()), nil
+}
+
+// documentSymbolEntries flattens a textDocument/documentSymbol result into
+// an outline in document order
+func documentSymbolEntries(value any, path string) []symbolEntry {
+	var entries []symbolEntry
+	switch v := value.(type) {
+	case []protocol.DocumentSymbol:
+		var walk func(symbols []protocol.DocumentSymbol, depth int)
+		walk = func(symbols []protocol.DocumentSymbol, depth int) {
+			sort.SliceStable(symbols, func(i, j int) bool {
+				return symbols[i].Range.Start.Line < symbols[j].Range.Start.Line
+			})
+			for _, sym := range symbols {
+				entries = append(entries, symbolEntry{
+					name:   sym.Name,
+					detail: sym.Detail,
+					kind:   sym.Kind,
+					path:   path,
+					rng:    sym.Range,
+					depth:  depth,
+				})
+				walk(sym.Children, depth+1)
+			}
+		}
+		walk(v, 0)
+	case []protocol.SymbolInformation:
+		// Flat results only carry the container name, nest one level by it
+		sort.SliceStable(v, func(i, j int) bool {
+			return v[i].Location.Range.Start.Line < v[j].Location.Range.Start.Line
+		})
+		for _, sym := range v {
+			depth := 0
+			if sym.ContainerName != "" {
+				depth = 1
+			}
+			entries = append(entries, symbolEntry{
+				name:      sym.Name,
+				kind:      sym.Kind,
+				container: sym.ContainerName,
+				path:      path,
+				rng:       sym.Location.Range,
+				depth:     depth,
+			})
+		}
+	}
+	return entries
+}
+
+// workspaceSymbolEntries converts the result variants of workspace/symbol
+func workspaceSymbolEntries(value any) []symbolEntry {
+	var entries []symbolEntry
+	switch v := value.(type) {
+	case []protocol.SymbolInformation:
+		for _, sym := range v {
+			entries = append(entries, symbolEntry{
+				name:      sym.Name,
+				kind:      sym.Kind,
+				container: sym.ContainerName,
+				path:      sym.Location.URI.Path(),
+				rng:       sym.Location.Range,
+			})
+		}
+	case []protocol.WorkspaceSymbol:
+		for _, sym := range v {
+			entry := symbolEntry{
+				name:      sym.Name,
+				kind:      sym.Kind,
+				container: sym.ContainerName,
+			}
+			switch loc := sym.Location.Value.(type) {
+			case protocol.Location:
+				entry.path = loc.URI.Path()
+				entry.rng = loc.Range
+			case protocol.LocationUriOnly:
+				entry.path = loc.URI.Path()
+			}
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+// filterSymbols keeps the symbols whose name contains query and whose kind
+// matches kind. Outline entries keep their parents so the nesting stays
+// readable.
+func filterSymbols(entries []symbolEntry, query, kind string) []symbolEntry {
+	if query == "" && kind == "" {
+		return entries
+	}
+	query = strings.ToLower(query)
+	kind = strings.ToLower(kind)
+
+	var result []symbolEntry
+	var parents []symbolEntry
+	for _, e := range entries {
+		parents = append(parents[:min(e.depth, len(parents))], e)
+		if query != "" && !strings.Contains(strings.ToLower(e.name), query) {
+			continue
+		}
+		if kind != "" && symbolKindName(e.kind) != kind {
+			continue
+		}
+		// Add parents that are not in the result yet
+		for _, p := range parents[:len(parents)-1] {
+			if !containsSymbol(result, p) {
+				result = append(result, p)
+			}
+		}
+		result = append(result, e)
+	}
+	return result
+}
+
+func containsSymbol(entries []symbolEntry, e symbolEntry) bool {
+	for _, r := range entries {
+		if r.name == e.name && r.rng == e.rng && r.depth == e.depth {
+			return true
+		}
+	}
+	return false
+}
+
+// matchRank orders search results: exact matches first, then prefix and
+// substring matches, then the server's fuzzy matches
+func matchRank(name, query string) int {
+	name, query = strings.ToLower(name), strings.ToLower(query)
+	switch {
+	case name == query:
+		return 0
+	case strings.HasPrefix(name, query):
+		return 1
+	case strings.Contains(name, query):
+		return 2
+	default:
+		return 3
+	}
+}
+
+func lineRange(rng protocol.Range) string {
+	if rng.Start.Line == rng.End.Line {
+		return fmt.Sprintf("line %d", rng.Start.Line+1)
+	}
+	return fmt.Sprintf("lines %d-%d", rng.Start.Line+1, rng.End.Line+1)
+}
@@ -0,0 +1,92 @@
+package tools
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/omnitrix-sh/cli/internal/lsp/protocol"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDocumentSymbolEntries(t *testing.T) {
+	rng := func(start, end uint32) protocol.Range {
+		return protocol.Range{Start: protocol.Position{Line: start}, End: protocol.Position{Line: end}}
+	}
+
+	t.Run("nested symbols in document order", func(t *testing.T) {
+		symbols := []protocol.DocumentSymbol{
+			{Name: "main", Kind: protocol.Function, Range: rng(20, 25)},
+			{Name: "Server", Kind: protocol.Struct, Range: rng(2, 15), Children: []protocol.DocumentSymbol{
+				{Name: "Stop", Kind: protocol.Method, Range: rng(10, 12)},
+				{Name: "Start", Kind: protocol.Method, Range: rng(5, 8)},
+			}},
+		}
+		var got []string
+		for _, e := range documentSymbolEntries(symbols, "main.go") {
+			got = append(got, fmt.Sprintf("%d %s %s", e.depth, symbolKindName(e.kind), e.name))
+		}
+		assert.Equal(t, []string{"0 struct Server", "1 method Start", "1 method Stop", "0 function main"}, got)
+	})
+
+	t.Run("flat symbols nested by container", func(t *testing.T) {
+		symbols := []protocol.SymbolInformation{
+			{Name: "Start", Kind: protocol.Method, ContainerName: "Server", Location: protocol.Location{Range: rng(5, 8)}},
+			{Name: "Server", Kind: protocol.Struct, Location: protocol.Location{Range: rng(2, 15)}},
+		}
+		entries := documentSymbolEntries(symbols, "main.go")
+		if assert.Len(t, entries, 2) {
+			assert.Equal(t, "Server", entries[0].name)
+			assert.Equal(t, 0, entries[0].depth)
+			assert.Equal(t, "Start", entries[1].name)
+			assert.Equal(t, 1, entries[1].depth)
+		}
+	})
+}
+
+func TestFilterSymbols(t *testing.T) {
+	entries := []symbolEntry{
+		{name: "Server", kind: protocol.Struct, rng: protocol.Range{Start: protocol.Position{Line: 2}}},
+		{name: "Start", kind: protocol.Method, rng: protocol.Range{Start: protocol.Position{Line: 5}}, depth: 1},
+		{name: "Stop", kind: protocol.Method, rng: protocol.Range{Start: protocol.Position{Line: 10}}, depth: 1},
+		{name: "startServer", kind: protocol.Function, rng: protocol.Range{Start: protocol.Position{Line: 20}}},
+	}
+
+	tests := []struct {
+		name  string
+		query string
+		kind  string
+		want  []string
+	}{
+		{name: "no filter", want: []string{"Server", "Start", "Stop", "startServer"}},
+		{name: "query is case insensitive", query: "start", want: []string{"Server", "Start", "startServer"}},
+		{name: "kind keeps parents", kind: "Method", want: []string{"Server", "Start", "Stop"}},
+		{name: "query and kind", query: "server", kind: "function", want: []string{"startServer"}},
+		{name: "no match", query: "client", want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, e := range filterSymbols(entries, tt.query, tt.kind) {
+				got = append(got, e.name)
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestMatchRank(t *testing.T) {
+	tests := []struct {
+		name string
+		want int
+	}{
+		{"userservice", 0},
+		{"UserServiceImpl", 1},
+		{"NewUserService", 2},
+		{"UsrSvc", 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, matchRank(tt.name, "UserService"))
+		})
+	}
+}
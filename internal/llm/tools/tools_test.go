@@ -0,0 +1,19 @@
+package tools
+
+import (
+	"testing"
+
+	"github.com/omnitrix-sh/cli/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+// loadTestConfig loads the configuration for a test working in dir and
+// unloads it when the test ends, so it does not leak into other tests
+func loadTestConfig(t *testing.T, dir string) *config.Config {
+	t.Helper()
+	config.Reset()
+	cfg, err := config.Load(dir, false)
+	require.NoError(t, err)
+	t.Cleanup(config.Reset)
+	return cfg
+}
@@ -0,0 +1,121 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/omnitrix-sh/cli/internal/diff"
+	"github.com/omnitrix-sh/cli/internal/history"
+	"github.com/omnitrix-sh/cli/internal/logging"
+	"github.com/omnitrix-sh/cli/internal/lsp/protocol"
+	"github.com/omnitrix-sh/cli/internal/lsp/util"
+)
+
+// fileEdit is the content of a file before and after an LSP workspace edit
+type fileEdit struct {
+	path       string
+	oldContent string
+	newContent string
+}
+
+// resolveWorkspaceEdit computes the result of the text edits in a
+// WorkspaceEdit without writing anything, so the changes can be shown to the
+// user and recorded in the file history before they are applied.
+func resolveWorkspaceEdit(edit protocol.WorkspaceEdit) ([]fileEdit, error) {
+	textEdits := make(map[string][]protocol.TextEdit)
+	for uri, edits := range edit.Changes {
+		path := uri.Path()
+		textEdits[path] = append(textEdits[path], edits...)
+	}
+	for _, change := range edit.DocumentChanges {
+		if change.TextDocumentEdit == nil {
+			return nil, fmt.Errorf("workspace edit contains file operations, which are not supported")
+		}
+		path := change.TextDocumentEdit.TextDocument.URI.Path()
+		for _, e := range change.TextDocumentEdit.Edits {
+			textEdit, err := e.AsTextEdit()
+			if err != nil {
+				return nil, fmt.Errorf("invalid edit for %s: %w", path, err)
+			}
+			textEdits[path] = append(textEdits[path], textEdit)
+		}
+	}
+
+	paths := make([]string, 0, len(textEdits))
+	for path := range textEdits {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	result := make([]fileEdit, 0, len(paths))
+	for _, path := range paths {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		newContent, err := util.ApplyTextEditsToContent(string(content), textEdits[path])
+		if err != nil {
+			return nil, fmt.Errorf("failed to apply edits to %s: %w", path, err)
+		}
+		if newContent == string(content) {
+			continue
+		}
+		result = append(result, fileEdit{
+			path:       path,
+			oldContent: string(content),
+			newContent: newContent,
+		})
+	}
+	return result, nil
+}
+
+// combinedDiff concatenates the diffs of several file edits for display in
+// the permission dialog.
+func combinedDiff(edits []fileEdit) (string, int, int) {
+	var sb strings.Builder
+	totalAdditions, totalRemovals := 0, 0
+	for _, e := range edits {
+		fileDiff, additions, removals := diff.GenerateDiff(e.oldContent, e.newContent, e.path)
+		sb.WriteString(fileDiff)
+		totalAdditions += additions
+		totalRemovals += removals
+	}
+	return sb.String(), totalAdditions, totalRemovals
+}
+
+// writeFileEdits writes resolved edits to disk and records a new history
+// version for every touched file.
+func writeFileEdits(ctx context.Context, files history.Service, sessionID string, edits []fileEdit) error {
+	for _, e := range edits {
+		if err := os.WriteFile(e.path, []byte(e.newContent), 0o644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", e.path, err)
+		}
+		recordFileHistory(ctx, files, sessionID, e.path, e.oldContent, e.newContent)
+		recordFileWrite(e.path)
+		recordFileRead(e.path)
+	}
+	return nil
+}
+
+// recordFileHistory stores newContent as the latest version of path, keeping
+// an intermediate version when the file changed outside of the session.
+func recordFileHistory(ctx context.Context, files history.Service, sessionID, path, oldContent, newContent string) {
+	file, err := files.GetByPathAndSession(ctx, path, sessionID)
+	if err != nil {
+		if _, err = files.Create(ctx, sessionID, path, oldContent); err != nil {
+			logging.Debug("Error creating file history", "error", err)
+			return
+		}
+	} else if file.Content != oldContent {
+		// User manually changed the content, store an intermediate version
+		if _, err = files.CreateVersion(ctx, sessionID, path, oldContent); err != nil {
+			logging.Debug("Error creating file history version", "error", err)
+		}
+	}
+	if _, err = files.CreateVersion(ctx, sessionID, path, newContent); err != nil {
+		logging.Debug("Error creating file history version", "error", err)
+	}
+}
@@ -0,0 +1,102 @@
+package tools
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/omnitrix-sh/cli/internal/lsp/protocol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveWorkspaceEdit(t *testing.T) {
+	dir := t.TempDir()
+	mainFile := filepath.Join(dir, "main.go")
+	handlerFile := filepath.Join(dir, "handler.go")
+	require.NoError(t, os.WriteFile(mainFile, []byte("package main\n\nfunc main() { handler() }\n"), 0o644))
+	require.NoError(t, os.WriteFile(handlerFile, []byte("package main\n\nfunc handler() {}\n"), 0o644))
+
+	edit := func(line, start, end uint32, text string) protocol.TextEdit {
+		return protocol.TextEdit{
+			Range: protocol.Range{
+				Start: protocol.Position{Line: line, Character: start},
+				End:   protocol.Position{Line: line, Character: end},
+			},
+			NewText: text,
+		}
+	}
+	documentEdit := func(path string, edits ...protocol.TextEdit) protocol.DocumentChange {
+		elems := make([]protocol.Or_TextDocumentEdit_edits_Elem, 0, len(edits))
+		for _, e := range edits {
+			elems = append(elems, protocol.Or_TextDocumentEdit_edits_Elem{Value: e})
+		}
+		return protocol.DocumentChange{TextDocumentEdit: &protocol.TextDocumentEdit{
+			TextDocument: protocol.OptionalVersionedTextDocumentIdentifier{
+				TextDocumentIdentifier: protocol.TextDocumentIdentifier{URI: protocol.URIFromPath(path)},
+			},
+			Edits: elems,
+		}}
+	}
+
+	tests := []struct {
+		name    string
+		edit    protocol.WorkspaceEdit
+		want    []fileEdit
+		wantErr string
+	}{
+		{
+			name: "changes in several files, sorted by path",
+			edit: protocol.WorkspaceEdit{Changes: map[protocol.DocumentUri][]protocol.TextEdit{
+				protocol.URIFromPath(mainFile):    {edit(2, 14, 21, "serve")},
+				protocol.URIFromPath(handlerFile): {edit(2, 5, 12, "serve")},
+			}},
+			want: []fileEdit{
+				{path: handlerFile, oldContent: "package main\n\nfunc handler() {}\n", newContent: "package main\n\nfunc serve() {}\n"},
+				{path: mainFile, oldContent: "package main\n\nfunc main() { handler() }\n", newContent: "package main\n\nfunc main() { serve() }\n"},
+			},
+		},
+		{
+			name: "document changes",
+			edit: protocol.WorkspaceEdit{DocumentChanges: []protocol.DocumentChange{
+				documentEdit(mainFile, edit(0, 8, 12, "app"), edit(2, 14, 21, "serve")),
+			}},
+			want: []fileEdit{
+				{path: mainFile, oldContent: "package main\n\nfunc main() { handler() }\n", newContent: "package app\n\nfunc main() { serve() }\n"},
+			},
+		},
+		{
+			name: "files left unchanged are skipped",
+			edit: protocol.WorkspaceEdit{Changes: map[protocol.DocumentUri][]protocol.TextEdit{
+				protocol.URIFromPath(handlerFile): {edit(2, 5, 12, "handler")},
+			}},
+			want: []fileEdit{},
+		},
+		{
+			name: "file operations are not supported",
+			edit: protocol.WorkspaceEdit{DocumentChanges: []protocol.DocumentChange{
+				{RenameFile: &protocol.RenameFile{OldURI: protocol.URIFromPath(mainFile), NewURI: protocol.URIFromPath(handlerFile)}},
+			}},
+			wantErr: "file operations, which are not supported",
+		},
+		{
+			name: "missing file",
+			edit: protocol.WorkspaceEdit{Changes: map[protocol.DocumentUri][]protocol.TextEdit{
+				protocol.URIFromPath(filepath.Join(dir, "missing.go")): {edit(0, 0, 0, "x")},
+			}},
+			wantErr: "failed to read",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resolveWorkspaceEdit(tt.edit)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
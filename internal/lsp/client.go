@@ -4,6 +4,7 @@ import (
 	"bufio"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"os"
@@ -17,6 +18,7 @@ import (
 	"github.com/omnitrix-sh/cli/internal/config"
 	"github.com/omnitrix-sh/cli/internal/logging"
 	"github.com/omnitrix-sh/cli/internal/lsp/protocol"
+	"github.com/omnitrix-sh/cli/internal/lsp/util"
 )
 
 type Client struct {
@@ -50,6 +52,28 @@ type Client struct {
 
 	// Server state
 	serverState atomic.Value
+
+	// Capabilities announced by the server during initialization
+	capabilities protocol.ServerCapabilities
+
+	// Name of the server's LSP configuration, used to route files to it
+	name string
+
+	// File watch registrations received from the server
+	fileWatchRegistrations []fileWatchRegistration
+	fileWatchHandler       FileWatchRegistrationHandler
+	fileWatchMu            sync.Mutex
+
+	// done is closed when the connection to the server is lost, exited when
+	// the server process has exited
+	done     chan struct{}
+	doneOnce sync.Once
+	doneErr  error
+	exited   chan struct{}
+
+	// Writer the JSON-RPC traffic is traced to
+	traceWriter io.Writer
+	traceMu     sync.Mutex
 }
 
 func NewClient(ctx context.Context, command string, args ...string) (*Client, error) {
@@ -82,6 +106,8 @@ func NewClient(ctx context.Context, command string, args ...string) (*Client, er
 		serverRequestHandlers: make(map[string]ServerRequestHandler),
 		diagnostics:           make(map[protocol.DocumentUri][]protocol.Diagnostic),
 		openFiles:             make(map[string]*OpenFileInfo),
+		done:                  make(chan struct{}),
+		exited:                make(chan struct{}),
 	}
 
 	// Initialize server state
@@ -92,8 +118,28 @@ func NewClient(ctx context.Context, command string, args ...string) (*Client, er
 		return nil, fmt.Errorf("failed to start LSP server: %w", err)
 	}
 
+	// Wait closes the pipes, so it is only called once both have been read
+	// to the end
+	stdoutDone := make(chan struct{})
+	stderrDone := make(chan struct{})
+
+	// Watch for the server process exiting
+	go func() {
+		<-stdoutDone
+		<-stderrDone
+		err := cmd.Wait()
+		close(client.exited)
+		if err == nil {
+			err = errors.New("server process exited")
+		} else {
+			err = fmt.Errorf("server process exited: %w", err)
+		}
+		client.stop(err)
+	}()
+
 	// Handle stderr in a separate goroutine
 	go func() {
+		defer close(stderrDone)
 		scanner := bufio.NewScanner(stderr)
 		for scanner.Scan() {
 			fmt.Fprintf(os.Stderr, "LSP Server: %s\n", scanner.Text())
@@ -107,8 +153,18 @@ func NewClient(ctx context.Context, command string, args ...string) (*Client, er
 	go func() {
 		defer logging.RecoverPanic("LSP-message-handler", func() {
 			logging.ErrorPersist("LSP message handler crashed, LSP functionality may be impaired")
+			client.stop(errors.New("LSP message handler crashed"))
 		})
-		client.handleMessages()
+		err := func() error {
+			defer close(stdoutDone)
+			return client.handleMessages()
+		}()
+		// Prefer reporting the exit status when the process died
+		select {
+		case <-client.exited:
+		case <-time.After(time.Second):
+			client.stop(fmt.Errorf("connection to server lost: %w", err))
+		}
 	}()
 
 	return client, nil
@@ -155,6 +211,13 @@ func (c *Client) InitializeLSPClient(ctx context.Context, workspaceDir string) (
 						DynamicRegistration:    true,
 						RelativePatternSupport: true,
 					},
+					FileOperations: &protocol.FileOperationClientCapabilities{
+						WillRename: true,
+						DidRename:  true,
+					},
+					WorkspaceEdit: &protocol.WorkspaceEditClientCapabilities{
+						DocumentChanges: true,
+					},
 				},
 				TextDocument: protocol.TextDocumentClientCapabilities{
 					Synchronization: &protocol.TextDocumentSyncClientCapabilities{
@@ -167,13 +230,36 @@ func (c *Client) InitializeLSPClient(ctx context.Context, workspaceDir string) (
 					CodeLens: &protocol.CodeLensClientCapabilities{
 						DynamicRegistration: true,
 					},
-					DocumentSymbol: protocol.DocumentSymbolClientCapabilities{},
+					DocumentSymbol: protocol.DocumentSymbolClientCapabilities{
+						HierarchicalDocumentSymbolSupport: true,
+					},
+					Rename: &protocol.RenameClientCapabilities{
+						PrepareSupport: true,
+					},
+					Formatting: &protocol.DocumentFormattingClientCapabilities{},
 					CodeAction: protocol.CodeActionClientCapabilities{
 						CodeActionLiteralSupport: protocol.ClientCodeActionLiteralOptions{
 							CodeActionKind: protocol.ClientCodeActionKindOptions{
-								ValueSet: []protocol.CodeActionKind{},
+								ValueSet: []protocol.CodeActionKind{
+									protocol.Empty,
+									protocol.QuickFix,
+									protocol.Refactor,
+									protocol.RefactorExtract,
+									protocol.RefactorInline,
+									protocol.RefactorMove,
+									protocol.RefactorRewrite,
+									protocol.Source,
+									protocol.SourceOrganizeImports,
+									protocol.SourceFixAll,
+								},
 							},
 						},
+						IsPreferredSupport: true,
+						DisabledSupport:    true,
+						DataSupport:        true,
+						ResolveSupport: &protocol.ClientCodeActionResolveOptions{
+							Properties: []string{"edit"},
+						},
 					},
 					PublishDiagnostics: protocol.PublishDiagnosticsClientCapabilities{
 						VersionSupport: true,
@@ -208,6 +294,7 @@ func (c *Client) InitializeLSPClient(ctx context.Context, workspaceDir string) (
 	if err := c.Call(ctx, "initialize", initParams, &result); err != nil {
 		return nil, fmt.Errorf("initialize failed: %w", err)
 	}
+	c.capabilities = result.Capabilities
 
 	if err := c.Notify(ctx, "initialized", struct{}{}); err != nil {
 		return nil, fmt.Errorf("initialized notification failed: %w", err)
@@ -216,7 +303,8 @@ func (c *Client) InitializeLSPClient(ctx context.Context, workspaceDir string) (
 	// Register handlers
 	c.RegisterServerRequestHandler("workspace/applyEdit", HandleApplyEdit)
 	c.RegisterServerRequestHandler("workspace/configuration", HandleWorkspaceConfiguration)
-	c.RegisterServerRequestHandler("client/registerCapability", HandleRegisterCapability)
+	c.RegisterServerRequestHandler("client/registerCapability",
+		func(params json.RawMessage) (any, error) { return HandleRegisterCapability(c, params) })
 	c.RegisterNotificationHandler("window/showMessage", HandleServerMessage)
 	c.RegisterNotificationHandler("textDocument/publishDiagnostics",
 		func(params json.RawMessage) { HandleDiagnostics(c, params) })
@@ -231,6 +319,13 @@ func (c *Client) InitializeLSPClient(ctx context.Context, workspaceDir string) (
 }
 
 func (c *Client) Close() error {
+	// Nothing to clean up once the process has exited
+	select {
+	case <-c.exited:
+		return nil
+	default:
+	}
+
 	// Try to close all open files first
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
@@ -243,16 +338,10 @@ func (c *Client) Close() error {
 		return fmt.Errorf("failed to close stdin: %w", err)
 	}
 
-	// Use a channel to handle the Wait with timeout
-	done := make(chan error, 1)
-	go func() {
-		done <- c.Cmd.Wait()
-	}()
-
 	// Wait for process to exit with timeout
 	select {
-	case err := <-done:
-		return err
+	case <-c.exited:
+		return nil
 	case <-time.After(2 * time.Second):
 		// If we timeout, try to kill the process
 		if err := c.Cmd.Process.Kill(); err != nil {
@@ -262,14 +351,55 @@ func (c *Client) Close() error {
 	}
 }
 
+// stop marks the connection to the server as lost, failing pending requests
+func (c *Client) stop(err error) {
+	c.doneOnce.Do(func() {
+		c.doneErr = err
+		close(c.done)
+		c.SetServerState(StateError)
+	})
+}
+
+// Done returns a channel that is closed when the server process exits or
+// the connection to it is lost.
+func (c *Client) Done() <-chan struct{} {
+	return c.done
+}
+
+// Err returns why the connection to the server was lost, or nil while it is
+// running.
+func (c *Client) Err() error {
+	select {
+	case <-c.done:
+		return c.doneErr
+	default:
+		return nil
+	}
+}
+
 type ServerState int
 
 const (
 	StateStarting ServerState = iota
 	StateReady
 	StateError
+	StateRestarting
 )
 
+func (s ServerState) String() string {
+	switch s {
+	case StateStarting:
+		return "starting"
+	case StateReady:
+		return "ready"
+	case StateError:
+		return "error"
+	case StateRestarting:
+		return "restarting"
+	}
+	return "unknown"
+}
+
 // GetServerState returns the current state of the LSP server
 func (c *Client) GetServerState() ServerState {
 	if val := c.serverState.Load(); val != nil {
@@ -280,7 +410,14 @@ func (c *Client) GetServerState() ServerState {
 
 // SetServerState sets the current state of the LSP server
 func (c *Client) SetServerState(state ServerState) {
-	c.serverState.Store(state)
+	if old := c.serverState.Swap(state); old == state || c.name == "" {
+		return
+	}
+	var err error
+	if state == StateError {
+		err = c.Err()
+	}
+	PublishServerStatus(c.name, state, err)
 }
 
 // WaitForServerReady waits for the server to be ready by polling the server
@@ -601,12 +738,16 @@ func (c *Client) OpenFile(ctx context.Context, filepath string) error {
 		return fmt.Errorf("error reading file: %w", err)
 	}
 
+	return c.openDocument(ctx, uri, string(content))
+}
+
+func (c *Client) openDocument(ctx context.Context, uri, content string) error {
 	params := protocol.DidOpenTextDocumentParams{
 		TextDocument: protocol.TextDocumentItem{
 			URI:        protocol.DocumentUri(uri),
 			LanguageID: DetectLanguageID(uri),
 			Version:    1,
-			Text:       string(content),
+			Text:       content,
 		},
 	}
 
@@ -632,11 +773,15 @@ func (c *Client) NotifyChange(ctx context.Context, filepath string) error {
 		return fmt.Errorf("error reading file: %w", err)
 	}
 
+	return c.changeDocument(ctx, uri, string(content))
+}
+
+func (c *Client) changeDocument(ctx context.Context, uri, content string) error {
 	c.openFilesMu.Lock()
 	fileInfo, isOpen := c.openFiles[uri]
 	if !isOpen {
 		c.openFilesMu.Unlock()
-		return fmt.Errorf("cannot notify change for unopened file: %s", filepath)
+		return fmt.Errorf("cannot notify change for unopened file: %s", uri)
 	}
 
 	// Increment version
@@ -654,7 +799,7 @@ func (c *Client) NotifyChange(ctx context.Context, filepath string) error {
 		ContentChanges: []protocol.TextDocumentContentChangeEvent{
 			{
 				Value: protocol.TextDocumentContentChangeWholeDocument{
-					Text: string(content),
+					Text: content,
 				},
 			},
 		},
@@ -694,6 +839,173 @@ func (c *Client) CloseFile(ctx context.Context, filepath string) error {
 	return nil
 }
 
+// RenameFile moves an open document from oldPath to newPath and sends
+// workspace/didRenameFiles to servers that asked for it.
+func (c *Client) RenameFile(ctx context.Context, oldPath, newPath string) error {
+	wasOpen := c.IsFileOpen(oldPath)
+	if wasOpen {
+		if err := c.CloseFile(ctx, oldPath); err != nil {
+			return err
+		}
+	}
+
+	if ops := c.fileOperations(); ops != nil && ops.DidRename != nil {
+		err := c.DidRenameFiles(ctx, protocol.RenameFilesParams{
+			Files: []protocol.FileRename{{
+				OldURI: "file://" + oldPath,
+				NewURI: "file://" + newPath,
+			}},
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if wasOpen {
+		return c.OpenFile(ctx, newPath)
+	}
+	return nil
+}
+
+// WillRenameFile asks the server for the edits it wants applied before
+// oldPath is moved to newPath, such as updated import paths. An empty edit is
+// returned when the server doesn't handle workspace/willRenameFiles.
+func (c *Client) WillRenameFile(ctx context.Context, oldPath, newPath string) (protocol.WorkspaceEdit, error) {
+	if ops := c.fileOperations(); ops == nil || ops.WillRename == nil {
+		return protocol.WorkspaceEdit{}, nil
+	}
+	return c.WillRenameFiles(ctx, protocol.RenameFilesParams{
+		Files: []protocol.FileRename{{
+			OldURI: "file://" + oldPath,
+			NewURI: "file://" + newPath,
+		}},
+	})
+}
+
+func (c *Client) fileOperations() *protocol.FileOperationOptions {
+	if c.capabilities.Workspace == nil {
+		return nil
+	}
+	return c.capabilities.Workspace.FileOperations
+}
+
+// ServerCapabilities returns the capabilities the server announced in its
+// initialize response
+func (c *Client) ServerCapabilities() protocol.ServerCapabilities {
+	return c.capabilities
+}
+
+// RenameSupport reports whether the server handles textDocument/rename and
+// textDocument/prepareRename
+func (c *Client) RenameSupport() (rename bool, prepare bool) {
+	switch v := c.capabilities.RenameProvider.(type) {
+	case bool:
+		return v, false
+	case map[string]any:
+		prepare, _ := v["prepareProvider"].(bool)
+		return true, prepare
+	case protocol.RenameOptions:
+		return true, v.PrepareProvider
+	}
+	return false, false
+}
+
+// SetName associates the client with its LSP configuration.
+func (c *Client) SetName(name string) {
+	c.name = name
+}
+
+// Name returns the name of the client's LSP configuration.
+func (c *Client) Name() string {
+	return c.name
+}
+
+// HandlesFile reports whether path should be sent to the server according
+// to the filetypes and root markers of its configuration. Clients without a
+// configuration handle every file.
+func (c *Client) HandlesFile(path string) bool {
+	cfg := config.Get()
+	if c.name == "" || cfg == nil {
+		return true
+	}
+	lspCfg, ok := cfg.LSP[c.name]
+	if !ok {
+		return true
+	}
+	return HandlesFile(c.name, lspCfg, path)
+}
+
+// FormattingSupport reports whether the server can format whole documents.
+func (c *Client) FormattingSupport() bool {
+	if c.capabilities.DocumentFormattingProvider == nil {
+		return false
+	}
+	switch v := c.capabilities.DocumentFormattingProvider.Value.(type) {
+	case bool:
+		return v
+	case protocol.DocumentFormattingOptions:
+		return true
+	}
+	return false
+}
+
+// FormatContent asks the server to format content as the text of filepath
+// without writing it. The server is sent the content for the request and the
+// file as it is on disk afterwards, files that do not exist yet are closed
+// again.
+func (c *Client) FormatContent(ctx context.Context, filepath, content string, options protocol.FormattingOptions) (string, error) {
+	uri := fmt.Sprintf("file://%s", filepath)
+
+	if !c.IsFileOpen(filepath) {
+		if _, err := os.Stat(filepath); os.IsNotExist(err) {
+			if err := c.openDocument(ctx, uri, content); err != nil {
+				return "", err
+			}
+			defer func() {
+				if err := c.CloseFile(ctx, filepath); err != nil {
+					logging.Debug("Failed to close formatted file", "file", filepath, "error", err)
+				}
+			}()
+			return c.formatDocument(ctx, uri, content, options)
+		}
+		if err := c.OpenFile(ctx, filepath); err != nil {
+			return "", err
+		}
+	}
+
+	if err := c.changeDocument(ctx, uri, content); err != nil {
+		return "", err
+	}
+	defer func() {
+		if err := c.NotifyChange(ctx, filepath); err != nil {
+			logging.Debug("Failed to restore formatted file", "file", filepath, "error", err)
+		}
+	}()
+	return c.formatDocument(ctx, uri, content, options)
+}
+
+func (c *Client) formatDocument(ctx context.Context, uri, content string, options protocol.FormattingOptions) (string, error) {
+	edits, err := c.Formatting(ctx, protocol.DocumentFormattingParams{
+		TextDocument: protocol.TextDocumentIdentifier{URI: protocol.DocumentUri(uri)},
+		Options:      options,
+	})
+	if err != nil {
+		return "", err
+	}
+	return util.ApplyTextEditsToContent(content, edits)
+}
+
+// OpenFilePaths returns the paths of the files open in the server.
+func (c *Client) OpenFilePaths() []string {
+	c.openFilesMu.RLock()
+	defer c.openFilesMu.RUnlock()
+	paths := make([]string, 0, len(c.openFiles))
+	for uri := range c.openFiles {
+		paths = append(paths, strings.TrimPrefix(uri, "file://"))
+	}
+	return paths
+}
+
 func (c *Client) IsFileOpen(filepath string) bool {
 	uri := fmt.Sprintf("file://%s", filepath)
 	c.openFilesMu.RLock()
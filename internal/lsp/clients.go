@@ -0,0 +1,58 @@
+package lsp
+
+import (
+	"maps"
+	"sync"
+)
+
+// Clients holds the running clients by the name of their configuration. It
+// is safe for concurrent use, as servers are started and restarted while the
+// tools and the TUI read it.
+type Clients struct {
+	mu      sync.RWMutex
+	clients map[string]*Client
+}
+
+func NewClients() *Clients {
+	return &Clients{clients: make(map[string]*Client)}
+}
+
+// Get returns the client running under name
+func (c *Clients) Get(name string) (*Client, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	client, ok := c.clients[name]
+	return client, ok
+}
+
+// Set makes client the one running under name
+func (c *Clients) Set(name string, client *Client) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.clients[name] = client
+}
+
+// Remove removes the client running under name if it is still client, so
+// that a stopped client does not remove the one that replaced it
+func (c *Clients) Remove(name string, client *Client) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.clients[name] == client {
+		delete(c.clients, name)
+	}
+}
+
+// Snapshot returns a copy of the running clients, which can be iterated while
+// servers restart
+func (c *Clients) Snapshot() map[string]*Client {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return maps.Clone(c.clients)
+}
+
+// Len returns the number of running clients
+func (c *Clients) Len() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return len(c.clients)
+}
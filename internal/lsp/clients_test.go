@@ -0,0 +1,27 @@
+package lsp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClients(t *testing.T) {
+	clients := NewClients()
+	old, restarted := &Client{}, &Client{}
+
+	clients.Set("gopls", old)
+	snapshot := clients.Snapshot()
+	clients.Set("gopls", restarted)
+
+	// A stopped client does not remove the one that replaced it
+	clients.Remove("gopls", old)
+	client, ok := clients.Get("gopls")
+	assert.True(t, ok)
+	assert.Same(t, restarted, client)
+	assert.Same(t, old, snapshot["gopls"])
+
+	clients.Remove("gopls", restarted)
+	assert.Equal(t, 0, clients.Len())
+	assert.Len(t, snapshot, 1)
+}
@@ -0,0 +1,176 @@
+package lsp
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+
+	"github.com/omnitrix-sh/cli/internal/config"
+)
+
+// serverCommand is a way to run a language server
+type serverCommand struct {
+	command string
+	args    []string
+}
+
+// knownServer describes a language server that can be set up automatically
+// for projects containing one of its marker files
+type knownServer struct {
+	name     string
+	language string
+	markers  []string
+	commands []serverCommand
+	install  string
+}
+
+var knownServers = []knownServer{
+	{
+		name:     "go",
+		language: "Go",
+		markers:  []string{"go.mod", "go.work"},
+		commands: []serverCommand{{command: "gopls"}},
+		install:  "go install golang.org/x/tools/gopls@latest",
+	},
+	{
+		name:     "typescript",
+		language: "TypeScript/JavaScript",
+		markers:  []string{"tsconfig.json", "jsconfig.json", "package.json"},
+		commands: []serverCommand{{command: "typescript-language-server", args: []string{"--stdio"}}},
+		install:  "npm install -g typescript-language-server typescript",
+	},
+	{
+		name:     "rust",
+		language: "Rust",
+		markers:  []string{"Cargo.toml"},
+		commands: []serverCommand{{command: "rust-analyzer"}},
+		install:  "rustup component add rust-analyzer",
+	},
+	{
+		name:     "python",
+		language: "Python",
+		markers:  []string{"pyproject.toml", "setup.py", "setup.cfg", "requirements.txt", "Pipfile"},
+		commands: []serverCommand{
+			{command: "pyright-langserver", args: []string{"--stdio"}},
+			{command: "pylsp"},
+		},
+		install: "npm install -g pyright",
+	},
+	{
+		name:     "clangd",
+		language: "C/C++",
+		markers:  []string{"compile_commands.json", "CMakeLists.txt", ".clangd"},
+		commands: []serverCommand{{command: "clangd"}},
+		install:  "install clangd with your system package manager",
+	},
+}
+
+// DetectedServer is a language server suggested for a language found in the
+// project.
+type DetectedServer struct {
+	// Name is the key of the server's entry in the lsp configuration
+	Name     string
+	Language string
+	// Marker is the file, relative to the project root, the language was
+	// detected by
+	Marker string
+	// Command and Args run the server, Command is empty when none of the
+	// known servers for the language is installed
+	Command string
+	Args    []string
+	// Install is a hint on how to install the server
+	Install string
+	// Configured is set when the lsp configuration already has a server for
+	// the language
+	Configured bool
+}
+
+// Available reports whether the server is installed and not configured yet.
+func (d DetectedServer) Available() bool {
+	return d.Command != "" && !d.Configured
+}
+
+// Config returns the lsp configuration entry for the server.
+func (d DetectedServer) Config() config.LSPConfig {
+	return config.LSPConfig{
+		Command: d.Command,
+		Args:    d.Args,
+	}
+}
+
+// DetectServers finds the languages used in the project at workingDir by
+// their marker files, in the project root or a directory directly below it,
+// and looks up language servers for them on PATH.
+func DetectServers(workingDir string) []DetectedServer {
+	dirs := []string{workingDir}
+	if entries, err := os.ReadDir(workingDir); err == nil {
+		for _, entry := range entries {
+			if entry.IsDir() && !shouldSkipDir(entry.Name()) {
+				dirs = append(dirs, filepath.Join(workingDir, entry.Name()))
+			}
+		}
+	}
+
+	var configured map[string]config.LSPConfig
+	if cfg := config.Get(); cfg != nil {
+		configured = cfg.LSP
+	}
+
+	var detected []DetectedServer
+	for _, server := range knownServers {
+		marker := findMarker(dirs, server.markers)
+		if marker == "" {
+			continue
+		}
+		if rel, err := filepath.Rel(workingDir, marker); err == nil {
+			marker = rel
+		}
+
+		d := DetectedServer{
+			Name:       server.name,
+			Language:   server.language,
+			Marker:     marker,
+			Install:    server.install,
+			Configured: server.isConfigured(configured),
+		}
+		for _, c := range server.commands {
+			if _, err := exec.LookPath(c.command); err == nil {
+				d.Command = c.command
+				d.Args = c.args
+				break
+			}
+		}
+		detected = append(detected, d)
+	}
+	return detected
+}
+
+// isConfigured reports whether an lsp entry exists for the server, either
+// under its name or running one of its commands
+func (s knownServer) isConfigured(configured map[string]config.LSPConfig) bool {
+	if _, ok := configured[s.name]; ok {
+		return true
+	}
+	for _, lspCfg := range configured {
+		if slices.ContainsFunc(s.commands, func(c serverCommand) bool {
+			return filepath.Base(lspCfg.Command) == c.command
+		}) {
+			return true
+		}
+	}
+	return false
+}
+
+// findMarker returns the path of the first marker file found in dirs
+func findMarker(dirs []string, markers []string) string {
+	for _, dir := range dirs {
+		for _, marker := range markers {
+			path := filepath.Join(dir, marker)
+			if _, err := os.Stat(path); err == nil {
+				return path
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,36 @@
+package lsp
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetectServers(t *testing.T) {
+	project := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(project, "go.mod"), []byte("module x\n"), 0o644))
+	require.NoError(t, os.MkdirAll(filepath.Join(project, "web"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(project, "web", "package.json"), []byte("{}"), 0o644))
+	require.NoError(t, os.MkdirAll(filepath.Join(project, "node_modules", "dep"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(project, "node_modules", "Cargo.toml"), nil, 0o644))
+
+	bin := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(bin, "gopls"), []byte("#!/bin/sh\n"), 0o755))
+	t.Setenv("PATH", bin)
+
+	detected := DetectServers(project)
+	require.Len(t, detected, 2)
+
+	assert.Equal(t, "go", detected[0].Name)
+	assert.Equal(t, "go.mod", detected[0].Marker)
+	assert.Equal(t, "gopls", detected[0].Command)
+	assert.True(t, detected[0].Available())
+
+	assert.Equal(t, "typescript", detected[1].Name)
+	assert.Equal(t, filepath.Join("web", "package.json"), detected[1].Marker)
+	assert.Empty(t, detected[1].Command)
+	assert.False(t, detected[1].Available())
+}
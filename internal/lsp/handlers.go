@@ -2,6 +2,7 @@ package lsp
 
 import (
 	"encoding/json"
+	"slices"
 
 	"github.com/omnitrix-sh/cli/internal/config"
 	"github.com/omnitrix-sh/cli/internal/logging"
@@ -15,7 +16,7 @@ func HandleWorkspaceConfiguration(params json.RawMessage) (any, error) {
 	return []map[string]any{{}}, nil
 }
 
-func HandleRegisterCapability(params json.RawMessage) (any, error) {
+func HandleRegisterCapability(client *Client, params json.RawMessage) (any, error) {
 	var registerParams protocol.RegistrationParams
 	if err := json.Unmarshal(params, &registerParams); err != nil {
 		logging.Error("Error unmarshaling registration params", "error", err)
@@ -39,7 +40,7 @@ func HandleRegisterCapability(params json.RawMessage) (any, error) {
 			}
 
 			// Store the file watchers registrations
-			notifyFileWatchRegistration(reg.ID, options.Watchers)
+			client.addFileWatchRegistration(reg.ID, options.Watchers)
 		}
 	}
 
@@ -64,18 +65,35 @@ func HandleApplyEdit(params json.RawMessage) (any, error) {
 // FileWatchRegistrationHandler is a function that will be called when file watch registrations are received
 type FileWatchRegistrationHandler func(id string, watchers []protocol.FileSystemWatcher)
 
-// fileWatchHandler holds the current handler for file watch registrations
-var fileWatchHandler FileWatchRegistrationHandler
+// fileWatchRegistration is a file watch registration received from a server
+type fileWatchRegistration struct {
+	id       string
+	watchers []protocol.FileSystemWatcher
+}
 
-// RegisterFileWatchHandler sets the handler for file watch registrations
-func RegisterFileWatchHandler(handler FileWatchRegistrationHandler) {
-	fileWatchHandler = handler
+// RegisterFileWatchHandler sets the handler for the client's file watch
+// registrations. Registrations received before the handler was set, such as
+// those made during initialization, are passed to it right away.
+func (c *Client) RegisterFileWatchHandler(handler FileWatchRegistrationHandler) {
+	c.fileWatchMu.Lock()
+	c.fileWatchHandler = handler
+	registrations := slices.Clone(c.fileWatchRegistrations)
+	c.fileWatchMu.Unlock()
+
+	for _, reg := range registrations {
+		handler(reg.id, reg.watchers)
+	}
 }
 
-// notifyFileWatchRegistration notifies the handler about new file watch registrations
-func notifyFileWatchRegistration(id string, watchers []protocol.FileSystemWatcher) {
-	if fileWatchHandler != nil {
-		fileWatchHandler(id, watchers)
+// addFileWatchRegistration stores a registration and notifies the handler
+func (c *Client) addFileWatchRegistration(id string, watchers []protocol.FileSystemWatcher) {
+	c.fileWatchMu.Lock()
+	c.fileWatchRegistrations = append(c.fileWatchRegistrations, fileWatchRegistration{id: id, watchers: watchers})
+	handler := c.fileWatchHandler
+	c.fileWatchMu.Unlock()
+
+	if handler != nil {
+		handler(id, watchers)
 	}
 }
 
@@ -1,12 +1,119 @@
 package lsp
 
 import (
+	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 
+	"github.com/omnitrix-sh/cli/internal/config"
 	"github.com/omnitrix-sh/cli/internal/lsp/protocol"
 )
 
+// serverLanguages lists the languages of well known servers, for
+// configurations named after their server rather than a language
+var serverLanguages = map[string][]protocol.LanguageKind{
+	"gopls":                      {protocol.LangGo},
+	"rust-analyzer":              {protocol.LangRust},
+	"pyright-langserver":         {protocol.LangPython},
+	"pylsp":                      {protocol.LangPython},
+	"typescript-language-server": {protocol.LangTypeScript, protocol.LangTypeScriptReact, protocol.LangJavaScript, protocol.LangJavaScriptReact},
+	"clangd":                     {protocol.LangC, protocol.LangCPP},
+	"jdtls":                      {protocol.LangJava},
+	"lua-language-server":        {protocol.LangLua},
+}
+
+// HandlesFile reports whether the LSP configured under name handles path.
+// Configured filetypes, language IDs or extensions, take precedence. Without
+// them a configuration named after a language handles that language, and one
+// named after a well known server, or running one, handles its languages.
+// Other servers with a command handle every file. When root markers are
+// configured, one of them must also exist in a directory above path.
+func HandlesFile(name string, lspCfg config.LSPConfig, path string) bool {
+	if len(lspCfg.RootMarkers) > 0 && !hasRootMarker(filepath.Dir(path), lspCfg.RootMarkers) {
+		return false
+	}
+
+	language := DetectLanguageID(path)
+	if len(lspCfg.Filetypes) > 0 {
+		ext := strings.ToLower(filepath.Ext(path))
+		for _, ft := range lspCfg.Filetypes {
+			ft = strings.ToLower(ft)
+			if ft == string(language) || "."+strings.TrimPrefix(ft, ".") == ext {
+				return true
+			}
+		}
+		return false
+	}
+
+	if isLanguage(name) {
+		return handlesLanguage(name, language)
+	}
+	if languages, ok := serverLanguages[name]; ok {
+		return slices.Contains(languages, language)
+	}
+	if lspCfg.Command == "" {
+		return false
+	}
+	if languages, ok := serverLanguages[filepath.Base(lspCfg.Command)]; ok {
+		return slices.Contains(languages, language)
+	}
+	return true
+}
+
+// handlesLanguage reports whether a configuration named after a language
+// handles files of language. typescript and javascript configurations also
+// cover their react variants.
+func handlesLanguage(name string, language protocol.LanguageKind) bool {
+	return string(language) == name || string(language) == name+"react"
+}
+
+// isLanguage reports whether name is a language ID
+func isLanguage(name string) bool {
+	for _, language := range knownLanguages {
+		if handlesLanguage(name, language) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasRootMarker reports whether dir or one of its parents contains one of
+// the marker files
+func hasRootMarker(dir string, markers []string) bool {
+	for {
+		for _, marker := range markers {
+			if _, err := os.Stat(filepath.Join(dir, marker)); err == nil {
+				return true
+			}
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return false
+		}
+		dir = parent
+	}
+}
+
+// knownLanguages lists the language IDs DetectLanguageID recognises
+var knownLanguages = []protocol.LanguageKind{
+	protocol.LangABAP, protocol.LangWindowsBat, protocol.LangBibTeX, protocol.LangClojure,
+	protocol.LangCoffeescript, protocol.LangC, protocol.LangCPP, protocol.LangCSharp,
+	protocol.LangCSS, protocol.LangD, protocol.LangDelphi, protocol.LangDiff,
+	protocol.LangDart, protocol.LangDockerfile, protocol.LangElixir, protocol.LangErlang,
+	protocol.LangFSharp, protocol.LangGitCommit, protocol.LangGitRebase, protocol.LangGo,
+	protocol.LangGroovy, protocol.LangHandlebars, protocol.LangHaskell, protocol.LangHTML,
+	protocol.LangIni, protocol.LangJava, protocol.LangJavaScript,
+	protocol.LangJavaScriptReact, protocol.LangJSON, protocol.LangLaTeX, protocol.LangLess,
+	protocol.LangLua, protocol.LangMakefile, protocol.LangMarkdown, protocol.LangObjectiveC,
+	protocol.LangObjectiveCPP, protocol.LangPerl, protocol.LangPerl6, protocol.LangPHP,
+	protocol.LangPowershell, protocol.LangPug, protocol.LangPython, protocol.LangR,
+	protocol.LangRazor, protocol.LangRuby, protocol.LangRust, protocol.LangSCSS,
+	protocol.LangSASS, protocol.LangScala, protocol.LangShaderLab, protocol.LangShellScript,
+	protocol.LangSQL, protocol.LangSwift, protocol.LangTypeScript,
+	protocol.LangTypeScriptReact, protocol.LangXML, protocol.LangXSL, protocol.LangYAML,
+}
+
 func DetectLanguageID(uri string) protocol.LanguageKind {
 	ext := strings.ToLower(filepath.Ext(uri))
 	switch ext {
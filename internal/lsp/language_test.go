@@ -0,0 +1,65 @@
+package lsp
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/omnitrix-sh/cli/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandlesFile(t *testing.T) {
+	t.Run("well known server by name", func(t *testing.T) {
+		assert.True(t, HandlesFile("gopls", config.LSPConfig{}, "/src/main.go"))
+		assert.False(t, HandlesFile("gopls", config.LSPConfig{}, "/src/app.ts"))
+	})
+
+	t.Run("well known server by command", func(t *testing.T) {
+		cfg := config.LSPConfig{Command: "/usr/local/bin/typescript-language-server"}
+		assert.True(t, HandlesFile("typescript", cfg, "/src/app.tsx"))
+		assert.False(t, HandlesFile("typescript", cfg, "/src/main.go"))
+	})
+
+	t.Run("filetypes by language and extension", func(t *testing.T) {
+		cfg := config.LSPConfig{Command: "gopls", Filetypes: []string{"python", ".pyi"}}
+		assert.True(t, HandlesFile("custom", cfg, "/src/app.py"))
+		assert.True(t, HandlesFile("custom", cfg, "/src/app.PYI"))
+		assert.False(t, HandlesFile("custom", cfg, "/src/main.go"))
+	})
+
+	t.Run("unknown server handles every file", func(t *testing.T) {
+		assert.True(t, HandlesFile("custom", config.LSPConfig{Command: "custom-ls"}, "/src/main.go"))
+		assert.False(t, HandlesFile("custom", config.LSPConfig{}, "/src/main.go"))
+	})
+
+	t.Run("configuration named after a language", func(t *testing.T) {
+		tests := []struct {
+			name   string
+			cfg    config.LSPConfig
+			path   string
+			handle bool
+		}{
+			{"typescript", config.LSPConfig{FormatOnWrite: true, Command: "prettier"}, "/src/main.go", false},
+			{"typescript", config.LSPConfig{FormatOnWrite: true, Command: "prettier"}, "/src/app.tsx", true},
+			{"python", config.LSPConfig{Command: "pyright"}, "/src/main.go", false},
+			{"python", config.LSPConfig{Command: "pyright"}, "/src/app.py", true},
+			{"go", config.LSPConfig{}, "/src/main.go", true},
+		}
+		for _, tt := range tests {
+			assert.Equal(t, tt.handle, HandlesFile(tt.name, tt.cfg, tt.path), "%s %s", tt.name, tt.path)
+		}
+	})
+
+	t.Run("root markers", func(t *testing.T) {
+		dir := t.TempDir()
+		project := filepath.Join(dir, "project")
+		require.NoError(t, os.MkdirAll(filepath.Join(project, "pkg"), 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(project, "go.mod"), nil, 0o644))
+
+		cfg := config.LSPConfig{RootMarkers: []string{"go.mod"}}
+		assert.True(t, HandlesFile("gopls", cfg, filepath.Join(project, "pkg", "main.go")))
+		assert.False(t, HandlesFile("gopls", cfg, filepath.Join(dir, "main.go")))
+	})
+}
@@ -0,0 +1,32 @@
+package lsp
+
+import (
+	"context"
+
+	"github.com/omnitrix-sh/cli/internal/pubsub"
+)
+
+// ServerStatus is published whenever the state of a configured language
+// server changes.
+type ServerStatus struct {
+	Name  string
+	State ServerState
+	Error string
+}
+
+var statusBroker = pubsub.NewBroker[ServerStatus]()
+
+// PublishServerStatus announces the state of the language server configured
+// as name, with the error that caused it if any.
+func PublishServerStatus(name string, state ServerState, err error) {
+	status := ServerStatus{Name: name, State: state}
+	if err != nil {
+		status.Error = err.Error()
+	}
+	statusBroker.Publish(pubsub.UpdatedEvent, status)
+}
+
+// SubscribeStatus returns the status changes of the language servers.
+func SubscribeStatus(ctx context.Context) <-chan pubsub.Event[ServerStatus] {
+	return statusBroker.Subscribe(ctx)
+}
@@ -0,0 +1,48 @@
+package lsp
+
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
+// SetTraceWriter makes the client write every JSON-RPC message exchanged with
+// the server to w, one line per message. A nil writer turns tracing off.
+func (c *Client) SetTraceWriter(w io.Writer) {
+	c.traceMu.Lock()
+	defer c.traceMu.Unlock()
+	c.traceWriter = w
+}
+
+// trace writes msg to the trace writer, if any. outgoing is set for messages
+// sent to the server.
+func (c *Client) trace(outgoing bool, msg *Message) {
+	c.traceMu.Lock()
+	defer c.traceMu.Unlock()
+	if c.traceWriter == nil {
+		return
+	}
+
+	prefix := time.Now().Format("15:04:05.000")
+	if c.name != "" {
+		prefix += " [" + c.name + "]"
+	}
+	if outgoing {
+		prefix += " -->"
+	} else {
+		prefix += " <--"
+	}
+
+	var line string
+	switch {
+	case msg.Method != "" && msg.ID != 0:
+		line = fmt.Sprintf("request #%d %s %s", msg.ID, msg.Method, msg.Params)
+	case msg.Method != "":
+		line = fmt.Sprintf("notification %s %s", msg.Method, msg.Params)
+	case msg.Error != nil:
+		line = fmt.Sprintf("error #%d %d %s", msg.ID, msg.Error.Code, msg.Error.Message)
+	default:
+		line = fmt.Sprintf("response #%d %s", msg.ID, msg.Result)
+	}
+	fmt.Fprintf(c.traceWriter, "%s %s\n", prefix, line)
+}
@@ -89,8 +89,9 @@ func ReadMessage(r *bufio.Reader) (*Message, error) {
 	return &msg, nil
 }
 
-// handleMessages reads and dispatches messages in a loop
-func (c *Client) handleMessages() {
+// handleMessages reads and dispatches messages in a loop until reading
+// fails, returning why
+func (c *Client) handleMessages() error {
 	cnf := config.Get()
 	for {
 		msg, err := ReadMessage(c.stdout)
@@ -98,8 +99,9 @@ func (c *Client) handleMessages() {
 			if cnf.DebugLSP {
 				logging.Error("Error reading message", "error", err)
 			}
-			return
+			return err
 		}
+		c.trace(false, msg)
 
 		// Handle server->client request (has both Method and ID)
 		if msg.Method != "" && msg.ID != 0 {
@@ -143,6 +145,7 @@ func (c *Client) handleMessages() {
 			}
 
 			// Send response back to server
+			c.trace(true, response)
 			if err := WriteMessage(c.stdin, response); err != nil {
 				logging.Error("Error sending response to server", "error", err)
 			}
@@ -213,6 +216,7 @@ func (c *Client) Call(ctx context.Context, method string, params any, result any
 	}()
 
 	// Send request
+	c.trace(true, msg)
 	if err := WriteMessage(c.stdin, msg); err != nil {
 		return fmt.Errorf("failed to send request: %w", err)
 	}
@@ -222,7 +226,14 @@ func (c *Client) Call(ctx context.Context, method string, params any, result any
 	}
 
 	// Wait for response
-	resp := <-ch
+	var resp *Message
+	select {
+	case resp = <-ch:
+	case <-c.done:
+		return fmt.Errorf("server stopped: %w", c.doneErr)
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 
 	if cnf.DebugLSP {
 		logging.Debug("Received response", "id", id)
@@ -259,6 +270,7 @@ func (c *Client) Notify(ctx context.Context, method string, params any) error {
 		return fmt.Errorf("failed to create notification: %w", err)
 	}
 
+	c.trace(true, msg)
 	if err := WriteMessage(c.stdin, msg); err != nil {
 		return fmt.Errorf("failed to send notification: %w", err)
 	}
@@ -0,0 +1,85 @@
+package watcher
+
+import (
+	"context"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// ChangeWatcher reports files in the workspace that are written, removed or
+// renamed. Unlike WorkspaceWatcher it does not depend on a language server, so
+// it can track edits made outside of the session for every file.
+type ChangeWatcher struct {
+	onChange func(path string)
+
+	debounceTime time.Duration
+	debounceMap  map[string]*time.Timer
+	debounceMu   sync.Mutex
+}
+
+// NewChangeWatcher creates a watcher that calls onChange with the absolute
+// path of every changed file, once the file has stopped changing
+func NewChangeWatcher(onChange func(path string)) *ChangeWatcher {
+	return &ChangeWatcher{
+		onChange:     onChange,
+		debounceTime: 300 * time.Millisecond,
+		debounceMap:  make(map[string]*time.Timer),
+	}
+}
+
+// Watch reports the changes in workspace until the context is cancelled
+func (w *ChangeWatcher) Watch(ctx context.Context, workspace *Workspace) {
+	events := workspace.Subscribe(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			w.stopTimers()
+			return
+		case event, ok := <-events:
+			if !ok {
+				w.stopTimers()
+				return
+			}
+
+			if event.Op&fsnotify.Create != 0 {
+				if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+					continue
+				}
+			}
+
+			if event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Remove|fsnotify.Rename) != 0 {
+				w.debounceChange(event.Name)
+			}
+		}
+	}
+}
+
+func (w *ChangeWatcher) debounceChange(path string) {
+	w.debounceMu.Lock()
+	defer w.debounceMu.Unlock()
+
+	if timer, exists := w.debounceMap[path]; exists {
+		timer.Stop()
+	}
+
+	w.debounceMap[path] = time.AfterFunc(w.debounceTime, func() {
+		w.debounceMu.Lock()
+		delete(w.debounceMap, path)
+		w.debounceMu.Unlock()
+
+		w.onChange(path)
+	})
+}
+
+func (w *ChangeWatcher) stopTimers() {
+	w.debounceMu.Lock()
+	defer w.debounceMu.Unlock()
+
+	for path, timer := range w.debounceMap {
+		timer.Stop()
+		delete(w.debounceMap, path)
+	}
+}
@@ -303,9 +303,11 @@ func (w *WorkspaceWatcher) openHighPriorityFiles(ctx context.Context, serverName
 	return filesOpened
 }
 
-// WatchWorkspace sets up file watching for a workspace
-func (w *WorkspaceWatcher) WatchWorkspace(ctx context.Context, workspacePath string) {
+// WatchWorkspace forwards the file events of a workspace to the language
+// server until the context is canceled
+func (w *WorkspaceWatcher) WatchWorkspace(ctx context.Context, workspace *Workspace) {
 	cnf := config.Get()
+	workspacePath := workspace.Path()
 	w.workspacePath = workspacePath
 
 	// Store the watcher in the context for later use
@@ -321,73 +323,33 @@ func (w *WorkspaceWatcher) WatchWorkspace(ctx context.Context, workspacePath str
 	logging.Debug("Starting workspace watcher", "workspacePath", workspacePath, "serverName", serverName)
 
 	// Register handler for file watcher registrations from the server
-	lsp.RegisterFileWatchHandler(func(id string, watchers []protocol.FileSystemWatcher) {
+	w.client.RegisterFileWatchHandler(func(id string, watchers []protocol.FileSystemWatcher) {
 		w.AddRegistrations(ctx, id, watchers)
 	})
 
-	watcher, err := fsnotify.NewWatcher()
-	if err != nil {
-		logging.Error("Error creating watcher", "error", err)
-	}
-	defer watcher.Close()
-
-	// Watch the workspace recursively
-	err = filepath.WalkDir(workspacePath, func(path string, d os.DirEntry, err error) error {
-		if err != nil {
-			return err
-		}
-
-		// Skip excluded directories (except workspace root)
-		if d.IsDir() && path != workspacePath {
-			if shouldExcludeDir(path) {
-				if cnf.DebugLSP {
-					logging.Debug("Skipping excluded directory", "path", path)
-				}
-				return filepath.SkipDir
-			}
-		}
-
-		// Add directories to watcher
-		if d.IsDir() {
-			err = watcher.Add(path)
-			if err != nil {
-				logging.Error("Error watching path", "path", path, "error", err)
-			}
-		}
-
-		return nil
-	})
-	if err != nil {
-		logging.Error("Error walking workspace", "error", err)
-	}
+	// Unsubscribe when the watcher stops for any reason, so the workspace
+	// does not wait for it
+	subCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	events := workspace.Subscribe(subCtx)
 
 	// Event loop
 	for {
 		select {
 		case <-ctx.Done():
 			return
-		case event, ok := <-watcher.Events:
+		case event, ok := <-events:
 			if !ok {
 				return
 			}
 
 			uri := fmt.Sprintf("file://%s", event.Name)
 
-			// Add new directories to the watcher
+			// Open newly created files, the workspace watches new directories
 			if event.Op&fsnotify.Create != 0 {
-				if info, err := os.Stat(event.Name); err == nil {
-					if info.IsDir() {
-						// Skip excluded directories
-						if !shouldExcludeDir(event.Name) {
-							if err := watcher.Add(event.Name); err != nil {
-								logging.Error("Error adding directory to watcher", "path", event.Name, "error", err)
-							}
-						}
-					} else {
-						// For newly created files
-						if !shouldExcludeFile(event.Name) {
-							w.openMatchingFile(ctx, event.Name)
-						}
+				if info, err := os.Stat(event.Name); err == nil && !info.IsDir() {
+					if !shouldExcludeFile(event.Name) {
+						w.openMatchingFile(ctx, event.Name)
 					}
 				}
 			}
@@ -440,15 +402,40 @@ func (w *WorkspaceWatcher) WatchWorkspace(ctx context.Context, workspacePath str
 					}
 				}
 			}
-		case err, ok := <-watcher.Errors:
-			if !ok {
-				return
-			}
-			logging.Error("Error watching file", "error", err)
 		}
 	}
 }
 
+// addWorkspaceDirs adds the workspace root and all of its non-excluded
+// subdirectories to an fsnotify watcher
+func addWorkspaceDirs(watcher *fsnotify.Watcher, workspacePath string) error {
+	cnf := config.Get()
+	return filepath.WalkDir(workspacePath, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		// Skip excluded directories (except workspace root)
+		if d.IsDir() && path != workspacePath {
+			if shouldExcludeDir(path) {
+				if cnf.DebugLSP {
+					logging.Debug("Skipping excluded directory", "path", path)
+				}
+				return filepath.SkipDir
+			}
+		}
+
+		// Add directories to watcher
+		if d.IsDir() {
+			if err := watcher.Add(path); err != nil {
+				logging.Error("Error watching path", "path", path, "error", err)
+			}
+		}
+
+		return nil
+	})
+}
+
 // isPathWatched checks if a path should be watched based on server registrations
 func (w *WorkspaceWatcher) isPathWatched(path string) (bool, protocol.WatchKind) {
 	w.registrationMu.RLock()
@@ -920,7 +907,7 @@ func (w *WorkspaceWatcher) openMatchingFile(ctx context.Context, path string) {
 				shouldOpen = false
 			}
 
-			if shouldOpen {
+			if shouldOpen && w.client.HandlesFile(path) {
 				// Don't need to check if it's already open - the client.OpenFile handles that
 				if err := w.client.OpenFile(ctx, path); err != nil && cnf.DebugLSP {
 					logging.Error("Error opening file", "path", path, "error", err)
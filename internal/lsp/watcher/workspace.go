@@ -0,0 +1,136 @@
+package watcher
+
+import (
+	"context"
+	"maps"
+	"os"
+	"sync"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/omnitrix-sh/cli/internal/logging"
+)
+
+// eventBufferSize is the number of events buffered for each subscriber
+const eventBufferSize = 256
+
+// Workspace watches the directories of a workspace with a single fsnotify
+// watcher and hands its events to every subscriber, such as the change
+// watcher and the workspace watcher of each language server. Events are
+// never dropped: a subscriber that falls behind holds up the others until
+// it catches up or unsubscribes.
+type Workspace struct {
+	path string
+
+	mu      sync.Mutex
+	subs    map[chan fsnotify.Event]context.Context
+	stopped bool
+}
+
+// NewWorkspace creates a watcher for the workspace at path, which watches it
+// once Watch is called
+func NewWorkspace(path string) *Workspace {
+	return &Workspace{
+		path: path,
+		subs: make(map[chan fsnotify.Event]context.Context),
+	}
+}
+
+// Path returns the root of the workspace
+func (w *Workspace) Path() string {
+	return w.path
+}
+
+// Subscribe returns the events for the files and directories of the
+// workspace until ctx is canceled. The channel is closed when the workspace
+// stops being watched.
+func (w *Workspace) Subscribe(ctx context.Context) <-chan fsnotify.Event {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	ch := make(chan fsnotify.Event, eventBufferSize)
+	if w.stopped {
+		close(ch)
+		return ch
+	}
+	w.subs[ch] = ctx
+
+	go func() {
+		<-ctx.Done()
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		delete(w.subs, ch)
+	}()
+	return ch
+}
+
+// Watch watches the workspace recursively until ctx is canceled, adding the
+// directories created in it as they appear
+func (w *Workspace) Watch(ctx context.Context) {
+	defer w.stop()
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		logging.Error("Error creating workspace watcher", "error", err)
+		return
+	}
+	defer watcher.Close()
+
+	if err := addWorkspaceDirs(watcher, w.path); err != nil {
+		logging.Error("Error walking workspace", "error", err)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+
+			if event.Op&fsnotify.Create != 0 {
+				if info, err := os.Stat(event.Name); err == nil && info.IsDir() && !shouldExcludeDir(event.Name) {
+					if err := addWorkspaceDirs(watcher, event.Name); err != nil {
+						logging.Error("Error adding directory to watcher", "path", event.Name, "error", err)
+					}
+				}
+			}
+			w.publish(ctx, event)
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			logging.Error("Error watching workspace", "error", err)
+		}
+	}
+}
+
+// publish sends event to every subscriber, waiting for those whose buffer is
+// full
+func (w *Workspace) publish(ctx context.Context, event fsnotify.Event) {
+	w.mu.Lock()
+	subs := maps.Clone(w.subs)
+	w.mu.Unlock()
+
+	for ch, subCtx := range subs {
+		select {
+		case ch <- event:
+		case <-subCtx.Done():
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// stop closes the channels of the subscribers once the workspace is no
+// longer watched
+func (w *Workspace) stop() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.stopped = true
+	for ch := range w.subs {
+		delete(w.subs, ch)
+		close(ch)
+	}
+}
@@ -0,0 +1,90 @@
+package watcher
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/omnitrix-sh/cli/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWorkspace(t *testing.T) {
+	dir := t.TempDir()
+	_, err := config.Load(dir, false)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	workspace := NewWorkspace(dir)
+	first := workspace.Subscribe(ctx)
+	second := workspace.Subscribe(ctx)
+	// A subscriber that stopped reading does not hold up the others
+	idleCtx, stopIdle := context.WithCancel(ctx)
+	workspace.Subscribe(idleCtx)
+	stopIdle()
+
+	done := make(chan struct{})
+	go func() {
+		workspace.Watch(ctx)
+		close(done)
+	}()
+
+	// wait returns the first event for path
+	wait := func(events <-chan fsnotify.Event, path string) fsnotify.Event {
+		t.Helper()
+		timeout := time.After(5 * time.Second)
+		for {
+			select {
+			case event := <-events:
+				if event.Name == path {
+					return event
+				}
+			case <-timeout:
+				t.Fatalf("no event for %s", path)
+				return fsnotify.Event{}
+			}
+		}
+	}
+
+	// Retry until the watcher has added the workspace directories
+	file := filepath.Join(dir, "main.go")
+	require.Eventually(t, func() bool {
+		require.NoError(t, os.WriteFile(file, []byte("package main\n"), 0o644))
+		select {
+		case event := <-first:
+			return event.Name == file
+		case <-time.After(100 * time.Millisecond):
+			return false
+		}
+	}, 5*time.Second, 10*time.Millisecond)
+	wait(second, file)
+
+	// Directories created after the start are watched too
+	sub := filepath.Join(dir, "pkg")
+	require.NoError(t, os.Mkdir(sub, 0o755))
+	assert.True(t, wait(first, sub).Has(fsnotify.Create))
+	wait(second, sub)
+
+	nested := filepath.Join(sub, "lib.go")
+	require.Eventually(t, func() bool {
+		require.NoError(t, os.WriteFile(nested, []byte("package pkg\n"), 0o644))
+		select {
+		case event := <-first:
+			return event.Name == nested
+		case <-time.After(100 * time.Millisecond):
+			return false
+		}
+	}, 5*time.Second, 10*time.Millisecond)
+	wait(second, nested)
+
+	cancel()
+	<-done
+	_, ok := <-workspace.Subscribe(context.Background())
+	assert.False(t, ok, "subscribing after the workspace stopped returns a closed channel")
+}
@@ -37,6 +37,15 @@ type ContentPart interface {
 
 type ReasoningContent struct {
 	Thinking string `json:"thinking"`
+	// Encrypted holds the reasoning of providers that only return it
+	// encrypted, to be sent back with the following requests
+	Encrypted []EncryptedReasoning `json:"encrypted,omitempty"`
+}
+
+// EncryptedReasoning is an opaque reasoning item returned by a provider.
+type EncryptedReasoning struct {
+	ID      string `json:"id"`
+	Content string `json:"content"`
 }
 
 func (tc ReasoningContent) String() string {
@@ -116,6 +125,26 @@ type Message struct {
 	Model     models.ModelID
 	CreatedAt int64
 	UpdatedAt int64
+	// Usage is the usage of the request that produced an assistant message
+	Usage Usage
+}
+
+// Usage is the tokens, the cost and the timing of a request to a model
+type Usage struct {
+	InputTokens         int64
+	OutputTokens        int64
+	CacheReadTokens     int64
+	CacheCreationTokens int64
+	Cost                float64
+	// Latency is the time the whole response took and TimeToFirstToken the
+	// time until its first token was streamed
+	Latency          time.Duration
+	TimeToFirstToken time.Duration
+}
+
+// Tokens returns the number of tokens of all kinds
+func (u Usage) Tokens() int64 {
+	return u.InputTokens + u.OutputTokens + u.CacheReadTokens + u.CacheCreationTokens
 }
 
 func (m *Message) Content() TextContent {
@@ -227,7 +256,8 @@ func (m *Message) AppendReasoningContent(delta string) {
 	found := false
 	for i, part := range m.Parts {
 		if c, ok := part.(ReasoningContent); ok {
-			m.Parts[i] = ReasoningContent{Thinking: c.Thinking + delta}
+			c.Thinking += delta
+			m.Parts[i] = c
 			found = true
 		}
 	}
@@ -236,6 +266,17 @@ func (m *Message) AppendReasoningContent(delta string) {
 	}
 }
 
+func (m *Message) SetEncryptedReasoning(encrypted []EncryptedReasoning) {
+	for i, part := range m.Parts {
+		if c, ok := part.(ReasoningContent); ok {
+			c.Encrypted = encrypted
+			m.Parts[i] = c
+			return
+		}
+	}
+	m.Parts = append(m.Parts, ReasoningContent{Encrypted: encrypted})
+}
+
 func (m *Message) FinishToolCall(toolCallID string) {
 	for i, part := range m.Parts {
 		if c, ok := part.(ToolCall); ok {
@@ -111,7 +111,16 @@ func (s *service) Update(ctx context.Context, message Message) error {
 	err = s.q.UpdateMessage(ctx, db.UpdateMessageParams{
 		ID:         message.ID,
 		Parts:      string(parts),
+		Model:      sql.NullString{String: string(message.Model), Valid: true},
 		FinishedAt: finishedAt,
+
+		InputTokens:         message.Usage.InputTokens,
+		OutputTokens:        message.Usage.OutputTokens,
+		CacheReadTokens:     message.Usage.CacheReadTokens,
+		CacheCreationTokens: message.Usage.CacheCreationTokens,
+		Cost:                message.Usage.Cost,
+		LatencyMs:           message.Usage.Latency.Milliseconds(),
+		FirstTokenMs:        message.Usage.TimeToFirstToken.Milliseconds(),
 	})
 	if err != nil {
 		return err
@@ -157,6 +166,15 @@ func (s *service) fromDBItem(item db.Message) (Message, error) {
 		Model:     models.ModelID(item.Model.String),
 		CreatedAt: item.CreatedAt,
 		UpdatedAt: item.UpdatedAt,
+		Usage: Usage{
+			InputTokens:         item.InputTokens,
+			OutputTokens:        item.OutputTokens,
+			CacheReadTokens:     item.CacheReadTokens,
+			CacheCreationTokens: item.CacheCreationTokens,
+			Cost:                item.Cost,
+			Latency:             time.Duration(item.LatencyMs) * time.Millisecond,
+			TimeToFirstToken:    time.Duration(item.FirstTokenMs) * time.Millisecond,
+		},
 	}, nil
 }
 
@@ -3,8 +3,10 @@ package session
 import (
 	"context"
 	"database/sql"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/omnitrix-sh/cli/internal/config"
 	"github.com/omnitrix-sh/cli/internal/db"
 	"github.com/omnitrix-sh/cli/internal/pubsub"
 )
@@ -20,6 +22,36 @@ type Session struct {
 	Cost             float64
 	CreatedAt        int64
 	UpdatedAt        int64
+
+	// InputTokens, OutputTokens, CacheReadTokens and CacheCreationTokens are
+	// the totals of all the requests made in the session
+	InputTokens         int64
+	OutputTokens        int64
+	CacheReadTokens     int64
+	CacheCreationTokens int64
+
+	// Project is the working directory the session was created in
+	Project string
+}
+
+// Usage is the spend and the tokens of one or more sessions
+type Usage struct {
+	Cost   float64
+	Tokens int64
+}
+
+// Add returns the sum of two usages
+func (u Usage) Add(other Usage) Usage {
+	return Usage{Cost: u.Cost + other.Cost, Tokens: u.Tokens + other.Tokens}
+}
+
+// Usage returns the spend and the tokens of all the requests made in the
+// session
+func (s Session) Usage() Usage {
+	return Usage{
+		Cost:   s.Cost,
+		Tokens: s.InputTokens + s.OutputTokens + s.CacheReadTokens + s.CacheCreationTokens,
+	}
 }
 
 type Service interface {
@@ -31,6 +63,9 @@ type Service interface {
 	List(ctx context.Context) ([]Session, error)
 	Save(ctx context.Context, session Session) (Session, error)
 	Delete(ctx context.Context, id string) error
+	// Totals returns the usage of the sessions of the current project updated
+	// since a time, including the usage of their task sessions
+	Totals(ctx context.Context, since time.Time) (Usage, error)
 }
 
 type service struct {
@@ -40,8 +75,9 @@ type service struct {
 
 func (s *service) Create(ctx context.Context, title string) (Session, error) {
 	dbSession, err := s.q.CreateSession(ctx, db.CreateSessionParams{
-		ID:    uuid.New().String(),
-		Title: title,
+		ID:      uuid.New().String(),
+		Title:   title,
+		Project: config.WorkingDirectory(),
 	})
 	if err != nil {
 		return Session{}, err
@@ -56,6 +92,7 @@ func (s *service) CreateTaskSession(ctx context.Context, toolCallID, parentSessi
 		ID:              toolCallID,
 		ParentSessionID: sql.NullString{String: parentSessionID, Valid: true},
 		Title:           title,
+		Project:         config.WorkingDirectory(),
 	})
 	if err != nil {
 		return Session{}, err
@@ -70,6 +107,7 @@ func (s *service) CreateTitleSession(ctx context.Context, parentSessionID string
 		ID:              "title-" + parentSessionID,
 		ParentSessionID: sql.NullString{String: parentSessionID, Valid: true},
 		Title:           "Generate a title",
+		Project:         config.WorkingDirectory(),
 	})
 	if err != nil {
 		return Session{}, err
@@ -110,7 +148,11 @@ func (s *service) Save(ctx context.Context, session Session) (Session, error) {
 			String: session.SummaryMessageID,
 			Valid:  session.SummaryMessageID != "",
 		},
-		Cost: session.Cost,
+		Cost:                session.Cost,
+		InputTokens:         session.InputTokens,
+		CacheReadTokens:     session.CacheReadTokens,
+		CacheCreationTokens: session.CacheCreationTokens,
+		OutputTokens:        session.OutputTokens,
 	})
 	if err != nil {
 		return Session{}, err
@@ -132,6 +174,17 @@ func (s *service) List(ctx context.Context) ([]Session, error) {
 	return sessions, nil
 }
 
+func (s *service) Totals(ctx context.Context, since time.Time) (Usage, error) {
+	totals, err := s.q.GetUsageTotals(ctx, db.GetUsageTotalsParams{
+		Since:   since.Unix(),
+		Project: config.WorkingDirectory(),
+	})
+	if err != nil {
+		return Usage{}, err
+	}
+	return Usage{Cost: totals.Cost, Tokens: totals.Tokens}, nil
+}
+
 func (s service) fromDBItem(item db.Session) Session {
 	return Session{
 		ID:               item.ID,
@@ -144,6 +197,12 @@ func (s service) fromDBItem(item db.Session) Session {
 		Cost:             item.Cost,
 		CreatedAt:        item.CreatedAt,
 		UpdatedAt:        item.UpdatedAt,
+
+		InputTokens:         item.InputTokens,
+		CacheReadTokens:     item.CacheReadTokens,
+		CacheCreationTokens: item.CacheCreationTokens,
+		OutputTokens:        item.OutputTokens,
+		Project:             item.Project,
 	}
 }
 
@@ -152,12 +152,23 @@ func renderAssistantMessage(
 	if finished {
 		switch finishData.Reason {
 		case message.FinishReasonEndTurn:
-			took := formatTimestampDiff(msg.CreatedAt, finishData.Time)
+			details := []string{formatTimestampDiff(msg.CreatedAt, finishData.Time)}
+			if msg.Usage.Tokens() > 0 {
+				details = append(details, formatUsage(msg.Usage))
+			}
 			info = append(info, baseStyle.
 				Width(width-1).
 				Foreground(t.TextMuted()).
-				Render(fmt.Sprintf(" %s (%s)", models.SupportedModels[msg.Model].Name, took)),
+				Render(fmt.Sprintf(" %s (%s)", models.SupportedModels[msg.Model].Name, strings.Join(details, ", "))),
 			)
+		case message.FinishReasonToolUse:
+			if msg.Usage.Tokens() > 0 {
+				info = append(info, baseStyle.
+					Width(width-1).
+					Foreground(t.TextMuted()).
+					Render(fmt.Sprintf(" %s (%s)", models.SupportedModels[msg.Model].Name, formatUsage(msg.Usage))),
+				)
+			}
 		case message.FinishReasonCanceled:
 			info = append(info, baseStyle.
 				Width(width-1).
@@ -253,6 +264,20 @@ func toolName(name string) string {
 		return fmt.Sprintf("%s Write", styles.EditIcon)
 	case tools.PatchToolName:
 		return fmt.Sprintf("%s Patch", styles.EditIcon)
+	case tools.DefinitionToolName:
+		return fmt.Sprintf("%s Definition", styles.CodeIcon)
+	case tools.ReferencesToolName:
+		return fmt.Sprintf("%s References", styles.CodeIcon)
+	case tools.HoverToolName:
+		return fmt.Sprintf("%s Hover", styles.CodeIcon)
+	case tools.RenameSymbolToolName:
+		return fmt.Sprintf("%s Rename", styles.EditIcon)
+	case tools.SymbolsToolName:
+		return fmt.Sprintf("%s Symbols", styles.CodeIcon)
+	case tools.HierarchyToolName:
+		return fmt.Sprintf("%s Hierarchy", styles.CodeIcon)
+	case tools.CodeActionsToolName:
+		return fmt.Sprintf("%s Code Actions", styles.EditIcon)
 	}
 	return fmt.Sprintf("%s %s", styles.Dot, name)
 }
@@ -281,6 +306,20 @@ func getToolAction(name string) string {
 		return "Preparing write..."
 	case tools.PatchToolName:
 		return "Preparing patch..."
+	case tools.DefinitionToolName:
+		return "Finding definition..."
+	case tools.ReferencesToolName:
+		return "Finding references..."
+	case tools.HoverToolName:
+		return "Reading symbol info..."
+	case tools.RenameSymbolToolName:
+		return "Preparing rename..."
+	case tools.SymbolsToolName:
+		return "Finding symbols..."
+	case tools.HierarchyToolName:
+		return "Building hierarchy..."
+	case tools.CodeActionsToolName:
+		return "Finding code actions..."
 	}
 	return "Working..."
 }
@@ -362,7 +401,15 @@ func renderToolParams(paramWidth int, toolCall message.ToolCall) string {
 		var params tools.EditParams
 		json.Unmarshal([]byte(toolCall.Input), &params)
 		filePath := removeWorkingDirPrefix(params.FilePath)
-		return renderParams(paramWidth, filePath)
+		toolParams := []string{
+			filePath,
+		}
+		if len(params.Edits) > 0 {
+			toolParams = append(toolParams, "edits", fmt.Sprintf("%d", len(params.Edits)))
+		} else if params.ReplaceAll {
+			toolParams = append(toolParams, "replace_all", "true")
+		}
+		return renderParams(paramWidth, toolParams...)
 	case tools.FetchToolName:
 		var params tools.FetchParams
 		json.Unmarshal([]byte(toolCall.Input), &params)
@@ -436,6 +483,70 @@ func renderToolParams(paramWidth int, toolCall message.ToolCall) string {
 		json.Unmarshal([]byte(toolCall.Input), &params)
 		filePath := removeWorkingDirPrefix(params.FilePath)
 		return renderParams(paramWidth, filePath)
+	case tools.DefinitionToolName:
+		var params tools.DefinitionParams
+		json.Unmarshal([]byte(toolCall.Input), &params)
+		toolParams := symbolPositionParams(params.SymbolPositionParams)
+		if params.Kind != "" {
+			toolParams = append(toolParams, "kind", params.Kind)
+		}
+		return renderParams(paramWidth, toolParams...)
+	case tools.ReferencesToolName:
+		var params tools.ReferencesParams
+		json.Unmarshal([]byte(toolCall.Input), &params)
+		return renderParams(paramWidth, symbolPositionParams(params.SymbolPositionParams)...)
+	case tools.HoverToolName:
+		var params tools.HoverParams
+		json.Unmarshal([]byte(toolCall.Input), &params)
+		return renderParams(paramWidth, symbolPositionParams(params.SymbolPositionParams)...)
+	case tools.RenameSymbolToolName:
+		var params tools.RenameSymbolParams
+		json.Unmarshal([]byte(toolCall.Input), &params)
+		toolParams := append(symbolPositionParams(params.SymbolPositionParams), "new_name", params.NewName)
+		return renderParams(paramWidth, toolParams...)
+	case tools.CodeActionsToolName:
+		var params tools.CodeActionsParams
+		json.Unmarshal([]byte(toolCall.Input), &params)
+		toolParams := []string{
+			removeWorkingDirPrefix(params.FilePath),
+		}
+		if params.Line != 0 {
+			toolParams = append(toolParams, "line", fmt.Sprintf("%d", params.Line))
+		}
+		if params.Kind != "" {
+			toolParams = append(toolParams, "kind", params.Kind)
+		}
+		if params.Apply != 0 {
+			toolParams = append(toolParams, "apply", fmt.Sprintf("%d", params.Apply))
+		}
+		return renderParams(paramWidth, toolParams...)
+	case tools.HierarchyToolName:
+		var params tools.HierarchyParams
+		json.Unmarshal([]byte(toolCall.Input), &params)
+		toolParams := symbolPositionParams(params.SymbolPositionParams)
+		if params.Direction != "" {
+			toolParams = append(toolParams, "direction", params.Direction)
+		}
+		if params.Depth != 0 {
+			toolParams = append(toolParams, "depth", fmt.Sprintf("%d", params.Depth))
+		}
+		return renderParams(paramWidth, toolParams...)
+	case tools.SymbolsToolName:
+		var params tools.SymbolsParams
+		json.Unmarshal([]byte(toolCall.Input), &params)
+		var toolParams []string
+		if params.FilePath != "" {
+			toolParams = append(toolParams, removeWorkingDirPrefix(params.FilePath))
+			if params.Query != "" {
+				toolParams = append(toolParams, "query", params.Query)
+			}
+		} else {
+			toolParams = append(toolParams, params.Query)
+		}
+		if params.Kind != "" {
+			toolParams = append(toolParams, "kind", params.Kind)
+		}
+		return renderParams(paramWidth, toolParams...)
 	default:
 		input := strings.ReplaceAll(toolCall.Input, "\n", " ")
 		params = renderParams(paramWidth, input)
@@ -443,6 +554,23 @@ func renderToolParams(paramWidth int, toolCall message.ToolCall) string {
 	return params
 }
 
+// symbolPositionParams renders the parameters of the LSP navigation tools
+func symbolPositionParams(params tools.SymbolPositionParams) []string {
+	toolParams := []string{
+		removeWorkingDirPrefix(params.FilePath),
+	}
+	if params.Symbol != "" {
+		toolParams = append(toolParams, "symbol", params.Symbol)
+	}
+	if params.Line != 0 {
+		toolParams = append(toolParams, "line", fmt.Sprintf("%d", params.Line))
+	}
+	if params.Column != 0 {
+		toolParams = append(toolParams, "column", fmt.Sprintf("%d", params.Column))
+	}
+	return toolParams
+}
+
 func truncateHeight(content string, height int) string {
 	lines := strings.Split(content, "\n")
 	if len(lines) > height {
@@ -477,9 +605,13 @@ func renderToolResponse(toolCall message.ToolCall, response message.ToolResult,
 			toMarkdown(resultContent, true, width),
 			t.Background(),
 		)
-	case tools.EditToolName:
+	case tools.EditToolName, tools.RenameSymbolToolName, tools.CodeActionsToolName:
 		metadata := tools.EditResponseMetadata{}
 		json.Unmarshal([]byte(response.Metadata), &metadata)
+		if metadata.Diff == "" {
+			// Listing code actions doesn't change anything
+			return baseStyle.Width(width).Foreground(t.TextMuted()).Render(resultContent)
+		}
 		truncDiff := truncateHeight(metadata.Diff, maxResultHeight)
 		formattedDiff, _ := diff.FormatDiff(truncDiff, diff.WithTotalWidth(width))
 		return formattedDiff
@@ -506,6 +638,8 @@ func renderToolResponse(toolCall message.ToolCall, response message.ToolResult,
 		return baseStyle.Width(width).Foreground(t.TextMuted()).Render(resultContent)
 	case tools.SourcegraphToolName:
 		return baseStyle.Width(width).Foreground(t.TextMuted()).Render(resultContent)
+	case tools.DefinitionToolName, tools.ReferencesToolName, tools.HoverToolName, tools.SymbolsToolName, tools.HierarchyToolName:
+		return baseStyle.Width(width).Foreground(t.TextMuted()).Render(resultContent)
 	case tools.ViewToolName:
 		metadata := tools.ViewResponseMetadata{}
 		json.Unmarshal([]byte(response.Metadata), &metadata)
@@ -659,6 +793,23 @@ func renderToolMessage(
 	return toolMsg
 }
 
+// formatUsage describes the tokens, the cost and the time to first token of a
+// response
+func formatUsage(usage message.Usage) string {
+	input := usage.InputTokens + usage.CacheReadTokens + usage.CacheCreationTokens
+	details := []string{
+		formatTokenCount(input) + " in",
+		formatTokenCount(usage.OutputTokens) + " out",
+	}
+	if usage.Cost > 0 {
+		details = append(details, fmt.Sprintf("$%.3f", usage.Cost))
+	}
+	if usage.TimeToFirstToken > 0 {
+		details = append(details, "first token "+formatTimestampDiff(0, usage.TimeToFirstToken.Milliseconds()))
+	}
+	return strings.Join(details, ", ")
+}
+
 // Helper function to format the time difference between two Unix timestamps
 func formatTimestampDiff(start, end int64) string {
 	diffSeconds := float64(end-start) / 1000.0 // Convert to seconds
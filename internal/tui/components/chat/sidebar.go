@@ -5,12 +5,17 @@ import (
 	"fmt"
 	"sort"
 	"strings"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/omnitrix-sh/cli/internal/config"
 	"github.com/omnitrix-sh/cli/internal/diff"
 	"github.com/omnitrix-sh/cli/internal/history"
+	"github.com/omnitrix-sh/cli/internal/llm/models"
+	"github.com/omnitrix-sh/cli/internal/llm/tools"
+	"github.com/omnitrix-sh/cli/internal/logging"
+	"github.com/omnitrix-sh/cli/internal/message"
 	"github.com/omnitrix-sh/cli/internal/pubsub"
 	"github.com/omnitrix-sh/cli/internal/session"
 	"github.com/omnitrix-sh/cli/internal/tui/styles"
@@ -21,13 +26,29 @@ type sidebarCmp struct {
 	width, height int
 	session       session.Session
 	history       history.Service
-	modFiles      map[string]struct {
+	messages      message.Service
+	// turns are the model and usage of the assistant messages of the
+	// session, by message ID
+	turns    map[string]turn
+	modFiles map[string]struct {
 		additions int
 		removals  int
 	}
+	// files changed outside of the session since the agent read them
+	driftedFiles map[string]bool
+}
+
+type turn struct {
+	model models.ModelID
+	usage message.Usage
 }
 
 func (m *sidebarCmp) Init() tea.Cmd {
+	for _, path := range tools.DriftedFiles() {
+		m.driftedFiles[path] = true
+	}
+	m.loadTurns(context.Background())
+
 	if m.history != nil {
 		ctx := context.Background()
 		// Subscribe to file events
@@ -57,6 +78,15 @@ func (m *sidebarCmp) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.session = msg
 			ctx := context.Background()
 			m.loadModifiedFiles(ctx)
+			m.loadTurns(ctx)
+		}
+	case pubsub.Event[message.Message]:
+		if msg.Payload.SessionID == m.session.ID && msg.Payload.Role == message.Assistant {
+			if msg.Type == pubsub.DeletedEvent {
+				delete(m.turns, msg.Payload.ID)
+			} else if msg.Payload.Usage.Tokens() > 0 {
+				m.turns[msg.Payload.ID] = turn{model: msg.Payload.Model, usage: msg.Payload.Usage}
+			}
 		}
 	case pubsub.Event[session.Session]:
 		if msg.Type == pubsub.UpdatedEvent {
@@ -64,11 +94,22 @@ func (m *sidebarCmp) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.session = msg.Payload
 			}
 		}
+	case pubsub.Event[tools.FileDrift]:
+		if msg.Type == pubsub.DeletedEvent {
+			delete(m.driftedFiles, msg.Payload.Path)
+		} else {
+			m.driftedFiles[msg.Payload.Path] = true
+		}
 	case pubsub.Event[history.File]:
 		if msg.Payload.SessionID == m.session.ID {
-			// Process the individual file change instead of reloading all files
 			ctx := context.Background()
-			m.processFileChanges(ctx, msg.Payload)
+			if msg.Type == pubsub.UpdatedEvent {
+				// Updates can move a file's history to another path, reload everything
+				m.loadModifiedFiles(ctx)
+			} else {
+				// Process the individual file change instead of reloading all files
+				m.processFileChanges(ctx, msg.Payload)
+			}
 
 			// Return a command to continue receiving events
 			return m, func() tea.Msg {
@@ -95,10 +136,13 @@ func (m *sidebarCmp) View() string {
 				header(m.width),
 				" ",
 				m.sessionSection(),
+				m.cacheSection(),
+				m.modelsSection(),
 				" ",
 				lspsConfigured(m.width),
 				" ",
 				m.modifiedFiles(),
+				m.externalChanges(),
 			),
 		)
 }
@@ -124,6 +168,110 @@ func (m *sidebarCmp) sessionSection() string {
 	)
 }
 
+// cacheSection shows how much of the session's input was read from the
+// provider's prompt cache
+func (m *sidebarCmp) cacheSection() string {
+	read, written := m.session.CacheReadTokens, m.session.CacheCreationTokens
+	total := m.session.InputTokens + read + written
+	if read+written == 0 || total == 0 {
+		return ""
+	}
+
+	t := theme.CurrentTheme()
+	baseStyle := styles.BaseStyle()
+
+	cacheKey := baseStyle.
+		Foreground(t.Primary()).
+		Bold(true).
+		Render("Cache")
+
+	cacheValue := baseStyle.
+		Foreground(t.TextMuted()).
+		Width(m.width - lipgloss.Width(cacheKey)).
+		Render(fmt.Sprintf(": %d%% hits, %s read, %s written",
+			read*100/total, formatTokenCount(read), formatTokenCount(written)))
+
+	return lipgloss.JoinHorizontal(
+		lipgloss.Left,
+		cacheKey,
+		cacheValue,
+	)
+}
+
+// modelsSection compares the models that answered in the session by the
+// number of turns, the cost and the average time to first token
+func (m *sidebarCmp) modelsSection() string {
+	type modelUsage struct {
+		name             string
+		turns            int
+		cost             float64
+		timeToFirstToken time.Duration
+		timed            int
+	}
+	byModel := make(map[models.ModelID]*modelUsage)
+	for _, turn := range m.turns {
+		usage, ok := byModel[turn.model]
+		if !ok {
+			usage = &modelUsage{name: string(turn.model)}
+			if model, ok := models.SupportedModels[turn.model]; ok {
+				usage.name = model.Name
+			}
+			byModel[turn.model] = usage
+		}
+		usage.turns++
+		usage.cost += turn.usage.Cost
+		if turn.usage.TimeToFirstToken > 0 {
+			usage.timeToFirstToken += turn.usage.TimeToFirstToken
+			usage.timed++
+		}
+	}
+	if len(byModel) == 0 {
+		return ""
+	}
+	usages := make([]*modelUsage, 0, len(byModel))
+	for _, usage := range byModel {
+		usages = append(usages, usage)
+	}
+	sort.Slice(usages, func(i, j int) bool {
+		return usages[i].cost > usages[j].cost
+	})
+
+	t := theme.CurrentTheme()
+	baseStyle := styles.BaseStyle()
+
+	views := []string{" ", baseStyle.
+		Width(m.width).
+		Foreground(t.Primary()).
+		Bold(true).
+		Render("Models:")}
+	for _, usage := range usages {
+		details := fmt.Sprintf("%d turns, $%.2f", usage.turns, usage.cost)
+		if usage.timed > 0 {
+			average := usage.timeToFirstToken / time.Duration(usage.timed)
+			details += fmt.Sprintf(", first token %.1fs", average.Seconds())
+		}
+		views = append(views, baseStyle.
+			Width(m.width).
+			Foreground(t.TextMuted()).
+			Render(fmt.Sprintf("%s: %s", usage.name, details)))
+	}
+	return baseStyle.
+		Width(m.width).
+		Render(lipgloss.JoinVertical(lipgloss.Top, views...))
+}
+
+// formatTokenCount formats a number of tokens like 950, 12K or 1.2M
+func formatTokenCount(tokens int64) string {
+	switch {
+	case tokens >= 1_000_000:
+		return strings.TrimSuffix(fmt.Sprintf("%.1f", float64(tokens)/1_000_000), ".0") + "M"
+	case tokens >= 1_000:
+		return strings.TrimSuffix(fmt.Sprintf("%.1f", float64(tokens)/1_000), ".0") + "K"
+	default:
+		return fmt.Sprintf("%d", tokens)
+	}
+}
+
 func (m *sidebarCmp) modifiedFile(filePath string, additions, removals int) string {
 	t := theme.CurrentTheme()
 	baseStyle := styles.BaseStyle()
@@ -225,6 +373,41 @@ func (m *sidebarCmp) modifiedFiles() string {
 		)
 }
 
+// externalChanges lists the files that changed on disk after the agent read
+// them. The agent has to read them again before it can edit them.
+func (m *sidebarCmp) externalChanges() string {
+	if len(m.driftedFiles) == 0 {
+		return ""
+	}
+
+	t := theme.CurrentTheme()
+	baseStyle := styles.BaseStyle()
+
+	title := baseStyle.
+		Width(m.width).
+		Foreground(t.Warning()).
+		Bold(true).
+		Render("Changed Outside Session:")
+
+	var paths []string
+	for path := range m.driftedFiles {
+		paths = append(paths, getDisplayPath(path))
+	}
+	sort.Strings(paths)
+
+	fileViews := []string{" ", title}
+	for _, path := range paths {
+		fileViews = append(fileViews, baseStyle.
+			Width(m.width).
+			Foreground(t.TextMuted()).
+			Render(path))
+	}
+
+	return baseStyle.
+		Width(m.width).
+		Render(lipgloss.JoinVertical(lipgloss.Top, fileViews...))
+}
+
 func (m *sidebarCmp) SetSize(width, height int) tea.Cmd {
 	m.width = width
 	m.height = height
@@ -235,10 +418,31 @@ func (m *sidebarCmp) GetSize() (int, int) {
 	return m.width, m.height
 }
 
-func NewSidebarCmp(session session.Session, history history.Service) tea.Model {
+func NewSidebarCmp(session session.Session, history history.Service, messages message.Service) tea.Model {
 	return &sidebarCmp{
-		session: session,
-		history: history,
+		session:      session,
+		history:      history,
+		messages:     messages,
+		turns:        make(map[string]turn),
+		driftedFiles: make(map[string]bool),
+	}
+}
+
+// loadTurns loads the usage of the assistant messages of the session
+func (m *sidebarCmp) loadTurns(ctx context.Context) {
+	m.turns = make(map[string]turn)
+	if m.messages == nil || m.session.ID == "" {
+		return
+	}
+	msgs, err := m.messages.List(ctx, m.session.ID)
+	if err != nil {
+		logging.Error("Failed to load session messages", "error", err)
+		return
+	}
+	for _, msg := range msgs {
+		if msg.Role == message.Assistant && msg.Usage.Tokens() > 0 {
+			m.turns[msg.ID] = turn{model: msg.Model, usage: msg.Usage}
+		}
 	}
 }
 
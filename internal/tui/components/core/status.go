@@ -2,12 +2,15 @@ package core
 
 import (
 	"fmt"
+	"maps"
+	"slices"
 	"strings"
 	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/omnitrix-sh/cli/internal/config"
+	"github.com/omnitrix-sh/cli/internal/llm/agent"
 	"github.com/omnitrix-sh/cli/internal/llm/models"
 	"github.com/omnitrix-sh/cli/internal/lsp"
 	"github.com/omnitrix-sh/cli/internal/lsp/protocol"
@@ -27,8 +30,12 @@ type statusCmp struct {
 	info       util.InfoMsg
 	width      int
 	messageTTL time.Duration
-	lspClients map[string]*lsp.Client
+	lspClients *lsp.Clients
+	lspStatus  map[string]lsp.ServerStatus
 	session    session.Session
+	// contextTokens is the size of the conversation of each session counted
+	// by the agent before and after each request
+	contextTokens map[string]int64
 }
 
 // clearMessageCmd is a command that clears status messages after a timeout
@@ -57,6 +64,12 @@ func (m statusCmp) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.session = msg.Payload
 			}
 		}
+	case pubsub.Event[agent.AgentEvent]:
+		if msg.Payload.Type == agent.AgentEventTypeContext {
+			m.contextTokens[msg.Payload.SessionID] = msg.Payload.ContextTokens
+		}
+	case pubsub.Event[lsp.ServerStatus]:
+		m.lspStatus[msg.Payload.Name] = msg.Payload
 	case util.InfoMsg:
 		m.info = msg
 		ttl := msg.TTL
@@ -128,7 +141,10 @@ func (m statusCmp) View() string {
 
 	tokenInfoWidth := 0
 	if m.session.ID != "" {
-		totalTokens := m.session.PromptTokens + m.session.CompletionTokens
+		totalTokens := m.contextTokens[m.session.ID]
+		if totalTokens == 0 {
+			totalTokens = m.session.PromptTokens + m.session.CompletionTokens
+		}
 		tokens := formatTokensAndCost(totalTokens, model.ContextWindow, m.session.Cost)
 		tokensStyle := styles.Padded().
 			Background(t.Text()).
@@ -184,28 +200,41 @@ func (m statusCmp) View() string {
 func (m *statusCmp) projectDiagnostics() string {
 	t := theme.CurrentTheme()
 
-	// Check if any LSP server is still initializing
+	// Check if any LSP server is still initializing, and collect the servers
+	// that are restarting or failed
 	initializing := false
-	for _, client := range m.lspClients {
-		if client.GetServerState() == lsp.StateStarting {
+	servers := []string{}
+	statuses := m.serverStatuses()
+	for _, name := range slices.Sorted(maps.Keys(statuses)) {
+		switch statuses[name].State {
+		case lsp.StateStarting:
 			initializing = true
-			break
+		case lsp.StateRestarting:
+			servers = append(servers, lipgloss.NewStyle().
+				Background(t.BackgroundDarker()).
+				Foreground(t.Warning()).
+				Render(fmt.Sprintf("%s %s restarting", styles.SpinnerIcon, name)))
+		case lsp.StateError:
+			servers = append(servers, lipgloss.NewStyle().
+				Background(t.BackgroundDarker()).
+				Foreground(t.Error()).
+				Render(fmt.Sprintf("%s %s failed", styles.ErrorIcon, name)))
 		}
 	}
 
 	// If any server is initializing, show that status
 	if initializing {
-		return lipgloss.NewStyle().
+		return strings.Join(append(servers, lipgloss.NewStyle().
 			Background(t.BackgroundDarker()).
 			Foreground(t.Warning()).
-			Render(fmt.Sprintf("%s Initializing LSP...", styles.SpinnerIcon))
+			Render(fmt.Sprintf("%s Initializing LSP...", styles.SpinnerIcon))), " ")
 	}
 
 	errorDiagnostics := []protocol.Diagnostic{}
 	warnDiagnostics := []protocol.Diagnostic{}
 	hintDiagnostics := []protocol.Diagnostic{}
 	infoDiagnostics := []protocol.Diagnostic{}
-	for _, client := range m.lspClients {
+	for _, client := range m.lspClients.Snapshot() {
 		for _, d := range client.GetDiagnostics() {
 			for _, diag := range d {
 				switch diag.Severity {
@@ -223,10 +252,10 @@ func (m *statusCmp) projectDiagnostics() string {
 	}
 
 	if len(errorDiagnostics) == 0 && len(warnDiagnostics) == 0 && len(hintDiagnostics) == 0 && len(infoDiagnostics) == 0 {
-		return "No diagnostics"
+		return strings.Join(append(servers, "No diagnostics"), " ")
 	}
 
-	diagnostics := []string{}
+	diagnostics := servers
 
 	if len(errorDiagnostics) > 0 {
 		errStr := lipgloss.NewStyle().
@@ -260,6 +289,19 @@ func (m *statusCmp) projectDiagnostics() string {
 	return strings.Join(diagnostics, " ")
 }
 
+// serverStatuses returns the status of every language server. Published
+// statuses take precedence, as they also cover servers that are restarting
+// and have no client.
+func (m *statusCmp) serverStatuses() map[string]lsp.ServerStatus {
+	clients := m.lspClients.Snapshot()
+	statuses := make(map[string]lsp.ServerStatus, len(clients)+len(m.lspStatus))
+	for name, client := range clients {
+		statuses[name] = lsp.ServerStatus{Name: name, State: client.GetServerState()}
+	}
+	maps.Copy(statuses, m.lspStatus)
+	return statuses
+}
+
 func (m statusCmp) availableFooterMsgWidth(diagnostics, tokenInfo string) int {
 	tokensWidth := 0
 	if m.session.ID != "" {
@@ -291,11 +333,13 @@ func (m statusCmp) model() string {
 		Render(modelText)
 }
 
-func NewStatusCmp(lspClients map[string]*lsp.Client) StatusCmp {
+func NewStatusCmp(lspClients *lsp.Clients) StatusCmp {
 	helpWidget = getHelpWidget()
 
 	return &statusCmp{
-		messageTTL: 10 * time.Second,
-		lspClients: lspClients,
+		messageTTL:    10 * time.Second,
+		lspClients:    lspClients,
+		lspStatus:     make(map[string]lsp.ServerStatus),
+		contextTokens: make(map[string]int64),
 	}
 }
@@ -0,0 +1,125 @@
+package dialog
+
+import (
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/omnitrix-sh/cli/internal/budget"
+	"github.com/omnitrix-sh/cli/internal/tui/layout"
+	"github.com/omnitrix-sh/cli/internal/tui/styles"
+	"github.com/omnitrix-sh/cli/internal/tui/theme"
+	"github.com/omnitrix-sh/cli/internal/tui/util"
+)
+
+const budgetDialogWidth = 60
+
+// BudgetResponseMsg is sent when the user decides whether the agent may go
+// over a budget cap
+type BudgetResponseMsg struct {
+	Request  budget.Request
+	Approved bool
+}
+
+// BudgetDialogCmp asks the user whether the agent may go over a budget cap
+type BudgetDialogCmp interface {
+	tea.Model
+	layout.Bindings
+	SetRequest(request budget.Request)
+}
+
+type budgetDialogCmp struct {
+	request    budget.Request
+	selectedNo bool
+}
+
+func (b *budgetDialogCmp) Init() tea.Cmd {
+	return nil
+}
+
+func (b *budgetDialogCmp) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch {
+		case key.Matches(msg, helpKeys.LeftRight) || key.Matches(msg, helpKeys.Tab):
+			b.selectedNo = !b.selectedNo
+			return b, nil
+		case key.Matches(msg, helpKeys.EnterSpace):
+			return b, b.respond(!b.selectedNo)
+		case key.Matches(msg, helpKeys.Yes):
+			return b, b.respond(true)
+		case key.Matches(msg, helpKeys.No):
+			return b, b.respond(false)
+		}
+	}
+	return b, nil
+}
+
+func (b *budgetDialogCmp) respond(approved bool) tea.Cmd {
+	return util.CmdHandler(BudgetResponseMsg{Request: b.request, Approved: approved})
+}
+
+func (b *budgetDialogCmp) SetRequest(request budget.Request) {
+	b.request = request
+	b.selectedNo = true
+}
+
+func (b *budgetDialogCmp) View() string {
+	t := theme.CurrentTheme()
+	baseStyle := styles.BaseStyle()
+
+	yesStyle := baseStyle
+	noStyle := baseStyle
+	spacerStyle := baseStyle.Background(t.Background())
+
+	if b.selectedNo {
+		noStyle = noStyle.Background(t.Primary()).Foreground(t.Background())
+		yesStyle = yesStyle.Background(t.Background()).Foreground(t.Primary())
+	} else {
+		yesStyle = yesStyle.Background(t.Primary()).Foreground(t.Background())
+		noStyle = noStyle.Background(t.Background()).Foreground(t.Primary())
+	}
+
+	buttons := lipgloss.JoinHorizontal(
+		lipgloss.Left,
+		yesStyle.Padding(0, 1).Render("Continue"),
+		spacerStyle.Render("  "),
+		noStyle.Padding(0, 1).Render("Stop"),
+	)
+
+	title := baseStyle.
+		Foreground(t.Warning()).
+		Bold(true).
+		Width(budgetDialogWidth).
+		Render("Budget cap reached")
+	description := baseStyle.
+		Width(budgetDialogWidth).
+		Render(b.request.Description() + ". Continuing lets the session go over this cap.")
+
+	content := baseStyle.Render(
+		lipgloss.JoinVertical(
+			lipgloss.Left,
+			title,
+			"",
+			description,
+			"",
+			baseStyle.Width(budgetDialogWidth).Align(lipgloss.Right).Render(buttons),
+		),
+	)
+
+	return baseStyle.Padding(1, 2).
+		Border(lipgloss.RoundedBorder()).
+		BorderBackground(t.Background()).
+		BorderForeground(t.TextMuted()).
+		Width(lipgloss.Width(content) + 4).
+		Render(content)
+}
+
+func (b *budgetDialogCmp) BindingKeys() []key.Binding {
+	return layout.KeyMapToSlice(helpKeys)
+}
+
+func NewBudgetDialogCmp() BudgetDialogCmp {
+	return &budgetDialogCmp{
+		selectedNo: true,
+	}
+}
@@ -1,10 +1,14 @@
 package dialog
 
 import (
+	"fmt"
+	"strings"
+
 	"github.com/charmbracelet/bubbles/key"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 
+	"github.com/omnitrix-sh/cli/internal/lsp"
 	"github.com/omnitrix-sh/cli/internal/tui/styles"
 	"github.com/omnitrix-sh/cli/internal/tui/theme"
 	"github.com/omnitrix-sh/cli/internal/tui/util"
@@ -15,6 +19,11 @@ type InitDialogCmp struct {
 	width, height int
 	selected      int
 	keys          initDialogKeyMap
+
+	// servers are the language servers detected for the project, enabled
+	// unless the user opts out
+	servers   []lsp.DetectedServer
+	enableLSP bool
 }
 
 // NewInitDialogCmp creates a new InitDialogCmp.
@@ -54,6 +63,10 @@ func (k initDialogKeyMap) ShortHelp() []key.Binding {
 			key.WithKeys("y", "n"),
 			key.WithHelp("y/n", "yes/no"),
 		),
+		key.NewBinding(
+			key.WithKeys(" "),
+			key.WithHelp("space", "toggle language servers"),
+		),
 	}
 }
 
@@ -77,12 +90,15 @@ func (m InitDialogCmp) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		case key.Matches(msg, key.NewBinding(key.WithKeys("tab", "left", "right", "h", "l"))):
 			m.selected = (m.selected + 1) % 2
 			return m, nil
+		case key.Matches(msg, key.NewBinding(key.WithKeys(" "))):
+			m.enableLSP = !m.enableLSP
+			return m, nil
 		case key.Matches(msg, key.NewBinding(key.WithKeys("enter"))):
-			return m, util.CmdHandler(CloseInitDialogMsg{Initialize: m.selected == 0})
+			return m, util.CmdHandler(CloseInitDialogMsg{Initialize: m.selected == 0, EnableLSP: m.lspServers()})
 		case key.Matches(msg, key.NewBinding(key.WithKeys("y"))):
-			return m, util.CmdHandler(CloseInitDialogMsg{Initialize: true})
+			return m, util.CmdHandler(CloseInitDialogMsg{Initialize: true, EnableLSP: m.lspServers()})
 		case key.Matches(msg, key.NewBinding(key.WithKeys("n"))):
-			return m, util.CmdHandler(CloseInitDialogMsg{Initialize: false})
+			return m, util.CmdHandler(CloseInitDialogMsg{Initialize: false, EnableLSP: m.lspServers()})
 		}
 	case tea.WindowSizeMsg:
 		m.width = msg.Width
@@ -91,6 +107,20 @@ func (m InitDialogCmp) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, nil
 }
 
+// SetServers sets the language servers offered to be enabled.
+func (m *InitDialogCmp) SetServers(servers []lsp.DetectedServer) {
+	m.servers = servers
+	m.enableLSP = len(servers) > 0
+}
+
+// lspServers returns the servers to enable when the dialog is closed
+func (m InitDialogCmp) lspServers() []lsp.DetectedServer {
+	if !m.enableLSP {
+		return nil
+	}
+	return m.servers
+}
+
 // View implements tea.Model.
 func (m InitDialogCmp) View() string {
 	t := theme.CurrentTheme()
@@ -149,15 +179,38 @@ func (m InitDialogCmp) View() string {
 		Padding(1, 0).
 		Render(buttons)
 
-	content := lipgloss.JoinVertical(
-		lipgloss.Left,
+	parts := []string{
 		title,
 		baseStyle.Width(maxWidth).Render(""),
 		explanation,
 		question,
 		buttons,
-		baseStyle.Width(maxWidth).Render(""),
-	)
+	}
+
+	if len(m.servers) > 0 {
+		checkbox := "[ ]"
+		if m.enableLSP {
+			checkbox = "[x]"
+		}
+		names := make([]string, 0, len(m.servers))
+		for _, server := range m.servers {
+			names = append(names, fmt.Sprintf("%s (%s)", server.Language, server.Command))
+		}
+		servers := baseStyle.
+			Foreground(t.Text()).
+			Width(maxWidth).
+			Padding(0, 1).
+			Render(fmt.Sprintf("%s Enable language servers: %s", checkbox, strings.Join(names, ", ")))
+		hint := baseStyle.
+			Foreground(t.TextMuted()).
+			Width(maxWidth).
+			Padding(0, 1).
+			Render("press space to toggle")
+		parts = append(parts, servers, hint)
+	}
+	parts = append(parts, baseStyle.Width(maxWidth).Render(""))
+
+	content := lipgloss.JoinVertical(lipgloss.Left, parts...)
 
 	return baseStyle.Padding(1, 2).
 		Border(lipgloss.RoundedBorder()).
@@ -181,9 +234,13 @@ func (m InitDialogCmp) Bindings() []key.Binding {
 // CloseInitDialogMsg is a message that is sent when the init dialog is closed.
 type CloseInitDialogMsg struct {
 	Initialize bool
+	// EnableLSP are the detected language servers the user chose to enable
+	EnableLSP []lsp.DetectedServer
 }
 
 // ShowInitDialogMsg is a message that is sent to show the init dialog.
 type ShowInitDialogMsg struct {
 	Show bool
+	// Servers are the detected language servers that can be enabled
+	Servers []lsp.DetectedServer
 }
@@ -1,11 +1,13 @@
 package dialog
 
 import (
+	"context"
 	"fmt"
 	"slices"
 	"strings"
 
 	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/omnitrix-sh/cli/internal/config"
@@ -29,6 +31,12 @@ type ModelSelectedMsg struct {
 // CloseModelDialogMsg is sent when a model is selected
 type CloseModelDialogMsg struct{}
 
+// OllamaModelPulledMsg is sent when a model pulled from the dialog can be
+// used
+type OllamaModelPulledMsg struct {
+	Model models.Model
+}
+
 // ModelDialog interface for the model selection dialog
 type ModelDialog interface {
 	tea.Model
@@ -46,6 +54,10 @@ type modelDialogCmp struct {
 	scrollOffset    int
 	hScrollOffset   int
 	hScrollPossible bool
+
+	// pulling is set while the name of an Ollama model to pull is entered
+	pulling   bool
+	pullInput textinput.Model
 }
 
 type modelKeyMap struct {
@@ -59,6 +71,7 @@ type modelKeyMap struct {
 	K      key.Binding
 	H      key.Binding
 	L      key.Binding
+	Pull   key.Binding
 }
 
 var modelKeys = modelKeyMap{
@@ -102,6 +115,10 @@ var modelKeys = modelKeyMap{
 		key.WithKeys("l"),
 		key.WithHelp("l", "scroll right"),
 	),
+	Pull: key.NewBinding(
+		key.WithKeys("p"),
+		key.WithHelp("p", "pull an Ollama model"),
+	),
 }
 
 func (m *modelDialogCmp) Init() tea.Cmd {
@@ -112,6 +129,9 @@ func (m *modelDialogCmp) Init() tea.Cmd {
 func (m *modelDialogCmp) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
+		if m.pulling {
+			return m, m.updatePullInput(msg)
+		}
 		switch {
 		case key.Matches(msg, modelKeys.Up) || key.Matches(msg, modelKeys.K):
 			m.moveSelectionUp()
@@ -125,7 +145,14 @@ func (m *modelDialogCmp) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			if m.hScrollPossible {
 				m.switchProvider(1)
 			}
+		case key.Matches(msg, modelKeys.Pull) && m.provider == models.ProviderOllama:
+			m.pulling = true
+			m.pullInput = newPullInput()
+			return m, textinput.Blink
 		case key.Matches(msg, modelKeys.Enter):
+			if len(m.models) == 0 {
+				return m, nil
+			}
 			util.ReportInfo(fmt.Sprintf("selected model: %s", m.models[m.selectedIdx].Name))
 			return m, util.CmdHandler(ModelSelectedMsg{Model: m.models[m.selectedIdx]})
 		case key.Matches(msg, modelKeys.Escape):
@@ -134,13 +161,67 @@ func (m *modelDialogCmp) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case tea.WindowSizeMsg:
 		m.width = msg.Width
 		m.height = msg.Height
+	case OllamaModelPulledMsg:
+		if m.provider == models.ProviderOllama {
+			m.setupModelsForProvider(m.provider)
+		}
 	}
 
 	return m, nil
 }
 
+func newPullInput() textinput.Model {
+	t := theme.CurrentTheme()
+	ti := textinput.New()
+	ti.Placeholder = "qwen3:8b"
+	ti.Width = maxDialogWidth
+	ti.Prompt = ""
+	ti.PlaceholderStyle = ti.PlaceholderStyle.Background(t.Background())
+	ti.TextStyle = ti.TextStyle.Background(t.Background()).Foreground(t.Primary())
+	ti.Focus()
+	return ti
+}
+
+// updatePullInput handles the keys typed while entering the name of the
+// model to pull, which is pulled in the background on enter
+func (m *modelDialogCmp) updatePullInput(msg tea.KeyMsg) tea.Cmd {
+	switch {
+	case key.Matches(msg, modelKeys.Escape):
+		m.pulling = false
+		return nil
+	case key.Matches(msg, modelKeys.Enter):
+		name := strings.TrimSpace(m.pullInput.Value())
+		if name == "" {
+			return nil
+		}
+		m.pulling = false
+		return tea.Batch(
+			util.ReportInfo(fmt.Sprintf("Pulling %s...", name)),
+			pullOllamaModel(name),
+		)
+	}
+	var cmd tea.Cmd
+	m.pullInput, cmd = m.pullInput.Update(msg)
+	return cmd
+}
+
+// pullOllamaModel pulls a model, whose progress is published by the models
+// package
+func pullOllamaModel(name string) tea.Cmd {
+	return func() tea.Msg {
+		model, err := models.PullOllamaModel(context.Background(), name)
+		if err != nil {
+			return util.InfoMsg{Type: util.InfoTypeError, Msg: err.Error()}
+		}
+		return OllamaModelPulledMsg{Model: model}
+	}
+}
+
 // moveSelectionUp moves the selection up or wraps to bottom
 func (m *modelDialogCmp) moveSelectionUp() {
+	if len(m.models) == 0 {
+		return
+	}
 	if m.selectedIdx > 0 {
 		m.selectedIdx--
 	} else {
@@ -156,6 +237,9 @@ func (m *modelDialogCmp) moveSelectionUp() {
 
 // moveSelectionDown moves the selection down or wraps to top
 func (m *modelDialogCmp) moveSelectionDown() {
+	if len(m.models) == 0 {
+		return
+	}
 	if m.selectedIdx < len(m.models)-1 {
 		m.selectedIdx++
 	} else {
@@ -211,14 +295,28 @@ func (m *modelDialogCmp) View() string {
 		modelItems = append(modelItems, itemStyle.Render(m.models[i].Name))
 	}
 
+	if len(modelItems) == 0 {
+		modelItems = append(modelItems, baseStyle.Width(maxDialogWidth).Foreground(t.TextMuted()).Render("No models installed"))
+	}
+
 	scrollIndicator := m.getScrollIndicators(maxDialogWidth)
 
-	content := lipgloss.JoinVertical(
-		lipgloss.Left,
+	sections := []string{
 		title,
 		baseStyle.Width(maxDialogWidth).Render(lipgloss.JoinVertical(lipgloss.Left, modelItems...)),
 		scrollIndicator,
-	)
+	}
+	if m.pulling {
+		sections = append(sections,
+			baseStyle.Width(maxDialogWidth).Padding(1, 0, 0).Foreground(t.TextMuted()).Render("Model to pull:"),
+			baseStyle.Width(maxDialogWidth).Render(m.pullInput.View()),
+		)
+	} else if m.provider == models.ProviderOllama {
+		sections = append(sections,
+			baseStyle.Width(maxDialogWidth).Padding(1, 0, 0).Foreground(t.TextMuted()).Render("p to pull a model"),
+		)
+	}
+	content := lipgloss.JoinVertical(lipgloss.Left, sections...)
 
 	return baseStyle.Padding(1, 2).
 		Border(lipgloss.RoundedBorder()).
@@ -408,7 +408,7 @@ func (p *permissionDialogCmp) render() string {
 		contentFinal = p.renderBashContent()
 	case tools.EditToolName:
 		contentFinal = p.renderEditContent()
-	case tools.PatchToolName:
+	case tools.PatchToolName, tools.RenameSymbolToolName, tools.CodeActionsToolName:
 		contentFinal = p.renderPatchContent()
 	case tools.WriteToolName:
 		contentFinal = p.renderWriteContent()
@@ -456,7 +456,7 @@ func (p *permissionDialogCmp) SetSize() tea.Cmd {
 	case tools.BashToolName:
 		p.width = int(float64(p.windowSize.Width) * 0.5) // Increased from 0.4
 		p.height = int(float64(p.windowSize.Height) * 0.4) // Increased from 0.3
-	case tools.EditToolName:
+	case tools.EditToolName, tools.RenameSymbolToolName, tools.CodeActionsToolName:
 		p.width = int(float64(p.windowSize.Width) * 0.7) // Reduced from 0.8
 		p.height = int(float64(p.windowSize.Height) * 0.6) // Reduced from 0.8
 	case tools.WriteToolName:
@@ -4,14 +4,19 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/charmbracelet/bubbles/key"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/omnitrix-sh/cli/internal/app"
+	"github.com/omnitrix-sh/cli/internal/budget"
 	"github.com/omnitrix-sh/cli/internal/config"
 	"github.com/omnitrix-sh/cli/internal/llm/agent"
+	"github.com/omnitrix-sh/cli/internal/llm/models"
+	"github.com/omnitrix-sh/cli/internal/llm/provider"
 	"github.com/omnitrix-sh/cli/internal/logging"
+	"github.com/omnitrix-sh/cli/internal/lsp"
 	"github.com/omnitrix-sh/cli/internal/permission"
 	"github.com/omnitrix-sh/cli/internal/pubsub"
 	"github.com/omnitrix-sh/cli/internal/session"
@@ -108,6 +113,9 @@ type appModel struct {
 	showPermissions bool
 	permissions     dialog.PermissionDialogCmp
 
+	showBudget   bool
+	budgetDialog dialog.BudgetDialogCmp
+
 	showHelp bool
 	help     dialog.HelpCmp
 
@@ -138,6 +146,9 @@ type appModel struct {
 
 	isCompacting      bool
 	compactingMessage string
+	// contextTokens is the size of the conversation of the selected session
+	// counted by the agent
+	contextTokens int64
 }
 
 func (a appModel) Init() tea.Cmd {
@@ -173,7 +184,18 @@ func (a appModel) Init() tea.Cmd {
 				Msg:  "Failed to check init status: " + err.Error(),
 			}
 		}
-		return dialog.ShowInitDialogMsg{Show: shouldShow}
+		if !shouldShow {
+			return dialog.ShowInitDialogMsg{Show: false}
+		}
+
+		// Offer the language servers found for the project
+		var servers []lsp.DetectedServer
+		for _, server := range lsp.DetectServers(config.WorkingDirectory()) {
+			if server.Available() {
+				servers = append(servers, server)
+			}
+		}
+		return dialog.ShowInitDialogMsg{Show: true, Servers: servers}
 	})
 
 	return tea.Batch(cmds...)
@@ -267,6 +289,23 @@ func (a appModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				cmds = append(cmds, cmd)
 			}
 		}
+	case pubsub.Event[provider.RetryStatus]:
+		status := msg.Payload
+		s, cmd := a.status.Update(util.InfoMsg{
+			Type: util.InfoTypeWarn,
+			Msg:  fmt.Sprintf("%s: %s", status.Provider, status.Message()),
+			TTL:  status.Delay + time.Second,
+		})
+		a.status = s.(core.StatusCmp)
+		cmds = append(cmds, cmd)
+	case pubsub.Event[models.OllamaPullStatus]:
+		s, cmd := a.status.Update(util.InfoMsg{
+			Type: util.InfoTypeInfo,
+			Msg:  msg.Payload.Message(),
+			TTL:  10 * time.Second,
+		})
+		a.status = s.(core.StatusCmp)
+		cmds = append(cmds, cmd)
 	case util.ClearStatusMsg:
 		s, _ := a.status.Update(msg)
 		a.status = s.(core.StatusCmp)
@@ -288,6 +327,20 @@ func (a appModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		a.showPermissions = false
 		return a, cmd
 
+	// Budget
+	case pubsub.Event[budget.Request]:
+		a.showBudget = true
+		a.budgetDialog.SetRequest(msg.Payload)
+		return a, nil
+	case dialog.BudgetResponseMsg:
+		if msg.Approved {
+			a.app.Budgets.Approve(msg.Request)
+		} else {
+			a.app.Budgets.Deny(msg.Request)
+		}
+		a.showBudget = false
+		return a, nil
+
 	case page.PageChangeMsg:
 		return a, a.moveToPage(msg.ID)
 
@@ -322,6 +375,14 @@ func (a appModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 	case pubsub.Event[agent.AgentEvent]:
 		payload := msg.Payload
+		if payload.Type == agent.AgentEventTypeContext {
+			if payload.SessionID == a.selectedSession.ID {
+				a.contextTokens = payload.ContextTokens
+			}
+			s, _ := a.status.Update(msg)
+			a.status = s.(core.StatusCmp)
+			return a, nil
+		}
 		if payload.Error != nil {
 			a.isCompacting = false
 			return a, util.ReportError(payload.Error)
@@ -335,7 +396,10 @@ func (a appModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		} else if payload.Done && payload.Type == agent.AgentEventTypeResponse && a.selectedSession.ID != "" {
 			model := a.app.CoderAgent.Model()
 			contextWindow := model.ContextWindow
-			tokens := a.selectedSession.CompletionTokens + a.selectedSession.PromptTokens
+			tokens := a.contextTokens
+			if tokens == 0 {
+				tokens = a.selectedSession.CompletionTokens + a.selectedSession.PromptTokens
+			}
 			if (tokens >= int64(float64(contextWindow)*0.95)) && config.Get().AutoCompact {
 				return a, util.CmdHandler(startCompactSessionMsg{})
 			}
@@ -356,6 +420,12 @@ func (a appModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		a.showModelDialog = false
 		return a, nil
 
+	case dialog.OllamaModelPulledMsg:
+		models.SupportedModels[msg.Model.ID] = msg.Model
+		d, _ := a.modelDialog.Update(msg)
+		a.modelDialog = d.(dialog.ModelDialog)
+		return a, util.CmdHandler(dialog.ModelSelectedMsg{Model: msg.Model})
+
 	case dialog.ModelSelectedMsg:
 		a.showModelDialog = false
 
@@ -368,10 +438,15 @@ func (a appModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 	case dialog.ShowInitDialogMsg:
 		a.showInitDialog = msg.Show
+		a.initDialog.SetServers(msg.Servers)
 		return a, nil
 
 	case dialog.CloseInitDialogMsg:
 		a.showInitDialog = false
+		var lspCmd tea.Cmd
+		if len(msg.EnableLSP) > 0 {
+			lspCmd = a.enableLSPServers(msg.EnableLSP)
+		}
 		if msg.Initialize {
 			// Run the initialization command
 			for _, cmd := range a.commands {
@@ -380,7 +455,7 @@ func (a appModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					if err := config.MarkProjectInitialized(); err != nil {
 						return a, util.ReportError(err)
 					}
-					return a, cmd.Handler(cmd)
+					return a, tea.Batch(lspCmd, cmd.Handler(cmd))
 				}
 			}
 		} else {
@@ -389,9 +464,12 @@ func (a appModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				return a, util.ReportError(err)
 			}
 		}
-		return a, nil
+		return a, lspCmd
 
 	case chat.SessionSelectedMsg:
+		if msg.ID != a.selectedSession.ID {
+			a.contextTokens = 0
+		}
 		a.selectedSession = msg
 		a.sessionDialog.SetSelectedSession(msg.ID)
 
@@ -606,6 +684,16 @@ func (a appModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 	}
 
+	if a.showBudget {
+		d, budgetCmd := a.budgetDialog.Update(msg)
+		a.budgetDialog = d.(dialog.BudgetDialogCmp)
+		cmds = append(cmds, budgetCmd)
+		// Only block key messages send all other messages down
+		if _, ok := msg.(tea.KeyMsg); ok {
+			return a, tea.Batch(cmds...)
+		}
+	}
+
 	if a.showSessionDialog {
 		d, sessionCmd := a.sessionDialog.Update(msg)
 		a.sessionDialog = d.(dialog.SessionDialog)
@@ -677,6 +765,26 @@ func (a *appModel) findCommand(id string) (dialog.Command, bool) {
 	return dialog.Command{}, false
 }
 
+// enableLSPServers adds the servers chosen in the init dialog to the project
+// configuration and starts them
+func (a *appModel) enableLSPServers(detected []lsp.DetectedServer) tea.Cmd {
+	servers := make(map[string]config.LSPConfig, len(detected))
+	names := make([]string, 0, len(detected))
+	for _, server := range detected {
+		servers[server.Name] = server.Config()
+		names = append(names, server.Name)
+	}
+	if err := config.AddLSPServers(servers); err != nil {
+		return util.ReportError(err)
+	}
+	info := fmt.Sprintf("Enabled language servers: %s", strings.Join(names, ", "))
+	if err := a.app.StartLSPClients(context.Background(), names); err != nil {
+		logging.Warn("Failed to give the agent LSP tools", "error", err)
+		info += " (restart to give the agent LSP tools)"
+	}
+	return util.ReportInfo(info)
+}
+
 func (a *appModel) moveToPage(pageID page.PageID) tea.Cmd {
 	if a.app.CoderAgent.IsBusy() {
 		// For now we don't move to any page if the agent is busy
@@ -723,6 +831,21 @@ func (a appModel) View() string {
 		)
 	}
 
+	if a.showBudget {
+		overlay := a.budgetDialog.View()
+		row := lipgloss.Height(appView) / 2
+		row -= lipgloss.Height(overlay) / 2
+		col := lipgloss.Width(appView) / 2
+		col -= lipgloss.Width(overlay) / 2
+		appView = layout.PlaceOverlay(
+			col,
+			row,
+			overlay,
+			appView,
+			true,
+		)
+	}
+
 	if a.showFilepicker {
 		overlay := a.filepicker.View()
 		row := lipgloss.Height(appView) / 2
@@ -772,6 +895,9 @@ func (a appModel) View() string {
 		if a.showPermissions {
 			bindings = append(bindings, a.permissions.BindingKeys()...)
 		}
+		if a.showBudget {
+			bindings = append(bindings, a.budgetDialog.BindingKeys()...)
+		}
 		if a.currentPage == page.LogsPage {
 			bindings = append(bindings, logsKeyReturnKey)
 		}
@@ -910,6 +1036,7 @@ func New(app *app.App) tea.Model {
 		commandDialog: dialog.NewCommandDialogCmp(),
 		modelDialog:   dialog.NewModelDialogCmp(),
 		permissions:   dialog.NewPermissionDialogCmp(),
+		budgetDialog:  dialog.NewBudgetDialogCmp(),
 		initDialog:    dialog.NewInitDialogCmp(),
 		themeDialog:   dialog.NewThemeDialogCmp(),
 		app:           app,
@@ -0,0 +1,451 @@
+// Package usage reports the tokens and the spend of the sessions stored in
+// the database.
+package usage
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/omnitrix-sh/cli/internal/db"
+)
+
+const dateLayout = "2006-01-02"
+
+// Filter selects the sessions of a report. Zero values match all sessions.
+type Filter struct {
+	// Since and Until bound the creation time of the sessions, and the time
+	// the responses listed by model and turn were answered, Until being
+	// excluded
+	Since   time.Time
+	Until   time.Time
+	Model   string
+	Project string
+}
+
+// Tokens are the tokens of the requests made in one or more sessions
+type Tokens struct {
+	Input      int64 `json:"input"`
+	Output     int64 `json:"output"`
+	CacheRead  int64 `json:"cache_read"`
+	CacheWrite int64 `json:"cache_write"`
+}
+
+func (t Tokens) add(other Tokens) Tokens {
+	return Tokens{
+		Input:      t.Input + other.Input,
+		Output:     t.Output + other.Output,
+		CacheRead:  t.CacheRead + other.CacheRead,
+		CacheWrite: t.CacheWrite + other.CacheWrite,
+	}
+}
+
+// Total returns the number of tokens of all kinds
+func (t Tokens) Total() int64 {
+	return t.Input + t.Output + t.CacheRead + t.CacheWrite
+}
+
+// Session is the usage of a session, including its task sessions
+type Session struct {
+	ID        string    `json:"id"`
+	Title     string    `json:"title"`
+	Project   string    `json:"project"`
+	CreatedAt time.Time `json:"created_at"`
+	Models    []string  `json:"models"`
+	Tokens    Tokens    `json:"tokens"`
+	Cost      float64   `json:"cost"`
+}
+
+// ModelUsage is the usage of the responses of a model
+type ModelUsage struct {
+	Model               string  `json:"model"`
+	Turns               int64   `json:"turns"`
+	Tokens              Tokens  `json:"tokens"`
+	Cost                float64 `json:"cost"`
+	AverageLatencyMs    int64   `json:"average_latency_ms"`
+	AverageFirstTokenMs int64   `json:"average_first_token_ms"`
+}
+
+// Turn is the usage of a response of a model
+type Turn struct {
+	MessageID    string    `json:"message_id"`
+	SessionID    string    `json:"session_id"`
+	SessionTitle string    `json:"session_title"`
+	Model        string    `json:"model"`
+	CreatedAt    time.Time `json:"created_at"`
+	Tokens       Tokens    `json:"tokens"`
+	Cost         float64   `json:"cost"`
+	LatencyMs    int64     `json:"latency_ms"`
+	FirstTokenMs int64     `json:"first_token_ms"`
+}
+
+// Report is the usage of the sessions matching a filter
+type Report struct {
+	Filter   Filter
+	Sessions int
+	// Tokens and Cost are the usage of the sessions, or only that of the
+	// responses of the model when filtering by model
+	Tokens Tokens
+	Cost   float64
+	// TopSessions are the most expensive sessions, the most expensive first
+	TopSessions []Session
+	// Models compares the models that answered in the sessions, the most
+	// expensive first
+	Models []ModelUsage
+	// TopTurns are the most expensive responses, the most expensive first
+	TopTurns []Turn
+}
+
+// CSVRows is what the rows of the CSV output of a report are
+type CSVRows string
+
+const (
+	SessionRows CSVRows = "session"
+	ModelRows   CSVRows = "model"
+	TurnRows    CSVRows = "turn"
+)
+
+// Generate builds the report of the sessions matching a filter, listing up
+// to top sessions and turns, or all of them when top is 0
+func Generate(ctx context.Context, q db.Querier, filter Filter, top int) (Report, error) {
+	until := int64(math.MaxInt64)
+	if !filter.Until.IsZero() {
+		until = filter.Until.Unix()
+	}
+	dbSessions, err := q.ListUsageSessions(ctx, db.ListUsageSessionsParams{
+		Since:   filter.Since.Unix(),
+		Until:   until,
+		Project: filter.Project,
+		Model:   filter.Model,
+	})
+	if err != nil {
+		return Report{}, fmt.Errorf("failed to list sessions: %w", err)
+	}
+	sessionModels, err := q.ListSessionModels(ctx)
+	if err != nil {
+		return Report{}, fmt.Errorf("failed to list session models: %w", err)
+	}
+	modelsBySession := make(map[string][]string)
+	for _, row := range sessionModels {
+		modelsBySession[row.SessionID] = append(modelsBySession[row.SessionID], row.Model)
+	}
+
+	modelUsages, err := q.ListModelUsage(ctx, db.ListModelUsageParams{
+		Since:   filter.Since.Unix(),
+		Until:   until,
+		Project: filter.Project,
+		Model:   filter.Model,
+	})
+	if err != nil {
+		return Report{}, fmt.Errorf("failed to list model usage: %w", err)
+	}
+	maxTurns := int64(top)
+	if top == 0 {
+		maxTurns = -1
+	}
+	turns, err := q.ListExpensiveTurns(ctx, db.ListExpensiveTurnsParams{
+		Since:    filter.Since.Unix(),
+		Until:    until,
+		Project:  filter.Project,
+		Model:    filter.Model,
+		MaxTurns: maxTurns,
+	})
+	if err != nil {
+		return Report{}, fmt.Errorf("failed to list turns: %w", err)
+	}
+
+	report := Report{Filter: filter, Sessions: len(dbSessions)}
+	for _, row := range modelUsages {
+		model := ModelUsage{
+			Model: row.Model,
+			Turns: row.Turns,
+			Tokens: Tokens{
+				Input:      row.InputTokens,
+				Output:     row.OutputTokens,
+				CacheRead:  row.CacheReadTokens,
+				CacheWrite: row.CacheCreationTokens,
+			},
+			Cost:                row.Cost,
+			AverageLatencyMs:    row.AverageLatencyMs,
+			AverageFirstTokenMs: row.AverageFirstTokenMs,
+		}
+		report.Models = append(report.Models, model)
+		if filter.Model != "" {
+			report.Tokens = report.Tokens.add(model.Tokens)
+			report.Cost += model.Cost
+		}
+	}
+	for _, row := range turns {
+		report.TopTurns = append(report.TopTurns, Turn{
+			MessageID:    row.ID,
+			SessionID:    row.SessionID,
+			SessionTitle: row.SessionTitle,
+			Model:        row.Model,
+			CreatedAt:    time.Unix(row.CreatedAt, 0),
+			Tokens: Tokens{
+				Input:      row.InputTokens,
+				Output:     row.OutputTokens,
+				CacheRead:  row.CacheReadTokens,
+				CacheWrite: row.CacheCreationTokens,
+			},
+			Cost:         row.Cost,
+			LatencyMs:    row.LatencyMs,
+			FirstTokenMs: row.FirstTokenMs,
+		})
+	}
+
+	// Sessions are sorted by cost
+	for i, dbSession := range dbSessions {
+		session := Session{
+			ID:        dbSession.ID,
+			Title:     dbSession.Title,
+			Project:   dbSession.Project,
+			CreatedAt: time.Unix(dbSession.CreatedAt, 0),
+			Models:    modelsBySession[dbSession.ID],
+			Tokens: Tokens{
+				Input:      dbSession.InputTokens,
+				Output:     dbSession.OutputTokens,
+				CacheRead:  dbSession.CacheReadTokens,
+				CacheWrite: dbSession.CacheCreationTokens,
+			},
+			Cost: dbSession.Cost,
+		}
+		// The sessions matching a model also include the usage of the
+		// other models
+		if filter.Model == "" {
+			report.Tokens = report.Tokens.add(session.Tokens)
+			report.Cost += session.Cost
+		}
+		if top == 0 || i < top {
+			report.TopSessions = append(report.TopSessions, session)
+		}
+	}
+	return report, nil
+}
+
+// Description describes the filter of the report
+func (r Report) Description() string {
+	var parts []string
+	switch {
+	case !r.Filter.Since.IsZero() && !r.Filter.Until.IsZero():
+		// Until is the day after the last day of the range
+		parts = append(parts, fmt.Sprintf("from %s to %s", r.Filter.Since.Format(dateLayout), r.Filter.Until.AddDate(0, 0, -1).Format(dateLayout)))
+	case !r.Filter.Since.IsZero():
+		parts = append(parts, "since "+r.Filter.Since.Format(dateLayout))
+	case !r.Filter.Until.IsZero():
+		parts = append(parts, "until "+r.Filter.Until.AddDate(0, 0, -1).Format(dateLayout))
+	}
+	if r.Filter.Model != "" {
+		parts = append(parts, "with model "+r.Filter.Model)
+	}
+	if r.Filter.Project != "" {
+		parts = append(parts, "in "+r.Filter.Project)
+	}
+	if len(parts) == 0 {
+		return "All sessions"
+	}
+	return "Sessions " + strings.Join(parts, ", ")
+}
+
+// WriteText writes the report as a table for the terminal
+func (r Report) WriteText(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, r.Description())
+	fmt.Fprintln(tw)
+	fmt.Fprintf(tw, "Sessions:\t%d\n", r.Sessions)
+	fmt.Fprintf(tw, "Input tokens:\t%d\n", r.Tokens.Input)
+	fmt.Fprintf(tw, "Output tokens:\t%d\n", r.Tokens.Output)
+	fmt.Fprintf(tw, "Cache read tokens:\t%d\n", r.Tokens.CacheRead)
+	fmt.Fprintf(tw, "Cache write tokens:\t%d\n", r.Tokens.CacheWrite)
+	fmt.Fprintf(tw, "Cost:\t$%.2f\n", r.Cost)
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	if len(r.Models) > 0 {
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "Models")
+		fmt.Fprintln(w)
+		tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "MODEL\tTURNS\tINPUT\tOUTPUT\tCACHE READ\tCACHE WRITE\tCOST\tAVG LATENCY\tAVG FIRST TOKEN")
+		for _, model := range r.Models {
+			fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%d\t%d\t$%.2f\t%s\t%s\n",
+				model.Model,
+				model.Turns,
+				model.Tokens.Input,
+				model.Tokens.Output,
+				model.Tokens.CacheRead,
+				model.Tokens.CacheWrite,
+				model.Cost,
+				formatMs(model.AverageLatencyMs),
+				formatMs(model.AverageFirstTokenMs),
+			)
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+	}
+
+	if len(r.TopSessions) > 0 {
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "Most expensive sessions")
+		fmt.Fprintln(w)
+		tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "COST\tTOKENS\tCREATED\tMODELS\tTITLE")
+		for _, session := range r.TopSessions {
+			fmt.Fprintf(tw, "$%.2f\t%d\t%s\t%s\t%s\n",
+				session.Cost,
+				session.Tokens.Total(),
+				session.CreatedAt.Format(dateLayout),
+				strings.Join(session.Models, ", "),
+				session.Title,
+			)
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+	}
+
+	if len(r.TopTurns) > 0 {
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "Most expensive turns")
+		fmt.Fprintln(w)
+		tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "COST\tTOKENS\tLATENCY\tMODEL\tSESSION")
+		for _, turn := range r.TopTurns {
+			fmt.Fprintf(tw, "$%.3f\t%d\t%s\t%s\t%s\n",
+				turn.Cost,
+				turn.Tokens.Total(),
+				formatMs(turn.LatencyMs),
+				turn.Model,
+				turn.SessionTitle,
+			)
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// formatMs formats a duration in milliseconds in seconds
+func formatMs(ms int64) string {
+	if ms == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.1fs", float64(ms)/1000)
+}
+
+// WriteJSON writes the report as a JSON object
+func (r Report) WriteJSON(w io.Writer) error {
+	type filter struct {
+		Since   string `json:"since,omitempty"`
+		Until   string `json:"until,omitempty"`
+		Model   string `json:"model,omitempty"`
+		Project string `json:"project,omitempty"`
+	}
+	f := filter{Model: r.Filter.Model, Project: r.Filter.Project}
+	if !r.Filter.Since.IsZero() {
+		f.Since = r.Filter.Since.Format(dateLayout)
+	}
+	if !r.Filter.Until.IsZero() {
+		f.Until = r.Filter.Until.AddDate(0, 0, -1).Format(dateLayout)
+	}
+	report := struct {
+		Filter      filter       `json:"filter"`
+		Sessions    int          `json:"sessions"`
+		Tokens      Tokens       `json:"tokens"`
+		Cost        float64      `json:"cost"`
+		Models      []ModelUsage `json:"models"`
+		TopSessions []Session    `json:"most_expensive_sessions"`
+		TopTurns    []Turn       `json:"most_expensive_turns"`
+	}{f, r.Sessions, r.Tokens, r.Cost, r.Models, r.TopSessions, r.TopTurns}
+	// Empty lists are written as [] rather than null
+	if report.Models == nil {
+		report.Models = []ModelUsage{}
+	}
+	if report.TopSessions == nil {
+		report.TopSessions = []Session{}
+	}
+	if report.TopTurns == nil {
+		report.TopTurns = []Turn{}
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(report)
+}
+
+// WriteCSV writes a row for each of the listed sessions, each model or each
+// of the listed turns, the most expensive first
+func (r Report) WriteCSV(w io.Writer, rows CSVRows) error {
+	var records [][]string
+	switch rows {
+	case ModelRows:
+		records = append(records, []string{
+			"model", "turns",
+			"input_tokens", "output_tokens", "cache_read_tokens", "cache_write_tokens", "cost",
+			"average_latency_ms", "average_first_token_ms",
+		})
+		for _, model := range r.Models {
+			records = append(records, append(
+				[]string{model.Model, strconv.FormatInt(model.Turns, 10)},
+				append(csvUsage(model.Tokens, model.Cost),
+					strconv.FormatInt(model.AverageLatencyMs, 10),
+					strconv.FormatInt(model.AverageFirstTokenMs, 10),
+				)...,
+			))
+		}
+	case TurnRows:
+		records = append(records, []string{
+			"message_id", "session_id", "session_title", "model", "created_at",
+			"input_tokens", "output_tokens", "cache_read_tokens", "cache_write_tokens", "cost",
+			"latency_ms", "first_token_ms",
+		})
+		for _, turn := range r.TopTurns {
+			records = append(records, append(
+				[]string{turn.MessageID, turn.SessionID, turn.SessionTitle, turn.Model, turn.CreatedAt.Format(time.RFC3339)},
+				append(csvUsage(turn.Tokens, turn.Cost),
+					strconv.FormatInt(turn.LatencyMs, 10),
+					strconv.FormatInt(turn.FirstTokenMs, 10),
+				)...,
+			))
+		}
+	default:
+		records = append(records, []string{
+			"session_id", "title", "project", "created_at", "models",
+			"input_tokens", "output_tokens", "cache_read_tokens", "cache_write_tokens", "cost",
+		})
+		for _, session := range r.TopSessions {
+			records = append(records, append(
+				[]string{
+					session.ID,
+					session.Title,
+					session.Project,
+					session.CreatedAt.Format(time.RFC3339),
+					strings.Join(session.Models, ";"),
+				},
+				csvUsage(session.Tokens, session.Cost)...,
+			))
+		}
+	}
+	return csv.NewWriter(w).WriteAll(records)
+}
+
+// csvUsage returns the columns of tokens and cost of a CSV row
+func csvUsage(tokens Tokens, cost float64) []string {
+	return []string{
+		strconv.FormatInt(tokens.Input, 10),
+		strconv.FormatInt(tokens.Output, 10),
+		strconv.FormatInt(tokens.CacheRead, 10),
+		strconv.FormatInt(tokens.CacheWrite, 10),
+		strconv.FormatFloat(cost, 'f', 6, 64),
+	}
+}
@@ -0,0 +1,147 @@
+package usage
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"testing"
+	"time"
+
+	"github.com/omnitrix-sh/cli/internal/config"
+	"github.com/omnitrix-sh/cli/internal/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerate(t *testing.T) {
+	dir := t.TempDir()
+	cfg, err := config.Load(dir, false)
+	require.NoError(t, err)
+	cfg.Data.Directory = dir
+	conn, err := db.Connect()
+	require.NoError(t, err)
+	defer conn.Close()
+
+	ctx := context.Background()
+	q := db.New(conn)
+	createSession := func(id, parentID, project, model string, cost float64, input int64) {
+		_, err := q.CreateSession(ctx, db.CreateSessionParams{
+			ID:              id,
+			ParentSessionID: sql.NullString{String: parentID, Valid: parentID != ""},
+			Title:           id,
+			Project:         project,
+		})
+		require.NoError(t, err)
+		_, err = q.UpdateSession(ctx, db.UpdateSessionParams{ID: id, Title: id, Cost: cost, InputTokens: input, OutputTokens: 10})
+		require.NoError(t, err)
+		_, err = q.CreateMessage(ctx, db.CreateMessageParams{
+			ID:        id + "-message",
+			SessionID: id,
+			Role:      "assistant",
+			Parts:     "[]",
+			Model:     sql.NullString{String: model, Valid: true},
+		})
+		require.NoError(t, err)
+		err = q.UpdateMessage(ctx, db.UpdateMessageParams{
+			ID:           id + "-message",
+			Parts:        "[]",
+			Model:        sql.NullString{String: model, Valid: true},
+			InputTokens:  input,
+			OutputTokens: 10,
+			Cost:         cost,
+			LatencyMs:    2000,
+			FirstTokenMs: 500,
+		})
+		require.NoError(t, err)
+	}
+	createSession("cheap", "", "/work/api", "gpt-4.1", 0.5, 100)
+	createSession("expensive", "", "/work/web", "claude-3.7-sonnet", 3, 1000)
+	createSession("task", "cheap", "/work/api", "claude-3.7-sonnet", 0.2, 50)
+
+	t.Run("reports all top level sessions", func(t *testing.T) {
+		report, err := Generate(ctx, q, Filter{}, 1)
+		require.NoError(t, err)
+		assert.Equal(t, 2, report.Sessions)
+		assert.InDelta(t, 3.5, report.Cost, 1e-9)
+		assert.Equal(t, Tokens{Input: 1100, Output: 20}, report.Tokens)
+		require.Len(t, report.TopSessions, 1)
+		assert.Equal(t, "expensive", report.TopSessions[0].ID)
+	})
+
+	t.Run("filters by model of the task sessions", func(t *testing.T) {
+		report, err := Generate(ctx, q, Filter{Model: "claude-3.7-sonnet"}, 0)
+		require.NoError(t, err)
+		assert.Equal(t, 2, report.Sessions)
+		assert.Equal(t, []string{"claude-3.7-sonnet", "gpt-4.1"}, report.TopSessions[1].Models)
+		// The totals only count the responses of the model
+		assert.InDelta(t, 3.2, report.Cost, 1e-9)
+		assert.Equal(t, Tokens{Input: 1050, Output: 20}, report.Tokens)
+	})
+
+	t.Run("filters by project and date", func(t *testing.T) {
+		report, err := Generate(ctx, q, Filter{Project: "/work/api"}, 0)
+		require.NoError(t, err)
+		assert.Equal(t, 1, report.Sessions)
+
+		tomorrow := time.Now().AddDate(0, 0, 1)
+		report, err = Generate(ctx, q, Filter{Since: tomorrow}, 0)
+		require.NoError(t, err)
+		assert.Equal(t, 0, report.Sessions)
+	})
+
+	t.Run("writes a CSV row per session", func(t *testing.T) {
+		report, err := Generate(ctx, q, Filter{}, 0)
+		require.NoError(t, err)
+		var buf bytes.Buffer
+		require.NoError(t, report.WriteCSV(&buf, SessionRows))
+		rows, err := csv.NewReader(&buf).ReadAll()
+		require.NoError(t, err)
+		require.Len(t, rows, 3)
+		assert.Equal(t, "expensive", rows[1][0])
+		assert.Equal(t, "3.000000", rows[1][9])
+	})
+
+	t.Run("compares models and lists expensive turns", func(t *testing.T) {
+		report, err := Generate(ctx, q, Filter{}, 2)
+		require.NoError(t, err)
+		require.Len(t, report.Models, 2)
+		assert.Equal(t, "claude-3.7-sonnet", report.Models[0].Model)
+		assert.Equal(t, int64(2), report.Models[0].Turns)
+		assert.InDelta(t, 3.2, report.Models[0].Cost, 1e-9)
+		assert.Equal(t, Tokens{Input: 1050, Output: 20}, report.Models[0].Tokens)
+		assert.Equal(t, int64(500), report.Models[0].AverageFirstTokenMs)
+
+		require.Len(t, report.TopTurns, 2)
+		assert.Equal(t, "expensive-message", report.TopTurns[0].MessageID)
+		assert.Equal(t, "cheap-message", report.TopTurns[1].MessageID)
+		assert.Equal(t, int64(2000), report.TopTurns[1].LatencyMs)
+
+		var buf bytes.Buffer
+		require.NoError(t, report.WriteCSV(&buf, ModelRows))
+		rows, err := csv.NewReader(&buf).ReadAll()
+		require.NoError(t, err)
+		require.Len(t, rows, 3)
+		assert.Equal(t, []string{"gpt-4.1", "1"}, rows[2][:2])
+	})
+
+	t.Run("lists models and turns by the date of the responses", func(t *testing.T) {
+		lastMonth := time.Now().AddDate(0, -1, 0)
+		_, err := conn.Exec("UPDATE messages SET created_at = ? WHERE id = ?", lastMonth.Unix(), "cheap-message")
+		require.NoError(t, err)
+
+		report, err := Generate(ctx, q, Filter{Since: time.Now().AddDate(0, 0, -1)}, 0)
+		require.NoError(t, err)
+		assert.Equal(t, 2, report.Sessions)
+		require.Len(t, report.Models, 1)
+		assert.Equal(t, "claude-3.7-sonnet", report.Models[0].Model)
+		require.Len(t, report.TopTurns, 2)
+
+		report, err = Generate(ctx, q, Filter{Model: "gpt-4.1", Until: time.Now().AddDate(0, 0, -1)}, 0)
+		require.NoError(t, err)
+		assert.Equal(t, 0, report.Sessions)
+		require.Len(t, report.TopTurns, 1)
+		assert.Equal(t, "cheap-message", report.TopTurns[0].MessageID)
+		assert.InDelta(t, 0.5, report.Cost, 1e-9)
+	})
+}